@@ -0,0 +1,135 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hashicorp/cli"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+const DEFAULT_AUDIT_LIMIT = 100
+
+type AuditCommand struct {
+	Command
+}
+
+func BuildAuditCommand(name string, ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		flags := flag.NewFlagSet("audit", flag.ContinueOnError)
+		data := make(map[string]interface{})
+		setDefaultFlags(flags, data)
+		data["file"] = flags.String("file", utility.AuditLogPath(), "audit log path")
+		data["since"] = flags.String("since", "", "only show records since duration (24h) or time (RFC3339)")
+		data["client"] = flags.String("client", "", "only show records from client")
+		data["action"] = flags.String("action", "", "only show records with action prefix (vmnet, portforward.add)")
+		data["request_id"] = flags.String("request-id", "", "only show records for request ID")
+		data["failed"] = flags.Bool("failed", false, "only show records which failed or were denied")
+		data["limit"] = flags.Int("limit", DEFAULT_AUDIT_LIMIT, "maximum number of records to show (0 for all)")
+		data["json"] = flags.Bool("json", false, "output records as JSON lines")
+
+		return &AuditCommand{
+			Command: Command{
+				DefaultConfig: &Config{},
+				Name:          name,
+				Flags:         flags,
+				HelpText:      name + " audit [options]",
+				SynopsisText:  "Query the audit log",
+				UI:            ui,
+				flagdata:      data}}, nil
+	}
+}
+
+func (c *AuditCommand) Run(args []string) int {
+	exitCode := 1
+	err := c.setup(args)
+	if err != nil {
+		c.UI.Error("Failed to initialize: " + err.Error())
+		return exitCode
+	}
+
+	filter := &utility.AuditFilter{
+		Client:    *(c.flagdata["client"].(*string)),
+		Action:    *(c.flagdata["action"].(*string)),
+		RequestId: *(c.flagdata["request_id"].(*string)),
+		Failed:    *(c.flagdata["failed"].(*bool)),
+		Limit:     *(c.flagdata["limit"].(*int)),
+	}
+	if since := *(c.flagdata["since"].(*string)); since != "" {
+		filter.Since, err = parseSince(since)
+		if err != nil {
+			c.UI.Error("Invalid since value: " + err.Error())
+			return exitCode
+		}
+	}
+
+	records, err := utility.ReadAuditLog(*(c.flagdata["file"].(*string)), filter)
+	if err != nil {
+		c.UI.Error("Failed to read audit log: " + err.Error())
+		return exitCode
+	}
+
+	if *(c.flagdata["json"].(*bool)) {
+		for _, record := range records {
+			line, err := json.Marshal(record)
+			if err != nil {
+				c.UI.Error("Failed to encode audit record: " + err.Error())
+				return exitCode
+			}
+			c.UI.Output(string(line))
+		}
+		return 0
+	}
+
+	if len(records) < 1 {
+		c.UI.Info("No audit records found")
+		return 0
+	}
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tREQUEST\tCLIENT\tACTION\tRESULT\tDURATION\tARGUMENTS")
+	for _, record := range records {
+		arguments := ""
+		if len(record.Arguments) > 0 {
+			if encoded, err := json.Marshal(record.Arguments); err == nil {
+				arguments = string(encoded)
+			}
+		}
+		result := record.Result
+		if record.Error != "" {
+			result += ": " + record.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			record.Time.Local().Format(time.RFC3339),
+			record.RequestId,
+			record.Client,
+			record.Action,
+			result,
+			time.Duration(record.DurationMs)*time.Millisecond,
+			arguments)
+	}
+	w.Flush()
+	c.UI.Output(strings.TrimRight(buf.String(), "\n"))
+	return 0
+}
+
+func (c *AuditCommand) setup(args []string) (err error) {
+	return c.defaultSetup(args)
+}
+
+// Parse a since value which may be a duration before
+// the current time or an RFC3339 timestamp
+func parseSince(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
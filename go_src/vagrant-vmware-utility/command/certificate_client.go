@@ -0,0 +1,110 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/cli"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+type CertificateClientCommand struct {
+	Command
+}
+
+func BuildCertificateClientCommand(name string, ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		flags := flag.NewFlagSet("certificate client", flag.ContinueOnError)
+		data := make(map[string]interface{})
+		setDefaultFlags(flags, data)
+		data["name"] = flags.String("name", "", "name of the client")
+		data["role"] = flags.String("role", string(utility.ROLE_READ_ONLY),
+			"role of the client ("+strings.Join(utility.RoleNames(), ", ")+")")
+		data["list"] = flags.Bool("list", false, "list existing client certificates")
+		data["key_type"] = flags.String("key-type", "",
+			"type of key to generate ("+strings.Join(utility.KeyTypeNames(), ", ")+") (default signing certificate key type)")
+		setCertificateFlags(flags, data)
+
+		return &CertificateClientCommand{
+			Command: Command{
+				DefaultConfig: &Config{},
+				Name:          name,
+				Flags:         flags,
+				HelpText:      name + " certificate client -name NAME [-role ROLE]",
+				SynopsisText:  "Generate a named client certificate",
+				UI:            ui,
+				flagdata:      data}}, nil
+	}
+}
+
+func (c *CertificateClientCommand) Run(args []string) int {
+	exitCode := 1
+	err := c.setup(args)
+	if err != nil {
+		c.UI.Error("Failed to initialize: " + err.Error())
+		return exitCode
+	}
+
+	if *(c.flagdata["list"].(*bool)) {
+		identities, err := utility.ClientIdentities()
+		if err != nil {
+			c.UI.Error("Failed to load client certificates: " + err.Error())
+			return exitCode
+		}
+		if len(identities) < 1 {
+			c.UI.Info("No client certificates found")
+			return 0
+		}
+		for _, identity := range identities {
+			c.UI.Output(fmt.Sprintf("%-24s %s", identity.Name, identity.Role))
+		}
+		return 0
+	}
+
+	name := *(c.flagdata["name"].(*string))
+	if name == "" {
+		c.UI.Error("Client name is required")
+		return exitCode
+	}
+	if name == utility.DEFAULT_CLIENT_NAME {
+		c.UI.Error("Client name is reserved: " + name)
+		return exitCode
+	}
+	role, err := utility.ParseRole(*(c.flagdata["role"].(*string)))
+	if err != nil {
+		c.UI.Error("Invalid client role: " + err.Error())
+		return exitCode
+	}
+
+	var keyType utility.KeyType
+	if v := *(c.flagdata["key_type"].(*string)); v != "" {
+		if keyType, err = utility.ParseKeyType(v); err != nil {
+			c.UI.Error("Invalid key type: " + err.Error())
+			return exitCode
+		}
+	}
+
+	paths, err := utility.GenerateClientCertificate(name, role, keyType)
+	if err != nil {
+		c.UI.Error("Client certificate generation failed: " + err.Error())
+		return exitCode
+	}
+
+	c.UI.Info("Client certificate generation complete!")
+	c.UI.Output(" -> " + paths.Certificate)
+	c.UI.Output(" -> " + paths.PrivateKey)
+	return 0
+}
+
+func (c *CertificateClientCommand) setup(args []string) (err error) {
+	err = c.defaultSetup(args)
+	if err != nil {
+		return
+	}
+	c.loadCertificateDirectory()
+	return
+}
@@ -6,6 +6,7 @@ package command
 import (
 	"flag"
 	"path/filepath"
+	"strings"
 
 	"github.com/hashicorp/cli"
 	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
@@ -20,13 +21,17 @@ func BuildCertificateGenerateCommand(name string, ui cli.Ui) cli.CommandFactory
 		flags := flag.NewFlagSet("certificate generate", flag.ContinueOnError)
 		data := make(map[string]interface{})
 		setDefaultFlags(flags, data)
+		setCertificateFlags(flags, data)
+		data["key_type"] = flags.String("key-type", string(utility.KEY_TYPE_RSA),
+			"type of key to generate ("+strings.Join(utility.KeyTypeNames(), ", ")+")")
+		data["split_ca"] = flags.Bool("split-ca", false, "sign certificates with a dedicated certificate authority")
 
 		return &CertificateGenerateCommand{
 			Command: Command{
 				DefaultConfig: &Config{},
 				Name:          name,
 				Flags:         flags,
-				HelpText:      name + " certificate generate",
+				HelpText:      name + " certificate generate [-key-type TYPE] [-split-ca]",
 				SynopsisText:  "Generate required certificates",
 				UI:            ui,
 				flagdata:      data}}, nil
@@ -41,6 +46,12 @@ func (c *CertificateGenerateCommand) Run(args []string) int {
 		return exitCode
 	}
 
+	keyType, err := utility.ParseKeyType(*(c.flagdata["key_type"].(*string)))
+	if err != nil {
+		c.UI.Error("Invalid key type: " + err.Error())
+		return exitCode
+	}
+
 	paths, err := utility.GetCertificatePaths()
 	if err != nil {
 		c.UI.Error("Certificate generation setup failed: " + err.Error())
@@ -49,7 +60,10 @@ func (c *CertificateGenerateCommand) Run(args []string) int {
 
 	certDir := filepath.Dir(paths.Certificate)
 
-	if err := utility.GenerateCertificate(); err != nil {
+	opts := &utility.CertificateOptions{
+		KeyType: keyType,
+		SplitCa: *(c.flagdata["split_ca"].(*bool))}
+	if err := utility.GenerateCertificate(opts); err != nil {
 		c.UI.Error("Certificate generation failed: " + err.Error())
 		return exitCode
 	}
@@ -60,5 +74,10 @@ func (c *CertificateGenerateCommand) Run(args []string) int {
 }
 
 func (c *CertificateGenerateCommand) setup(args []string) (err error) {
-	return c.defaultSetup(args)
+	err = c.defaultSetup(args)
+	if err != nil {
+		return
+	}
+	c.loadCertificateDirectory()
+	return
 }
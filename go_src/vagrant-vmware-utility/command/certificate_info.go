@@ -0,0 +1,181 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"path"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hashicorp/cli"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+type CertificateInfoCommand struct {
+	Command
+}
+
+// Details of an installed certificate
+type certificateInfo struct {
+	Name         string     `json:"name"`
+	Path         string     `json:"path"`
+	Subject      string     `json:"subject"`
+	Issuer       string     `json:"issuer"`
+	KeyType      string     `json:"key_type"`
+	Serial       string     `json:"serial"`
+	Fingerprint  string     `json:"fingerprint"`
+	NotBefore    time.Time  `json:"not_before"`
+	NotAfter     time.Time  `json:"not_after"`
+	OverlapUntil *time.Time `json:"overlap_until,omitempty"`
+	Expiring     bool       `json:"expiring"`
+}
+
+func BuildCertificateInfoCommand(name string, ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		flags := flag.NewFlagSet("certificate info", flag.ContinueOnError)
+		data := make(map[string]interface{})
+		setDefaultFlags(flags, data)
+		data["json"] = flags.Bool("json", false, "output certificate information as JSON")
+		setCertificateFlags(flags, data)
+
+		return &CertificateInfoCommand{
+			Command: Command{
+				DefaultConfig: &Config{},
+				Name:          name,
+				Flags:         flags,
+				HelpText:      name + " certificate info [-json]",
+				SynopsisText:  "Display certificate information",
+				UI:            ui,
+				flagdata:      data}}, nil
+	}
+}
+
+func (c *CertificateInfoCommand) Run(args []string) int {
+	exitCode := 1
+	err := c.setup(args)
+	if err != nil {
+		c.UI.Error("Failed to initialize: " + err.Error())
+		return exitCode
+	}
+
+	infos, err := c.certificates()
+	if err != nil {
+		c.UI.Error("Failed to load certificates: " + err.Error())
+		return exitCode
+	}
+
+	if *(c.flagdata["json"].(*bool)) {
+		out, err := json.MarshalIndent(infos, "", "  ")
+		if err != nil {
+			c.UI.Error("Failed to output certificate information: " + err.Error())
+			return exitCode
+		}
+		c.UI.Output(string(out))
+		return 0
+	}
+
+	for i, info := range infos {
+		if i > 0 {
+			c.UI.Output("")
+		}
+		buf := &bytes.Buffer{}
+		w := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "Name:\t%s\n", info.Name)
+		fmt.Fprintf(w, "Path:\t%s\n", info.Path)
+		fmt.Fprintf(w, "Subject:\t%s\n", info.Subject)
+		fmt.Fprintf(w, "Issuer:\t%s\n", info.Issuer)
+		fmt.Fprintf(w, "Key Type:\t%s\n", info.KeyType)
+		fmt.Fprintf(w, "Serial:\t%s\n", info.Serial)
+		fmt.Fprintf(w, "Fingerprint:\t%s\n", info.Fingerprint)
+		fmt.Fprintf(w, "Not Before:\t%s\n", info.NotBefore.Format(time.RFC3339))
+		fmt.Fprintf(w, "Not After:\t%s\n", info.NotAfter.Format(time.RFC3339))
+		if info.OverlapUntil != nil {
+			fmt.Fprintf(w, "Accepted Until:\t%s\n", info.OverlapUntil.Format(time.RFC3339))
+		}
+		w.Flush()
+		c.UI.Output(strings.TrimRight(buf.String(), "\n"))
+		if info.Expiring {
+			c.UI.Warn(fmt.Sprintf("Certificate expires in %d days, rotate certificates with `certificate rotate`",
+				int(time.Until(info.NotAfter).Hours()/24)))
+		}
+	}
+	return 0
+}
+
+// Collect the server, client and named client certificates
+func (c *CertificateInfoCommand) certificates() ([]*certificateInfo, error) {
+	paths, err := utility.GetCertificatePaths()
+	if err != nil {
+		return nil, err
+	}
+	infos := []*certificateInfo{}
+	for _, entry := range []struct {
+		name string
+		path string
+	}{{"certificate authority", paths.CaCertificate}, {"server", paths.Certificate}, {"client", paths.ClientCertificate}} {
+		// The certificate authority only exists when split from the server certificate
+		if entry.path == paths.CaCertificate && !utility.FileExists(entry.path) {
+			continue
+		}
+		cert, err := utility.ReadCertificate(entry.path)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, newCertificateInfo(entry.name, entry.path, cert))
+	}
+	previous, until, err := utility.ReadPreviousCertificate()
+	if err != nil {
+		return nil, err
+	}
+	if previous != nil {
+		info := newCertificateInfo("previous signing", paths.PreviousCertificate, previous)
+		info.OverlapUntil = &until
+		info.Expiring = false
+		infos = append(infos, info)
+	}
+	basePath := path.Join(path.Dir(paths.Certificate), "clients")
+	identities, err := utility.ClientIdentities()
+	if err != nil {
+		return nil, err
+	}
+	for _, identity := range identities {
+		certPath := path.Join(basePath, identity.Name+".crt")
+		cert, err := utility.ReadCertificate(certPath)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, newCertificateInfo("client "+identity.Name, certPath, cert))
+	}
+	return infos, nil
+}
+
+func newCertificateInfo(name, certPath string, cert *x509.Certificate) *certificateInfo {
+	return &certificateInfo{
+		Name:        name,
+		Path:        certPath,
+		Subject:     cert.Subject.String(),
+		Issuer:      cert.Issuer.String(),
+		KeyType:     string(utility.CertificateKeyType(cert)),
+		Serial:      cert.SerialNumber.Text(16),
+		Fingerprint: utility.CertificateFingerprint(cert),
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+		Expiring:    time.Until(cert.NotAfter) < utility.CERTIFICATE_RENEWAL_WINDOW,
+	}
+}
+
+func (c *CertificateInfoCommand) setup(args []string) (err error) {
+	err = c.defaultSetup(args)
+	if err != nil {
+		return
+	}
+	c.loadCertificateDirectory()
+	return
+}
@@ -0,0 +1,97 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"flag"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/cli"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+type CertificateRotateCommand struct {
+	Command
+}
+
+func BuildCertificateRotateCommand(name string, ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		flags := flag.NewFlagSet("certificate rotate", flag.ContinueOnError)
+		data := make(map[string]interface{})
+		setDefaultFlags(flags, data)
+		data["overlap"] = flags.Duration("overlap", utility.CERTIFICATE_OVERLAP,
+			"time client certificates signed by the replaced certificate remain valid")
+		data["key_type"] = flags.String("key-type", "",
+			"type of key to generate ("+strings.Join(utility.KeyTypeNames(), ", ")+") (default current key type)")
+		data["split_ca"] = flags.Bool("split-ca", false, "sign certificates with a dedicated certificate authority (default current setting)")
+		setCertificateFlags(flags, data)
+
+		return &CertificateRotateCommand{
+			Command: Command{
+				DefaultConfig: &Config{},
+				Name:          name,
+				Flags:         flags,
+				HelpText:      name + " certificate rotate [-overlap DURATION] [-key-type TYPE] [-split-ca]",
+				SynopsisText:  "Replace certificates without interrupting clients",
+				UI:            ui,
+				flagdata:      data}}, nil
+	}
+}
+
+func (c *CertificateRotateCommand) Run(args []string) int {
+	exitCode := 1
+	err := c.setup(args)
+	if err != nil {
+		c.UI.Error("Failed to initialize: " + err.Error())
+		return exitCode
+	}
+
+	overlap := *(c.flagdata["overlap"].(*time.Duration))
+	if overlap < 0 {
+		c.UI.Error("Overlap must not be negative")
+		return exitCode
+	}
+
+	// Options not provided are kept from the current certificates
+	opts := utility.CurrentCertificateOptions()
+	if v := *(c.flagdata["key_type"].(*string)); v != "" {
+		if opts.KeyType, err = utility.ParseKeyType(v); err != nil {
+			c.UI.Error("Invalid key type: " + err.Error())
+			return exitCode
+		}
+	}
+	if !c.isDefaultValue("split_ca") {
+		opts.SplitCa = *(c.flagdata["split_ca"].(*bool))
+	}
+
+	paths, err := utility.GetCertificatePaths()
+	if err != nil {
+		c.UI.Error("Certificate rotation setup failed: " + err.Error())
+		return exitCode
+	}
+
+	if err := utility.RotateCertificate(overlap, opts); err != nil {
+		c.UI.Error("Certificate rotation failed: " + err.Error())
+		return exitCode
+	}
+
+	c.UI.Info("Certificate rotation complete!")
+	c.UI.Output(" -> " + filepath.Dir(paths.Certificate))
+	if overlap > 0 {
+		c.UI.Output("Previous client certificates are accepted for " + overlap.String())
+	}
+	c.UI.Output("The running service will load the new certificates automatically")
+	return 0
+}
+
+func (c *CertificateRotateCommand) setup(args []string) (err error) {
+	err = c.defaultSetup(args)
+	if err != nil {
+		return
+	}
+	c.loadCertificateDirectory()
+	return
+}
@@ -0,0 +1,369 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/driver"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/server"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+// Time allowed for requests to the API
+const CLIENT_REQUEST_TIMEOUT = 5 * time.Minute
+
+// Operations available to the management commands. Operations
+// are performed by the running service or directly by a driver.
+type managementClient interface {
+	Vmnets(ctx context.Context) (*driver.Vmnets, error)
+	AddVmnet(ctx context.Context, vmnet *driver.Vmnet) (*driver.Vmnet, error)
+	UpdateVmnet(ctx context.Context, vmnet *driver.Vmnet) (*driver.Vmnet, error)
+	DeleteVmnet(ctx context.Context, name string) error
+	VerifyVmnet(ctx context.Context) error
+	PortFwds(ctx context.Context, slot string) (*driver.PortFwds, error)
+	AddPortFwd(ctx context.Context, slot int, fwd *driver.PortFwd) error
+	DeletePortFwd(ctx context.Context, slot int, fwd *driver.PortFwd) error
+	PrunePortFwds(ctx context.Context, opts *driver.PruneOptions) (*driver.PruneResult, error)
+	LookupDhcpAddress(ctx context.Context, device, mac string) (string, error)
+	ReserveDhcpAddress(ctx context.Context, slot int, mac, ip string) error
+}
+
+// Base for commands which manage VMware through the utility
+type ClientCommand struct {
+	Command
+}
+
+// Used by client commands to setup the connection options
+func setClientFlags(flags *flag.FlagSet, data map[string]interface{}) {
+	data["local"] = flags.Bool("local", false, "Use the driver directly instead of the running service")
+	data["address"] = flags.String("address", "127.0.0.1", "Address of the API")
+	data["port"] = flags.Int64("port", DEFAULT_RESTAPI_PORT, "Port of the API")
+	data["socket"] = flags.String("socket", "", "Path of Unix socket or name of named pipe of the API (default api socket from configuration)")
+	data["client_cert"] = flags.String("client-cert", "", "Client certificate path (default utility client certificate)")
+	data["client_key"] = flags.String("client-key", "", "Client key path (default utility client key)")
+	data["ca_cert"] = flags.String("ca-cert", "", "Certificate used to verify the API (default utility certificate authority)")
+	data["driver"] = flags.String("driver", "", "Driver to use with -local (simple or advanced)")
+	data["license_override"] = flags.String("license-override", "", "Override VMware license detection with -local (standard or professional)")
+	data["json"] = flags.Bool("json", false, "output as JSON")
+	setCertificateFlags(flags, data)
+}
+
+func (c *ClientCommand) setup(args []string) (err error) {
+	err = c.defaultSetup(args)
+	if err != nil {
+		return
+	}
+	c.loadCertificateDirectory()
+	return
+}
+
+// Build the client for performing operations
+func (c *ClientCommand) client() (managementClient, error) {
+	var rc RestApiConfig
+	if c.DefaultConfig.configFile != nil && c.DefaultConfig.configFile.RestApiConfig != nil {
+		rc = *c.DefaultConfig.configFile.RestApiConfig
+	}
+	if *(c.flagdata["local"].(*bool)) {
+		return c.localClient(&rc)
+	}
+	return c.apiClient(&rc)
+}
+
+func (c *ClientCommand) localClient(rc *RestApiConfig) (managementClient, error) {
+	driverName := c.getConfigValue("driver", rc.Pdriver)
+	if driverName == "vmrest" {
+		driverName = ""
+	}
+	drv, err := c.buildDriver(driverName, c.getConfigValue("license_override", rc.PlicenseOverride), false)
+	if err != nil {
+		return nil, err
+	}
+	if !drv.Validate() {
+		return nil, errors.New("Validation failure: " + drv.ValidationReason())
+	}
+	// Port forwards managed by the internal port forwarding
+	// service can only be modified by the running service
+	internal := c.getConfigBool("internal_port_forwarding", rc.PinternalPortForwarding) || utility.IsBigSurMin()
+	name := "cli"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if auditLog, err := utility.OpenAuditLog(utility.AuditLogPath(), c.logger); err == nil {
+		utility.SetAuditLog(auditLog)
+	} else {
+		c.logger.Warn("failed to open audit log", "error", err)
+	}
+	return &localClient{
+		driver:   driver.NewAuditDriver(drv),
+		internal: internal,
+		identity: &utility.ClientIdentity{
+			Name: server.LOCAL_CLIENT_PREFIX + name,
+			Role: utility.ROLE_ADMIN}}, nil
+}
+
+func (c *ClientCommand) apiClient(rc *RestApiConfig) (managementClient, error) {
+	// The local socket is used when configured unless the
+	// address or port of the API is provided
+	socket := c.getConfigValue("socket", rc.Psocket)
+	if socket != "" && c.isDefaultValue("address") && c.isDefaultValue("port") {
+		c.logger.Debug("using api", "socket", socket)
+		return &apiClient{
+			origin: "http://localhost",
+			client: &http.Client{
+				Timeout: CLIENT_REQUEST_TIMEOUT,
+				Transport: &http.Transport{
+					DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+						return utility.DialSocket(ctx, socket)
+					}}}}, nil
+	}
+	paths, err := utility.GetCertificatePaths()
+	if err != nil {
+		return nil, err
+	}
+	certPath, keyPath := paths.ClientCertificate, paths.ClientKey
+	if v := *(c.flagdata["client_cert"].(*string)); v != "" {
+		certPath = v
+	}
+	if v := *(c.flagdata["client_key"].(*string)); v != "" {
+		keyPath = v
+	}
+	caPath, _ := paths.Signing()
+	if v := *(c.flagdata["ca_cert"].(*string)); v != "" {
+		caPath = v
+	}
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, errors.New("failed to load client certificate - " + err.Error())
+	}
+	ca, err := os.ReadFile(caPath)
+	if err != nil {
+		return nil, errors.New("failed to read CA certificate - " + err.Error())
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("failed to load CA certificate - " + caPath)
+	}
+	address := *(c.flagdata["address"].(*string))
+	port := c.getConfigInt64("port", rc.Pport)
+	origin := fmt.Sprintf("https://%s:%d", address, port)
+	c.logger.Debug("using api", "origin", origin, "certificate", certPath)
+	return &apiClient{
+		origin: origin,
+		client: &http.Client{
+			Timeout: CLIENT_REQUEST_TIMEOUT,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					Certificates: []tls.Certificate{cert},
+					RootCAs:      pool,
+					MinVersion:   tls.VersionTLS12}}}}, nil
+}
+
+// Parse the slot number from a vmnet name or number
+func parseVmnetSlot(value string) (int, error) {
+	slot, err := strconv.Atoi(strings.TrimPrefix(value, driver.VMWARE_NETDEV_PREFIX))
+	if err != nil || slot < 0 {
+		return 0, fmt.Errorf("invalid vmnet '%s'", value)
+	}
+	return slot, nil
+}
+
+// Client for the running service
+type apiClient struct {
+	client *http.Client
+	origin string
+}
+
+func (a *apiClient) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(content)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, a.origin+server.API_V2_PREFIX+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", server.API_CONTENT_TYPE)
+	req.Header.Set("Origin", a.origin)
+	req.Header.Set("X-Requested-With", "Vagrant")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		var errResp server.StandardResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Message == "" {
+			return fmt.Errorf("request failed - %s", resp.Status)
+		}
+		if errResp.ErrorCode == "" {
+			return errors.New(errResp.Message)
+		}
+		return driver.NewError(errResp.ErrorCode, "%s", errResp.Message)
+	}
+	if result == nil || resp.StatusCode == 204 {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func (a *apiClient) Vmnets(ctx context.Context) (*driver.Vmnets, error) {
+	vmnets := &driver.Vmnets{}
+	return vmnets, a.do(ctx, "GET", "/vmnet", nil, vmnets)
+}
+
+func (a *apiClient) AddVmnet(ctx context.Context, vmnet *driver.Vmnet) (*driver.Vmnet, error) {
+	result := &driver.Vmnet{}
+	return result, a.do(ctx, "POST", "/vmnet", vmnet, result)
+}
+
+func (a *apiClient) UpdateVmnet(ctx context.Context, vmnet *driver.Vmnet) (*driver.Vmnet, error) {
+	result := &driver.Vmnet{}
+	return result, a.do(ctx, "PUT", "/vmnet/"+url.PathEscape(vmnet.Name), vmnet, result)
+}
+
+func (a *apiClient) DeleteVmnet(ctx context.Context, name string) error {
+	return a.do(ctx, "DELETE", "/vmnet/"+url.PathEscape(name), nil, nil)
+}
+
+func (a *apiClient) VerifyVmnet(ctx context.Context) error {
+	return a.do(ctx, "POST", "/vmnet/verify", nil, nil)
+}
+
+func (a *apiClient) PortFwds(ctx context.Context, slot string) (*driver.PortFwds, error) {
+	path := "/portforwards"
+	if slot != "" {
+		path = "/vmnet/" + driver.VMWARE_NETDEV_PREFIX + slot + "/portforward"
+	}
+	fwds := &driver.PortFwds{}
+	return fwds, a.do(ctx, "GET", path, nil, fwds)
+}
+
+func (a *apiClient) AddPortFwd(ctx context.Context, slot int, fwd *driver.PortFwd) error {
+	return a.do(ctx, "PUT", fmt.Sprintf("/vmnet/%s%d/portforward", driver.VMWARE_NETDEV_PREFIX, slot),
+		[]*driver.PortFwd{fwd}, nil)
+}
+
+func (a *apiClient) DeletePortFwd(ctx context.Context, slot int, fwd *driver.PortFwd) error {
+	return a.do(ctx, "DELETE", fmt.Sprintf("/vmnet/%s%d/portforward", driver.VMWARE_NETDEV_PREFIX, slot),
+		[]*driver.PortFwd{fwd}, nil)
+}
+
+func (a *apiClient) PrunePortFwds(ctx context.Context, opts *driver.PruneOptions) (*driver.PruneResult, error) {
+	query := url.Values{}
+	query.Set("dry_run", strconv.FormatBool(opts.DryRun))
+	query.Set("prune_missing", strconv.FormatBool(opts.PruneMissing))
+	result := &driver.PruneResult{}
+	return result, a.do(ctx, "DELETE", "/portforwards?"+query.Encode(), nil, result)
+}
+
+func (a *apiClient) LookupDhcpAddress(ctx context.Context, device, mac string) (string, error) {
+	result := map[string]string{}
+	err := a.do(ctx, "GET", "/vmnet/"+url.PathEscape(device)+"/dhcplease/"+url.PathEscape(mac), nil, &result)
+	return result["ip"], err
+}
+
+func (a *apiClient) ReserveDhcpAddress(ctx context.Context, slot int, mac, ip string) error {
+	return a.do(ctx, "PUT", fmt.Sprintf("/vmnet/%s%d/dhcpreserve/%s/%s", driver.VMWARE_NETDEV_PREFIX, slot,
+		url.PathEscape(mac), url.PathEscape(ip)), nil, nil)
+}
+
+// Client using a driver directly
+type localClient struct {
+	driver   driver.Driver
+	identity *utility.ClientIdentity
+	internal bool
+}
+
+func (l *localClient) context(ctx context.Context) context.Context {
+	return utility.ContextWithClient(ctx, l.identity)
+}
+
+func (l *localClient) Vmnets(ctx context.Context) (*driver.Vmnets, error) {
+	return l.driver.Vmnets(l.context(ctx))
+}
+
+func (l *localClient) AddVmnet(ctx context.Context, vmnet *driver.Vmnet) (*driver.Vmnet, error) {
+	return vmnet, l.driver.AddVmnet(l.context(ctx), vmnet)
+}
+
+func (l *localClient) UpdateVmnet(ctx context.Context, vmnet *driver.Vmnet) (*driver.Vmnet, error) {
+	return vmnet, l.driver.UpdateVmnet(l.context(ctx), vmnet)
+}
+
+func (l *localClient) DeleteVmnet(ctx context.Context, name string) error {
+	return l.driver.DeleteVmnet(l.context(ctx), &driver.Vmnet{Name: name})
+}
+
+func (l *localClient) VerifyVmnet(ctx context.Context) error {
+	return l.driver.VerifyVmnet(l.context(ctx))
+}
+
+func (l *localClient) PortFwds(ctx context.Context, slot string) (*driver.PortFwds, error) {
+	if err := l.external(); err != nil {
+		return nil, err
+	}
+	return l.driver.PortFwds(l.context(ctx), slot)
+}
+
+func (l *localClient) AddPortFwd(ctx context.Context, slot int, fwd *driver.PortFwd) error {
+	if err := l.external(); err != nil {
+		return err
+	}
+	fwd.SlotNumber = slot
+	return l.driver.AddPortFwd(l.context(ctx), []*driver.PortFwd{fwd})
+}
+
+func (l *localClient) DeletePortFwd(ctx context.Context, slot int, fwd *driver.PortFwd) error {
+	if err := l.external(); err != nil {
+		return err
+	}
+	fwd.SlotNumber = slot
+	return l.driver.DeletePortFwd(l.context(ctx), []*driver.PortFwd{fwd})
+}
+
+func (l *localClient) PrunePortFwds(ctx context.Context, opts *driver.PruneOptions) (*driver.PruneResult, error) {
+	if err := l.external(); err != nil {
+		return nil, err
+	}
+	return l.driver.PrunePortFwds(l.context(ctx), l.driver.PortFwds, l.driver.DeletePortFwd, opts)
+}
+
+func (l *localClient) LookupDhcpAddress(ctx context.Context, device, mac string) (string, error) {
+	return l.driver.LookupDhcpAddress(l.context(ctx), device, mac)
+}
+
+func (l *localClient) ReserveDhcpAddress(ctx context.Context, slot int, mac, ip string) error {
+	return l.driver.ReserveDhcpAddress(l.context(ctx), slot, mac, ip)
+}
+
+// Port forwards are only managed locally when the internal
+// port forwarding service is not in use
+func (l *localClient) external() error {
+	if l.internal {
+		return driver.NewError(driver.ERROR_CONFLICT,
+			"port forwards are managed by the internal port forwarding service, use the running service")
+	}
+	return nil
+}
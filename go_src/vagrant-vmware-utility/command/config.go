@@ -0,0 +1,431 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/cli"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/server"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+// Flag data names of configuration options which differ
+// from the name used within the configuration file
+var configFlagKeys = map[string]string{
+	"service.runit_dir": "runit_sv",
+}
+
+// Command for validating and displaying the configuration
+type ConfigCommand struct {
+	Command
+	action string
+}
+
+// Configuration option with its effective value
+type configOption struct {
+	Name        string      `json:"name"`
+	Value       interface{} `json:"value"`
+	Source      string      `json:"source,omitempty"`
+	Env         string      `json:"env"`
+	Description string      `json:"description,omitempty"`
+}
+
+// Problem found while validating the configuration
+type configProblem struct {
+	Option  string `json:"option,omitempty"`
+	Message string `json:"message"`
+}
+
+// Result of validating the configuration
+type configValidation struct {
+	Valid    bool             `json:"valid"`
+	Errors   []*configProblem `json:"errors"`
+	Warnings []*configProblem `json:"warnings"`
+}
+
+func BuildConfigCommand(name, action string, ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		flags := flag.NewFlagSet("config "+action, flag.ContinueOnError)
+		data := make(map[string]interface{})
+		setDefaultFlags(flags, data)
+		setRestApiFlags(flags, data)
+		data["runit_sv"] = flags.String("runit-sv", RUNIT_DIR, "Path to runit sv directory")
+		data["init"] = flags.String("init-style", "", "Init in use (systemd, runit, sysv)")
+		data["json"] = flags.Bool("json", false, "Output as JSON")
+
+		var help, synopsis string
+		switch action {
+		case "validate":
+			help, synopsis = " config validate [options]", "Validate the configuration"
+		case "print":
+			help, synopsis = " config print [options]", "Display the effective configuration and the source of each value"
+		case "defaults":
+			help, synopsis = " config defaults [options]", "Display the default configuration"
+		}
+
+		return &ConfigCommand{
+			Command: Command{
+				DefaultConfig: &Config{},
+				Name:          name,
+				Flags:         flags,
+				HelpText:      name + help,
+				SynopsisText:  synopsis,
+				UI:            ui,
+				flagdata:      data},
+			action: action}, nil
+	}
+}
+
+func (c *ConfigCommand) Run(args []string) int {
+	exitCode := 1
+	if err := c.Flags.Parse(args); err != nil {
+		c.UI.Error("Failed to initialize: " + err.Error())
+		return exitCode
+	}
+	if c.Flags.NArg() != 0 {
+		c.UI.Error("Unexpected arguments provided\n\n" + c.Help())
+		return exitCode
+	}
+
+	// The configuration file is loaded directly so failures
+	// are reported instead of halting the command
+	path := c.getConfigValue("config_file", nil)
+	file := &ConfigFile{}
+	var fileErr error
+	if path != "" && c.action != "defaults" {
+		fileErr = decodeConfigFile(path, file)
+	}
+	if fileErr != nil {
+		file = &ConfigFile{}
+	}
+	c.DefaultConfig = c.coreConfig(file)
+	c.DefaultConfig.configFile = file
+	// The configured log outputs are not opened as they may be
+	// invalid and the log file may be in use by the service
+	c.logger = hclog.NewNullLogger()
+
+	switch c.action {
+	case "validate":
+		result := &configValidation{Errors: []*configProblem{}, Warnings: []*configProblem{}}
+		if fileErr != nil {
+			result.Errors = append(result.Errors, fileProblems(fileErr)...)
+		} else {
+			c.validate(c.options(file), result)
+		}
+		result.Valid = len(result.Errors) == 0
+		if c.jsonOutput() {
+			if err := c.outputJson(result); err != nil {
+				c.UI.Error("Failed to output validation: " + err.Error())
+				return exitCode
+			}
+		} else {
+			for _, p := range result.Warnings {
+				c.UI.Warn("Warning: " + p.String())
+			}
+			for _, p := range result.Errors {
+				c.UI.Error("Error: " + p.String())
+			}
+			if result.Valid {
+				c.UI.Info("Configuration is valid!")
+			}
+		}
+		if !result.Valid {
+			return exitCode
+		}
+	case "print":
+		if fileErr != nil {
+			c.UI.Error(fileErr.Error())
+			return exitCode
+		}
+		options := c.options(file)
+		if c.jsonOutput() {
+			out := map[string]interface{}{"config_file": path, "options": options}
+			if err := c.outputJson(out); err != nil {
+				c.UI.Error("Failed to output configuration: " + err.Error())
+				return exitCode
+			}
+			break
+		}
+		if path != "" {
+			c.UI.Output("Configuration file: " + path + "\n")
+		}
+		rows := [][]string{}
+		for _, o := range options {
+			source := o.Source
+			if source == CONFIG_SOURCE_ENV {
+				source = fmt.Sprintf("%s (%s)", source, o.Env)
+			}
+			rows = append(rows, []string{o.Name, formatConfigValue(o.Value), source})
+		}
+		c.outputTable([]string{"OPTION", "VALUE", "SOURCE"}, rows)
+	case "defaults":
+		options := c.defaults()
+		if c.jsonOutput() {
+			if err := c.outputJson(options); err != nil {
+				c.UI.Error("Failed to output defaults: " + err.Error())
+				return exitCode
+			}
+			break
+		}
+		rows := [][]string{}
+		for _, o := range options {
+			rows = append(rows, []string{o.Name, formatConfigValue(o.Value), o.Env, o.Description})
+		}
+		c.outputTable([]string{"OPTION", "DEFAULT", "ENV", "DESCRIPTION"}, rows)
+	}
+	return 0
+}
+
+// Effective value and source of all configuration options. Options
+// are discovered from the configuration file blocks so new options
+// are included automatically.
+func (c *ConfigCommand) options(file *ConfigFile) []*configOption {
+	options := []*configOption{}
+	c.eachOption(file, func(name, key string, current interface{}) {
+		o := &configOption{Name: name, Env: c.envName(key)}
+		switch v := current.(type) {
+		case *string:
+			o.Value, o.Source = c.configValue(key, v)
+		case *bool:
+			o.Value, o.Source = c.configBool(key, v)
+		case *int64:
+			o.Value, o.Source = c.configInt64(key, v)
+		case []string:
+			o.Value, o.Source = c.configArray(key, v)
+		default:
+			return
+		}
+		options = append(options, o)
+	})
+	return options
+}
+
+// Default value of all configuration options
+func (c *ConfigCommand) defaults() []*configOption {
+	options := []*configOption{}
+	c.eachOption(&ConfigFile{}, func(name, key string, current interface{}) {
+		f := c.Flags.Lookup(flagName(key))
+		options = append(options, &configOption{
+			Name:        name,
+			Value:       f.DefValue,
+			Env:         c.envName(key),
+			Description: f.Usage})
+	})
+	return options
+}
+
+// Call the function for each option of the configuration
+// file blocks which can also be set using a flag. The
+// current value is the value set in the configuration file.
+func (c *ConfigCommand) eachOption(file *ConfigFile, fn func(name, key string, current interface{})) {
+	blocks := []struct {
+		name  string
+		value interface{}
+	}{
+		{"core", file.Config},
+		{"api", file.RestApiConfig},
+		{"service", file.ServiceInstallConfig},
+	}
+	for _, block := range blocks {
+		v := reflect.ValueOf(block.value)
+		t := v.Type().Elem()
+		for i := 0; i < t.NumField(); i++ {
+			tag := strings.Split(t.Field(i).Tag.Get("hcl"), ",")[0]
+			if tag == "" {
+				continue
+			}
+			name := block.name + "." + tag
+			key := tag
+			if k, ok := configFlagKeys[name]; ok {
+				key = k
+			}
+			if !c.hasFlag(key) {
+				continue
+			}
+			current := reflect.Zero(t.Field(i).Type).Interface()
+			if !v.IsNil() {
+				current = v.Elem().Field(i).Interface()
+			}
+			fn(name, key, current)
+		}
+	}
+}
+
+// Validate the effective configuration values
+func (c *ConfigCommand) validate(options []*configOption, result *configValidation) {
+	opts := map[string]*configOption{}
+	for _, o := range options {
+		opts[o.Name] = o
+	}
+	str := func(name string) string { return opts[name].Value.(string) }
+	boolean := func(name string) bool { return opts[name].Value.(bool) }
+	invalid := func(name, msg string, args ...interface{}) {
+		result.Errors = append(result.Errors, &configProblem{Option: name, Message: fmt.Sprintf(msg, args...)})
+	}
+	warn := func(name, msg string, args ...interface{}) {
+		result.Warnings = append(result.Warnings, &configProblem{Option: name, Message: fmt.Sprintf(msg, args...)})
+	}
+
+	if level := str("core.level"); level != "" && hclog.LevelFromString(level) == hclog.NoLevel {
+		invalid("core.level", "invalid level '%s' (expected trace, debug, info, warn, error or off)", level)
+	}
+	if boolean("core.debug") && str("core.level") != "" {
+		warn("core.level", "level is ignored when debug is enabled")
+	}
+	switch format := str("core.log_format"); format {
+	case "", LOG_FORMAT_TEXT, LOG_FORMAT_JSON:
+	default:
+		invalid("core.log_format", "invalid log format '%s' (expected %s or %s)", format, LOG_FORMAT_TEXT, LOG_FORMAT_JSON)
+	}
+	if output := str("core.log_output"); output != "" {
+		if !slices.Contains(utility.LogOutputNames(), output) {
+			invalid("core.log_output", "unsupported log output '%s' (valid outputs: %s)", output,
+				strings.Join(utility.LogOutputNames(), ", "))
+		}
+		if str("core.log_file") != "" {
+			warn("core.log_file", "log_file is not used when log_output is set")
+		}
+	}
+	for _, name := range []string{"core.log_max_size", "core.log_max_backups"} {
+		if v := opts[name].Value.(int64); v < 0 {
+			invalid(name, "invalid value %d (must not be negative)", v)
+		}
+	}
+	if age := str("core.log_max_age"); age != "" {
+		if d, err := time.ParseDuration(age); err != nil || d <= 0 {
+			invalid("core.log_max_age", "invalid duration '%s'", age)
+		}
+	}
+	if str("core.log_file") == "" {
+		if boolean("core.log_append") {
+			warn("core.log_append", "log_append has no effect without log_file")
+		}
+		rotation := opts["core.log_max_size"].Value.(int64) != 0 || str("core.log_max_age") != "" ||
+			opts["core.log_max_backups"].Value.(int64) != 0 || boolean("core.log_compress")
+		if rotation {
+			warn("core.log_file", "log rotation options have no effect without log_file")
+		}
+	}
+
+	for _, block := range []string{"api", "service"} {
+		if port := opts[block+".port"].Value.(int64); port < 1 || port > 65535 {
+			invalid(block+".port", "invalid port %d (expected 1-65535)", port)
+		}
+		switch driver := str(block + ".driver"); driver {
+		case "", "simple", "advanced", "vmrest":
+		default:
+			invalid(block+".driver", "unknown driver '%s' (expected simple, advanced or vmrest)", driver)
+		}
+		switch license := str(block + ".license_override"); license {
+		case "", "standard", "professional":
+		default:
+			invalid(block+".license_override", "unknown license '%s' (expected standard or professional)", license)
+		}
+		if _, err := server.ParseTlsVersion(str(block + ".tls_min_version")); err != nil {
+			invalid(block+".tls_min_version", "%s", err)
+		}
+		if dir := str(block + ".certificate_directory"); dir != "" {
+			if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+				invalid(block+".certificate_directory", "'%s' is not a directory", dir)
+			}
+		}
+	}
+
+	if r := str("api.port_range"); r != "" {
+		if _, err := parsePortRange(r); err != nil {
+			invalid("api.port_range", "%s", err)
+		}
+	}
+	if _, err := parseDrainTimeout(str("api.drain_timeout")); err != nil {
+		invalid("api.drain_timeout", "invalid drain timeout '%s' - %s", str("api.drain_timeout"), err)
+	}
+	if dir := str("api.forward_socket_directory"); dir != "" && !filepath.IsAbs(dir) {
+		invalid("api.forward_socket_directory", "'%s' is not an absolute path", dir)
+	}
+	if mode := str("api.socket_mode"); mode != "" {
+		if m, err := strconv.ParseUint(mode, 8, 32); err != nil || m > 0777 {
+			invalid("api.socket_mode", "invalid socket mode '%s'", mode)
+		}
+	}
+	if _, err := utility.ParseRole(str("api.socket_role")); err != nil {
+		invalid("api.socket_role", "%s", err)
+	}
+	if str("api.socket") == "" {
+		if boolean("api.disable_tcp") {
+			invalid("api.disable_tcp", "no listeners are enabled when the TCP listener is disabled without a socket")
+		}
+		for _, name := range []string{"api.socket_mode", "api.socket_group"} {
+			if str(name) != "" {
+				warn(name, "has no effect without socket")
+			}
+		}
+		if len(opts["api.socket_users"].Value.([]string)) > 0 {
+			warn("api.socket_users", "has no effect without socket")
+		}
+	}
+
+	switch init := str("service.init"); init {
+	case "", "systemd", "runit", "sysv":
+	default:
+		invalid("service.init", "unknown init style '%s' (expected systemd, runit or sysv)", init)
+	}
+
+	// The installed service writes the service values to the
+	// api block of the service configuration file
+	for _, name := range []string{"port", "driver", "license_override", "tls_min_version", "certificate_directory"} {
+		a, s := opts["api."+name], opts["service."+name]
+		if a.Source == CONFIG_SOURCE_FILE && s.Source == CONFIG_SOURCE_FILE && a.Value != s.Value {
+			warn("service."+name, "value %s differs from api.%s value %s, the installed service uses %s",
+				formatConfigValue(s.Value), name, formatConfigValue(a.Value), formatConfigValue(s.Value))
+		}
+	}
+}
+
+// Problems reported while loading the configuration file. Each
+// parse diagnostic is reported with its location in the file.
+func fileProblems(err error) []*configProblem {
+	var diags hcl.Diagnostics
+	if !errors.As(err, &diags) {
+		return []*configProblem{{Message: err.Error()}}
+	}
+	problems := []*configProblem{}
+	for _, d := range diags {
+		if d.Severity == hcl.DiagError {
+			problems = append(problems, &configProblem{Message: d.Error()})
+		}
+	}
+	return problems
+}
+
+func (p *configProblem) String() string {
+	if p.Option == "" {
+		return p.Message
+	}
+	return p.Option + ": " + p.Message
+}
+
+func formatConfigValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		if val == "" {
+			return `""`
+		}
+		return val
+	case []string:
+		return "[" + strings.Join(val, ", ") + "]"
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -5,17 +5,21 @@ package command
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"path"
 	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/hashicorp/cli"
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
 )
 
 type ForceExit struct {
@@ -24,12 +28,48 @@ type ForceExit struct {
 
 const ENV_VAR_PREFIX = "VAGRANT_VMWARE_UTILITY_"
 
+// Formats available for log output
+const (
+	LOG_FORMAT_TEXT = "text"
+	LOG_FORMAT_JSON = "json"
+)
+
+// Flag data names which are not the flag name with
+// underscores replaced by dashes
+var flagNames = map[string]string{
+	"init": "init-style",
+}
+
 func Commands(name string, ui cli.Ui) (cmds map[string]cli.CommandFactory) {
 	cmds = map[string]cli.CommandFactory{
 		"api":                  BuildRestApiCommand(name, ui),
+		"audit":                BuildAuditCommand(name, ui),
+		"certificate client":   BuildCertificateClientCommand(name, ui),
 		"certificate generate": BuildCertificateGenerateCommand(name, ui),
+		"certificate info":     BuildCertificateInfoCommand(name, ui),
+		"certificate rotate":   BuildCertificateRotateCommand(name, ui),
+		"config defaults":      BuildConfigCommand(name, "defaults", ui),
+		"config print":         BuildConfigCommand(name, "print", ui),
+		"config validate":      BuildConfigCommand(name, "validate", ui),
+		"dhcp lease":           BuildDhcpCommand(name, "lease", ui),
+		"dhcp reserve":         BuildDhcpCommand(name, "reserve", ui),
+		"doctor":               BuildDoctorCommand(name, ui),
+		"portforward add":      BuildPortForwardCommand(name, "add", ui),
+		"portforward delete":   BuildPortForwardCommand(name, "delete", ui),
+		"portforward list":     BuildPortForwardCommand(name, "list", ui),
+		"portforward prune":    BuildPortForwardCommand(name, "prune", ui),
 		"service install":      BuildServiceInstallCommand(name, ui),
+		"service restart":      BuildServiceRestartCommand(name, ui),
+		"service status":       BuildServiceStatusCommand(name, ui),
 		"service uninstall":    BuildServiceUninstallCommand(name, ui),
+		"settings repair":      BuildSettingsCommand(name, "repair", ui),
+		"settings show":        BuildSettingsCommand(name, "show", ui),
+		"settings verify":      BuildSettingsCommand(name, "verify", ui),
+		"vmnet create":         BuildVmnetCommand(name, "create", ui),
+		"vmnet delete":         BuildVmnetCommand(name, "delete", ui),
+		"vmnet list":           BuildVmnetCommand(name, "list", ui),
+		"vmnet update":         BuildVmnetCommand(name, "update", ui),
+		"vmnet verify":         BuildVmnetCommand(name, "verify", ui),
 	}
 	platformSpecificCommands(name, ui, cmds)
 	return
@@ -48,15 +88,27 @@ type Command struct {
 }
 
 type Config struct {
-	Debug     bool
-	Level     string
-	LogFile   string
-	LogAppend bool
-
-	Pdebug     *bool   `hcl:"debug"`
-	Plevel     *string `hcl:"level"`
-	PlogFile   *string `hcl:"log_file"`
-	PlogAppend *bool   `hcl:"log_append"`
+	Debug         bool
+	Level         string
+	LogFile       string
+	LogAppend     bool
+	LogFormat     string
+	LogOutput     string
+	LogMaxSize    int64
+	LogMaxAge     string
+	LogMaxBackups int64
+	LogCompress   bool
+
+	Pdebug         *bool   `hcl:"debug"`
+	Plevel         *string `hcl:"level"`
+	PlogFile       *string `hcl:"log_file"`
+	PlogAppend     *bool   `hcl:"log_append"`
+	PlogFormat     *string `hcl:"log_format"`
+	PlogOutput     *string `hcl:"log_output"`
+	PlogMaxSize    *int64  `hcl:"log_max_size"`
+	PlogMaxAge     *string `hcl:"log_max_age"`
+	PlogMaxBackups *int64  `hcl:"log_max_backups"`
+	PlogCompress   *bool   `hcl:"log_compress"`
 
 	configFile *ConfigFile
 }
@@ -91,6 +143,12 @@ func setDefaultFlags(flags *flag.FlagSet, c map[string]interface{}) {
 	c["level"] = flags.String("level", "", "logger output level")
 	c["log_file"] = flags.String("log-file", "", "log file path")
 	c["log_append"] = flags.Bool("log-append", false, "append log output to existing log file")
+	c["log_format"] = flags.String("log-format", "", "log output format (text or json)")
+	c["log_output"] = flags.String("log-output", "", "write log output to the system log ("+strings.Join(utility.LogOutputNames(), " or ")+")")
+	c["log_max_size"] = flags.Int64("log-max-size", 0, "rotate log file once it reaches size in megabytes")
+	c["log_max_age"] = flags.String("log-max-age", "", "rotate log file once it reaches age (24h)")
+	c["log_max_backups"] = flags.Int64("log-max-backups", 0, "number of rotated log files to keep (default all)")
+	c["log_compress"] = flags.Bool("log-compress", false, "compress rotated log files")
 }
 
 // Used by commands to process default flags and initialize the logger
@@ -101,21 +159,29 @@ func (c *Command) defaultSetup(args []string) (err error) {
 	}
 
 	c.DefaultConfig = c.loadConfig()
-	c.initlogger(c.DefaultConfig, c.Flags.Name())
+	err = c.initlogger(c.DefaultConfig, c.Flags.Name())
 	return
 }
 
 // Loads the default configuration values
 func (c *Command) loadConfig() *Config {
-	config := &Config{}
 	file := &ConfigFile{}
 	// Check if we have a configuration file to load and do that first
 	path := c.getConfigValue("config_file", nil)
 	if path != "" {
 		c.loadConfigFile(path, file)
-		c.DefaultConfig.configFile = file
 	}
+	config := c.coreConfig(file)
+	if path != "" {
+		config.configFile = file
+	}
+	return config
+}
 
+// Builds the core configuration using values from the
+// command line, environment and configuration file
+func (c *Command) coreConfig(file *ConfigFile) *Config {
+	config := &Config{}
 	var fc Config
 	if file.Config != nil {
 		fc = *file.Config
@@ -125,28 +191,59 @@ func (c *Command) loadConfig() *Config {
 	config.Level = c.getConfigValue("level", fc.Plevel)
 	config.LogFile = c.getConfigValue("log_file", fc.PlogFile)
 	config.LogAppend = c.getConfigBool("log_append", fc.PlogAppend)
+	config.LogFormat = c.getConfigValue("log_format", fc.PlogFormat)
+	config.LogOutput = c.getConfigValue("log_output", fc.PlogOutput)
+	config.LogMaxSize = c.getConfigInt64("log_max_size", fc.PlogMaxSize)
+	config.LogMaxAge = c.getConfigValue("log_max_age", fc.PlogMaxAge)
+	config.LogMaxBackups = c.getConfigInt64("log_max_backups", fc.PlogMaxBackups)
+	config.LogCompress = c.getConfigBool("log_compress", fc.PlogCompress)
 
 	return config
 }
 
+// Used by commands which access certificates to set the certificate directory
+func setCertificateFlags(flags *flag.FlagSet, data map[string]interface{}) {
+	data["certificate_directory"] = flags.String("certificate-directory", "", "Directory containing certificates (default utility certificate directory)")
+}
+
+// Sets the certificate directory using the API configuration
+func (c *Command) loadCertificateDirectory() {
+	var rc RestApiConfig
+	if c.DefaultConfig.configFile != nil && c.DefaultConfig.configFile.RestApiConfig != nil {
+		rc = *c.DefaultConfig.configFile.RestApiConfig
+	}
+	dir := c.getConfigValue("certificate_directory", rc.PcertificateDirectory)
+	if dir != "" {
+		c.logger.Debug("using certificate directory", "path", dir)
+	}
+	utility.SetCertificateDirectory(dir)
+}
+
 // Loads a configuration file and processes root configuration
 func (c *Command) loadConfigFile(path string, config *ConfigFile) {
+	if err := decodeConfigFile(path, config); err != nil {
+		configurationError("%s", err)
+	}
+}
+
+// Reads and parses a configuration file
+func decodeConfigFile(path string, config *ConfigFile) error {
 	f, err := os.Open(path)
 	if err != nil {
-		configurationError("Failed to open configuration - %s", err)
+		return errors.New("Failed to open configuration - " + err.Error())
 	}
 	defer f.Close()
 
 	contents, err := ioutil.ReadAll(f)
 	if err != nil {
-		configurationError("Failed to read configuration - %s", err)
+		return errors.New("Failed to read configuration - " + err.Error())
 	}
 
 	err = hclsimple.Decode(path, contents, nil, config)
 	if err != nil {
-		configurationError("Failed to parse configuration - %s", err)
+		return fmt.Errorf("Failed to parse configuration - %w", err)
 	}
-	return
+	return nil
 }
 
 // Initializes the logger based on configuration values
@@ -158,28 +255,33 @@ func (c *Command) initlogger(n *Config, name string) (err error) {
 	logOpt := &hclog.LoggerOptions{
 		Name:   c.Name,
 		Output: o}
-	if n.LogFile != "" {
-		err = os.MkdirAll(path.Dir(n.LogFile), 0755)
+	switch n.LogFormat {
+	case "", LOG_FORMAT_TEXT:
+	case LOG_FORMAT_JSON:
+		logOpt.JSONFormat = true
+	default:
+		return fmt.Errorf("invalid log format '%s' (expected %s or %s)", n.LogFormat, LOG_FORMAT_TEXT, LOG_FORMAT_JSON)
+	}
+	if n.LogOutput != "" {
+		out, err := utility.OpenLogOutput(n.LogOutput, logOutputName(c.Name))
 		if err != nil {
-			return
+			return err
 		}
-		md := os.O_CREATE | os.O_WRONLY
-		if n.LogAppend {
-			md = md | os.O_APPEND
+		logOpt.Output = out
+		// The system log records the time of each message
+		logOpt.DisableTime = !logOpt.JSONFormat
+	} else if n.LogFile != "" {
+		opts, err := n.logFileOptions()
+		if err != nil {
+			return err
 		}
-		f, err := os.OpenFile(n.LogFile, md, 0644)
+		f, err := utility.OpenLogFile(n.LogFile, opts)
 		if err != nil {
 			return err
 		}
 		logOpt.Output = f
 	}
-	if n.Debug {
-		logOpt.Level = hclog.LevelFromString("trace")
-	} else {
-		if n.Level != "" {
-			logOpt.Level = hclog.LevelFromString(n.Level)
-		}
-	}
+	logOpt.Level = n.logLevel()
 	c.logger = hclog.New(logOpt)
 	if name != "" {
 		c.logger = c.logger.Named(name)
@@ -187,79 +289,197 @@ func (c *Command) initlogger(n *Config, name string) (err error) {
 	return
 }
 
+// Log file options for the configuration
+func (n *Config) logFileOptions() (*utility.LogFileOptions, error) {
+	if n.LogMaxSize < 0 || n.LogMaxBackups < 0 {
+		return nil, errors.New("log file size and backups must not be negative")
+	}
+	opts := &utility.LogFileOptions{
+		Append:     n.LogAppend,
+		MaxSize:    n.LogMaxSize * 1024 * 1024,
+		MaxBackups: int(n.LogMaxBackups),
+		Compress:   n.LogCompress}
+	if n.LogMaxAge != "" {
+		age, err := time.ParseDuration(n.LogMaxAge)
+		if err != nil || age <= 0 {
+			return nil, fmt.Errorf("invalid log file age '%s'", n.LogMaxAge)
+		}
+		opts.MaxAge = age
+	}
+	return opts, nil
+}
+
+// Logger level for the configuration
+func (n *Config) logLevel() hclog.Level {
+	if n.Debug {
+		return hclog.Trace
+	}
+	if level := hclog.LevelFromString(n.Level); level != hclog.NoLevel {
+		return level
+	}
+	return hclog.DefaultLevel
+}
+
+// Logger output is discarded when no log file or level is configured
+func (n *Config) logDiscarded() bool {
+	return !n.Debug && n.Level == "" && n.LogFile == "" && n.LogOutput == ""
+}
+
 // Extracts value from environment variable with
 // configured application prefix
 func (c *Command) envName(name string) string {
 	return ENV_VAR_PREFIX + strings.ToUpper(name)
 }
 
+// Output is requested as JSON by commands with a json flag
+func (c *Command) jsonOutput() bool {
+	return *(c.flagdata["json"].(*bool))
+}
+
+// Print the value as JSON
+func (c *Command) outputJson(v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	c.UI.Output(string(out))
+	return nil
+}
+
+// Print rows as a table
+func (c *Command) outputTable(header []string, rows [][]string) {
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+	c.UI.Output(strings.TrimRight(buf.String(), "\n"))
+}
+
+// Sources of configuration values
+const (
+	CONFIG_SOURCE_FLAG    = "flag"
+	CONFIG_SOURCE_ENV     = "env"
+	CONFIG_SOURCE_FILE    = "file"
+	CONFIG_SOURCE_DEFAULT = "default"
+)
+
 // Gets a boolean configuration value. The current value
 // is supplied from the configuration file
 func (c *Command) getConfigBool(name string, current *bool) bool {
+	val, _ := c.configBool(name, current)
+	return val
+}
+
+// Gets a boolean configuration value and its source
+func (c *Command) configBool(name string, current *bool) (bool, string) {
 	val := *(c.flagdata[name].(*bool))
 	if val && !c.isDefaultValue(name) {
-		return val // cli set value
+		return val, CONFIG_SOURCE_FLAG // cli set value
 	}
 	evar, ok := os.LookupEnv(c.envName(name))
 	if ok && evar != "" {
-		return true // env var set value
+		return true, CONFIG_SOURCE_ENV // env var set value
 	}
 	if current != nil {
-		return *current // config file set value
+		return *current, CONFIG_SOURCE_FILE // config file set value
 	}
-	return val // default value
+	return val, CONFIG_SOURCE_DEFAULT // default value
 }
 
 // Gets a string configuration value. The current value
 // is supplied from the configuration file
 func (c *Command) getConfigValue(name string, current *string) string {
+	val, _ := c.configValue(name, current)
+	return val
+}
+
+// Gets a string configuration value and its source
+func (c *Command) configValue(name string, current *string) (string, string) {
 	val := *(c.flagdata[name].(*string))
 	if val != "" && !c.isDefaultValue(name) {
-		return val // cli set value
+		return val, CONFIG_SOURCE_FLAG // cli set value
 	}
 	eval, ok := os.LookupEnv(c.envName(name))
 	if ok {
-		return eval // env var set value
+		return eval, CONFIG_SOURCE_ENV // env var set value
 	}
 	if current != nil {
-		return *current // config file set value
+		return *current, CONFIG_SOURCE_FILE // config file set value
 	}
-	return val // default value
+	return val, CONFIG_SOURCE_DEFAULT // default value
 }
 
 // Gets a string configuration value. The current value
 // is supplied from the configuration file
 func (c *Command) getConfigArray(name string, current []string) []string {
-	val := c.getConfigValue(name, nil)
+	val, _ := c.configArray(name, current)
+	return val
+}
+
+// Gets an array configuration value and its source
+func (c *Command) configArray(name string, current []string) ([]string, string) {
+	val, source := c.configValue(name, nil)
 	if val == "" {
-		return []string{}
+		if current != nil {
+			return current, CONFIG_SOURCE_FILE // config file set value
+		}
+		return []string{}, CONFIG_SOURCE_DEFAULT
+	}
+	result := []string{}
+	for _, v := range strings.Split(val, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
 	}
-	return strings.Split(val, ",")
+	return result, source
 }
 
 // Gets an int64 configuration value. The current value
 // is supplied from the configuration file
 func (c *Command) getConfigInt64(name string, current *int64) int64 {
+	val, _ := c.configInt64(name, current)
+	return val
+}
+
+// Gets an int64 configuration value and its source
+func (c *Command) configInt64(name string, current *int64) (int64, string) {
 	dval := *(c.flagdata[name].(*int64))
 	if !c.isDefaultValue(name) {
-		return dval // cli set value
+		return dval, CONFIG_SOURCE_FLAG // cli set value
 	}
 	eval, ok := os.LookupEnv(c.envName(name))
 	if ok {
 		i, err := strconv.Atoi(eval)
 		if err == nil {
-			return int64(i) // env var set value
+			return int64(i), CONFIG_SOURCE_ENV // env var set value
 		}
 	}
 	if current != nil {
-		return *current // config file set value
+		return *current, CONFIG_SOURCE_FILE // config file set value
+	}
+	return dval, CONFIG_SOURCE_DEFAULT // default value
+}
+
+// Check if the command defines the given flag
+func (c *Command) hasFlag(name string) bool {
+	_, ok := c.flagdata[name]
+	return ok
+}
+
+// Name of the command line flag for a flag data name
+func flagName(name string) string {
+	if n, ok := flagNames[name]; ok {
+		return n
 	}
-	return dval // default value
+	return strings.ReplaceAll(name, "_", "-")
 }
 
 // Check if the value of a given flag is the default value
 func (c *Command) isDefaultValue(name string) bool {
-	name = strings.ReplaceAll(name, "_", "-")
+	name = flagName(name)
 	uset := false
 	c.Flags.Visit(func(f *flag.Flag) {
 		if f.Name == name {
@@ -11,3 +11,8 @@ import (
 )
 
 func platformSpecificCommands(name string, ui cli.Ui, cmds map[string]cli.CommandFactory) {}
+
+// Name identifying log messages within the system log
+func logOutputName(name string) string {
+	return name
+}
@@ -10,3 +10,9 @@ import (
 func platformSpecificCommands(name string, ui cli.Ui, cmds map[string]cli.CommandFactory) {
 	cmds["service run"] = BuildServiceRunCommand(name, ui)
 }
+
+// Name identifying log messages within the event log. The
+// event source is registered when the service is installed.
+func logOutputName(name string) string {
+	return WINDOWS_SERVICE_NAME
+}
@@ -0,0 +1,117 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+
+	"github.com/hashicorp/cli"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/driver"
+)
+
+// Command for managing DHCP leases and reservations
+type DhcpCommand struct {
+	ClientCommand
+	action string
+}
+
+func BuildDhcpCommand(name, action string, ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		flags := flag.NewFlagSet("dhcp "+action, flag.ContinueOnError)
+		data := make(map[string]interface{})
+		setDefaultFlags(flags, data)
+		setClientFlags(flags, data)
+
+		var help, synopsis string
+		switch action {
+		case "lease":
+			help, synopsis = " dhcp lease [options] VMNET MAC", "Lookup the DHCP lease of a MAC address"
+		case "reserve":
+			help, synopsis = " dhcp reserve [options] VMNET MAC IP", "Reserve an address for a MAC address"
+		}
+
+		return &DhcpCommand{
+			ClientCommand: ClientCommand{
+				Command: Command{
+					DefaultConfig: &Config{},
+					Name:          name,
+					Flags:         flags,
+					HelpText:      name + help,
+					SynopsisText:  synopsis,
+					UI:            ui,
+					flagdata:      data}},
+			action: action}, nil
+	}
+}
+
+func (c *DhcpCommand) Run(args []string) int {
+	exitCode := 1
+	err := c.setup(args)
+	if err != nil {
+		c.UI.Error("Failed to initialize: " + err.Error())
+		return exitCode
+	}
+
+	expected := 2
+	if c.action == "reserve" {
+		expected = 3
+	}
+	if c.Flags.NArg() != expected {
+		c.UI.Error("Invalid arguments provided\n\n" + c.Help())
+		return exitCode
+	}
+	device, mac := c.Flags.Arg(0), c.Flags.Arg(1)
+	slot, err := parseVmnetSlot(device)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return exitCode
+	}
+	device = fmt.Sprintf("%s%d", driver.VMWARE_NETDEV_PREFIX, slot)
+	if _, err := net.ParseMAC(mac); err != nil {
+		c.UI.Error(fmt.Sprintf("invalid MAC address '%s'", mac))
+		return exitCode
+	}
+
+	client, err := c.client()
+	if err != nil {
+		c.UI.Error("Failed to setup client: " + err.Error())
+		return exitCode
+	}
+	ctx := context.Background()
+
+	switch c.action {
+	case "lease":
+		ip, err := client.LookupDhcpAddress(ctx, device, mac)
+		if err != nil {
+			c.UI.Error("Failed to lookup DHCP lease: " + err.Error())
+			return exitCode
+		}
+		if c.jsonOutput() {
+			err = c.outputJson(map[string]string{"vmnet": device, "mac": mac, "ip": ip})
+		} else {
+			c.outputTable([]string{"VMNET", "MAC", "IP"}, [][]string{{device, mac, ip}})
+		}
+		if err != nil {
+			c.UI.Error("Failed to output result: " + err.Error())
+			return exitCode
+		}
+	case "reserve":
+		ip := c.Flags.Arg(2)
+		if net.ParseIP(ip) == nil {
+			c.UI.Error(fmt.Sprintf("invalid IP address '%s'", ip))
+			return exitCode
+		}
+		if err := client.ReserveDhcpAddress(ctx, slot, mac, ip); err != nil {
+			c.UI.Error("Failed to reserve DHCP address: " + err.Error())
+			return exitCode
+		}
+		if !c.jsonOutput() {
+			c.UI.Info(fmt.Sprintf("Reserved %s for %s on %s", ip, mac, device))
+		}
+	}
+	return 0
+}
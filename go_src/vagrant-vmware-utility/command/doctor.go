@@ -0,0 +1,515 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/cli"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/driver"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/service"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+// Results of doctor checks
+const (
+	DOCTOR_PASS = "pass"
+	DOCTOR_WARN = "warn"
+	DOCTOR_FAIL = "fail"
+	DOCTOR_SKIP = "skip"
+)
+
+// Time allowed for checking if the API port is in use by the utility
+const DOCTOR_PORT_TIMEOUT = 5 * time.Second
+
+type DoctorCommand struct {
+	Command
+	checks []*doctorCheck
+}
+
+type doctorCheck struct {
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	Message     string `json:"message"`
+	Path        string `json:"path,omitempty"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+type doctorReport struct {
+	Healthy bool           `json:"healthy"`
+	Checks  []*doctorCheck `json:"checks"`
+}
+
+func BuildDoctorCommand(name string, ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+		data := make(map[string]interface{})
+		setDefaultFlags(flags, data)
+		data["port"] = flags.Int64("port", DEFAULT_RESTAPI_PORT, "Port the API listens on")
+		data["json"] = flags.Bool("json", false, "output report as JSON")
+		setCertificateFlags(flags, data)
+
+		return &DoctorCommand{
+			Command: Command{
+				DefaultConfig: &Config{},
+				Name:          name,
+				Flags:         flags,
+				HelpText:      name + " doctor [options]",
+				SynopsisText:  "Diagnose problems with the VMware installation and utility",
+				UI:            ui,
+				flagdata:      data}}, nil
+	}
+}
+
+func (c *DoctorCommand) Run(args []string) int {
+	exitCode := 1
+	err := c.setup(args)
+	if err != nil {
+		c.UI.Error("Failed to initialize: " + err.Error())
+		return exitCode
+	}
+
+	var rc RestApiConfig
+	if c.DefaultConfig.configFile != nil && c.DefaultConfig.configFile.RestApiConfig != nil {
+		rc = *c.DefaultConfig.configFile.RestApiConfig
+	}
+	port := c.getConfigInt64("port", rc.Pport)
+
+	ctx := context.Background()
+	paths := c.checkVmwarePaths()
+	if paths != nil {
+		c.checkInstallation()
+	} else {
+		c.skip("installation", "VMware paths could not be loaded")
+	}
+	c.checkCertificates()
+	var netF *utility.VMWareNetworkingFile
+	if paths != nil {
+		netF = c.checkNetworkingFile(paths)
+		c.checkVmnetCli(ctx, paths)
+		c.checkVmrest(paths)
+	} else {
+		c.skip("networking file", "VMware paths could not be loaded")
+		c.skip("vmnet-cli", "VMware paths could not be loaded")
+		c.skip("vmrest", "VMware paths could not be loaded")
+	}
+	c.checkRouting(netF)
+	c.checkPort(int(port))
+
+	report := &doctorReport{Healthy: true, Checks: c.checks}
+	for _, check := range c.checks {
+		if check.Status == DOCTOR_FAIL {
+			report.Healthy = false
+		}
+	}
+	if report.Healthy {
+		exitCode = 0
+	}
+
+	if *(c.flagdata["json"].(*bool)) {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			c.UI.Error("Failed to encode report: " + err.Error())
+			return 1
+		}
+		c.UI.Output(string(out))
+		return exitCode
+	}
+
+	for _, check := range c.checks {
+		line := fmt.Sprintf("[%s] %s: %s", strings.ToUpper(check.Status), check.Name, check.Message)
+		switch check.Status {
+		case DOCTOR_FAIL:
+			c.UI.Error(line)
+		case DOCTOR_WARN:
+			c.UI.Warn(line)
+		default:
+			c.UI.Output(line)
+		}
+		if check.Path != "" {
+			c.UI.Output("       path: " + check.Path)
+		}
+		if check.Remediation != "" {
+			c.UI.Output("       -> " + check.Remediation)
+		}
+	}
+	if report.Healthy {
+		c.UI.Info("\nNo problems detected")
+	} else {
+		c.UI.Error("\nProblems detected")
+	}
+	return exitCode
+}
+
+func (c *DoctorCommand) setup(args []string) (err error) {
+	err = c.defaultSetup(args)
+	if err != nil {
+		return
+	}
+	c.loadCertificateDirectory()
+	return
+}
+
+func (c *DoctorCommand) add(check *doctorCheck) {
+	c.logger.Debug("check complete", "name", check.Name, "status", check.Status,
+		"message", check.Message, "path", check.Path)
+	c.checks = append(c.checks, check)
+}
+
+func (c *DoctorCommand) skip(name, reason string) {
+	c.add(&doctorCheck{Name: name, Status: DOCTOR_SKIP, Message: reason})
+}
+
+// Locate the VMware installation and required executables
+func (c *DoctorCommand) checkVmwarePaths() *utility.VmwarePaths {
+	name := "vmware paths"
+	paths, err := utility.LoadVmwarePaths(c.logger)
+	if err != nil {
+		c.add(&doctorCheck{
+			Name:        name,
+			Status:      DOCTOR_FAIL,
+			Message:     "Failed to locate VMware installation - " + err.Error(),
+			Remediation: "Install VMware Workstation or VMware Fusion"})
+		return nil
+	}
+	required := []string{paths.Vmx, paths.Vmrun, paths.VmnetCli, paths.Vnetlib}
+	for _, checkPath := range required {
+		if checkPath != "" && !utility.FileExists(checkPath) {
+			c.add(&doctorCheck{
+				Name:        name,
+				Status:      DOCTOR_FAIL,
+				Message:     "Required VMware executable is missing",
+				Path:        checkPath,
+				Remediation: "Re-install VMware"})
+			return nil
+		}
+	}
+	c.add(&doctorCheck{
+		Name:    name,
+		Status:  DOCTOR_PASS,
+		Message: "VMware installation located",
+		Path:    paths.InstallDir})
+	return paths
+}
+
+// Validate ownership and permissions of the VMware installation
+func (c *DoctorCommand) checkInstallation() {
+	name := "installation"
+	b, err := driver.NewBaseDriver(nil, "", c.logger)
+	if err != nil {
+		c.add(&doctorCheck{
+			Name:        name,
+			Status:      DOCTOR_FAIL,
+			Message:     "Failed to load VMware installation information - " + err.Error(),
+			Remediation: "Verify VMware starts successfully and re-install VMware if it does not"})
+		return
+	}
+	if !b.Validate() {
+		c.add(&doctorCheck{
+			Name:    name,
+			Status:  DOCTOR_FAIL,
+			Message: "Invalid ownership/permissions detected for VMware installation",
+			Path:    b.ValidationPath(),
+			Remediation: "Path must be owned by root and must not be writable by group " +
+				"or others. Re-install VMware to restore permissions."})
+		return
+	}
+	info, err := b.VmwareInfo(context.Background())
+	if err != nil {
+		c.add(&doctorCheck{Name: name, Status: DOCTOR_PASS, Message: "VMware installation is valid"})
+		return
+	}
+	c.add(&doctorCheck{
+		Name:   name,
+		Status: DOCTOR_PASS,
+		Message: fmt.Sprintf("VMware %s %s (build %s, license %s) is valid",
+			info.Product, info.Version, info.Build, info.License)})
+}
+
+// Check certificates used by the API exist and are not expired
+func (c *DoctorCommand) checkCertificates() {
+	name := "certificates"
+	remediation := fmt.Sprintf("Run `%s certificate generate` and restart the service", c.Name)
+	paths, err := utility.GetCertificatePaths()
+	if err != nil {
+		c.add(&doctorCheck{
+			Name:        name,
+			Status:      DOCTOR_FAIL,
+			Message:     "Failed to determine certificate paths - " + err.Error(),
+			Remediation: remediation})
+		return
+	}
+	for _, checkPath := range []string{paths.Certificate, paths.PrivateKey, paths.ClientCertificate, paths.ClientKey} {
+		if !utility.FileExists(checkPath) {
+			c.add(&doctorCheck{
+				Name:        name,
+				Status:      DOCTOR_FAIL,
+				Message:     "Certificate file is missing",
+				Path:        checkPath,
+				Remediation: remediation})
+			return
+		}
+	}
+	checkPaths := []string{paths.Certificate, paths.ClientCertificate}
+	if utility.FileExists(paths.CaCertificate) {
+		checkPaths = append(checkPaths, paths.CaCertificate)
+	}
+	var expires time.Time
+	for _, checkPath := range checkPaths {
+		cert, err := utility.ReadCertificate(checkPath)
+		if err != nil {
+			c.add(&doctorCheck{
+				Name:        name,
+				Status:      DOCTOR_FAIL,
+				Message:     "Invalid certificate - " + err.Error(),
+				Path:        checkPath,
+				Remediation: remediation})
+			return
+		}
+		if time.Now().After(cert.NotAfter) {
+			c.add(&doctorCheck{
+				Name:        name,
+				Status:      DOCTOR_FAIL,
+				Message:     "Certificate expired on " + cert.NotAfter.Local().Format(time.RFC3339),
+				Path:        checkPath,
+				Remediation: remediation})
+			return
+		}
+		if expires.IsZero() || cert.NotAfter.Before(expires) {
+			expires = cert.NotAfter
+		}
+	}
+	check := &doctorCheck{
+		Name:    name,
+		Status:  DOCTOR_PASS,
+		Message: "Certificates valid until " + expires.Local().Format(time.RFC3339)}
+	if time.Until(expires) < utility.CERTIFICATE_RENEWAL_WINDOW {
+		check.Status = DOCTOR_WARN
+		check.Message = "Certificates expire on " + expires.Local().Format(time.RFC3339)
+		check.Remediation = fmt.Sprintf("Rotate the certificates: %s certificate rotate", c.Name)
+	}
+	c.add(check)
+}
+
+// Parse the VMware networking file
+func (c *DoctorCommand) checkNetworkingFile(paths *utility.VmwarePaths) *utility.VMWareNetworkingFile {
+	name := "networking file"
+	if runtime.GOOS == "windows" {
+		c.skip(name, "Networking configuration is stored in the registry on Windows")
+		return nil
+	}
+	netF, err := utility.LoadNetworkingFile(paths.Networking, c.logger)
+	if err != nil {
+		c.add(&doctorCheck{
+			Name:        name,
+			Status:      DOCTOR_FAIL,
+			Message:     "Failed to parse networking file - " + err.Error(),
+			Path:        paths.Networking,
+			Remediation: "Restore the default networks using the VMware network editor"})
+		return nil
+	}
+	c.add(&doctorCheck{
+		Name:   name,
+		Status: DOCTOR_PASS,
+		Message: fmt.Sprintf("Parsed %d devices and %d port forwards",
+			len(netF.Devices), len(netF.PortFwds)),
+		Path: paths.Networking})
+	return netF
+}
+
+// Check the VMware networking services are running
+func (c *DoctorCommand) checkVmnetCli(ctx context.Context, paths *utility.VmwarePaths) {
+	name := "vmnet-cli"
+	if paths.VmnetCli == "" {
+		c.skip(name, "vmnet-cli is not available on this platform")
+		return
+	}
+	services, err := service.NewVmwareServices(paths.Services, c.logger)
+	if err != nil {
+		c.add(&doctorCheck{
+			Name:        name,
+			Status:      DOCTOR_FAIL,
+			Message:     "Failed to setup VMware services - " + err.Error(),
+			Path:        paths.Services,
+			Remediation: "Re-install VMware"})
+		return
+	}
+	vmnet, err := service.NewVmnetCli(paths.VmnetCli, services, c.logger)
+	if err != nil {
+		c.add(&doctorCheck{
+			Name:        name,
+			Status:      DOCTOR_FAIL,
+			Message:     err.Error(),
+			Path:        paths.VmnetCli,
+			Remediation: "Re-install VMware"})
+		return
+	}
+	if !vmnet.Status(ctx) {
+		c.add(&doctorCheck{
+			Name:        name,
+			Status:      DOCTOR_FAIL,
+			Message:     "VMware networking services are not running",
+			Path:        paths.VmnetCli,
+			Remediation: fmt.Sprintf("Start the networking services with `%s --start`", paths.VmnetCli)})
+		return
+	}
+	c.add(&doctorCheck{Name: name, Status: DOCTOR_PASS, Message: "VMware networking services are running"})
+}
+
+// Check the vmrest version satisfies the version constraint
+func (c *DoctorCommand) checkVmrest(paths *utility.VmwarePaths) {
+	name := "vmrest"
+	if paths.Vmrest == "" {
+		c.skip(name, "vmrest is not available on this platform")
+		return
+	}
+	v, err := driver.ValidateVmrest(paths.Vmrest, c.logger)
+	if err != nil {
+		message := err.Error()
+		if v != "" {
+			message = fmt.Sprintf("%s (detected %s, requires %s)", message, v, driver.VMREST_VERSION_CONSTRAINT)
+		}
+		c.add(&doctorCheck{
+			Name:        name,
+			Status:      DOCTOR_WARN,
+			Message:     message,
+			Path:        paths.Vmrest,
+			Remediation: "The vmrest driver is unavailable. Upgrade VMware to enable it."})
+		return
+	}
+	c.add(&doctorCheck{
+		Name:    name,
+		Status:  DOCTOR_PASS,
+		Message: fmt.Sprintf("vmrest %s satisfies %s", v, driver.VMREST_VERSION_CONSTRAINT)})
+}
+
+// Check host only networks do not conflict with host routes or each other
+func (c *DoctorCommand) checkRouting(netF *utility.VMWareNetworkingFile) {
+	name := "routing"
+	if netF == nil {
+		c.skip(name, "No VMware networks available to check")
+		return
+	}
+	table, err := utility.LoadRoutingTable(nil, c.logger)
+	if err != nil {
+		c.add(&doctorCheck{
+			Name:    name,
+			Status:  DOCTOR_WARN,
+			Message: "Failed to load host routing table - " + err.Error()})
+		return
+	}
+	conflicts := []string{}
+	networks := map[string]*net.IPNet{}
+	for _, device := range netF.Devices {
+		if device.HostonlySubnet == "" {
+			continue
+		}
+		mask := net.IPMask(net.ParseIP(device.HostonlyNetmask).To4())
+		ones, bits := mask.Size()
+		if bits == 0 {
+			continue
+		}
+		network := fmt.Sprintf("%s/%d", device.HostonlySubnet, ones)
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			continue
+		}
+		for otherName, other := range networks {
+			if utility.NetworksOverlap(ipNet, other) {
+				conflicts = append(conflicts, fmt.Sprintf("%s (%s) overlaps %s (%s)",
+					device.Name, network, otherName, other))
+			}
+		}
+		networks[device.Name] = ipNet
+		for _, rDev := range table.Overlapping(network) {
+			if c.vmwareInterface(device.Name, rDev, network) {
+				continue
+			}
+			conflicts = append(conflicts, fmt.Sprintf("%s (%s) overlaps host interface %s (%s)",
+				device.Name, network, rDev.Name, rDev.Network()))
+		}
+	}
+	if len(conflicts) > 0 {
+		c.add(&doctorCheck{
+			Name:        name,
+			Status:      DOCTOR_FAIL,
+			Message:     "Network conflicts detected: " + strings.Join(conflicts, ", "),
+			Remediation: "Change the subnet of the conflicting VMware network or host interface"})
+		return
+	}
+	c.add(&doctorCheck{
+		Name:    name,
+		Status:  DOCTOR_PASS,
+		Message: fmt.Sprintf("No conflicts detected for %d networks", len(networks))})
+}
+
+// Check if the host interface is the adapter VMware created for the device
+func (c *DoctorCommand) vmwareInterface(device string, rDev *utility.RoutingDevice, network string) bool {
+	if strings.Contains(strings.ToLower(rDev.Name), strings.ToLower(device)) {
+		return true
+	}
+	// Newer versions of Fusion use bridge interfaces for vmnets
+	return runtime.GOOS == "darwin" && strings.HasPrefix(rDev.Name, "bridge") && rDev.Match(network)
+}
+
+// Check the API port is available or in use by the utility
+func (c *DoctorCommand) checkPort(port int) {
+	name := "api port"
+	if utility.PortAvailable("tcp", port) {
+		c.add(&doctorCheck{
+			Name:    name,
+			Status:  DOCTOR_PASS,
+			Message: fmt.Sprintf("Port %d is available", port)})
+		return
+	}
+	if c.utilityListening(port) {
+		c.add(&doctorCheck{
+			Name:    name,
+			Status:  DOCTOR_PASS,
+			Message: fmt.Sprintf("Port %d is in use by the vagrant-vmware-utility service", port)})
+		return
+	}
+	c.add(&doctorCheck{
+		Name:    name,
+		Status:  DOCTOR_FAIL,
+		Message: fmt.Sprintf("Port %d is in use by another process", port),
+		Remediation: "Stop the process using the port or configure a different port " +
+			"for the utility and Vagrant"})
+}
+
+// Check if the process listening on the port presents the
+// utility certificate
+func (c *DoctorCommand) utilityListening(port int) bool {
+	paths, err := utility.GetCertificatePaths()
+	if err != nil {
+		return false
+	}
+	cert, err := utility.ReadCertificate(paths.Certificate)
+	if err != nil {
+		return false
+	}
+	dialer := &net.Dialer{Timeout: DOCTOR_PORT_TIMEOUT}
+	conn, err := tls.DialWithDialer(dialer, "tcp", fmt.Sprintf("127.0.0.1:%d", port),
+		&tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		c.logger.Debug("failed to connect to api port", "port", port, "error", err)
+		return false
+	}
+	defer conn.Close()
+	for _, peer := range conn.ConnectionState().PeerCertificates {
+		if bytes.Equal(peer.Raw, cert.Raw) {
+			return true
+		}
+	}
+	return false
+}
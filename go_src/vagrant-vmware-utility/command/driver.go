@@ -0,0 +1,57 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/driver"
+)
+
+// Build the driver used for managing VMware. When vmrest is
+// enabled an upgrade to the vmrest driver is attempted unless
+// a specific driver was requested.
+func (c *Command) buildDriver(driverName, licenseOverride string, vmrest bool) (drv driver.Driver, err error) {
+	// Start with building the base driver
+	b, err := driver.NewBaseDriver(nil, licenseOverride, c.logger)
+	if err != nil {
+		c.logger.Error("base driver setup failure", "error", err)
+		return
+	}
+
+	// Allow the user to define the driver. It may not work, but they're the boss
+	attempt_vmrest := vmrest
+	switch driverName {
+	case "simple":
+		c.logger.Warn("creating simple driver via user request")
+		drv, err = driver.NewSimpleDriver(nil, b, c.logger)
+		attempt_vmrest = false
+	case "advanced":
+		c.logger.Warn("creating advanced driver via user request")
+		drv, err = driver.NewAdvancedDriver(nil, b, c.logger)
+		attempt_vmrest = false
+	default:
+		if driverName != "" {
+			c.logger.Warn("unknown driver name provided, detecting appropriate driver", "name", driverName)
+		}
+		drv, err = driver.CreateDriver(nil, b, c.logger)
+	}
+	if err != nil {
+		c.logger.Error("driver setup failure", "error", err)
+		return nil, errors.New("failed to setup Vagrant VMware driver - " + err.Error())
+	}
+
+	// Now that we are setup, we can attempt to upgrade the driver to the
+	// vmrest driver if possible or requested
+	if attempt_vmrest {
+		c.logger.Info("attempting to upgrade to vmrest driver")
+		drv, err = driver.NewVmrestDriver(context.Background(), drv, c.logger)
+		if err != nil {
+			c.logger.Error("failed to upgrade to vmrest driver", "error", err)
+			return
+		}
+	}
+	return
+}
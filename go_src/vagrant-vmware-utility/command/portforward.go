@@ -0,0 +1,247 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/cli"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/driver"
+)
+
+// Command for managing port forwards
+type PortForwardCommand struct {
+	ClientCommand
+	action string
+}
+
+func BuildPortForwardCommand(name, action string, ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		flags := flag.NewFlagSet("portforward "+action, flag.ContinueOnError)
+		data := make(map[string]interface{})
+		setDefaultFlags(flags, data)
+		setClientFlags(flags, data)
+
+		var help, synopsis string
+		switch action {
+		case "list":
+			help, synopsis = " portforward list [options] [VMNET]", "List port forwards"
+		case "add":
+			help, synopsis = " portforward add [options] VMNET", "Add a port forward"
+			setPortFwdFlags(flags, data)
+			data["guest_ip"] = flags.String("guest-ip", "", "Guest address to forward to")
+			data["guest_port"] = flags.Int("guest-port", 0, "Guest port to forward to")
+			data["description"] = flags.String("description", "", "Description of the port forward")
+		case "delete":
+			help, synopsis = " portforward delete [options] VMNET", "Delete a port forward"
+			setPortFwdFlags(flags, data)
+		case "prune":
+			help, synopsis = " portforward prune [options]", "Remove port forwards of VMs which are no longer running"
+			data["dry_run"] = flags.Bool("dry-run", false, "Report port forwards which would be pruned without removing them")
+			data["prune_missing"] = flags.Bool("prune-missing", true, "Prune port forwards of VMs which no longer exist")
+		}
+
+		return &PortForwardCommand{
+			ClientCommand: ClientCommand{
+				Command: Command{
+					DefaultConfig: &Config{},
+					Name:          name,
+					Flags:         flags,
+					HelpText:      name + help,
+					SynopsisText:  synopsis,
+					UI:            ui,
+					flagdata:      data}},
+			action: action}, nil
+	}
+}
+
+func setPortFwdFlags(flags *flag.FlagSet, data map[string]interface{}) {
+	data["host_port"] = flags.Int("host-port", 0, "Host port of the port forward")
+	data["protocol"] = flags.String("protocol", "tcp", "Protocol of the port forward (tcp or udp)")
+}
+
+func (c *PortForwardCommand) Run(args []string) int {
+	exitCode := 1
+	err := c.setup(args)
+	if err != nil {
+		c.UI.Error("Failed to initialize: " + err.Error())
+		return exitCode
+	}
+
+	var slot int
+	switch c.action {
+	case "list":
+		if c.Flags.NArg() > 1 {
+			c.UI.Error("Unexpected arguments provided\n\n" + c.Help())
+			return exitCode
+		}
+		if c.Flags.NArg() == 1 {
+			if slot, err = parseVmnetSlot(c.Flags.Arg(0)); err != nil {
+				c.UI.Error(err.Error())
+				return exitCode
+			}
+		}
+	case "add", "delete":
+		if c.Flags.NArg() != 1 {
+			c.UI.Error("Name of the vmnet device is required\n\n" + c.Help())
+			return exitCode
+		}
+		if slot, err = parseVmnetSlot(c.Flags.Arg(0)); err != nil {
+			c.UI.Error(err.Error())
+			return exitCode
+		}
+	default:
+		if c.Flags.NArg() != 0 {
+			c.UI.Error("Unexpected arguments provided\n\n" + c.Help())
+			return exitCode
+		}
+	}
+
+	var fwd *driver.PortFwd
+	if c.action == "add" || c.action == "delete" {
+		if fwd, err = c.portFwd(); err != nil {
+			c.UI.Error(err.Error())
+			return exitCode
+		}
+	}
+
+	client, err := c.client()
+	if err != nil {
+		c.UI.Error("Failed to setup client: " + err.Error())
+		return exitCode
+	}
+	ctx := context.Background()
+
+	switch c.action {
+	case "list":
+		filter := ""
+		if c.Flags.NArg() == 1 {
+			filter = strconv.Itoa(slot)
+		}
+		fwds, err := client.PortFwds(ctx, filter)
+		if err != nil {
+			c.UI.Error("Failed to list port forwards: " + err.Error())
+			return exitCode
+		}
+		if c.jsonOutput() {
+			err = c.outputJson(fwds)
+			break
+		}
+		rows := [][]string{}
+		for _, f := range fwds.PortForwards {
+			host := strconv.Itoa(f.Port)
+			if f.IsPath() {
+				host = f.HostPath
+			}
+			guest := ""
+			if f.Guest != nil {
+				guest = fmt.Sprintf("%s:%d", f.Guest.Ip, f.Guest.Port)
+			}
+			rows = append(rows, []string{f.Protocol, host, guest, f.Description})
+		}
+		c.outputTable([]string{"PROTOCOL", "HOST", "GUEST", "DESCRIPTION"}, rows)
+	case "add":
+		if err = client.AddPortFwd(ctx, slot, fwd); err != nil {
+			c.UI.Error("Failed to add port forward: " + err.Error())
+			return exitCode
+		}
+		if c.jsonOutput() {
+			err = c.outputJson(fwd)
+			break
+		}
+		c.UI.Info(fmt.Sprintf("Port forward %s/%d added", fwd.Protocol, fwd.Port))
+	case "delete":
+		// Deletion requires the complete port forward so
+		// the existing port forward is used
+		if fwd, err = c.existing(ctx, client, slot, fwd); err != nil {
+			c.UI.Error("Failed to delete port forward: " + err.Error())
+			return exitCode
+		}
+		if err = client.DeletePortFwd(ctx, slot, fwd); err != nil {
+			c.UI.Error("Failed to delete port forward: " + err.Error())
+			return exitCode
+		}
+		if !c.jsonOutput() {
+			c.UI.Info(fmt.Sprintf("Port forward %s/%d deleted", fwd.Protocol, fwd.Port))
+		}
+	case "prune":
+		result, err := client.PrunePortFwds(ctx, &driver.PruneOptions{
+			DryRun:       *(c.flagdata["dry_run"].(*bool)),
+			PruneMissing: *(c.flagdata["prune_missing"].(*bool))})
+		if err != nil {
+			c.UI.Error("Failed to prune port forwards: " + err.Error())
+			return exitCode
+		}
+		if c.jsonOutput() {
+			err = c.outputJson(result)
+			break
+		}
+		rows := [][]string{}
+		for _, entries := range []struct {
+			state   string
+			entries []*driver.PortFwdPruneEntry
+		}{{"pruned", result.Pruned}, {"kept", result.Kept}} {
+			state := entries.state
+			if result.DryRun && state == "pruned" {
+				state = "would prune"
+			}
+			for _, e := range entries.entries {
+				rows = append(rows, []string{state, e.Protocol, strconv.Itoa(e.Port), e.Description, e.Reason})
+			}
+		}
+		c.outputTable([]string{"STATE", "PROTOCOL", "PORT", "DESCRIPTION", "REASON"}, rows)
+	}
+	if err != nil {
+		c.UI.Error("Failed to output result: " + err.Error())
+		return exitCode
+	}
+	return 0
+}
+
+// Build the port forward from the provided flags
+func (c *PortForwardCommand) portFwd() (*driver.PortFwd, error) {
+	fwd := &driver.PortFwd{
+		Port:     *(c.flagdata["host_port"].(*int)),
+		Protocol: *(c.flagdata["protocol"].(*string)),
+	}
+	if fwd.Port < 1 || fwd.Port > 65535 {
+		return nil, errors.New("Valid host port is required")
+	}
+	if fwd.Protocol != "tcp" && fwd.Protocol != "udp" {
+		return nil, fmt.Errorf("invalid protocol '%s' (expected tcp or udp)", fwd.Protocol)
+	}
+	if c.action == "add" {
+		fwd.Description = *(c.flagdata["description"].(*string))
+		fwd.Guest = &driver.PortFwdGuest{
+			Ip:   *(c.flagdata["guest_ip"].(*string)),
+			Port: *(c.flagdata["guest_port"].(*int)),
+		}
+		if fwd.Guest.Ip == "" {
+			return nil, errors.New("Guest address is required")
+		}
+		if fwd.Guest.Port < 1 || fwd.Guest.Port > 65535 {
+			return nil, errors.New("Valid guest port is required")
+		}
+	}
+	return fwd, nil
+}
+
+// Find the existing port forward on the device matching the port forward
+func (c *PortForwardCommand) existing(ctx context.Context, client managementClient, slot int, fwd *driver.PortFwd) (*driver.PortFwd, error) {
+	fwds, err := client.PortFwds(ctx, strconv.Itoa(slot))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fwds.PortForwards {
+		if f.Port == fwd.Port && f.Protocol == fwd.Protocol {
+			return f, nil
+		}
+	}
+	return nil, driver.NewError(driver.ERROR_NOT_FOUND, "port forward %s/%d does not exist on %s%d",
+		fwd.Protocol, fwd.Port, driver.VMWARE_NETDEV_PREFIX, slot)
+}
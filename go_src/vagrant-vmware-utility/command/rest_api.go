@@ -4,10 +4,16 @@
 package command
 
 import (
-	"context"
 	"errors"
 	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/cli"
 	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/driver"
@@ -18,6 +24,9 @@ import (
 
 var Shutdown sync.Cond
 
+// Prevents concurrent configuration reloads
+var reloadLock sync.Mutex
+
 const DEFAULT_RESTAPI_PORT = 9922
 
 // Command for starting the REST API
@@ -32,11 +41,33 @@ type RestApiConfig struct {
 	InternalPortForwarding bool
 	LicenseOverride        string
 	LogDisplay             bool
+	PortRange              string
+	DrainTimeout           string
+	Socket                 string
+	SocketMode             string
+	SocketGroup            string
+	SocketUsers            []string
+	SocketRole             string
+	DisableTcp             bool
+	TlsMinVersion          string
+	CertificateDirectory   string
+	ForwardSocketDirectory string
 
-	Pport                   *int64  `hcl:"port"`
-	Pdriver                 *string `hcl:"driver"`
-	PinternalPortForwarding *bool   `hcl:"internal_port_forwarding"`
-	PlicenseOverride        *string `hcl:"license_override"`
+	Pport                   *int64   `hcl:"port"`
+	Pdriver                 *string  `hcl:"driver"`
+	PinternalPortForwarding *bool    `hcl:"internal_port_forwarding"`
+	PlicenseOverride        *string  `hcl:"license_override"`
+	PportRange              *string  `hcl:"port_range"`
+	PdrainTimeout           *string  `hcl:"drain_timeout"`
+	Psocket                 *string  `hcl:"socket"`
+	PsocketMode             *string  `hcl:"socket_mode"`
+	PsocketGroup            *string  `hcl:"socket_group"`
+	PsocketUsers            []string `hcl:"socket_users,optional"`
+	PsocketRole             *string  `hcl:"socket_role"`
+	PdisableTcp             *bool    `hcl:"disable_tcp"`
+	PtlsMinVersion          *string  `hcl:"tls_min_version"`
+	PcertificateDirectory   *string  `hcl:"certificate_directory"`
+	PforwardSocketDirectory *string  `hcl:"forward_socket_directory"`
 }
 
 func BuildRestApiCommand(name string, ui cli.Ui) cli.CommandFactory {
@@ -44,11 +75,7 @@ func BuildRestApiCommand(name string, ui cli.Ui) cli.CommandFactory {
 		flags := flag.NewFlagSet("api", flag.ContinueOnError)
 		data := make(map[string]interface{})
 		setDefaultFlags(flags, data)
-
-		data["port"] = flags.Int64("port", DEFAULT_RESTAPI_PORT, "Port for API to listen")
-		data["driver"] = flags.String("driver", "", "Driver to use (simple, advanced, or vmrest)")
-		data["license_override"] = flags.String("license-override", "", "Override VMware license detection (standard or professional)")
-		data["internal_port_forwarding"] = flags.Bool("internal-port-forwarding", false, "Use internal port forwarding implementation")
+		setRestApiFlags(flags, data)
 
 		return &RestApiCommand{
 			Command: Command{
@@ -102,7 +129,28 @@ func (c *RestApiCommand) Run(args []string) int {
 		} else {
 			c.UI.Info("Halting the Vagrant VMware API service")
 		}
-		restApi.Stop()
+		if err := restApi.Stop(); err != nil {
+			c.logger.Error("shutdown failure", "error", err)
+		}
+	})
+	util.RegisterReloadTask(func() {
+		if c.Config.LogDisplay {
+			c.logger.Info("reloading configuration")
+		} else {
+			c.UI.Info("Reloading the Vagrant VMware API service configuration")
+		}
+		result, err := restApi.Reload()
+		if err != nil {
+			if c.Config.LogDisplay {
+				c.logger.Error("configuration reload failure", "error", err)
+			} else {
+				c.UI.Error("Failed to reload configuration - " + err.Error())
+			}
+			return
+		}
+		if len(result.RestartRequired) > 0 && !c.Config.LogDisplay {
+			c.UI.Warn("Restart required to apply: " + strings.Join(result.RestartRequired, ", "))
+		}
 	})
 	<-restApi.HaltedChan
 	return 0
@@ -112,54 +160,77 @@ func (c *RestApiCommand) buildRestApi(driverName string, port int64) (a *server.
 	bindAddr := "127.0.0.1" // Always bind to localhost
 	bindPort := int(port)
 
-	// Start with building the base driver
-	b, err := driver.NewBaseDriver(nil, c.Config.LicenseOverride, c.logger)
+	drv, err := c.buildApiDriver(driverName, c.Config.LicenseOverride, c.Config.InternalPortForwarding, nil)
 	if err != nil {
-		c.logger.Error("base driver setup failure", "error", err)
 		return
 	}
+	// All modifications are recorded in the audit log
+	auditLog, err := utility.OpenAuditLog(utility.AuditLogPath(), c.logger)
+	if err != nil {
+		c.logger.Error("audit log setup failure", "error", err)
+		return nil, errors.New("failed to open audit log - " + err.Error())
+	}
+	utility.SetAuditLog(auditLog)
 
-	// Allow the user to define the driver. It may not work, but they're the boss
-	attempt_vmrest := true
-	var drv driver.Driver
-	switch driverName {
-	case "simple":
-		c.logger.Warn("creating simple driver via user request")
-		drv, err = driver.NewSimpleDriver(nil, b, c.logger)
-		attempt_vmrest = false
-	case "advanced":
-		c.logger.Warn("creating advanced driver via user request")
-		drv, err = driver.NewAdvancedDriver(nil, b, c.logger)
-		attempt_vmrest = false
-	default:
-		if driverName != "" {
-			c.logger.Warn("unknown driver name provided, detecting appropriate driver", "name", driverName)
-		}
-		drv, err = driver.CreateDriver(nil, b, c.logger)
+	a, err = server.Create(bindAddr, bindPort, drv, c.logger)
+	if err != nil {
+		c.logger.Debug("utility server setup failure", "error", err)
+		return nil, errors.New("failed to setup Vagrant VMware API service - " + err.Error())
 	}
+	a.PortRange, err = parsePortRange(c.Config.PortRange)
 	if err != nil {
-		c.logger.Error("driver setup failure", "error", err)
-		return nil, errors.New("failed to setup Vagrant VMware driver - " + err.Error())
+		c.logger.Error("invalid port range", "range", c.Config.PortRange, "error", err)
+		return nil, errors.New("failed to setup Vagrant VMware API service - " + err.Error())
 	}
-
-	// Now that we are setup, we can attempt to upgrade the driver to the
-	// vmrest driver if possible or requested
-	if attempt_vmrest {
-		c.logger.Info("attempting to upgrade to vmrest driver")
-		drv, err = driver.NewVmrestDriver(context.Background(), drv, c.logger)
+	if c.Config.Socket != "" {
+		a.Socket, err = c.socketConfig()
 		if err != nil {
-			c.logger.Error("failed to upgrade to vmrest driver", "error", err)
-			return
+			c.logger.Error("invalid socket configuration", "error", err)
+			return nil, errors.New("failed to setup Vagrant VMware API service - invalid socket configuration: " + err.Error())
 		}
 	}
+	a.DisableTcp = c.Config.DisableTcp
+	a.TlsMinVersion, err = server.ParseTlsVersion(c.Config.TlsMinVersion)
+	if err != nil {
+		c.logger.Error("invalid tls minimum version", "version", c.Config.TlsMinVersion, "error", err)
+		return nil, errors.New("failed to setup Vagrant VMware API service - " + err.Error())
+	}
+	a.DrainTimeout, err = parseDrainTimeout(c.Config.DrainTimeout)
+	if err != nil {
+		c.logger.Error("invalid drain timeout", "timeout", c.Config.DrainTimeout, "error", err)
+		return nil, errors.New("failed to setup Vagrant VMware API service - invalid drain timeout: " + err.Error())
+	}
+	a.Reloader = func() (*server.ReloadResult, error) {
+		return c.reload(a)
+	}
+	return
+}
+
+// Build the driver used by the API. When a previous driver is
+// provided its running internal port forwarding service is
+// adopted by the new driver.
+func (c *RestApiCommand) buildApiDriver(driverName, licenseOverride string, internal bool, previous driver.Driver) (driver.Driver, error) {
+	drv, err := c.buildDriver(driverName, licenseOverride, true)
+	if err != nil {
+		return nil, err
+	}
 
 	// Finally check if the user wants internal port forwarding. If the platform
 	// requires it (Fusion + Big Sur) then we auto enable it. Otherwise we just
 	// check to see if the flag was used
-	if c.Config.InternalPortForwarding || utility.IsBigSurMin() {
+	if internal || utility.IsBigSurMin() {
+		if previous != nil {
+			c.logger.Info("adopting internal port forwarding service")
+			if err := drv.AdoptInternalPortForwarding(previous); err != nil {
+				c.logger.Error("failed to adopt internal port forwarding service", "error", err)
+				drv.Shutdown()
+				return nil, errors.New("failed to adopt internal port forwarding - " + err.Error())
+			}
+		}
 		c.logger.Info("enabling internal port forwarding service")
 		if err := drv.EnableInternalPortForwarding(); err != nil {
 			c.logger.Error("failed to enable internal port forwarding service", "error", err)
+			drv.Shutdown()
 			return nil, errors.New("failed to enable internal port forwarding - " + err.Error())
 		}
 	}
@@ -170,12 +241,190 @@ func (c *RestApiCommand) buildRestApi(driverName string, port int64) (a *server.
 		//       result in an error which includes the validation failure.
 		c.logger.Error("vmware validation failed")
 	}
-	a, err = server.Create(bindAddr, bindPort, drv, c.logger)
+	return driver.NewAuditDriver(drv), nil
+}
+
+// Re-read the configuration file and apply modified settings which
+// are safe to change while the API is running. All other modified
+// settings are reported as requiring a restart and the running
+// configuration retains the original values.
+func (c *RestApiCommand) reload(a *server.Api) (*server.ReloadResult, error) {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+
+	file := &ConfigFile{}
+	if path := c.getConfigValue("config_file", nil); path != "" {
+		c.logger.Debug("reading configuration", "path", path)
+		if err := decodeConfigFile(path, file); err != nil {
+			return nil, err
+		}
+	}
+	core := c.coreConfig(file)
+	var rc RestApiConfig
+	if file.RestApiConfig != nil {
+		rc = *file.RestApiConfig
+	}
+	config := &RestApiConfig{}
+	c.loadApiConfig(config, &rc)
+
+	// Validate everything before applying so a
+	// bad configuration is not partially applied
+	portRange, err := parsePortRange(config.PortRange)
 	if err != nil {
-		c.logger.Debug("utility server setup failure", "error", err)
-		return nil, errors.New("failed to setup Vagrant VMware API service - " + err.Error())
+		return nil, errors.New("Invalid port range - " + err.Error())
 	}
-	return
+	drainTimeout, err := parseDrainTimeout(config.DrainTimeout)
+	if err != nil {
+		return nil, errors.New("Invalid drain timeout - " + err.Error())
+	}
+
+	result := &server.ReloadResult{Applied: []string{}, RestartRequired: []string{}}
+	// Driver modifications are applied first as they are
+	// the only modifications which may fail to apply
+	if err := c.reloadDriver(a, config, result); err != nil {
+		return nil, err
+	}
+	levelNames := []string{}
+	if core.Debug != c.DefaultConfig.Debug {
+		levelNames = append(levelNames, "debug")
+	}
+	if core.Level != c.DefaultConfig.Level {
+		levelNames = append(levelNames, "level")
+	}
+	if len(levelNames) > 0 {
+		if c.DefaultConfig.logDiscarded() {
+			// Logger output is discarded when no level is configured
+			// at startup so output can only be enabled by a restart
+			result.RestartRequired = append(result.RestartRequired, levelNames...)
+		} else {
+			c.logger.SetLevel(core.logLevel())
+			c.DefaultConfig.Debug = core.Debug
+			c.DefaultConfig.Level = core.Level
+			result.Applied = append(result.Applied, levelNames...)
+		}
+	}
+	if config.PortRange != c.Config.PortRange {
+		a.SetPortRange(portRange)
+		c.Config.PortRange = config.PortRange
+		result.Applied = append(result.Applied, "port_range")
+	}
+	if config.DrainTimeout != c.Config.DrainTimeout {
+		a.SetDrainTimeout(drainTimeout)
+		c.Config.DrainTimeout = config.DrainTimeout
+		result.Applied = append(result.Applied, "drain_timeout")
+	}
+	if config.ForwardSocketDirectory != c.Config.ForwardSocketDirectory {
+		utility.SetSocketDirectory(config.ForwardSocketDirectory)
+		c.Config.ForwardSocketDirectory = config.ForwardSocketDirectory
+		result.Applied = append(result.Applied, "forward_socket_directory")
+	}
+
+	for name, changed := range map[string]bool{
+		"log_file":              core.LogFile != c.DefaultConfig.LogFile,
+		"log_append":            core.LogAppend != c.DefaultConfig.LogAppend,
+		"log_format":            core.LogFormat != c.DefaultConfig.LogFormat,
+		"log_output":            core.LogOutput != c.DefaultConfig.LogOutput,
+		"log_max_size":          core.LogMaxSize != c.DefaultConfig.LogMaxSize,
+		"log_max_age":           core.LogMaxAge != c.DefaultConfig.LogMaxAge,
+		"log_max_backups":       core.LogMaxBackups != c.DefaultConfig.LogMaxBackups,
+		"log_compress":          core.LogCompress != c.DefaultConfig.LogCompress,
+		"port":                  config.Port != c.Config.Port,
+		"driver":                config.Driver != c.Config.Driver,
+		"socket":                config.Socket != c.Config.Socket,
+		"socket_mode":           config.SocketMode != c.Config.SocketMode,
+		"socket_group":          config.SocketGroup != c.Config.SocketGroup,
+		"socket_users":          !slices.Equal(config.SocketUsers, c.Config.SocketUsers),
+		"socket_role":           config.SocketRole != c.Config.SocketRole,
+		"disable_tcp":           config.DisableTcp != c.Config.DisableTcp,
+		"tls_min_version":       config.TlsMinVersion != c.Config.TlsMinVersion,
+		"certificate_directory": config.CertificateDirectory != c.Config.CertificateDirectory,
+	} {
+		if changed {
+			result.RestartRequired = append(result.RestartRequired, name)
+		}
+	}
+	sort.Strings(result.Applied)
+	sort.Strings(result.RestartRequired)
+
+	c.logger.Info("configuration reloaded", "applied", result.Applied)
+	if len(result.RestartRequired) > 0 {
+		c.logger.Warn("modified settings require a restart", "settings", result.RestartRequired)
+	}
+	return result, nil
+}
+
+// Apply modifications to the license override and internal port
+// forwarding. The license override determines which driver is used
+// so the driver is rebuilt when it is modified and the running port
+// forwards are handed to the new driver. Otherwise internal port
+// forwarding is enabled or disabled on the running driver.
+func (c *RestApiCommand) reloadDriver(a *server.Api, config *RestApiConfig, result *server.ReloadResult) error {
+	license := config.LicenseOverride != c.Config.LicenseOverride
+	internal := config.InternalPortForwarding != c.Config.InternalPortForwarding
+	if !license && !internal {
+		return nil
+	}
+	enable := config.InternalPortForwarding || utility.IsBigSurMin()
+
+	var previous driver.Driver
+	err := a.ReplaceDriver(func(current driver.Driver) (driver.Driver, error) {
+		if !license {
+			if enable {
+				return current, current.EnableInternalPortForwarding()
+			}
+			return current, current.DisableInternalPortForwarding()
+		}
+		// Running port forwards are handed to the replacement
+		// so existing connections are not interrupted
+		drv, err := c.buildApiDriver(c.Config.Driver, config.LicenseOverride, config.InternalPortForwarding, current)
+		if err != nil {
+			return nil, err
+		}
+		previous = current
+		return drv, nil
+	})
+	if err != nil {
+		c.logger.Error("driver reload failure", "error", err)
+		return errors.New("Failed to apply driver configuration - " + err.Error())
+	}
+	if previous != nil {
+		if err := previous.Shutdown(); err != nil {
+			c.logger.Warn("previous driver shutdown failure", "error", err)
+		}
+	}
+	if license {
+		c.Config.LicenseOverride = config.LicenseOverride
+		result.Applied = append(result.Applied, "license_override")
+	}
+	if internal {
+		c.Config.InternalPortForwarding = config.InternalPortForwarding
+		result.Applied = append(result.Applied, "internal_port_forwarding")
+	}
+	return nil
+}
+
+// Parse the usable host port range. The default
+// range is used when no range is provided.
+func parsePortRange(r string) (*driver.PortRange, error) {
+	if r == "" {
+		return &driver.PortRange{
+			Min: driver.DEFAULT_PORT_RANGE_MIN,
+			Max: driver.DEFAULT_PORT_RANGE_MAX}, nil
+	}
+	return driver.ParsePortRange(r)
+}
+
+// Parse the drain timeout. The default timeout is
+// used when no timeout is provided.
+func parseDrainTimeout(t string) (time.Duration, error) {
+	if t == "" {
+		return server.DEFAULT_DRAIN_TIMEOUT, nil
+	}
+	timeout, err := time.ParseDuration(t)
+	if err == nil && timeout < 0 {
+		err = errors.New("duration must not be negative")
+	}
+	return timeout, err
 }
 
 func (c *RestApiCommand) setup(args []string) (err error) {
@@ -190,10 +439,93 @@ func (c *RestApiCommand) setup(args []string) (err error) {
 		rc = *c.DefaultConfig.configFile.RestApiConfig
 	}
 
-	c.Config.Port = c.getConfigInt64("port", rc.Pport)
-	c.Config.Driver = c.getConfigValue("driver", rc.Pdriver)
-	c.Config.InternalPortForwarding = c.getConfigBool("internal_port_forwarding", rc.PinternalPortForwarding)
-	c.Config.LicenseOverride = c.getConfigValue("license_override", rc.PlicenseOverride)
-	c.Config.LogDisplay = c.DefaultConfig.LogFile != ""
+	c.loadApiConfig(c.Config, &rc)
+	c.loadCertificateDirectory()
+	utility.SetSocketDirectory(c.Config.ForwardSocketDirectory)
+	c.Config.LogDisplay = c.DefaultConfig.LogFile != "" || c.DefaultConfig.LogOutput != ""
 	return
 }
+
+// Load the API configuration. Options without a flag
+// are not supported by the command and are skipped.
+func (c *RestApiCommand) loadApiConfig(config, rc *RestApiConfig) {
+	config.Port = c.getConfigInt64("port", rc.Pport)
+	config.Driver = c.getConfigValue("driver", rc.Pdriver)
+	config.LicenseOverride = c.getConfigValue("license_override", rc.PlicenseOverride)
+	config.DrainTimeout = c.getConfigValue("drain_timeout", rc.PdrainTimeout)
+	config.ForwardSocketDirectory = c.getConfigValue("forward_socket_directory", rc.PforwardSocketDirectory)
+	if c.hasFlag("internal_port_forwarding") {
+		config.InternalPortForwarding = c.getConfigBool("internal_port_forwarding", rc.PinternalPortForwarding)
+	}
+	if c.hasFlag("port_range") {
+		config.PortRange = c.getConfigValue("port_range", rc.PportRange)
+	}
+	c.loadSocketConfig(config, rc)
+	c.loadTlsConfig(config, rc)
+}
+
+// Used by commands to setup the API options
+func setRestApiFlags(flags *flag.FlagSet, data map[string]interface{}) {
+	data["port"] = flags.Int64("port", DEFAULT_RESTAPI_PORT, "Port for API to listen")
+	data["driver"] = flags.String("driver", "", "Driver to use (simple, advanced, or vmrest)")
+	data["license_override"] = flags.String("license-override", "", "Override VMware license detection (standard or professional)")
+	data["internal_port_forwarding"] = flags.Bool("internal-port-forwarding", false, "Use internal port forwarding implementation")
+	data["port_range"] = flags.String("port-range", "", "Usable host port range for port forward suggestions (MIN-MAX)")
+	data["drain_timeout"] = flags.String("drain-timeout", "", "Time allowed for inflight requests to complete on shutdown (default 30s)")
+	data["forward_socket_directory"] = flags.String("forward-socket-directory", "", "Directory unix socket port forwards are created within (default utility sockets directory)")
+	setSocketFlags(flags, data)
+	setTlsFlags(flags, data)
+}
+
+// Used by commands running the API to setup the local socket options
+func setSocketFlags(flags *flag.FlagSet, data map[string]interface{}) {
+	data["socket"] = flags.String("socket", "", "Path of Unix socket or name of named pipe for API to listen")
+	data["socket_mode"] = flags.String("socket-mode", "", "Permissions of the Unix socket (default 0600)")
+	data["socket_group"] = flags.String("socket-group", "", "Group owner of the Unix socket")
+	data["socket_users"] = flags.String("socket-users", "", "Comma separated list of users allowed to connect to the socket")
+	data["socket_role"] = flags.String("socket-role", string(utility.ROLE_ADMIN), "Role of clients connected to the socket")
+	data["disable_tcp"] = flags.Bool("disable-tcp", false, "Disable the TCP listener")
+}
+
+func (c *RestApiCommand) loadSocketConfig(config, rc *RestApiConfig) {
+	config.Socket = c.getConfigValue("socket", rc.Psocket)
+	config.SocketMode = c.getConfigValue("socket_mode", rc.PsocketMode)
+	config.SocketGroup = c.getConfigValue("socket_group", rc.PsocketGroup)
+	config.SocketUsers = c.getConfigArray("socket_users", rc.PsocketUsers)
+	config.SocketRole = c.getConfigValue("socket_role", rc.PsocketRole)
+	config.DisableTcp = c.getConfigBool("disable_tcp", rc.PdisableTcp)
+}
+
+// Used by commands running the API to setup the TLS options
+func setTlsFlags(flags *flag.FlagSet, data map[string]interface{}) {
+	data["tls_min_version"] = flags.String("tls-min-version", "1.2", "Minimum TLS version accepted by the API (1.2 or 1.3)")
+	setCertificateFlags(flags, data)
+}
+
+func (c *RestApiCommand) loadTlsConfig(config, rc *RestApiConfig) {
+	config.TlsMinVersion = c.getConfigValue("tls_min_version", rc.PtlsMinVersion)
+	config.CertificateDirectory = c.getConfigValue("certificate_directory", rc.PcertificateDirectory)
+}
+
+// Build the local socket configuration from the command configuration
+func (c *RestApiCommand) socketConfig() (*server.SocketConfig, error) {
+	config := &server.SocketConfig{
+		Path:  c.Config.Socket,
+		Mode:  server.DEFAULT_SOCKET_MODE,
+		Group: c.Config.SocketGroup,
+		Users: c.Config.SocketUsers,
+	}
+	if c.Config.SocketMode != "" {
+		mode, err := strconv.ParseUint(c.Config.SocketMode, 8, 32)
+		if err != nil || mode > 0777 {
+			return nil, fmt.Errorf("invalid socket mode '%s'", c.Config.SocketMode)
+		}
+		config.Mode = os.FileMode(mode)
+	}
+	role, err := utility.ParseRole(c.Config.SocketRole)
+	if err != nil {
+		return nil, err
+	}
+	config.Role = role
+	return config, nil
+}
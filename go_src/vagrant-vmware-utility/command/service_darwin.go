@@ -7,6 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
+	"strconv"
 
 	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/service"
 	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
@@ -95,6 +97,10 @@ const LAUNCHD_STOP_JOB = `<?xml version="1.0" encoding="UTF-8"?>
 </plist>
 `
 
+// Matches the listen port argument of the launchd job
+var launchdPortPattern = regexp.MustCompile(`<string>-port=(\d+)</string>`)
+
+const LAUNCHD_JOB_LABEL = `com.vagrant.vagrant-vmware-utility`
 const LAUNCHD_STOP_JOB_PATH = `/Library/LaunchDaemons/com.vagrant.vagrant-vmware-utility-stopper.plist`
 const LAUNCHD_JOB_PATH = `/Library/LaunchDaemons/com.vagrant.vagrant-vmware-utility.plist`
 const SERVICE_CONFIGURATION_FILE = `/Library/Application Support/vagrant-vmware-utility/config.hcl`
@@ -201,3 +207,52 @@ func (c *ServiceUninstallCommand) uninstall() error {
 	}
 	return nil
 }
+
+func (c *ServiceStatusCommand) status() (*serviceStatus, error) {
+	status := &serviceStatus{Init: "launchd"}
+	if !utility.FileExists(LAUNCHD_JOB_PATH) {
+		return status, nil
+	}
+	status.Installed = true
+	status.ServicePath = LAUNCHD_JOB_PATH
+	if content, err := os.ReadFile(LAUNCHD_JOB_PATH); err == nil {
+		// Paths within the job may include spaces so only the
+		// end of the string element terminates the value
+		status.ConfigPath = configFileArgument(string(content), "<")
+		if match := launchdPortPattern.FindSubmatch(content); match != nil {
+			status.Port, _ = strconv.ParseInt(string(match[1]), 10, 64)
+		}
+	}
+	launchctl, err := service.NewLaunchctl(c.logger)
+	if err != nil {
+		c.logger.Debug("launchctl service creation failure", "error", err)
+		return nil, err
+	}
+	lstatus, err := launchctl.Status(LAUNCHD_JOB_LABEL)
+	if err != nil {
+		c.logger.Debug("service status failure", "label", LAUNCHD_JOB_LABEL, "error", err)
+		return nil, err
+	}
+	status.Enabled = lstatus.Loaded
+	status.Running = lstatus.Running
+	status.Pid = lstatus.Pid
+	return status, nil
+}
+
+func (c *ServiceRestartCommand) restart() error {
+	if !utility.FileExists(LAUNCHD_JOB_PATH) {
+		return errors.New("service is not installed")
+	}
+	launchctl, err := service.NewLaunchctl(c.logger)
+	if err != nil {
+		c.logger.Debug("launchctl service creation failure", "error", err)
+		return err
+	}
+	c.logger.Trace("restarting service", "label", LAUNCHD_JOB_LABEL)
+	err = launchctl.Restart(LAUNCHD_JOB_LABEL)
+	if err != nil {
+		c.logger.Debug("service restart failure", "label", LAUNCHD_JOB_LABEL, "error", err)
+		return err
+	}
+	return nil
+}
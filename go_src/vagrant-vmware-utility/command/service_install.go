@@ -29,12 +29,16 @@ type ServiceInstallConfig struct {
 	ExePath         string // used for init printing
 	ConfigPath      string // used for init printing
 	ConfigWrite     string // used for init printing
+	TlsMinVersion   string
+	CertDirectory   string
 
 	Pdriver          *string `hcl:"driver"`
 	PlicenseOverride *string `hcl:"license_override"`
 	Pinit            *string `hcl:"init"`      // used on linux (style)
 	PrunitDir        *string `hcl:"runit_dir"` // used on linux
 	Pport            *int64  `hcl:"port"`
+	PtlsMinVersion   *string `hcl:"tls_min_version"`
+	PcertDirectory   *string `hcl:"certificate_directory"`
 }
 
 func (s *ServiceInstallConfig) Prepare() {
@@ -43,6 +47,8 @@ func (s *ServiceInstallConfig) Prepare() {
 	s.Pinit = &s.Init
 	s.PrunitDir = &s.RunitDir
 	s.Pport = &s.Port
+	s.PtlsMinVersion = &s.TlsMinVersion
+	s.PcertDirectory = &s.CertDirectory
 }
 
 // This is used for when we want to write
@@ -70,6 +76,7 @@ func BuildServiceInstallCommand(name string, ui cli.Ui) cli.CommandFactory {
 		data["port"] = flags.Int64("port", DEFAULT_RESTAPI_PORT, "Port for API to listen")
 		data["driver"] = flags.String("driver", "", "Driver to use (simple, advanced, or vmrest)")
 		data["license_override"] = flags.String("license-override", "", "Override VMware license detection (standard or professional)")
+		setTlsFlags(flags, data)
 		data["print"] = flags.Bool("print", false, "Print init file to STDOUT")
 		data["exe_path"] = flags.String("exe-path", "", "Path used for executable (used for print only)")
 		data["config_path"] = flags.String("config-path", "", "Path for configuration file (used for print only)")
@@ -132,6 +139,8 @@ func (c *ServiceInstallCommand) setup(args []string) (err error) {
 	c.Config.Port = c.getConfigInt64("port", sc.Pport)
 	c.Config.Driver = c.getConfigValue("driver", sc.Pdriver)
 	c.Config.LicenseOverride = c.getConfigValue("license_override", sc.PlicenseOverride)
+	c.Config.TlsMinVersion = c.getConfigValue("tls_min_version", sc.PtlsMinVersion)
+	c.Config.CertDirectory = c.getConfigValue("certificate_directory", sc.PcertDirectory)
 	c.Config.Print = c.getConfigBool("print", nil)
 	c.Config.ExePath = c.getConfigValue("exe_path", nil)
 	c.Config.ConfigPath = c.getConfigValue("config_path", nil)
@@ -160,6 +169,24 @@ func (c *ServiceInstallCommand) writeConfig(fpath string) (cpath string, err err
 	if c.DefaultConfig.LogAppend {
 		config.Config.PlogAppend = &c.DefaultConfig.LogAppend
 	}
+	if c.DefaultConfig.LogFormat != "" {
+		config.Config.PlogFormat = &c.DefaultConfig.LogFormat
+	}
+	if c.DefaultConfig.LogOutput != "" {
+		config.Config.PlogOutput = &c.DefaultConfig.LogOutput
+	}
+	if c.DefaultConfig.LogMaxSize != 0 {
+		config.Config.PlogMaxSize = &c.DefaultConfig.LogMaxSize
+	}
+	if c.DefaultConfig.LogMaxAge != "" {
+		config.Config.PlogMaxAge = &c.DefaultConfig.LogMaxAge
+	}
+	if c.DefaultConfig.LogMaxBackups != 0 {
+		config.Config.PlogMaxBackups = &c.DefaultConfig.LogMaxBackups
+	}
+	if c.DefaultConfig.LogCompress {
+		config.Config.PlogCompress = &c.DefaultConfig.LogCompress
+	}
 	config.RestApiConfig = &RestApiConfig{
 		Pport: &c.Config.Port}
 	if c.Config.Driver != "" {
@@ -168,6 +195,12 @@ func (c *ServiceInstallCommand) writeConfig(fpath string) (cpath string, err err
 	if c.Config.LicenseOverride != "" {
 		config.RestApiConfig.PlicenseOverride = &c.Config.LicenseOverride
 	}
+	if c.Config.TlsMinVersion != "" {
+		config.RestApiConfig.PtlsMinVersion = &c.Config.TlsMinVersion
+	}
+	if c.Config.CertDirectory != "" {
+		config.RestApiConfig.PcertificateDirectory = &c.Config.CertDirectory
+	}
 	err = utility.WriteConfigFile(cpath, config)
 	if err != nil {
 		c.logger.Debug("failed to create configuration file", "path", cpath, "error", err)
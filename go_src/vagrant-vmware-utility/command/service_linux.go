@@ -9,6 +9,9 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
@@ -124,14 +127,37 @@ exit 0
 //
 // string - exectuable path
 // string - configuration path
+//
+// The service must run as root to manage VMware networking and
+// writes VMware configuration files and port forward sockets
+// which may be located anywhere, so file system protections are
+// not applied. VMware networking daemons started by the service
+// inherit these settings and must not be stopped with the service.
 const SYSTEMD_TEMPLATE = `[Unit]
 Description=Vagrant VMware Utility
-After=network.target
+After=network-online.target
+Wants=network-online.target
+StartLimitIntervalSec=300
+StartLimitBurst=5
 
 [Service]
 Type=simple
 ExecStart=%s api -config-file=%s
-Restart=on-abort
+Restart=on-failure
+RestartSec=5
+KillMode=process
+TimeoutStopSec=60
+StandardOutput=journal
+StandardError=journal
+SyslogIdentifier=vagrant-vmware-utility
+NoNewPrivileges=yes
+LockPersonality=yes
+ProtectClock=yes
+ProtectControlGroups=yes
+ProtectHostname=yes
+RestrictRealtime=yes
+RestrictSUIDSGID=yes
+SystemCallArchitectures=native
 
 [Install]
 WantedBy=multi-user.target
@@ -145,14 +171,31 @@ const RUNIT_TEMPLATE = `#!/bin/sh
 exec %s api -config-file="%s"
 `
 
+// Matches the process ID in runit sv status output
+var runitPidPattern = regexp.MustCompile(`\(pid (\d+)\)`)
+
 const SYSV_PATH = "/etc/init.d/vagrant-vmware-utility"
+const SYSTEMD_UNIT_DIR = "/etc/systemd/system"
+
+// Directory which only exists when systemd is running
+const SYSTEMD_RUNTIME_DIR = "/run/systemd/system"
 
-func (c *Command) systemdServicePath(exePath string) string {
+func (c *Command) systemdServicePath() string {
+	return path.Join(SYSTEMD_UNIT_DIR, c.Name+".service")
+}
+
+// Unit files were previously installed next to the
+// executable and linked when enabled
+func (c *Command) legacySystemdServicePath(exePath string) string {
 	return path.Join(path.Dir(exePath), c.Name+".service")
 }
 
 // Attached to generic command so both install and uninstall can access
 func (c *Command) detectInit() string {
+	if utility.FileExists(SYSTEMD_RUNTIME_DIR) {
+		c.logger.Trace("systemd runtime check", "path", SYSTEMD_RUNTIME_DIR)
+		return "systemd"
+	}
 	// Get the command name for init
 	exitCode, out := utility.ExecuteWithOutput(
 		exec.Command("ps", "-o", "comm=", "1"))
@@ -266,8 +309,9 @@ func (c *ServiceInstallCommand) installSysv(exePath, configPath string) error {
 }
 
 func (c *ServiceInstallCommand) installSystemd(exePath, configPath string) error {
-	servicePath := c.systemdServicePath(exePath)
-	if utility.FileExists(servicePath) {
+	servicePath := c.systemdServicePath()
+	serviceName := path.Base(servicePath)
+	if utility.FileExists(servicePath) || utility.FileExists(c.legacySystemdServicePath(exePath)) {
 		return errors.New("service is already installed")
 	}
 	ifile, err := os.OpenFile(servicePath, os.O_CREATE|os.O_WRONLY, 0644)
@@ -282,15 +326,20 @@ func (c *ServiceInstallCommand) installSystemd(exePath, configPath string) error
 		return err
 	}
 	ifile.Close()
-	exitCode, out := utility.ExecuteWithOutput(exec.Command("systemctl", "enable", servicePath))
+	exitCode, out := utility.ExecuteWithOutput(exec.Command("systemctl", "daemon-reload"))
+	if exitCode != 0 {
+		c.logger.Debug("systemd reload failure", "exitcode", exitCode, "output", out)
+		return errors.New("Failed to reload systemd configuration")
+	}
+	exitCode, out = utility.ExecuteWithOutput(exec.Command("systemctl", "enable", serviceName))
 	if exitCode != 0 {
-		c.logger.Debug("service enable failure", "path", servicePath, "exitcode", exitCode,
+		c.logger.Debug("service enable failure", "name", serviceName, "exitcode", exitCode,
 			"output", out)
 		return errors.New("Failed to enable service")
 	}
-	exitCode, out = utility.ExecuteWithOutput(exec.Command("systemctl", "start", path.Base(servicePath)))
+	exitCode, out = utility.ExecuteWithOutput(exec.Command("systemctl", "start", serviceName))
 	if exitCode != 0 {
-		c.logger.Debug("service start failure", "name", path.Base(servicePath), "exitcode", exitCode,
+		c.logger.Debug("service start failure", "name", serviceName, "exitcode", exitCode,
 			"output", out)
 		return errors.New("Failed to start service")
 	}
@@ -384,10 +433,22 @@ func (c *ServiceUninstallCommand) uninstallSysv(exePath string) error {
 }
 
 func (c *ServiceUninstallCommand) uninstallSystemd(exePath string) error {
-	servicePath := c.systemdServicePath(exePath)
+	servicePath := c.systemdServicePath()
 	serviceName := path.Base(servicePath)
-	// Check if service is enabled
+	// Stop the service if it is running
 	exitCode, out := utility.ExecuteWithOutput(exec.Command(
+		"systemctl", "is-active", serviceName))
+	c.logger.Trace("service active check", "name", serviceName, "exitcode", exitCode, "output", out)
+	if exitCode == 0 {
+		exitCode, out = utility.ExecuteWithOutput(exec.Command(
+			"systemctl", "stop", serviceName))
+		c.logger.Trace("service stop", "name", serviceName, "exitcode", exitCode, "output", out)
+		if exitCode != 0 {
+			return errors.New("Failed to stop service")
+		}
+	}
+	// Check if service is enabled
+	exitCode, out = utility.ExecuteWithOutput(exec.Command(
 		"systemctl", "is-enabled", serviceName))
 	c.logger.Trace("service enable check", "name", serviceName, "exitcode", exitCode, "output", out)
 	if exitCode == 0 {
@@ -397,16 +458,19 @@ func (c *ServiceUninstallCommand) uninstallSystemd(exePath string) error {
 		if exitCode != 0 {
 			return errors.New("Failed to disable service")
 		}
-		// clean up systemd unit list
-		utility.Execute(exec.Command("systemctl", "reset-failed"))
 	}
-	if utility.FileExists(servicePath) {
-		err := os.Remove(servicePath)
-		if err != nil {
-			c.logger.Warn("service file remove failure", "path", servicePath, "error", err)
-			return errors.New("failed to remove systemd unit file")
+	for _, unitPath := range []string{servicePath, c.legacySystemdServicePath(exePath)} {
+		if utility.FileExists(unitPath) {
+			err := os.Remove(unitPath)
+			if err != nil {
+				c.logger.Warn("service file remove failure", "path", unitPath, "error", err)
+				return errors.New("failed to remove systemd unit file")
+			}
 		}
 	}
+	// clean up systemd unit list
+	utility.Execute(exec.Command("systemctl", "daemon-reload"))
+	utility.Execute(exec.Command("systemctl", "reset-failed"))
 	return nil
 }
 
@@ -446,3 +510,160 @@ func (c *ServiceUninstallCommand) uninstallRunit(exePath string) (err error) {
 	}
 	return nil
 }
+
+func (c *ServiceStatusCommand) status() (*serviceStatus, error) {
+	initStyle := c.Config.Init
+	if initStyle == "" {
+		initStyle = c.detectInit()
+	}
+	c.logger.Trace("checking service status", "init", initStyle)
+	status := &serviceStatus{Init: initStyle}
+	var err error
+	switch initStyle {
+	case "sysv":
+		err = c.statusSysv(status)
+	case "systemd":
+		err = c.statusSystemd(status)
+	case "runit":
+		err = c.statusRunit(status)
+	default:
+		return nil, errors.New("Unknown init for service: " + initStyle)
+	}
+	if err != nil {
+		c.logger.Debug("service status failure", "error", err)
+		return nil, err
+	}
+	return status, nil
+}
+
+func (c *ServiceStatusCommand) statusSysv(status *serviceStatus) error {
+	if !utility.FileExists(SYSV_PATH) {
+		return nil
+	}
+	status.Installed = true
+	status.ServicePath = SYSV_PATH
+	if content, err := os.ReadFile(SYSV_PATH); err == nil {
+		status.ConfigPath = configFileArgument(string(content), " \t\r\n")
+	}
+	// Enabled when linked into any of the default runlevels
+	links, _ := filepath.Glob(path.Join("/etc", "rc[2345].d", "S*"+path.Base(SYSV_PATH)))
+	status.Enabled = len(links) > 0
+	exitCode, out := utility.ExecuteWithOutput(exec.Command(SYSV_PATH, "status"))
+	c.logger.Trace("service status check", "path", SYSV_PATH, "exitcode", exitCode, "output", out)
+	status.Running = exitCode == 0
+	if status.Running {
+		pidFile := path.Join("/var/run", path.Base(SYSV_PATH)+".pid")
+		if content, err := os.ReadFile(pidFile); err == nil {
+			status.Pid, _ = strconv.Atoi(strings.TrimSpace(string(content)))
+		}
+	}
+	return nil
+}
+
+func (c *ServiceStatusCommand) statusSystemd(status *serviceStatus) error {
+	exePath, err := os.Executable()
+	if err != nil {
+		c.logger.Debug("path detection failure", "error", err)
+		return err
+	}
+	servicePath := c.systemdServicePath()
+	if !utility.FileExists(servicePath) {
+		servicePath = c.legacySystemdServicePath(exePath)
+		if !utility.FileExists(servicePath) {
+			return nil
+		}
+	}
+	serviceName := path.Base(servicePath)
+	status.Installed = true
+	status.ServicePath = servicePath
+	if content, err := os.ReadFile(servicePath); err == nil {
+		status.ConfigPath = configFileArgument(string(content), " \t\r\n")
+	}
+	exitCode, out := utility.ExecuteWithOutput(exec.Command(
+		"systemctl", "is-enabled", serviceName))
+	c.logger.Trace("service enable check", "name", serviceName, "exitcode", exitCode, "output", out)
+	status.Enabled = exitCode == 0
+	exitCode, out = utility.ExecuteWithOutput(exec.Command(
+		"systemctl", "is-active", serviceName))
+	c.logger.Trace("service active check", "name", serviceName, "exitcode", exitCode, "output", out)
+	status.Running = exitCode == 0
+	if status.Running {
+		exitCode, out = utility.ExecuteWithOutput(exec.Command(
+			"systemctl", "show", "--property=MainPID", "--value", serviceName))
+		c.logger.Trace("service pid check", "name", serviceName, "exitcode", exitCode, "output", out)
+		if exitCode == 0 {
+			status.Pid, _ = strconv.Atoi(strings.TrimSpace(out))
+		}
+	}
+	return nil
+}
+
+func (c *ServiceStatusCommand) statusRunit(status *serviceStatus) error {
+	svcPath := path.Join(c.Config.RunitDir, c.Name)
+	c.logger.Trace("runit service path", "path", svcPath)
+	if !utility.FileExists(svcPath) {
+		return nil
+	}
+	status.Installed = true
+	status.ServicePath = svcPath
+	if content, err := os.ReadFile(path.Join(svcPath, "run")); err == nil {
+		status.ConfigPath = configFileArgument(string(content), " \t\r\n")
+	}
+	// Services are enabled by linking into the service directory
+	// unless a down file prevents automatic start
+	status.Enabled = !utility.FileExists(path.Join(svcPath, "down"))
+	exitCode, out := utility.ExecuteWithOutput(exec.Command("sv", "status", c.Name))
+	c.logger.Trace("service status check", "name", c.Name, "exitcode", exitCode, "output", out)
+	if exitCode == 0 && strings.HasPrefix(strings.TrimSpace(out), "run:") {
+		status.Running = true
+		if match := runitPidPattern.FindStringSubmatch(out); match != nil {
+			status.Pid, _ = strconv.Atoi(match[1])
+		}
+	}
+	return nil
+}
+
+func (c *ServiceRestartCommand) restart() error {
+	initStyle := c.Config.Init
+	if initStyle == "" {
+		initStyle = c.detectInit()
+	}
+	c.logger.Trace("restarting service", "init", initStyle)
+	var cmd *exec.Cmd
+	switch initStyle {
+	case "sysv":
+		if !utility.FileExists(SYSV_PATH) {
+			return errors.New("service is not installed")
+		}
+		cmd = exec.Command(SYSV_PATH, "restart")
+	case "systemd":
+		exePath, err := os.Executable()
+		if err != nil {
+			c.logger.Debug("path detection failure", "error", err)
+			return err
+		}
+		servicePath := c.systemdServicePath()
+		if !utility.FileExists(servicePath) {
+			servicePath = c.legacySystemdServicePath(exePath)
+			if !utility.FileExists(servicePath) {
+				return errors.New("service is not installed")
+			}
+		}
+		cmd = exec.Command("systemctl", "restart", path.Base(servicePath))
+	case "runit":
+		svcPath := path.Join(c.Config.RunitDir, c.Name)
+		if !utility.FileExists(svcPath) {
+			return errors.New("service is not installed")
+		}
+		cmd = exec.Command("sv", "restart", c.Name)
+	default:
+		return errors.New("Unknown init for service: " + initStyle)
+	}
+	exitCode, out := utility.ExecuteWithOutput(cmd)
+	if exitCode != 0 {
+		c.logger.Debug("service restart failure", "init", initStyle, "exitcode", exitCode,
+			"output", out)
+		return errors.New("Failed to restart service")
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"flag"
+	"runtime"
+
+	"github.com/hashicorp/cli"
+)
+
+type ServiceRestartCommand struct {
+	Command
+	Config *ServiceInstallConfig
+}
+
+func BuildServiceRestartCommand(name string, ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		flags := flag.NewFlagSet("service restart", flag.ContinueOnError)
+		data := make(map[string]interface{})
+		setDefaultFlags(flags, data)
+
+		if runtime.GOOS != "windows" {
+			data["runit_sv"] = flags.String("runit-sv", RUNIT_DIR, "Path to runit sv directory")
+			data["init"] = flags.String("init-style", "", "Init in use (systemd, runit, sysv)")
+		}
+
+		return &ServiceRestartCommand{
+			Command: Command{
+				DefaultConfig: &Config{},
+				Name:          name,
+				Flags:         flags,
+				HelpText:      name + " service restart",
+				SynopsisText:  "Restart service",
+				UI:            ui,
+				flagdata:      data},
+			Config: &ServiceInstallConfig{}}, nil
+	}
+}
+
+func (c *ServiceRestartCommand) Run(args []string) int {
+	exitCode := 1
+	err := c.setup(args)
+	if err != nil {
+		c.UI.Error("Failed to initialize: " + err.Error())
+		return exitCode
+	}
+	err = c.restart()
+	if err != nil {
+		c.UI.Error("Failed to restart service: " + err.Error())
+		return exitCode
+	}
+	c.UI.Info("Service has been restarted!")
+	return 0
+}
+
+func (c *ServiceRestartCommand) setup(args []string) (err error) {
+	err = c.defaultSetup(args)
+	if err != nil {
+		return
+	}
+
+	var sc ServiceInstallConfig
+	if c.DefaultConfig.configFile != nil && c.DefaultConfig.configFile.ServiceInstallConfig != nil {
+		sc = *c.DefaultConfig.configFile.ServiceInstallConfig
+	}
+
+	if runtime.GOOS != "windows" {
+		c.Config.Init = c.getConfigValue("init", sc.Pinit)
+		c.Config.RunitDir = c.getConfigValue("runit_sv", sc.PrunitDir)
+	}
+	return
+}
@@ -6,9 +6,11 @@ package command
 import (
 	"flag"
 	"fmt"
+	"strings"
 
 	"github.com/hashicorp/cli"
 	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
 	"golang.org/x/sys/windows/svc"
 	"golang.org/x/sys/windows/svc/eventlog"
 )
@@ -21,6 +23,7 @@ const (
 	ServiceSetupFailure
 	ServiceStateChangeFailure
 	ServiceFailure
+	ServiceReload
 )
 
 type ServiceRunCommand struct {
@@ -36,6 +39,9 @@ func BuildServiceRunCommand(name string, ui cli.Ui) cli.CommandFactory {
 		data["port"] = flags.Int64("port", DEFAULT_RESTAPI_PORT, "Port for API to listen")
 		data["driver"] = flags.String("driver", "", "Driver to use (simple or advanced)")
 		data["license_override"] = flags.String("license-override", "", "Override VMware license detection (standard or professional)")
+		data["drain_timeout"] = flags.String("drain-timeout", "", "Time allowed for inflight requests to complete on shutdown (default 30s)")
+		setSocketFlags(flags, data)
+		setTlsFlags(flags, data)
 
 		return &ServiceRunCommand{
 			RestApiCommand: RestApiCommand{
@@ -51,7 +57,7 @@ func BuildServiceRunCommand(name string, ui cli.Ui) cli.CommandFactory {
 	}
 }
 
-const VALID_SERVICE_COMMANDS = svc.AcceptStop | svc.AcceptShutdown
+const VALID_SERVICE_COMMANDS = svc.AcceptStop | svc.AcceptShutdown | svc.AcceptParamChange
 
 type apiservice struct {
 	Command  *ServiceRunCommand
@@ -87,13 +93,30 @@ func (a *apiservice) Execute(args []string, r <-chan svc.ChangeRequest, changes
 				running = false
 				a.eventlog.Info(ServiceStop, "api shutdown requested")
 				a.logger.Trace("api shutdown requested")
+			case svc.ParamChange:
+				// Parameter change requests reload the configuration
+				a.eventlog.Info(ServiceReload, "api configuration reload requested")
+				result, err := restApi.Reload()
+				if err != nil {
+					a.logger.Debug("api configuration reload failure", "error", err)
+					a.eventlog.Error(ServiceReload, fmt.Sprintf(
+						"%s service configuration reload failed: %s", a.Command.Name, err))
+				} else if len(result.RestartRequired) > 0 {
+					a.eventlog.Warning(ServiceReload, fmt.Sprintf(
+						"%s service restart required to apply: %s", a.Command.Name,
+						strings.Join(result.RestartRequired, ", ")))
+				}
+				changes <- c.CurrentStatus
 			default:
 				a.logger.Trace("unexpected control request", "command", c)
 			}
 		}
 	}
 	a.eventlog.Info(ServiceStop, "api service is shutting down")
-	restApi.Stop()
+	changes <- svc.Status{State: svc.StopPending}
+	if err := restApi.Stop(); err != nil {
+		a.logger.Debug("api shutdown failure", "error", err)
+	}
 	<-restApi.HaltedChan
 	a.eventlog.Info(ServiceStop, "api service has been halted")
 	return
@@ -150,10 +173,10 @@ func (c *ServiceRunCommand) setup(args []string) (err error) {
 		rc = *c.DefaultConfig.configFile.RestApiConfig
 	}
 
-	c.Config.Port = c.getConfigInt64("port", rc.Pport)
-	c.Config.Driver = c.getConfigValue("driver", rc.Pdriver)
-	c.Config.LicenseOverride = c.getConfigValue("license_override", rc.PlicenseOverride)
-	c.Config.LogDisplay = c.DefaultConfig.LogFile != ""
+	c.loadApiConfig(c.Config, &rc)
+	c.loadCertificateDirectory()
+	utility.SetSocketDirectory(c.Config.ForwardSocketDirectory)
+	c.Config.LogDisplay = c.DefaultConfig.LogFile != "" || c.DefaultConfig.LogOutput != ""
 
 	return
 }
@@ -0,0 +1,186 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hashicorp/cli"
+	"github.com/hashicorp/hcl/v2/hclsimple"
+)
+
+// Exit code used when the service is not running. Matches
+// the LSB init script status code for a stopped service.
+const SERVICE_NOT_RUNNING_EXIT_CODE = 3
+
+type ServiceStatusCommand struct {
+	Command
+	Config *ServiceInstallConfig
+}
+
+// Current state of the installed service
+type serviceStatus struct {
+	Init        string `json:"init"`
+	Installed   bool   `json:"installed"`
+	Enabled     bool   `json:"enabled"`
+	Running     bool   `json:"running"`
+	Pid         int    `json:"pid,omitempty"`
+	ServicePath string `json:"service_path,omitempty"`
+	ConfigPath  string `json:"config_path,omitempty"`
+	Port        int64  `json:"port,omitempty"`
+}
+
+func BuildServiceStatusCommand(name string, ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		flags := flag.NewFlagSet("service status", flag.ContinueOnError)
+		data := make(map[string]interface{})
+		setDefaultFlags(flags, data)
+
+		data["json"] = flags.Bool("json", false, "Output status as JSON")
+		if runtime.GOOS != "windows" {
+			data["runit_sv"] = flags.String("runit-sv", RUNIT_DIR, "Path to runit sv directory")
+			data["init"] = flags.String("init-style", "", "Init in use (systemd, runit, sysv)")
+		}
+
+		return &ServiceStatusCommand{
+			Command: Command{
+				DefaultConfig: &Config{},
+				Name:          name,
+				Flags:         flags,
+				HelpText:      name + " service status",
+				SynopsisText:  "Display service status",
+				UI:            ui,
+				flagdata:      data},
+			Config: &ServiceInstallConfig{}}, nil
+	}
+}
+
+func (c *ServiceStatusCommand) Run(args []string) int {
+	exitCode := 1
+	err := c.setup(args)
+	if err != nil {
+		c.UI.Error("Failed to initialize: " + err.Error())
+		return exitCode
+	}
+	status, err := c.status()
+	if err != nil {
+		c.UI.Error("Failed to check service status: " + err.Error())
+		return exitCode
+	}
+	// A port argument of the service takes precedence over
+	// the port defined within the configuration file
+	if status.Installed && status.Port == 0 && status.ConfigPath != "" {
+		status.Port = c.configPort(status.ConfigPath)
+	}
+	if *(c.flagdata["json"].(*bool)) {
+		out, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			c.UI.Error("Failed to output status: " + err.Error())
+			return exitCode
+		}
+		c.UI.Output(string(out))
+	} else {
+		c.UI.Output(c.format(status))
+	}
+	if !status.Running {
+		return SERVICE_NOT_RUNNING_EXIT_CODE
+	}
+	return 0
+}
+
+func (c *ServiceStatusCommand) setup(args []string) (err error) {
+	err = c.defaultSetup(args)
+	if err != nil {
+		return
+	}
+
+	var sc ServiceInstallConfig
+	if c.DefaultConfig.configFile != nil && c.DefaultConfig.configFile.ServiceInstallConfig != nil {
+		sc = *c.DefaultConfig.configFile.ServiceInstallConfig
+	}
+
+	if runtime.GOOS != "windows" {
+		c.Config.Init = c.getConfigValue("init", sc.Pinit)
+		c.Config.RunitDir = c.getConfigValue("runit_sv", sc.PrunitDir)
+	}
+	return
+}
+
+// Read the API port from the configuration used by the service. The
+// default port is used when the configuration does not define a port.
+func (c *ServiceStatusCommand) configPort(configPath string) int64 {
+	contents, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		c.logger.Debug("service configuration read failure", "path", configPath, "error", err)
+		return DEFAULT_RESTAPI_PORT
+	}
+	config := &ConfigFile{}
+	if err = hclsimple.Decode(configPath, contents, nil, config); err != nil {
+		c.logger.Debug("service configuration parse failure", "path", configPath, "error", err)
+		return DEFAULT_RESTAPI_PORT
+	}
+	if config.RestApiConfig != nil && config.RestApiConfig.Pport != nil {
+		return *config.RestApiConfig.Pport
+	}
+	return DEFAULT_RESTAPI_PORT
+}
+
+func (c *ServiceStatusCommand) format(status *serviceStatus) string {
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Init:\t%s\n", status.Init)
+	fmt.Fprintf(w, "Installed:\t%s\n", yesNo(status.Installed))
+	fmt.Fprintf(w, "Enabled:\t%s\n", yesNo(status.Enabled))
+	fmt.Fprintf(w, "Running:\t%s\n", yesNo(status.Running))
+	if status.Pid > 0 {
+		fmt.Fprintf(w, "PID:\t%d\n", status.Pid)
+	}
+	if status.ServicePath != "" {
+		fmt.Fprintf(w, "Service:\t%s\n", status.ServicePath)
+	}
+	if status.ConfigPath != "" {
+		fmt.Fprintf(w, "Config:\t%s\n", status.ConfigPath)
+	}
+	if status.Port > 0 {
+		fmt.Fprintf(w, "Port:\t%s\n", strconv.FormatInt(status.Port, 10))
+	}
+	w.Flush()
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+func yesNo(v bool) string {
+	if v {
+		return "yes"
+	}
+	return "no"
+}
+
+// Extract the configuration file path from the command line of a
+// service definition. Unquoted values end at any of the terminators.
+func configFileArgument(definition, terminators string) string {
+	idx := strings.Index(definition, "-config-file=")
+	if idx < 0 {
+		return ""
+	}
+	value := definition[idx+len("-config-file="):]
+	if strings.HasPrefix(value, `"`) {
+		value = value[1:]
+		if end := strings.Index(value, `"`); end >= 0 {
+			return value[:end]
+		}
+		return value
+	}
+	if end := strings.IndexAny(value, terminators); end >= 0 {
+		return value[:end]
+	}
+	return value
+}
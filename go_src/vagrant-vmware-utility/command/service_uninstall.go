@@ -63,7 +63,7 @@ func (c *ServiceUninstallCommand) setup(args []string) (err error) {
 	}
 
 	var sc ServiceInstallConfig
-	if c.DefaultConfig.configFile != nil {
+	if c.DefaultConfig.configFile != nil && c.DefaultConfig.configFile.ServiceInstallConfig != nil {
 		sc = *c.DefaultConfig.configFile.ServiceInstallConfig
 	}
 
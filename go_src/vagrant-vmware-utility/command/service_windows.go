@@ -11,6 +11,7 @@ import (
 
 	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/service"
 	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+	"golang.org/x/sys/windows/svc"
 	"golang.org/x/sys/windows/svc/eventlog"
 	"golang.org/x/sys/windows/svc/mgr"
 )
@@ -119,3 +120,64 @@ func (c *ServiceUninstallCommand) uninstall() error {
 	c.logger.Trace("service uninstalled", "name", WINDOWS_SERVICE_NAME)
 	return nil
 }
+
+func (c *ServiceStatusCommand) status() (*serviceStatus, error) {
+	status := &serviceStatus{Init: "windows"}
+	m, err := service.ManagerConnect(c.logger)
+	if err != nil {
+		c.logger.Debug("service manager connect failure", "error", err)
+		return nil, errors.New("failed to connect to service manager")
+	}
+	defer m.Disconnect()
+	s, err := m.Get(WINDOWS_SERVICE_NAME)
+	if err != nil {
+		c.logger.Trace("failed to locate utility service", "error", err)
+		return status, nil
+	}
+	defer s.Close()
+	status.Installed = true
+	status.ServicePath = WINDOWS_SERVICE_NAME
+	scon, err := s.Config()
+	if err != nil {
+		c.logger.Debug("service config fetch failure", "name", WINDOWS_SERVICE_NAME,
+			"error", err)
+		return nil, errors.New("failed to fetch service configuration")
+	}
+	status.Enabled = scon.StartType != mgr.StartDisabled
+	status.ConfigPath = configFileArgument(scon.BinaryPathName, " \t")
+	sstat, err := s.Query()
+	if err != nil {
+		c.logger.Debug("service query failure", "name", WINDOWS_SERVICE_NAME, "error", err)
+		return nil, errors.New("failed to query service")
+	}
+	status.Running = sstat.State == svc.Running
+	if status.Running {
+		status.Pid = int(sstat.ProcessId)
+	}
+	return status, nil
+}
+
+func (c *ServiceRestartCommand) restart() error {
+	m, err := service.ManagerConnect(c.logger)
+	if err != nil {
+		c.logger.Debug("service manager connect failure", "error", err)
+		return errors.New("failed to connect to service manager")
+	}
+	defer m.Disconnect()
+	s, err := m.Get(WINDOWS_SERVICE_NAME)
+	if err != nil {
+		c.logger.Debug("failed to locate utility service", "error", err)
+		return errors.New("service is not installed")
+	}
+	defer s.Close()
+	if err = m.StopSvc(s); err != nil {
+		c.logger.Debug("service stop failure", "name", WINDOWS_SERVICE_NAME, "error", err)
+		return errors.New("failed to stop service")
+	}
+	if err = m.StartSvc(s); err != nil {
+		c.logger.Debug("service start failure", "name", WINDOWS_SERVICE_NAME, "error", err)
+		return errors.New("failed to start service")
+	}
+	c.logger.Trace("service restarted", "name", WINDOWS_SERVICE_NAME)
+	return nil
+}
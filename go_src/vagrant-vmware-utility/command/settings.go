@@ -0,0 +1,201 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/hashicorp/cli"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/settings"
+)
+
+// Command for inspecting and repairing the settings files
+type SettingsCommand struct {
+	Command
+	action string
+}
+
+// Result of verifying the settings files
+type settingsVerification struct {
+	Valid bool                   `json:"valid"`
+	Files []*settings.FileStatus `json:"files"`
+}
+
+func BuildSettingsCommand(name, action string, ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		flags := flag.NewFlagSet("settings "+action, flag.ContinueOnError)
+		data := make(map[string]interface{})
+		setDefaultFlags(flags, data)
+		data["settings_directory"] = flags.String("settings-directory", "", "Directory containing settings files (default utility settings directory)")
+		data["json"] = flags.Bool("json", false, "Output as JSON")
+
+		var help, synopsis string
+		switch action {
+		case "show":
+			help, synopsis = " settings show [options]", "Display the settings files"
+		case "verify":
+			help, synopsis = " settings verify [options]", "Verify the settings files can be loaded"
+		case "repair":
+			help = " settings repair [options]\n\n" +
+				"  Upgrades outdated settings files and replaces invalid settings files\n" +
+				"  with the newest valid backup. The service should be stopped first."
+			synopsis = "Upgrade and repair the settings files"
+		}
+
+		return &SettingsCommand{
+			Command: Command{
+				DefaultConfig: &Config{},
+				Name:          name,
+				Flags:         flags,
+				HelpText:      name + help,
+				SynopsisText:  synopsis,
+				UI:            ui,
+				flagdata:      data},
+			action: action}, nil
+	}
+}
+
+func (c *SettingsCommand) Run(args []string) int {
+	exitCode := 1
+	if err := c.defaultSetup(args); err != nil {
+		c.UI.Error("Failed to initialize: " + err.Error())
+		return exitCode
+	}
+	if c.Flags.NArg() != 0 {
+		c.UI.Error("Unexpected arguments provided\n\n" + c.Help())
+		return exitCode
+	}
+
+	paths := c.paths()
+	kinds := []string{}
+	for kind := range paths {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	switch c.action {
+	case "show", "verify":
+		result := &settingsVerification{Valid: true, Files: []*settings.FileStatus{}}
+		for _, kind := range kinds {
+			status := settings.Inspect(paths[kind], kind)
+			if status.Exists && (!status.Valid || status.Outdated) {
+				result.Valid = false
+			}
+			result.Files = append(result.Files, status)
+		}
+		if c.action == "show" {
+			if c.jsonOutput() {
+				if err := c.outputJson(result.Files); err != nil {
+					c.UI.Error("Failed to output settings: " + err.Error())
+					return exitCode
+				}
+				break
+			}
+			rows := [][]string{}
+			for _, s := range result.Files {
+				version, entries := "-", "-"
+				if s.Exists && s.Error == "" {
+					version, entries = strconv.Itoa(s.Version), strconv.Itoa(s.Entries)
+				}
+				rows = append(rows, []string{s.Kind, settingsState(s), version,
+					entries, strconv.Itoa(len(s.Backups)), s.Path})
+			}
+			c.outputTable([]string{"KIND", "STATE", "VERSION", "ENTRIES", "BACKUPS", "PATH"}, rows)
+			break
+		}
+
+		if c.jsonOutput() {
+			if err := c.outputJson(result); err != nil {
+				c.UI.Error("Failed to output verification: " + err.Error())
+				return exitCode
+			}
+		} else {
+			for _, s := range result.Files {
+				switch {
+				case !s.Exists:
+					c.UI.Output(fmt.Sprintf("%s settings file does not exist: %s", s.Kind, s.Path))
+				case !s.Valid:
+					c.UI.Error(fmt.Sprintf("Error: %s settings file is invalid: %s", s.Kind, s.Error))
+				case s.Outdated:
+					c.UI.Warn(fmt.Sprintf("Warning: %s settings file uses version %d and requires upgrade to version %d",
+						s.Kind, s.Version, settings.SETTINGS_VERSION))
+				}
+			}
+			if result.Valid {
+				c.UI.Info("Settings files are valid!")
+			} else {
+				c.UI.Output("Run `" + c.Name + " settings repair` to repair the settings files")
+			}
+		}
+		if !result.Valid {
+			return exitCode
+		}
+	case "repair":
+		results := []*settings.RepairResult{}
+		failed := false
+		for _, kind := range kinds {
+			result, err := settings.Repair(paths[kind], kind, c.logger)
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("Failed to repair %s settings: %s", kind, err))
+				failed = true
+				continue
+			}
+			results = append(results, result)
+		}
+		if c.jsonOutput() {
+			if err := c.outputJson(results); err != nil {
+				c.UI.Error("Failed to output repair results: " + err.Error())
+				return exitCode
+			}
+		} else {
+			for _, r := range results {
+				switch r.Action {
+				case settings.REPAIR_NONE:
+					c.UI.Output(fmt.Sprintf("%s settings file requires no repair", r.Kind))
+				case settings.REPAIR_UPGRADED:
+					c.UI.Info(fmt.Sprintf("%s settings file upgraded (backup: %s)", r.Kind, r.Backup))
+				case settings.REPAIR_RESTORED:
+					c.UI.Info(fmt.Sprintf("%s settings file restored from %s (invalid file: %s)",
+						r.Kind, r.Restored, r.Backup))
+				case settings.REPAIR_RESET:
+					c.UI.Warn(fmt.Sprintf("%s settings file reset, no valid backup found (invalid file: %s)",
+						r.Kind, r.Backup))
+				}
+			}
+		}
+		if failed {
+			return exitCode
+		}
+	}
+	return 0
+}
+
+// Paths of the settings files, using the settings
+// directory when provided
+func (c *SettingsCommand) paths() map[string]string {
+	paths := settings.Paths()
+	dir := c.getConfigValue("settings_directory", nil)
+	if dir != "" {
+		for kind, path := range paths {
+			paths[kind] = filepath.Join(dir, filepath.Base(path))
+		}
+	}
+	return paths
+}
+
+func settingsState(s *settings.FileStatus) string {
+	switch {
+	case !s.Exists:
+		return "missing"
+	case !s.Valid:
+		return "invalid"
+	case s.Outdated:
+		return "outdated"
+	}
+	return "valid"
+}
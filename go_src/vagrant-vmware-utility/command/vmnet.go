@@ -0,0 +1,191 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/hashicorp/cli"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/driver"
+)
+
+// Command for managing vmnet devices
+type VmnetCommand struct {
+	ClientCommand
+	action string
+}
+
+func BuildVmnetCommand(name, action string, ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		flags := flag.NewFlagSet("vmnet "+action, flag.ContinueOnError)
+		data := make(map[string]interface{})
+		setDefaultFlags(flags, data)
+		setClientFlags(flags, data)
+
+		var help, synopsis string
+		switch action {
+		case "list":
+			help, synopsis = " vmnet list [options]", "List vmnet devices"
+		case "create":
+			help, synopsis = " vmnet create -type TYPE [options]", "Create a vmnet device"
+			setVmnetFlags(flags, data)
+			data["name"] = flags.String("name", "", "Name of the device (default next available)")
+		case "update":
+			help, synopsis = " vmnet update [options] VMNET", "Update a vmnet device"
+			setVmnetFlags(flags, data)
+		case "delete":
+			help, synopsis = " vmnet delete [options] VMNET", "Delete a vmnet device"
+		case "verify":
+			help, synopsis = " vmnet verify [options]", "Verify vmnet devices and restore missing devices"
+		}
+
+		return &VmnetCommand{
+			ClientCommand: ClientCommand{
+				Command: Command{
+					DefaultConfig: &Config{},
+					Name:          name,
+					Flags:         flags,
+					HelpText:      name + help,
+					SynopsisText:  synopsis,
+					UI:            ui,
+					flagdata:      data}},
+			action: action}, nil
+	}
+}
+
+func setVmnetFlags(flags *flag.FlagSet, data map[string]interface{}) {
+	data["type"] = flags.String("type", "", "Type of the device (nat or hostOnly)")
+	data["dhcp"] = flags.String("dhcp", "", "Enable DHCP on the device (yes or no)")
+	data["subnet"] = flags.String("subnet", "", "Subnet of the device")
+	data["mask"] = flags.String("mask", "", "Subnet mask of the device")
+}
+
+func (c *VmnetCommand) Run(args []string) int {
+	exitCode := 1
+	err := c.setup(args)
+	if err != nil {
+		c.UI.Error("Failed to initialize: " + err.Error())
+		return exitCode
+	}
+
+	var device string
+	switch c.action {
+	case "update", "delete":
+		if c.Flags.NArg() != 1 {
+			c.UI.Error("Name of the vmnet device is required\n\n" + c.Help())
+			return exitCode
+		}
+		device = c.Flags.Arg(0)
+		if _, err := parseVmnetSlot(device); err != nil {
+			c.UI.Error(err.Error())
+			return exitCode
+		}
+	default:
+		if c.Flags.NArg() != 0 {
+			c.UI.Error("Unexpected arguments provided\n\n" + c.Help())
+			return exitCode
+		}
+	}
+
+	var vmnet *driver.Vmnet
+	if c.action == "create" || c.action == "update" {
+		if vmnet, err = c.vmnet(device); err != nil {
+			c.UI.Error(err.Error())
+			return exitCode
+		}
+	}
+
+	client, err := c.client()
+	if err != nil {
+		c.UI.Error("Failed to setup client: " + err.Error())
+		return exitCode
+	}
+	ctx := context.Background()
+
+	switch c.action {
+	case "list":
+		vmnets, err := client.Vmnets(ctx)
+		if err != nil {
+			c.UI.Error("Failed to list vmnet devices: " + err.Error())
+			return exitCode
+		}
+		if c.jsonOutput() {
+			err = c.outputJson(vmnets)
+			break
+		}
+		rows := [][]string{}
+		for _, v := range vmnets.Vmnets {
+			rows = append(rows, []string{v.Name, v.Type, v.Dhcp, v.Subnet, v.Mask})
+		}
+		c.outputTable([]string{"NAME", "TYPE", "DHCP", "SUBNET", "MASK"}, rows)
+	case "create", "update":
+		if c.action == "create" {
+			vmnet, err = client.AddVmnet(ctx, vmnet)
+		} else {
+			vmnet, err = client.UpdateVmnet(ctx, vmnet)
+		}
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Failed to %s vmnet device: %s", c.action, err))
+			return exitCode
+		}
+		if c.jsonOutput() {
+			err = c.outputJson(vmnet)
+			break
+		}
+		c.UI.Info(fmt.Sprintf("Device %s %sd", vmnet.Name, c.action))
+	case "delete":
+		if err = client.DeleteVmnet(ctx, device); err != nil {
+			c.UI.Error("Failed to delete vmnet device: " + err.Error())
+			return exitCode
+		}
+		if !c.jsonOutput() {
+			c.UI.Info(fmt.Sprintf("Device %s deleted", device))
+		}
+	case "verify":
+		if err = client.VerifyVmnet(ctx); err != nil {
+			c.UI.Error("Failed to verify vmnet devices: " + err.Error())
+			return exitCode
+		}
+		if !c.jsonOutput() {
+			c.UI.Info("Devices verified")
+		}
+	}
+	if err != nil {
+		c.UI.Error("Failed to output result: " + err.Error())
+		return exitCode
+	}
+	return 0
+}
+
+// Build the vmnet device from the provided flags
+func (c *VmnetCommand) vmnet(device string) (*driver.Vmnet, error) {
+	vmnet := &driver.Vmnet{
+		Name:   device,
+		Type:   *(c.flagdata["type"].(*string)),
+		Dhcp:   *(c.flagdata["dhcp"].(*string)),
+		Subnet: *(c.flagdata["subnet"].(*string)),
+		Mask:   *(c.flagdata["mask"].(*string)),
+	}
+	if c.action == "create" {
+		vmnet.Name = *(c.flagdata["name"].(*string))
+		if vmnet.Type == "" {
+			return nil, errors.New("Type of the vmnet device is required")
+		}
+		if vmnet.Name != "" {
+			if _, err := parseVmnetSlot(vmnet.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if vmnet.Type != "" && vmnet.Type != "nat" && vmnet.Type != "hostOnly" {
+		return nil, fmt.Errorf("invalid device type '%s' (expected nat or hostOnly)", vmnet.Type)
+	}
+	if vmnet.Dhcp != "" && vmnet.Dhcp != "yes" && vmnet.Dhcp != "no" {
+		return nil, fmt.Errorf("invalid dhcp value '%s' (expected yes or no)", vmnet.Dhcp)
+	}
+	return vmnet, nil
+}
@@ -4,6 +4,7 @@
 package driver
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 
@@ -45,218 +46,231 @@ func NewAdvancedDriver(vmxPath *string, b *BaseDriver, logger hclog.Logger) (a *
 	return
 }
 
-func (a *AdvancedDriver) AddVmnet(vmnet *Vmnet) error {
-	device, err := a.vnetlib.CreateDevice(vmnet.Name)
+func (a *AdvancedDriver) Capabilities() *Capabilities {
+	caps := a.BaseDriver.Capabilities()
+	caps.Driver = "advanced"
+	return caps
+}
+
+func (a *AdvancedDriver) AddVmnet(ctx context.Context, vmnet *Vmnet) error {
+	logger := utility.ContextLogger(ctx, a.logger)
+	device, err := a.vnetlib.CreateDevice(ctx, vmnet.Name)
 	if err != nil {
-		a.logger.Debug("device creation failed", "device-name", vmnet.Name, "error", err)
-		return err
+		logger.Debug("device creation failed", "device-name", vmnet.Name, "error", err)
+		return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 	}
 	// Configure any hostonly options
 	if vmnet.Mask != "" {
-		if err = a.vnetlib.SetSubnetMask(device, vmnet.Mask); err != nil {
-			a.logger.Debug("device subnet mask set failure", "device-name", vmnet.Name,
+		if err = a.vnetlib.SetSubnetMask(ctx, device, vmnet.Mask); err != nil {
+			logger.Debug("device subnet mask set failure", "device-name", vmnet.Name,
 				"mask", vmnet.Mask, "error", err)
-			return err
+			return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 		}
 	}
 	if vmnet.Subnet != "" {
-		if err = a.vnetlib.SetSubnetAddress(device, vmnet.Subnet); err != nil {
-			a.logger.Debug("device subnet set failure", "device-name", vmnet.Name,
+		if err = a.vnetlib.SetSubnetAddress(ctx, device, vmnet.Subnet); err != nil {
+			logger.Debug("device subnet set failure", "device-name", vmnet.Name,
 				"subnet", vmnet.Subnet, "error", err)
-			return err
+			return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 		}
 	}
 	// Enable the device
-	if err = a.vnetlib.EnableDevice(device); err != nil {
-		a.logger.Debug("device enable failure", "device-name", vmnet.Name, "error", err)
-		return err
+	if err = a.vnetlib.EnableDevice(ctx, device); err != nil {
+		logger.Debug("device enable failure", "device-name", vmnet.Name, "error", err)
+		return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 	}
 	// Enable any required services
 	if vmnet.Dhcp == "yes" {
-		if err = a.vnetlib.SetDHCP(device, true); err != nil {
-			a.logger.Debug("device DHCP enable failure", "device-name", vmnet.Name,
+		if err = a.vnetlib.SetDHCP(ctx, device, true); err != nil {
+			logger.Debug("device DHCP enable failure", "device-name", vmnet.Name,
 				"error", err)
-			return err
+			return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 		}
-		if err = a.vnetlib.StartDHCP(device); err != nil {
-			a.logger.Debug("device DHCP start failure", "device-name", vmnet.Name,
+		if err = a.vnetlib.StartDHCP(ctx, device); err != nil {
+			logger.Debug("device DHCP start failure", "device-name", vmnet.Name,
 				"error", err)
-			return err
+			return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 		}
 	}
 	if vmnet.Type == "nat" {
-		if err = a.vnetlib.SetNAT(device, true); err != nil {
-			a.logger.Debug("device NAT enable failure", "device-name", vmnet.Name,
+		if err = a.vnetlib.SetNAT(ctx, device, true); err != nil {
+			logger.Debug("device NAT enable failure", "device-name", vmnet.Name,
 				"error", err)
-			return err
+			return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 		}
-		if err = a.vnetlib.StartNAT(device); err != nil {
-			a.logger.Debug("device NAT start failure", "device-name", vmnet.Name,
+		if err = a.vnetlib.StartNAT(ctx, device); err != nil {
+			logger.Debug("device NAT start failure", "device-name", vmnet.Name,
 				"error", err)
-			return err
+			return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 		}
 	}
 	vmnet.Name = device
-	a.logger.Debug("vmnet create", "name", device, "dhcp", vmnet.Dhcp,
+	logger.Debug("vmnet create", "name", device, "dhcp", vmnet.Dhcp,
 		"type", vmnet.Type, "subnet", vmnet.Subnet, "mask",
 		vmnet.Mask)
 	return nil
 }
 
-func (a *AdvancedDriver) UpdateVmnet(vmnet *Vmnet) error {
+func (a *AdvancedDriver) UpdateVmnet(ctx context.Context, vmnet *Vmnet) error {
+	logger := utility.ContextLogger(ctx, a.logger)
 	device := vmnet.Name
 	// Configure any hostonly options
 	if vmnet.Mask != "" {
-		if err := a.vnetlib.SetSubnetMask(device, vmnet.Mask); err != nil {
-			a.logger.Debug("device subnet mask set failure", "device-name", vmnet.Name,
+		if err := a.vnetlib.SetSubnetMask(ctx, device, vmnet.Mask); err != nil {
+			logger.Debug("device subnet mask set failure", "device-name", vmnet.Name,
 				"mask", vmnet.Mask, "error", err)
-			return err
+			return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 		}
 	}
 	if vmnet.Subnet != "" {
-		if err := a.vnetlib.SetSubnetAddress(device, vmnet.Subnet); err != nil {
-			a.logger.Debug("device subnet set failure", "device-name", vmnet.Name,
+		if err := a.vnetlib.SetSubnetAddress(ctx, device, vmnet.Subnet); err != nil {
+			logger.Debug("device subnet set failure", "device-name", vmnet.Name,
 				"subnet", vmnet.Subnet, "error", err)
-			return err
+			return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 		}
 	}
 	// Apply new configuration
-	if err := a.vnetlib.UpdateDevice(device); err != nil {
-		a.logger.Debug("device update failure", "device-name", vmnet.Name,
+	if err := a.vnetlib.UpdateDevice(ctx, device); err != nil {
+		logger.Debug("device update failure", "device-name", vmnet.Name,
 			"error", err)
-		return err
+		return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 	}
 
 	// Enable/disable any required services
-	if a.vnetlib.StatusDHCP(device) {
+	if a.vnetlib.StatusDHCP(ctx, device) {
 		if vmnet.Dhcp == "no" {
-			if err := a.vnetlib.SetDHCP(device, false); err != nil {
-				a.logger.Debug("device DHCP disable failure", "device-name", vmnet.Name,
+			if err := a.vnetlib.SetDHCP(ctx, device, false); err != nil {
+				logger.Debug("device DHCP disable failure", "device-name", vmnet.Name,
 					"error", err)
-				return err
+				return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 			}
-			if err := a.vnetlib.StopDHCP(device); err != nil {
-				a.logger.Debug("device DHCP stop failure", "device-name", vmnet.Name,
+			if err := a.vnetlib.StopDHCP(ctx, device); err != nil {
+				logger.Debug("device DHCP stop failure", "device-name", vmnet.Name,
 					"error", err)
-				return err
+				return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 			}
 		}
 	} else {
 		if vmnet.Dhcp == "yes" {
-			if err := a.vnetlib.SetDHCP(device, true); err != nil {
-				a.logger.Debug("device DHCP enable failure", "device-name", vmnet.Name,
+			if err := a.vnetlib.SetDHCP(ctx, device, true); err != nil {
+				logger.Debug("device DHCP enable failure", "device-name", vmnet.Name,
 					"error", err)
-				return err
+				return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 			}
-			if err := a.vnetlib.StartDHCP(device); err != nil {
-				a.logger.Debug("device DHCP start failure", "device-name", vmnet.Name,
+			if err := a.vnetlib.StartDHCP(ctx, device); err != nil {
+				logger.Debug("device DHCP start failure", "device-name", vmnet.Name,
 					"error", err)
-				return err
+				return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 			}
 		}
 	}
 
-	if a.vnetlib.StatusNAT(device) {
+	if a.vnetlib.StatusNAT(ctx, device) {
 		if vmnet.Type != "nat" {
-			if err := a.vnetlib.SetNAT(device, false); err != nil {
-				a.logger.Debug("device NAT disable failure", "device-name", vmnet.Name,
+			if err := a.vnetlib.SetNAT(ctx, device, false); err != nil {
+				logger.Debug("device NAT disable failure", "device-name", vmnet.Name,
 					"error", err)
-				return err
+				return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 			}
-			if err := a.vnetlib.StopNAT(device); err != nil {
-				a.logger.Debug("device NAT stop failure", "device-name", vmnet.Name,
+			if err := a.vnetlib.StopNAT(ctx, device); err != nil {
+				logger.Debug("device NAT stop failure", "device-name", vmnet.Name,
 					"error", err)
-				return err
+				return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 			}
 		}
 	} else {
 		if vmnet.Type == "nat" {
-			if err := a.vnetlib.SetNAT(device, true); err != nil {
-				a.logger.Debug("device NAT enable failure", "device-name", vmnet.Name,
+			if err := a.vnetlib.SetNAT(ctx, device, true); err != nil {
+				logger.Debug("device NAT enable failure", "device-name", vmnet.Name,
 					"error", err)
-				return err
+				return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 			}
-			if err := a.vnetlib.StartNAT(device); err != nil {
-				a.logger.Debug("device NAT start failure", "device-name", vmnet.Name,
+			if err := a.vnetlib.StartNAT(ctx, device); err != nil {
+				logger.Debug("device NAT start failure", "device-name", vmnet.Name,
 					"error", err)
-				return err
+				return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 			}
 		}
 	}
-	a.logger.Debug("vmnet update", "name", device, "dhcp", vmnet.Dhcp,
+	logger.Debug("vmnet update", "name", device, "dhcp", vmnet.Dhcp,
 		"type", vmnet.Type, "subnet", vmnet.Subnet, "mask",
 		vmnet.Mask)
 	return nil
 }
 
-func (a *AdvancedDriver) DeleteVmnet(vmnet *Vmnet) error {
+func (a *AdvancedDriver) DeleteVmnet(ctx context.Context, vmnet *Vmnet) error {
+	logger := utility.ContextLogger(ctx, a.logger)
 	device := vmnet.Name
 	// First disable the device
-	if err := a.vnetlib.DisableDevice(device); err != nil {
-		a.logger.Debug("device disable failure", "device-name", device, "error", err)
-		return err
+	if err := a.vnetlib.DisableDevice(ctx, device); err != nil {
+		logger.Debug("device disable failure", "device-name", device, "error", err)
+		return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 	}
 	// Now remove the device
-	if err := a.vnetlib.DeleteDevice(device); err != nil {
-		a.logger.Debug("device delete failure", "device-name", device, "error", err)
-		return err
+	if err := a.vnetlib.DeleteDevice(ctx, device); err != nil {
+		logger.Debug("device delete failure", "device-name", device, "error", err)
+		return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 	}
 	return nil
 }
 
 // Lookup reserved DHCP address for MAC
-func (a *AdvancedDriver) LookupDhcpAddress(device, mac string) (addr string, err error) {
+func (a *AdvancedDriver) LookupDhcpAddress(ctx context.Context, device, mac string) (addr string, err error) {
+	logger := utility.ContextLogger(ctx, a.logger)
 	leases, err := utility.LoadDhcpLeaseFile(a.vmwarePaths.DhcpLeaseFile(device), a.logger)
 	if err != nil {
-		a.logger.Debug("dhcp leases file load failure", "error", err)
+		logger.Debug("dhcp leases file load failure", "error", err)
 		return addr, err
 	}
 	paddr, err := leases.IpForMac(mac)
 	if err == nil {
 		return paddr, err
 	}
-	return a.vnetlib.LookupReservedAddress(device, mac)
+	addr, err = a.vnetlib.LookupReservedAddress(ctx, device, mac)
+	return addr, WrapError(ERROR_NOT_FOUND, err)
 }
 
-func (a *AdvancedDriver) ReserveDhcpAddress(slot int, mac, ip string) error {
+func (a *AdvancedDriver) ReserveDhcpAddress(ctx context.Context, slot int, mac, ip string) error {
+	logger := utility.ContextLogger(ctx, a.logger)
 	device := fmt.Sprintf("vmnet%d", slot)
-	if err := a.vnetlib.ReserveAddress(device, mac, ip); err != nil {
-		a.logger.Debug("dhcp reservation failure", "device", device,
+	if err := a.vnetlib.ReserveAddress(ctx, device, mac, ip); err != nil {
+		logger.Debug("dhcp reservation failure", "device", device,
 			"mac", mac, "address", ip, "error", err)
-		return err
+		return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 	}
-	a.logger.Trace("restarting DHCP service to apply update", "device", device)
-	_ = a.vnetlib.StopDHCP(device)
-	err := a.vnetlib.StartDHCP(device)
+	logger.Trace("restarting DHCP service to apply update", "device", device)
+	_ = a.vnetlib.StopDHCP(ctx, device)
+	err := a.vnetlib.StartDHCP(context.WithoutCancel(ctx), device)
 	if err != nil {
-		a.logger.Error("dhcp service restart failure", "device", device, "error", err)
-		return err
+		logger.Error("dhcp service restart failure", "device", device, "error", err)
+		return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 	}
 	return nil
 }
 
 // For deletion of the port forward we can just use the vnetlib
 // CLI directly as we no longer care about the description
-func (a *AdvancedDriver) DeletePortFwd(pfwds []*PortFwd) error {
+func (a *AdvancedDriver) DeletePortFwd(ctx context.Context, pfwds []*PortFwd) error {
+	logger := utility.ContextLogger(ctx, a.logger)
 	devices := []string{}
 	for _, pfwd := range pfwds {
 		if a.InternalPortForwarding() {
-			if err := a.DeleteInternalPortForward(pfwd); err != nil {
+			if err := a.DeleteInternalPortForward(ctx, pfwd); err != nil {
 				return err
 			}
 		} else {
 			deviceName := fmt.Sprintf("vmnet%d", pfwd.SlotNumber)
-			if err := a.vnetlib.DeletePortFwd(deviceName, pfwd.Protocol, strconv.Itoa(pfwd.Port)); err != nil {
-				a.logger.Debug("port forward delete failure", "error", err)
-				return err
+			if err := a.vnetlib.DeletePortFwd(ctx, deviceName, pfwd.Protocol, strconv.Itoa(pfwd.Port)); err != nil {
+				logger.Debug("port forward delete failure", "error", err)
+				return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 			}
 			rfwd := &utility.PortFwd{HostPort: pfwd.Port, Protocol: pfwd.Protocol}
 			if err := a.settings.NAT.Remove(rfwd); err != nil {
-				a.logger.Debug("failed to remove forward from settings", "error", err)
+				logger.Debug("failed to remove forward from settings", "error", err)
 				return err
 			}
 			if err := a.settings.NAT.Save(); err != nil {
-				a.logger.Debug("failed to save nat settings", "error", err)
+				logger.Debug("failed to save nat settings", "error", err)
 				return err
 			}
 			found := false
@@ -271,25 +285,28 @@ func (a *AdvancedDriver) DeletePortFwd(pfwds []*PortFwd) error {
 		}
 	}
 	for _, deviceName := range devices {
-		if err := a.restartNAT(deviceName); err != nil {
+		if err := a.restartNAT(ctx, deviceName); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (a *AdvancedDriver) restartNAT(device string) error {
-	if err := a.vnetlib.StopNAT(device); err != nil {
-		a.logger.Debug("NAT stop failure", "device", device, "error", err)
-		return err
+func (a *AdvancedDriver) restartNAT(ctx context.Context, device string) error {
+	logger := utility.ContextLogger(ctx, a.logger)
+	if err := a.vnetlib.StopNAT(ctx, device); err != nil {
+		logger.Debug("NAT stop failure", "device", device, "error", err)
+		return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 	}
-	if err := a.vnetlib.UpdateDeviceNAT(device); err != nil {
-		a.logger.Debug("device NAT update failure", "device", device, "error", err)
-		return err
+	if err := a.vnetlib.UpdateDeviceNAT(ctx, device); err != nil {
+		logger.Debug("device NAT update failure", "device", device, "error", err)
+		return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 	}
-	if err := a.vnetlib.StartNAT(device); err != nil {
-		a.logger.Debug("device NAT start failure", "device", device, "error", err)
-		return err
+	// Service must be started even if the request has been
+	// canceled so networking is not left unavailable
+	if err := a.vnetlib.StartNAT(context.WithoutCancel(ctx), device); err != nil {
+		logger.Debug("device NAT start failure", "device", device, "error", err)
+		return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 	}
 	return nil
 }
@@ -6,8 +6,10 @@
 package driver
 
 import (
+	"context"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
 )
@@ -16,7 +18,8 @@ import (
 // utility to write the new rules directly into the file.
 // The vnetlib CLI does not support custom descriptions
 // used for forward rules, so we just do it manually.
-func (a *AdvancedDriver) AddPortFwd(pfwds []*PortFwd) error {
+func (a *AdvancedDriver) AddPortFwd(ctx context.Context, pfwds []*PortFwd) error {
+	logger := utility.ContextLogger(ctx, a.logger)
 	netF, err := utility.LoadNetworkingFile(
 		a.vmwarePaths.Networking, a.logger)
 	if err != nil {
@@ -25,10 +28,13 @@ func (a *AdvancedDriver) AddPortFwd(pfwds []*PortFwd) error {
 	rdev := []string{}
 	for _, pfwd := range pfwds {
 		if a.InternalPortForwarding() {
-			if err := a.AddInternalPortForward(pfwd); err != nil {
+			if err := a.AddInternalPortForward(ctx, pfwd); err != nil {
 				return err
 			}
 		} else {
+			if err := a.validateVmwarePortFwd(pfwd); err != nil {
+				return err
+			}
 			description, err := a.validatePortFwdDescription(pfwd.Description)
 			if err != nil {
 				return err
@@ -44,11 +50,11 @@ func (a *AdvancedDriver) AddPortFwd(pfwds []*PortFwd) error {
 			}
 			err = netF.AddPortFwd(newPf)
 			if err != nil {
-				a.logger.Debug("port forwarding failure", "error", err)
+				logger.Debug("port forwarding failure", "error", err)
 				return err
 			}
 			if err := a.savePortFwd(pfwd); err != nil {
-				a.logger.Debug("port forward settings failure", "error", err)
+				logger.Debug("port forward settings failure", "error", err)
 				return err
 			}
 			device := fmt.Sprintf("vmnet%d", pfwd.SlotNumber)
@@ -64,21 +70,25 @@ func (a *AdvancedDriver) AddPortFwd(pfwds []*PortFwd) error {
 		}
 	}
 	for _, device := range rdev {
-		if err := a.saveAndRestartNAT(device, netF); err != nil {
+		if err := a.saveAndRestartNAT(ctx, device, netF); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (a *AdvancedDriver) saveAndRestartNAT(device string, netF utility.NetworkingFile) error {
+func (a *AdvancedDriver) saveAndRestartNAT(ctx context.Context, device string, netF utility.NetworkingFile) error {
+	logger := utility.ContextLogger(ctx, a.logger)
 	if err := a.settings.NAT.Save(); err != nil {
-		a.logger.Debug("nat settings file save failure", "error", err)
+		logger.Debug("nat settings file save failure", "error", err)
 		return err
 	}
-	if _, err := netF.Save(); err != nil {
-		a.logger.Debug("network file save failure", "error", err)
+	start := time.Now()
+	path, err := netF.Save()
+	utility.Audit(ctx, "networking.write", map[string]interface{}{"path": path}, start, err)
+	if err != nil {
+		logger.Debug("network file save failure", "error", err)
 		return err
 	}
-	return a.restartNAT(device)
+	return a.restartNAT(ctx, device)
 }
@@ -4,22 +4,29 @@
 package driver
 
 import (
+	"context"
 	"fmt"
 	"golang.org/x/sys/windows/registry"
 	"strconv"
 	"strings"
+
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
 )
 
 const VMNETCONFIG_REGISTRY_PATH = `SOFTWARE\VMware, Inc.\VMnetLib\VMnetConfig`
 
-func (a *AdvancedDriver) AddPortFwd(pfwds []*PortFwd) error {
+func (a *AdvancedDriver) AddPortFwd(ctx context.Context, pfwds []*PortFwd) error {
+	logger := utility.ContextLogger(ctx, a.logger)
 	rdev := []string{}
 	for _, pfwd := range pfwds {
 		if a.InternalPortForwarding() {
-			if err := a.AddInternalPortForward(pfwd); err != nil {
+			if err := a.AddInternalPortForward(ctx, pfwd); err != nil {
 				return err
 			}
 		} else {
+			if err := a.validateVmwarePortFwd(pfwd); err != nil {
+				return err
+			}
 			device := fmt.Sprintf("vmnet%d", pfwd.SlotNumber)
 			fwdPath := VMNETCONFIG_REGISTRY_PATH + `\` + device + `\NAT\`
 			if pfwd.Protocol == "udp" {
@@ -31,12 +38,12 @@ func (a *AdvancedDriver) AddPortFwd(pfwds []*PortFwd) error {
 			if err != nil {
 				return err
 			}
-			a.logger.Trace("adding port forward", "device", device, "port", pfwd.Port,
+			logger.Trace("adding port forward", "device", device, "port", pfwd.Port,
 				"registry-path", fwdPath)
 			access := a.registryAccess(registry.ALL_ACCESS)
 			regKey, _, err := registry.CreateKey(registry.LOCAL_MACHINE, fwdPath, access)
 			if err != nil {
-				a.logger.Trace("failed to open registry", "path", fwdPath, "error", err)
+				logger.Trace("failed to open registry", "path", fwdPath, "error", err)
 				if a.registryTakeOwnership(registry.LOCAL_MACHINE, strings.Replace(fwdPath, "SOFTWARE", `SOFTWARE\WOW6432Node`, 1)) {
 					access := a.registryAccess(registry.ALL_ACCESS)
 					regKey, _, err = registry.CreateKey(registry.LOCAL_MACHINE, fwdPath, access)
@@ -51,16 +58,16 @@ func (a *AdvancedDriver) AddPortFwd(pfwds []*PortFwd) error {
 			guestPort := strconv.Itoa(pfwd.Guest.Port)
 			err = regKey.SetStringValue(hostPort, pfwd.Guest.Ip+":"+guestPort)
 			if err != nil {
-				a.logger.Trace("failed to set port forward", "path", fwdPath, "error", err)
+				logger.Trace("failed to set port forward", "path", fwdPath, "error", err)
 				return err
 			}
 			err = regKey.SetStringValue(hostPort+"Description", description)
 			if err != nil {
-				a.logger.Trace("failed to set port forward description", "path", fwdPath, "error", err)
+				logger.Trace("failed to set port forward description", "path", fwdPath, "error", err)
 				return err
 			}
 			if err := a.savePortFwd(pfwd); err != nil {
-				a.logger.Debug("port forward settings failure", "error", err)
+				logger.Debug("port forward settings failure", "error", err)
 				return err
 			}
 			found := false
@@ -75,7 +82,7 @@ func (a *AdvancedDriver) AddPortFwd(pfwds []*PortFwd) error {
 		}
 	}
 	for _, device := range rdev {
-		if err := a.restartNAT(device); err != nil {
+		if err := a.restartNAT(ctx, device); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,116 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package driver
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+// Driver which records all modifications in the audit log
+// before delegating to the wrapped driver
+type AuditDriver struct {
+	Driver
+}
+
+func NewAuditDriver(d Driver) *AuditDriver {
+	return &AuditDriver{Driver: d}
+}
+
+func (a *AuditDriver) AddVmnet(ctx context.Context, v *Vmnet) (err error) {
+	defer utility.AuditDeferred(ctx, "vmnet.add", map[string]interface{}{"vmnet": v}, time.Now(), &err)
+	return a.Driver.AddVmnet(ctx, v)
+}
+
+func (a *AuditDriver) UpdateVmnet(ctx context.Context, v *Vmnet) (err error) {
+	defer utility.AuditDeferred(ctx, "vmnet.update", map[string]interface{}{"vmnet": v}, time.Now(), &err)
+	return a.Driver.UpdateVmnet(ctx, v)
+}
+
+func (a *AuditDriver) DeleteVmnet(ctx context.Context, v *Vmnet) (err error) {
+	defer utility.AuditDeferred(ctx, "vmnet.delete", map[string]interface{}{"vmnet": v}, time.Now(), &err)
+	return a.Driver.DeleteVmnet(ctx, v)
+}
+
+func (a *AuditDriver) VerifyVmnet(ctx context.Context) (err error) {
+	defer utility.AuditDeferred(ctx, "vmnet.verify", nil, time.Now(), &err)
+	return a.Driver.VerifyVmnet(ctx)
+}
+
+func (a *AuditDriver) ReserveDhcpAddress(ctx context.Context, slot int, mac, ip string) (err error) {
+	defer utility.AuditDeferred(ctx, "dhcp.reserve", map[string]interface{}{
+		"slot": slot, "mac": mac, "ip": ip}, time.Now(), &err)
+	return a.Driver.ReserveDhcpAddress(ctx, slot, mac, ip)
+}
+
+func (a *AuditDriver) AddPortFwd(ctx context.Context, fwds []*PortFwd) (err error) {
+	defer utility.AuditDeferred(ctx, "portforward.add", portFwdArgs(fwds...), time.Now(), &err)
+	return a.Driver.AddPortFwd(ctx, fwds)
+}
+
+func (a *AuditDriver) DeletePortFwd(ctx context.Context, fwds []*PortFwd) (err error) {
+	defer utility.AuditDeferred(ctx, "portforward.delete", portFwdArgs(fwds...), time.Now(), &err)
+	return a.Driver.DeletePortFwd(ctx, fwds)
+}
+
+func (a *AuditDriver) PrunePortFwds(ctx context.Context, fwds func(context.Context, string) (*PortFwds, error), deleter func(context.Context, []*PortFwd) error, opts *PruneOptions) (result *PruneResult, err error) {
+	args := map[string]interface{}{}
+	if opts != nil {
+		args["dry_run"] = opts.DryRun
+		args["prune_missing"] = opts.PruneMissing
+	}
+	start := time.Now()
+	defer func() {
+		if result != nil {
+			args["pruned"] = len(result.Pruned)
+			args["kept"] = len(result.Kept)
+		}
+		utility.Audit(ctx, "portforward.prune", args, start, err)
+	}()
+	return a.Driver.PrunePortFwds(ctx, fwds, deleter, opts)
+}
+
+func (a *AuditDriver) AddInternalPortForward(ctx context.Context, fwd *PortFwd) (err error) {
+	defer utility.AuditDeferred(ctx, "portforward.internal.add", portFwdArgs(fwd), time.Now(), &err)
+	return a.Driver.AddInternalPortForward(ctx, fwd)
+}
+
+func (a *AuditDriver) DeleteInternalPortForward(ctx context.Context, fwd *PortFwd) (err error) {
+	defer utility.AuditDeferred(ctx, "portforward.internal.delete", portFwdArgs(fwd), time.Now(), &err)
+	return a.Driver.DeleteInternalPortForward(ctx, fwd)
+}
+
+func (a *AuditDriver) CloseInternalPortFwdConnection(ctx context.Context, fwd *PortFwd, id int64) (err error) {
+	args := map[string]interface{}{
+		"protocol": fwd.Protocol, "port": fwd.Port, "connection_id": id}
+	if fwd.HostPath != "" {
+		args["host_path"] = fwd.HostPath
+	}
+	defer utility.AuditDeferred(ctx, "portforward.connection.close", args, time.Now(), &err)
+	return a.Driver.CloseInternalPortFwdConnection(ctx, fwd, id)
+}
+
+// Arguments recorded for port forwards. The slot number is
+// not included in the serialized port forward so it is added.
+func portFwdArgs(fwds ...*PortFwd) map[string]interface{} {
+	entries := []map[string]interface{}{}
+	for _, fwd := range fwds {
+		entry := map[string]interface{}{
+			"slot":     fwd.SlotNumber,
+			"protocol": fwd.Protocol,
+			"port":     fwd.Port,
+		}
+		if fwd.HostPath != "" {
+			entry["host_path"] = fwd.HostPath
+		}
+		if fwd.Guest != nil {
+			entry["guest_ip"] = fwd.Guest.Ip
+			entry["guest_port"] = fwd.Guest.Port
+		}
+		entries = append(entries, entry)
+	}
+	return map[string]interface{}{"port_forwards": entries}
+}
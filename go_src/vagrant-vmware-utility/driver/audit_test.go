@@ -0,0 +1,67 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+type auditTestDriver struct {
+	Driver
+	err error
+}
+
+func (a *auditTestDriver) AddPortFwd(ctx context.Context, fwds []*PortFwd) error {
+	return a.err
+}
+
+func TestAuditDriver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), utility.AUDIT_LOG_FILE)
+	l, err := utility.OpenAuditLog(path, hclog.NewNullLogger())
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %s", err)
+	}
+	utility.SetAuditLog(l)
+	defer utility.SetAuditLog(nil)
+	defer l.Close()
+
+	inner := &auditTestDriver{}
+	d := NewAuditDriver(inner)
+	fwd := &PortFwd{Port: 2222, Protocol: "tcp", SlotNumber: 8,
+		Guest: &PortFwdGuest{Ip: "192.168.8.10", Port: 22}}
+	if err := d.AddPortFwd(context.Background(), []*PortFwd{fwd}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	inner.err = errors.New("add failed")
+	if err := d.AddPortFwd(context.Background(), []*PortFwd{fwd}); err != inner.err {
+		t.Fatalf("Expected error from wrapped driver, received %v", err)
+	}
+
+	records, err := utility.ReadAuditLog(path, nil)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %s", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Invalid number of records %d != 2", len(records))
+	}
+	if records[0].Action != "portforward.add" || records[0].Result != utility.AUDIT_RESULT_SUCCESS {
+		t.Errorf("Invalid record: %#v", records[0])
+	}
+	fwds, ok := records[0].Arguments["port_forwards"].([]interface{})
+	if !ok || len(fwds) != 1 {
+		t.Fatalf("Invalid port forward arguments: %#v", records[0].Arguments)
+	}
+	if entry := fwds[0].(map[string]interface{}); entry["slot"] != float64(8) || entry["guest_ip"] != "192.168.8.10" {
+		t.Errorf("Invalid port forward arguments: %#v", entry)
+	}
+	if records[1].Result != utility.AUDIT_RESULT_FAILURE || records[1].Error != "add failed" {
+		t.Errorf("Invalid record: %#v", records[1])
+	}
+}
@@ -4,6 +4,7 @@
 package driver
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -12,6 +13,8 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
 
@@ -23,6 +26,12 @@ import (
 
 const PORTFWD_PREFIX = "vagrant: "
 const VMWARE_VERSION_PATTERN = `(?i)VMware\s+(?P<product>[A-Za-z0-9-]+)\s+(?P<version>[\d.]+|e.x.p)\s*(?P<build>\S+)?\s*(?P<type>[A-Za-z0-9-]+)?`
+const VMWARE_INFO_TIMEOUT = 30 * time.Second
+
+// Maximum duration allowed for verifying the code signature
+// of the VMware installation. Verifying the Fusion app bundle
+// requires reading the entire bundle.
+const CODESIGN_TIMEOUT = 2 * time.Minute
 
 type BaseDriver struct {
 	Natfile          func(string) (*utility.VMWareNatFile, error)
@@ -34,10 +43,19 @@ type BaseDriver struct {
 	path             *string
 	settings         *settings.Settings
 	validated        bool
+	validationPath   string
 	validationReason string
 	vmnet            service.VmnetCli
 	vmwareInfo       *VmwareInfo
-	pfwdsvc          *intsvc.PortForwarding
+	pfwdsvc          *portForwardingService
+}
+
+// Internal port forwarding service of the driver. Copies of the
+// base driver share the service so it can be enabled or disabled
+// while the driver is in use.
+type portForwardingService struct {
+	l   sync.RWMutex
+	svc *intsvc.PortForwarding
 }
 
 func NewBaseDriver(vmxPath *string, licenseOverride string, logger hclog.Logger) (*BaseDriver, error) {
@@ -69,6 +87,7 @@ func NewBaseDriver(vmxPath *string, licenseOverride string, logger hclog.Logger)
 		settings:       s,
 		logger:         logger,
 		path:           vmxPath,
+		pfwdsvc:        &portForwardingService{},
 		validated:      false}
 	drv.Natfile = drv.LoadNatFile
 	drv.Networkingfile = drv.LoadNetworkingFile
@@ -83,7 +102,7 @@ func NewBaseDriver(vmxPath *string, licenseOverride string, logger hclog.Logger)
 	drv.vmnet = vmnet
 
 	logger.Debug("loading vmware information")
-	i, err := drv.VmwareInfo()
+	i, err := drv.VmwareInfo(context.Background())
 	if err != nil {
 		logger.Error("failed to generate VMware installation information", "error", err)
 		return nil, err
@@ -105,7 +124,7 @@ func NewBaseDriver(vmxPath *string, licenseOverride string, logger hclog.Logger)
 	}
 	i.Normalize()
 	logger.Debug("normalized vmware information", "license", i.License)
-	i, err = drv.VmwareInfo()
+	i, err = drv.VmwareInfo(context.Background())
 	return drv, nil
 }
 
@@ -121,6 +140,11 @@ func (b *BaseDriver) ValidationReason() string {
 	return b.validationReason
 }
 
+// Path which caused validation to fail
+func (b *BaseDriver) ValidationPath() string {
+	return b.validationPath
+}
+
 func (b *BaseDriver) VmwarePaths() *utility.VmwarePaths {
 	return b.vmwarePaths
 }
@@ -154,12 +178,23 @@ func (b *BaseDriver) LoadNetworkingFile() (utility.NetworkingFile, error) {
 	return netF, nil
 }
 
+// Enable the internal port forwarding service and start
+// the persisted port forwards. Does nothing if the service
+// is already enabled.
 func (b *BaseDriver) EnableInternalPortForwarding() (err error) {
 	defer func() {
 		if err != nil {
 			b.logger.Error("failed to enable internal port forwarding service", "error", err)
 		}
 	}()
+	if b.pfwdsvc == nil {
+		return errors.New("Internal port forwarding is not available")
+	}
+	b.pfwdsvc.l.Lock()
+	defer b.pfwdsvc.l.Unlock()
+	if b.pfwdsvc.svc != nil {
+		return
+	}
 	pfwd, err := intsvc.NewPortForwarding(b.Settings(), b.logger)
 	if err != nil {
 		return
@@ -170,60 +205,228 @@ func (b *BaseDriver) EnableInternalPortForwarding() (err error) {
 		return
 	}
 	if err = pfwd.Start(); err != nil {
+		pfwd.Stop()
 		return
 	}
 
 	b.logger.Debug("internal port forwarding service running")
-	b.pfwdsvc = pfwd
+	b.pfwdsvc.svc = pfwd
 	return
 }
 
+// Disable the internal port forwarding service. The port
+// forwards are stopped but remain in the settings so they
+// are started again when the service is enabled.
+func (b *BaseDriver) DisableInternalPortForwarding() error {
+	if b.pfwdsvc == nil {
+		return nil
+	}
+	b.pfwdsvc.l.Lock()
+	defer b.pfwdsvc.l.Unlock()
+	if b.pfwdsvc.svc == nil {
+		return nil
+	}
+	b.logger.Debug("stopping internal port forwarding service")
+	if err := b.pfwdsvc.svc.Stop(); err != nil {
+		b.logger.Error("failed to stop internal port forwarding service", "error", err)
+		return err
+	}
+	b.pfwdsvc.svc = nil
+	b.logger.Debug("internal port forwarding service stopped")
+	return nil
+}
+
+// Take over the running internal port forwarding service of
+// another driver. The port forwards and their connections are
+// not interrupted and are no longer managed by the other driver.
+// Does nothing if the other driver has no running service.
+func (b *BaseDriver) AdoptInternalPortForwarding(from Driver) error {
+	src := from.pfwdService()
+	if b.pfwdsvc == nil || src == nil {
+		return errors.New("Internal port forwarding is not available")
+	}
+	if src == b.pfwdsvc {
+		return nil
+	}
+	src.l.Lock()
+	pfwd := src.svc
+	src.svc = nil
+	src.l.Unlock()
+	if pfwd == nil {
+		return nil
+	}
+
+	b.pfwdsvc.l.Lock()
+	defer b.pfwdsvc.l.Unlock()
+	if b.pfwdsvc.svc != nil {
+		src.l.Lock()
+		src.svc = pfwd
+		src.l.Unlock()
+		return errors.New("Internal port forwarding is already enabled")
+	}
+	// Forwards modified through the service must be
+	// visible within the settings of this driver
+	b.settings.PortForwarding = pfwd.Settings()
+	b.pfwdsvc.svc = pfwd
+	b.logger.Debug("adopted internal port forwarding service")
+	return nil
+}
+
 func (b *BaseDriver) InternalPortForwarding() bool {
-	return b.pfwdsvc != nil
+	return b.portForwarding() != nil
+}
+
+func (b *BaseDriver) pfwdService() *portForwardingService {
+	return b.pfwdsvc
 }
 
-func (b *BaseDriver) InternalPortFwds() (fwds []*PortFwd, err error) {
+// Running internal port forwarding service. Returns nil
+// when internal port forwarding is not enabled.
+func (b *BaseDriver) portForwarding() *intsvc.PortForwarding {
 	if b.pfwdsvc == nil {
-		return nil, errors.New("internal port forwarding service is not enabled")
+		return nil
+	}
+	b.pfwdsvc.l.RLock()
+	defer b.pfwdsvc.l.RUnlock()
+	return b.pfwdsvc.svc
+}
+
+// Shutdown the driver. Stops the internal port forwarding
+// service if it is running.
+func (b *BaseDriver) Shutdown() error {
+	return b.DisableInternalPortForwarding()
+}
+
+// Features common to all drivers. Drivers adjust the result
+// for any features they do not support.
+func (b *BaseDriver) Capabilities() *Capabilities {
+	caps := &Capabilities{Features: map[string]*Capability{}}
+	for _, feature := range []string{CAPABILITY_VMNET_CREATE, CAPABILITY_VMNET_UPDATE,
+		CAPABILITY_VMNET_DELETE, CAPABILITY_VMNET_CUSTOM_SUBNET, CAPABILITY_DHCP_LEASE,
+		CAPABILITY_DHCP_RESERVATION, CAPABILITY_PORT_FORWARDING} {
+		caps.Features[feature] = &Capability{Supported: true}
+	}
+	internal := []string{CAPABILITY_INTERNAL_PORT_FORWARDING, CAPABILITY_PORT_FORWARD_CONNECTIONS,
+		CAPABILITY_PORT_FORWARD_PROXY_PROTOCOL, CAPABILITY_PORT_FORWARD_UNIX_SOCKET,
+		CAPABILITY_PORT_FORWARD_NAMED_PIPE}
+	for _, feature := range internal {
+		if b.InternalPortForwarding() {
+			caps.Features[feature] = &Capability{Supported: true}
+		} else {
+			caps.Unsupported(feature, "internal port forwarding service is not enabled")
+		}
+	}
+	if runtime.GOOS != "windows" {
+		caps.Unsupported(CAPABILITY_PORT_FORWARD_NAMED_PIPE, "named pipes are only available on Windows")
+	}
+	return caps
+}
+
+func (b *BaseDriver) InternalPortFwds(ctx context.Context) (fwds []*PortFwd, err error) {
+	pfwd := b.portForwarding()
+	if pfwd == nil {
+		return nil, ErrInternalPortForwardingDisabled
 	}
-	sFwds := b.pfwdsvc.Fwds()
+	sFwds := pfwd.Fwds()
 	for i := 0; i < len(sFwds); i++ {
 		pFwd := b.makePortFwd(sFwds[i].Fwd)
+		stats := sFwds[i].Stats()
+		pFwd.Stats = &PortFwdStats{
+			ActiveConnections: stats.ActiveConnections,
+			TotalConnections:  stats.TotalConnections,
+			BytesIn:           stats.BytesIn,
+			BytesOut:          stats.BytesOut,
+			DialFailures:      stats.DialFailures,
+		}
 		fwds = append(fwds, pFwd)
 	}
 	return
 }
 
-func (b *BaseDriver) AddInternalPortForward(fwd *PortFwd) (err error) {
-	if b.pfwdsvc == nil {
-		return errors.New("internal port forwarding service is not enabled")
+// Locate the running forward bound to the host port, or
+// the host path for path based forwards
+func (b *BaseDriver) lookupInternalPortFwd(fwd *PortFwd) (f *intsvc.Forward, err error) {
+	pfwd := b.portForwarding()
+	if pfwd == nil {
+		return nil, ErrInternalPortForwardingDisabled
 	}
-	return b.pfwdsvc.Add(b.makeSettingsFwd(fwd))
+	if fwd.IsPath() {
+		f, err = pfwd.LookupPath(fwd.Protocol, fwd.HostPath)
+	} else {
+		f, err = pfwd.Lookup(fwd.Protocol, fwd.Port)
+	}
+	return f, WrapError(ERROR_NOT_FOUND, err)
 }
 
-func (b *BaseDriver) DeleteInternalPortForward(fwd *PortFwd) (err error) {
-	if b.pfwdsvc == nil {
-		return errors.New("internal port forwarding service is not enabled")
+func (b *BaseDriver) InternalPortFwdConnections(ctx context.Context, fwd *PortFwd) (conns *PortFwdConnections, err error) {
+	f, err := b.lookupInternalPortFwd(fwd)
+	if err != nil {
+		return
+	}
+	conns = &PortFwdConnections{Connections: []*PortFwdConnection{}}
+	for _, c := range f.Connections() {
+		conns.Connections = append(conns.Connections, &PortFwdConnection{
+			Id:       c.Id,
+			Remote:   c.Remote,
+			Started:  c.Started,
+			BytesIn:  c.BytesIn(),
+			BytesOut: c.BytesOut(),
+		})
+	}
+	conns.Num = len(conns.Connections)
+	return
+}
+
+func (b *BaseDriver) CloseInternalPortFwdConnection(ctx context.Context, fwd *PortFwd, id int64) (err error) {
+	f, err := b.lookupInternalPortFwd(fwd)
+	if err != nil {
+		return
+	}
+	return WrapError(ERROR_NOT_FOUND, f.CloseConnection(id))
+}
+
+func (b *BaseDriver) AddInternalPortForward(ctx context.Context, fwd *PortFwd) (err error) {
+	pfwd := b.portForwarding()
+	if pfwd == nil {
+		return ErrInternalPortForwardingDisabled
 	}
-	return b.pfwdsvc.Remove(b.makeSettingsFwd(fwd))
+	sfwd := b.makeSettingsFwd(fwd)
+	if err := intsvc.ValidateForward(sfwd); err != nil {
+		return WrapError(ERROR_INVALID_INPUT, err)
+	}
+	return pfwd.Add(sfwd)
+}
+
+func (b *BaseDriver) DeleteInternalPortForward(ctx context.Context, fwd *PortFwd) (err error) {
+	pfwd := b.portForwarding()
+	if pfwd == nil {
+		return ErrInternalPortForwardingDisabled
+	}
+	return pfwd.Remove(b.makeSettingsFwd(fwd))
 }
 
 // Converts a settings.Forward struct into local PortFwd
 func (b *BaseDriver) makePortFwd(fwd *settings.Forward) *PortFwd {
-	return &PortFwd{
-		Description: fwd.Description,
-		Port:        fwd.Host.Port,
-		Protocol:    fwd.Host.Type,
+	pfwd := &PortFwd{
+		Description:   fwd.Description,
+		Port:          fwd.Host.Port,
+		Protocol:      fwd.Host.Type,
+		Permissions:   fwd.Permissions,
+		ProxyProtocol: fwd.ProxyProtocol,
 		Guest: &PortFwdGuest{
 			Ip:   fwd.Guest.Host,
 			Port: fwd.Guest.Port,
 		},
 	}
+	if fwd.Host.IsPath() {
+		pfwd.HostPath = fwd.Host.Host
+	}
+	return pfwd
 }
 
 // Converts a local PortFwd into a settings.Forward struct
 func (b *BaseDriver) makeSettingsFwd(fwd *PortFwd) *settings.Forward {
-	return &settings.Forward{
+	sfwd := &settings.Forward{
 		Host: &settings.Address{
 			Host: "0.0.0.0", // NOTE: vmware binds forwards to all devices so mimic here
 			Port: fwd.Port,
@@ -234,21 +437,47 @@ func (b *BaseDriver) makeSettingsFwd(fwd *PortFwd) *settings.Forward {
 			Port: fwd.Guest.Port,
 			Type: fwd.Protocol,
 		},
-		Description: fwd.Description,
+		Description:   fwd.Description,
+		Permissions:   fwd.Permissions,
+		ProxyProtocol: fwd.ProxyProtocol,
+	}
+	// Path based forwards listen on the host path and
+	// connect to the guest using tcp
+	if fwd.IsPath() {
+		sfwd.Host.Host = fwd.HostPath
+		sfwd.Host.Port = 0
+		sfwd.Guest.Type = "tcp"
+	}
+	return sfwd
+}
+
+// PROXY protocol headers and path based forwards are provided
+// by the internal port forwarding service so they are not
+// available for port forwards managed by VMware
+func (b *BaseDriver) validateVmwarePortFwd(fwd *PortFwd) error {
+	if fwd.ProxyProtocol != "" {
+		b.logger.Debug("port forward requests PROXY protocol without internal port forwarding", "fwd", fwd)
+		return NewError(ERROR_UNSUPPORTED_ON_PLATFORM, "PROXY protocol requires the internal port forwarding service")
+	}
+	if fwd.IsPath() {
+		b.logger.Debug("port forward requests host path without internal port forwarding", "fwd", fwd)
+		return NewError(ERROR_UNSUPPORTED_ON_PLATFORM, "%s port forwards require the internal port forwarding service", fwd.Protocol)
 	}
+	return nil
 }
 
-func (b *BaseDriver) detectNAT(d Driver) (vnet *Vmnet, err error) {
-	devices, err := d.Vmnets()
+func (b *BaseDriver) detectNAT(ctx context.Context, d Driver) (vnet *Vmnet, err error) {
+	logger := utility.ContextLogger(ctx, b.logger)
+	devices, err := d.Vmnets(ctx)
 	if err != nil {
-		b.logger.Warn("failed to fetch vmnet list for nat detection", "error", err)
+		logger.Warn("failed to fetch vmnet list for nat detection", "error", err)
 		return
 	}
 	for i := 0; i < len(devices.Vmnets); i++ {
 		n := devices.Vmnets[i]
-		b.logger.Trace("inspecting device for nat support", "vmnet", n)
+		logger.Trace("inspecting device for nat support", "vmnet", n)
 		if n.Type == "nat" {
-			b.logger.Debug("located nat device", "vmnet", n)
+			logger.Debug("located nat device", "vmnet", n)
 			vnet = n
 			break
 		}
@@ -259,66 +488,174 @@ func (b *BaseDriver) detectNAT(d Driver) (vnet *Vmnet, err error) {
 	return
 }
 
-// Prune any port
-func (b *BaseDriver) PrunePortFwds(pfwds func(string) (*PortFwds, error), deleter func([]*PortFwd) error) error {
-	fwds, err := pfwds("")
+// Prune any port forwards which are no longer in use. A port forward
+// is considered in use if the description does not reference a VMX
+// path (not managed by vagrant) or the referenced VM is running. Port
+// forwards referencing a VMX path which no longer exists are only
+// pruned when requested.
+func (b *BaseDriver) PrunePortFwds(ctx context.Context, pfwds func(context.Context, string) (*PortFwds, error), deleter func(context.Context, []*PortFwd) error, opts *PruneOptions) (*PruneResult, error) {
+	logger := utility.ContextLogger(ctx, b.logger)
+	if opts == nil {
+		opts = &PruneOptions{PruneMissing: true}
+	}
+	fwds, err := pfwds(ctx, "")
 	if err != nil {
-		return err
+		logger.Debug("list port forwards failure", "error", err)
+		return nil, err
 	}
-	if err != nil {
-		b.logger.Debug("list running vms failure", "error", err)
-		return err
+	result := &PruneResult{
+		DryRun: opts.DryRun,
+		Pruned: []*PortFwdPruneEntry{},
+		Kept:   []*PortFwdPruneEntry{},
 	}
 	delfwds := []*PortFwd{}
 	for i := 0; i < len(fwds.PortForwards); i++ {
 		fwd := fwds.PortForwards[i]
-		if !strings.Contains(fwd.Description, "vagrant: ") {
-			b.logger.Warn("prune check description no match", "wanted", "vagrant: ", "description", fwd.Description)
+		if !strings.Contains(fwd.Description, PORTFWD_PREFIX) {
+			logger.Warn("prune check description no match", "wanted", PORTFWD_PREFIX, "description", fwd.Description)
+			result.Kept = append(result.Kept, &PortFwdPruneEntry{PortFwd: fwd, Reason: PRUNE_REASON_UNMANAGED})
 			continue
 		}
-		checkPath, chkErr := b.matchVmPath(strings.Replace(fwd.Description, "vagrant: ", "", -1))
-		if chkErr == nil && b.vmAlive(checkPath) {
+		vmxPath := strings.Replace(fwd.Description, PORTFWD_PREFIX, "", -1)
+		checkPath, chkErr := b.matchVmPath(vmxPath)
+		if chkErr != nil {
+			if !opts.PruneMissing {
+				logger.Trace("prune forward skipped - vmx path missing", "path", vmxPath, "fwd", fwd)
+				result.Kept = append(result.Kept, &PortFwdPruneEntry{PortFwd: fwd, Reason: PRUNE_REASON_VMX_MISSING})
+				continue
+			}
+			logger.Trace("prune forward - vmx path missing", "path", vmxPath, "fwd", fwd)
+			result.Pruned = append(result.Pruned, &PortFwdPruneEntry{PortFwd: fwd, Reason: PRUNE_REASON_VMX_MISSING})
+			delfwds = append(delfwds, fwd)
 			continue
 		}
-		b.logger.Trace("prune forward - not in use", "path", checkPath, "fwd", fwd)
+		if b.vmAlive(ctx, checkPath) {
+			result.Kept = append(result.Kept, &PortFwdPruneEntry{PortFwd: fwd, Reason: PRUNE_REASON_VM_RUNNING})
+			continue
+		}
+		logger.Trace("prune forward - not in use", "path", checkPath, "fwd", fwd)
+		result.Pruned = append(result.Pruned, &PortFwdPruneEntry{PortFwd: fwd, Reason: PRUNE_REASON_VM_NOT_RUNNING})
 		delfwds = append(delfwds, fwd)
 	}
-	if err := deleter(delfwds); err != nil {
-		b.logger.Trace("prune forward failed", "error", err)
-		return err
+	if opts.DryRun {
+		logger.Debug("prune dry run complete", "pruned", len(result.Pruned), "kept", len(result.Kept))
+		return result, nil
 	}
-	return nil
+	if len(delfwds) == 0 {
+		logger.Trace("no port forwards to prune")
+		return result, nil
+	}
+	if err := deleter(ctx, delfwds); err != nil {
+		logger.Trace("prune forward failed", "error", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// Suggest free host ports for new port forwards. Ports are considered
+// used if they are defined by any VMware NAT port forward, any internal
+// port forward, or are currently bound on the host. The preferred port
+// is always checked first (even if outside the usable range).
+func (b *BaseDriver) SuggestPortFwds(ctx context.Context, pfwds func(context.Context, string) (*PortFwds, error), protocol string, preferred, count int, usable *PortRange) (*PortSuggestions, error) {
+	logger := utility.ContextLogger(ctx, b.logger)
+	if protocol != "tcp" && protocol != "udp" {
+		return nil, NewError(ERROR_INVALID_INPUT, "invalid protocol '%s' (expected tcp or udp)", protocol)
+	}
+	if count < 1 {
+		count = 1
+	}
+	if usable == nil {
+		usable = &PortRange{Min: DEFAULT_PORT_RANGE_MIN, Max: DEFAULT_PORT_RANGE_MAX}
+	}
+
+	used := map[int]bool{}
+	fwds, err := pfwds(ctx, "")
+	if err != nil {
+		logger.Debug("failed to list port forwards for suggestion", "error", err)
+		return nil, err
+	}
+	for _, fwd := range fwds.PortForwards {
+		if fwd.Protocol == protocol {
+			used[fwd.Port] = true
+		}
+	}
+	for _, fwd := range b.settings.NAT.PortFwds() {
+		if fwd.Protocol == protocol {
+			used[fwd.HostPort] = true
+		}
+	}
+	for _, fwd := range b.settings.PortForwarding.Forwards {
+		if strings.Contains(fwd.Host.Type, protocol) {
+			used[fwd.Host.Port] = true
+		}
+	}
+	logger.Trace("ports in use by port forwards", "protocol", protocol, "ports", used)
+
+	candidates := []int{}
+	if preferred > 0 && preferred <= 65535 {
+		candidates = append(candidates, preferred)
+	}
+	for port := usable.Min; port <= usable.Max; port++ {
+		if port != preferred {
+			candidates = append(candidates, port)
+		}
+	}
+
+	result := &PortSuggestions{
+		Protocol: protocol,
+		Ports:    []int{},
+		Range:    usable,
+	}
+	for _, port := range candidates {
+		if len(result.Ports) >= count {
+			break
+		}
+		if used[port] {
+			logger.Trace("port suggestion discard - port forward exists", "port", port)
+			continue
+		}
+		if !utility.PortAvailable(protocol, port) {
+			logger.Trace("port suggestion discard - port in use on host", "port", port)
+			continue
+		}
+		result.Ports = append(result.Ports, port)
+	}
+	if len(result.Ports) == 0 {
+		return nil, NewError(ERROR_CONFLICT, "no free %s ports available in range %d-%d", protocol, usable.Min, usable.Max)
+	}
+	return result, nil
 }
 
 // Verify the VMware networking services are up and healthy
-func (b *BaseDriver) VerifyVmnet() (err error) {
-	if b.vmnet.Status() {
-		b.logger.Trace("vmnet services reporting as healthy")
+func (b *BaseDriver) VerifyVmnet(ctx context.Context) (err error) {
+	logger := utility.ContextLogger(ctx, b.logger)
+	if b.vmnet.Status(ctx) {
+		logger.Trace("vmnet services reporting as healthy")
 		return nil
 	}
-	b.logger.Debug("ensuring vmnet service is stopped")
-	_ = b.vmnet.Stop()
-	b.logger.Debug("attempting to start the vmnet services")
-	err = b.vmnet.Start()
+	logger.Debug("ensuring vmnet service is stopped")
+	_ = b.vmnet.Stop(ctx)
+	logger.Debug("attempting to start the vmnet services")
+	err = b.vmnet.Start(ctx)
 	if err == nil {
-		b.logger.Trace("vmnet services started")
+		logger.Trace("vmnet services started")
 		return nil
 	}
-	b.logger.Debug("running vmnet configure after failed vmnet start")
-	_ = b.vmnet.Stop()
-	_ = b.vmnet.Configure("")
-	b.logger.Debug("attempting to start vmnet services again")
-	err = b.vmnet.Start()
+	logger.Debug("running vmnet configure after failed vmnet start")
+	_ = b.vmnet.Stop(ctx)
+	_ = b.vmnet.Configure(ctx, "")
+	logger.Debug("attempting to start vmnet services again")
+	err = b.vmnet.Start(ctx)
 	if err == nil {
-		b.logger.Trace("vmnet services started")
+		logger.Trace("vmnet services started")
 		return nil
 	}
-	b.logger.Debug("attempting final vmnet services start")
-	_ = b.vmnet.Stop()
-	err = b.vmnet.Start()
+	logger.Debug("attempting final vmnet services start")
+	_ = b.vmnet.Stop(ctx)
+	err = b.vmnet.Start(ctx)
 	if err != nil {
-		b.logger.Debug("failed to start vmnet services")
-		return err
+		logger.Debug("failed to start vmnet services")
+		return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 	}
 	return nil
 }
@@ -328,12 +665,12 @@ func (b *BaseDriver) validatePortFwdDescription(description string) (string, err
 	if strings.HasPrefix(description, PORTFWD_PREFIX) {
 		match, err := b.matchVmPath(strings.Replace(description, PORTFWD_PREFIX, "", -1))
 		if err != nil {
-			return "", err
+			return "", WrapError(ERROR_INVALID_INPUT, err)
 		}
 		return PORTFWD_PREFIX + match, nil
 	}
 	b.logger.Debug("port forward description prefix invalid", "description", description)
-	return "", errors.New("Invalid port forward description format")
+	return "", NewError(ERROR_INVALID_INPUT, "Invalid port forward description format")
 }
 
 // Check given path and determine if file system is case-(in)sensitive. If it
@@ -362,17 +699,17 @@ func (b *BaseDriver) matchVmPath(checkPath string) (string, error) {
 
 // Check if the VM at a given VMX path is alive. Since Windows filters based
 // on the user running the vmrun command we use a process ID lookup instead.
-func (b *BaseDriver) vmAlive(vmxPath string) bool {
+func (b *BaseDriver) vmAlive(ctx context.Context, vmxPath string) bool {
 	if runtime.GOOS == "windows" {
 		return b.vmPidAlive(vmxPath)
 	}
-	return b.vmrunAlive(vmxPath)
+	return b.vmrunAlive(ctx, vmxPath)
 }
 
-func (b *BaseDriver) vmrunAlive(vmxPath string) bool {
-	runningVms, err := b.Vmrun.RunningVms()
+func (b *BaseDriver) vmrunAlive(ctx context.Context, vmxPath string) bool {
+	runningVms, err := b.Vmrun.RunningVms(ctx)
 	if err != nil {
-		b.logger.Error("failed to list running vms", "error", err)
+		utility.ContextLogger(ctx, b.logger).Error("failed to list running vms", "error", err)
 		return true
 	}
 	for _, vm := range runningVms {
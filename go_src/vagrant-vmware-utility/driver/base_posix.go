@@ -6,8 +6,8 @@
 package driver
 
 import (
+	"context"
 	"errors"
-	"os/exec"
 	"path"
 	"runtime"
 	"strconv"
@@ -17,12 +17,13 @@ import (
 )
 
 // Generate current list of vmnets
-func (b *BaseDriver) Vmnets() (*Vmnets, error) {
-	b.logger.Info("collecting vmnets")
+func (b *BaseDriver) Vmnets(ctx context.Context) (*Vmnets, error) {
+	logger := utility.ContextLogger(ctx, b.logger)
+	logger.Info("collecting vmnets")
 	netF, err := utility.LoadNetworkingFile(
 		b.vmwarePaths.Networking, b.logger)
 	if err != nil {
-		b.logger.Debug(
+		logger.Debug(
 			"network file load failure", "path",
 			b.vmwarePaths.Networking, "error", err)
 		return nil, err
@@ -55,14 +56,15 @@ func (b *BaseDriver) Vmnets() (*Vmnets, error) {
 }
 
 // Generate current list of port forwards for given device
-func (b *BaseDriver) PortFwds(device string) (pfwds *PortFwds, err error) {
+func (b *BaseDriver) PortFwds(ctx context.Context, device string) (pfwds *PortFwds, err error) {
+	logger := utility.ContextLogger(ctx, b.logger)
 	pfwds = &PortFwds{}
 	if b.InternalPortForwarding() {
-		pfwds.PortForwards, err = b.InternalPortFwds()
+		pfwds.PortForwards, err = b.InternalPortFwds(ctx)
 		return
 	}
 
-	b.logger.Trace("loading networking file using dynamic loader", "loader", b.Networkingfile)
+	logger.Trace("loading networking file using dynamic loader", "loader", b.Networkingfile)
 	netF, err := b.Networkingfile()
 	if err != nil {
 		return nil, err
@@ -70,11 +72,11 @@ func (b *BaseDriver) PortFwds(device string) (pfwds *PortFwds, err error) {
 	fwdList := []*PortFwd{}
 	for _, fwd := range netF.GetPortFwds() {
 		if !fwd.Enable {
-			b.logger.Trace("portfoward discard - not enabled", "port", fwd.HostPort)
+			logger.Trace("portfoward discard - not enabled", "port", fwd.HostPort)
 			continue
 		}
 		if device != "" && fwd.Device != device {
-			b.logger.Trace("portforward discard", "device", fwd.Device, "wanted-device", device)
+			logger.Trace("portforward discard", "device", fwd.Device, "wanted-device", device)
 			continue
 		}
 		slot, err := strconv.Atoi(strings.Replace(fwd.Device, "vmnet", "", -1))
@@ -97,21 +99,24 @@ func (b *BaseDriver) PortFwds(device string) (pfwds *PortFwds, err error) {
 }
 
 // Find installed VMware product information
-func (b *BaseDriver) VmwareInfo() (*VmwareInfo, error) {
+func (b *BaseDriver) VmwareInfo(ctx context.Context) (*VmwareInfo, error) {
+	logger := utility.ContextLogger(ctx, b.logger)
 	if b.vmwareInfo != nil {
-		b.logger.Trace("returning cached vmware information")
+		logger.Trace("returning cached vmware information")
 		return b.vmwareInfo, nil
 	}
-	b.logger.Trace("vmware version check", "vmx-path", b.vmwarePaths.Vmx)
-	cmd := exec.Command(b.vmwarePaths.Vmx, "-v")
+	ctx, cancel := context.WithTimeout(ctx, VMWARE_INFO_TIMEOUT)
+	defer cancel()
+	logger.Trace("vmware version check", "vmx-path", b.vmwarePaths.Vmx)
+	cmd := utility.CommandContext(ctx, b.vmwarePaths.Vmx, "-v")
 	exitCode, out := utility.ExecuteWithOutput(cmd)
 	if exitCode != 0 {
-		b.logger.Trace("vmware version check failed", "output", out)
+		logger.Trace("vmware version check failed", "output", out)
 		return nil, errors.New("Failed attempting to check VMware version")
 	}
 	matches, err := utility.MatchPattern(VMWARE_VERSION_PATTERN, out)
 	if err != nil {
-		b.logger.Trace("vmware version match failed", "output", out, "pattern", VMWARE_VERSION_PATTERN, "error", err)
+		logger.Trace("vmware version match failed", "output", out, "pattern", VMWARE_VERSION_PATTERN, "error", err)
 		return nil, errors.New("Failed to extract VMware version information")
 	}
 	v := &VmwareInfo{
@@ -119,10 +124,10 @@ func (b *BaseDriver) VmwareInfo() (*VmwareInfo, error) {
 		Version: matches["version"],
 		Build:   matches["build"],
 		Type:    matches["type"]}
-	cmd = exec.Command(b.vmwarePaths.Vmx, "--query-license", "LicenseEdition")
+	cmd = utility.CommandContext(ctx, b.vmwarePaths.Vmx, "--query-license", "LicenseEdition")
 	exitCode, out = utility.ExecuteWithOutput(cmd)
 	if exitCode != 0 {
-		b.logger.Warn("failed to determine license edition", "output", out)
+		logger.Warn("failed to determine license edition", "output", out)
 		v.License = "unknown"
 	} else {
 		v.License = strings.TrimSpace(out)
@@ -139,11 +144,13 @@ func (b *BaseDriver) Validate() bool {
 	// Stub the reason as it is the same for all failures
 	b.validationReason = "Invalid ownership/permissions detected for VMware installation.\n" +
 		"Please re-install VMware and restart the vagrant-vmware-utility\nservice."
+	b.validationPath = ""
 
 	// Check permissions of install directory
 	if !utility.RootOwned(b.VmwarePaths().InstallDir, true) {
 		b.logger.Error("VMware validation failure", "cause", "invalid installation directory ownership/permissions")
 		b.logger.Trace("validation failure", "path", b.VmwarePaths().InstallDir)
+		b.validationPath = b.VmwarePaths().InstallDir
 		b.validated = false
 		return false
 	}
@@ -161,10 +168,10 @@ func (b *BaseDriver) Validate() bool {
 		}
 		// For darwin we can validate the signature of the executable
 		if runtime.GOOS == "darwin" {
-			cmd := exec.Command("/usr/bin/codesign", "--verify", "--verbose", checkPath)
-			exitCode, out := utility.ExecuteWithOutput(cmd)
+			exitCode, out := b.codesignVerify(checkPath)
 			if exitCode != 0 {
 				b.logger.Error("VMware validation failure", "cause", out)
+				b.validationPath = checkPath
 				b.validated = false
 				return false
 			}
@@ -172,12 +179,14 @@ func (b *BaseDriver) Validate() bool {
 		if !utility.RootOwned(checkPath, true) {
 			b.logger.Error("VMware validation failure", "cause", "invalid file ownership/permissions")
 			b.logger.Trace("validation failure", "path", checkPath)
+			b.validationPath = checkPath
 			b.validated = false
 			return false
 		}
 		if !utility.RootOwned(path.Dir(checkPath), true) {
 			b.logger.Error("VMware validation failure", "cause", "invalid file parent directory ownership/permissions")
 			b.logger.Trace("validation failure", "path", path.Dir(checkPath))
+			b.validationPath = path.Dir(checkPath)
 			b.validated = false
 			return false
 		}
@@ -194,11 +203,18 @@ func (b *BaseDriver) Validate() bool {
 //       the bundle. Use of this verification is for informational purposes only.
 func (b *BaseDriver) validateFusionApp() bool {
 	b.logger.Trace("running VMware Fusion app bundle validation")
-	cmd := exec.Command("/usr/bin/codesign", "--verify", "--verbose", b.VmwarePaths().InstallDir)
-	exitCode, out := utility.ExecuteWithOutput(cmd)
+	exitCode, out := b.codesignVerify(b.VmwarePaths().InstallDir)
 	if exitCode != 0 {
 		b.logger.Warn("failed to validate VMware Fusion app bundle", "cause", out)
 		return false
 	}
 	return true
 }
+
+// Verify the code signature of the given path
+func (b *BaseDriver) codesignVerify(checkPath string) (int, string) {
+	ctx, cancel := context.WithTimeout(context.Background(), CODESIGN_TIMEOUT)
+	defer cancel()
+	cmd := utility.CommandContext(ctx, "/usr/bin/codesign", "--verify", "--verbose", checkPath)
+	return utility.ExecuteWithOutput(cmd)
+}
@@ -4,13 +4,20 @@
 package driver
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"path"
+	"runtime"
 	"testing"
+	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/service"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/settings"
 )
 
 func TestMatchVmPathExact(t *testing.T) {
@@ -42,6 +49,313 @@ func TestMatchVmPathExact(t *testing.T) {
 	}
 }
 
+func TestPrunePortFwds(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("vm status is determined by process lookup on windows")
+	}
+	dir, err := createFiles([]string{"running.vmx", "halted.vmx"})
+	if err != nil {
+		t.Fatalf("Failed to create test files: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	running := path.Join(dir, "running.vmx")
+	halted := path.Join(dir, "halted.vmx")
+	missing := path.Join(dir, "missing.vmx")
+	vms := []*service.Vm{&service.Vm{Path: running}}
+	bt := &BaseDriver{
+		Vmrun: &service.VmrunMock{
+			Responses: []*service.VmrunResponse{
+				&service.VmrunResponse{Vms: vms},
+				&service.VmrunResponse{Vms: vms},
+			},
+		},
+		logger: logger("base-driver"),
+	}
+	fwds := &PortFwds{
+		PortForwards: []*PortFwd{
+			&PortFwd{Port: 2200, Description: "custom forward"},
+			&PortFwd{Port: 2201, Description: PORTFWD_PREFIX + running},
+			&PortFwd{Port: 2202, Description: PORTFWD_PREFIX + halted},
+			&PortFwd{Port: 2203, Description: PORTFWD_PREFIX + missing},
+		},
+	}
+	pfwds := func(context.Context, string) (*PortFwds, error) { return fwds, nil }
+	deleted := []*PortFwd{}
+	deleter := func(_ context.Context, d []*PortFwd) error {
+		deleted = append(deleted, d...)
+		return nil
+	}
+
+	result, err := bt.PrunePortFwds(context.Background(), pfwds, deleter, &PruneOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Unexpected error during prune - %s", err)
+	}
+	if len(deleted) != 0 {
+		t.Errorf("Dry run should not delete port forwards")
+	}
+	reasons := map[int]string{}
+	for _, e := range result.Pruned {
+		reasons[e.Port] = "pruned:" + e.Reason
+	}
+	for _, e := range result.Kept {
+		reasons[e.Port] = "kept:" + e.Reason
+	}
+	expected := map[int]string{
+		2200: "kept:" + PRUNE_REASON_UNMANAGED,
+		2201: "kept:" + PRUNE_REASON_VM_RUNNING,
+		2202: "pruned:" + PRUNE_REASON_VM_NOT_RUNNING,
+		2203: "kept:" + PRUNE_REASON_VMX_MISSING,
+	}
+	for port, reason := range expected {
+		if reasons[port] != reason {
+			t.Errorf("Invalid prune result for port %d '%s' != '%s'", port, reasons[port], reason)
+		}
+	}
+
+	bt.Vmrun.(*service.VmrunMock).AddResponse(&service.VmrunResponse{Vms: vms})
+	bt.Vmrun.(*service.VmrunMock).AddResponse(&service.VmrunResponse{Vms: vms})
+	result, err = bt.PrunePortFwds(context.Background(), pfwds, deleter, &PruneOptions{PruneMissing: true})
+	if err != nil {
+		t.Fatalf("Unexpected error during prune - %s", err)
+	}
+	if len(deleted) != 2 || len(result.Pruned) != 2 {
+		t.Errorf("Expected 2 port forwards pruned but found %d", len(deleted))
+	}
+}
+
+func TestSuggestPortFwdsSkipsUsed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vagrant-vmware-utility")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	bt, err := settingsDriver(dir)
+	if err != nil {
+		t.Fatalf("Failed to setup driver: %s", err)
+	}
+	bt.settings.PortForwarding.Forwards = []*settings.Forward{
+		&settings.Forward{
+			Host:  &settings.Address{Host: "0.0.0.0", Port: 42201, Type: "tcp"},
+			Guest: &settings.Address{Host: "127.0.0.2", Port: 22, Type: "tcp"},
+		},
+	}
+	pfwds := func(context.Context, string) (*PortFwds, error) {
+		return &PortFwds{
+			PortForwards: []*PortFwd{
+				&PortFwd{Port: 42200, Protocol: "tcp", Guest: &PortFwdGuest{Ip: "127.0.0.2", Port: 22}},
+				&PortFwd{Port: 42202, Protocol: "udp", Guest: &PortFwdGuest{Ip: "127.0.0.2", Port: 22}},
+			},
+		}, nil
+	}
+	result, err := bt.SuggestPortFwds(context.Background(), pfwds, "tcp", 42200, 2, &PortRange{Min: 42200, Max: 42210})
+	if err != nil {
+		t.Fatalf("Unexpected error during port suggestion - %s", err)
+	}
+	if len(result.Ports) != 2 {
+		t.Fatalf("Expected 2 suggested ports but received %d", len(result.Ports))
+	}
+	for _, port := range result.Ports {
+		if port == 42200 || port == 42201 {
+			t.Errorf("Suggested port %d is already in use by a port forward", port)
+		}
+	}
+}
+
+func TestSuggestPortFwdsInvalidProtocol(t *testing.T) {
+	bt := &BaseDriver{logger: logger("base-driver")}
+	_, err := bt.SuggestPortFwds(context.Background(), nil, "icmp", 0, 1, nil)
+	if err == nil {
+		t.Errorf("Expected error for invalid protocol")
+	}
+}
+
+func TestParsePortRange(t *testing.T) {
+	r, err := ParsePortRange("2200-2250")
+	if err != nil {
+		t.Fatalf("Unexpected error parsing port range - %s", err)
+	}
+	if r.Min != 2200 || r.Max != 2250 {
+		t.Errorf("Invalid port range parsed - %d-%d", r.Min, r.Max)
+	}
+	for _, invalid := range []string{"2200", "2250-2200", "0-10", "a-b"} {
+		if _, err := ParsePortRange(invalid); err == nil {
+			t.Errorf("Expected error parsing invalid port range '%s'", invalid)
+		}
+	}
+}
+
+func TestCapabilitiesInternalDisabled(t *testing.T) {
+	b := &BaseDriver{logger: logger("test-capabilities")}
+	caps := b.Capabilities()
+	if !caps.Features[CAPABILITY_VMNET_CREATE].Supported {
+		t.Errorf("Expected vmnet create to be supported")
+	}
+	fwd := caps.Features[CAPABILITY_INTERNAL_PORT_FORWARDING]
+	if fwd.Supported {
+		t.Errorf("Expected internal port forwarding to be unsupported")
+	}
+	if fwd.Reason == "" {
+		t.Errorf("Expected reason for unsupported internal port forwarding")
+	}
+	if caps.Features[CAPABILITY_PORT_FORWARD_CONNECTIONS].Supported {
+		t.Errorf("Expected port forward connections to be unsupported")
+	}
+}
+
+func TestInternalPortForwardingDisable(t *testing.T) {
+	b, err := settingsDriver(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create driver: %s", err)
+	}
+	b.pfwdsvc = &portForwardingService{}
+	l, err := net.Listen("tcp", "0.0.0.0:0")
+	if err != nil {
+		t.Fatalf("Failed to find free port: %s", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	if err := b.EnableInternalPortForwarding(); err != nil {
+		t.Fatalf("Failed to enable internal port forwarding: %s", err)
+	}
+	fwd := &PortFwd{Port: port, Protocol: "tcp", Description: "test",
+		Guest: &PortFwdGuest{Ip: "127.0.0.1", Port: 22}}
+	if err := b.AddInternalPortForward(context.Background(), fwd); err != nil {
+		t.Fatalf("Failed to add port forward: %s", err)
+	}
+
+	if err := b.DisableInternalPortForwarding(); err != nil {
+		t.Fatalf("Failed to disable internal port forwarding: %s", err)
+	}
+	if b.InternalPortForwarding() {
+		t.Errorf("Internal port forwarding enabled after disable")
+	}
+	if _, err := b.InternalPortFwds(context.Background()); err != ErrInternalPortForwardingDisabled {
+		t.Errorf("Expected disabled error but received: %v", err)
+	}
+	// Host port is released when the service is disabled
+	l, err = net.Listen("tcp", fmt.Sprintf("0.0.0.0:%d", port))
+	if err != nil {
+		t.Fatalf("Host port not released after disable: %s", err)
+	}
+	l.Close()
+
+	if err := b.EnableInternalPortForwarding(); err != nil {
+		t.Fatalf("Failed to enable internal port forwarding: %s", err)
+	}
+	defer b.Shutdown()
+	fwds, err := b.InternalPortFwds(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to list port forwards: %s", err)
+	}
+	if len(fwds) != 1 || fwds[0].Port != port {
+		t.Errorf("Persisted port forward not restored after enable")
+	}
+}
+
+func TestInternalPortForwardingAdopt(t *testing.T) {
+	dir := t.TempDir()
+	prev, err := settingsDriver(dir)
+	if err != nil {
+		t.Fatalf("Failed to create driver: %s", err)
+	}
+	prev.pfwdsvc = &portForwardingService{}
+	b, err := settingsDriver(dir)
+	if err != nil {
+		t.Fatalf("Failed to create driver: %s", err)
+	}
+	b.pfwdsvc = &portForwardingService{}
+
+	guest, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to create guest listener: %s", err)
+	}
+	defer guest.Close()
+	go func() {
+		for {
+			c, err := guest.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(c, c)
+		}
+	}()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find free port: %s", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	if err := prev.EnableInternalPortForwarding(); err != nil {
+		t.Fatalf("Failed to enable internal port forwarding: %s", err)
+	}
+	defer prev.Shutdown()
+	fwd := &PortFwd{Port: port, Protocol: "tcp", Description: "test",
+		Guest: &PortFwdGuest{Ip: "127.0.0.1", Port: guest.Addr().(*net.TCPAddr).Port}}
+	if err := prev.AddInternalPortForward(context.Background(), fwd); err != nil {
+		t.Fatalf("Failed to add port forward: %s", err)
+	}
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		t.Fatalf("Failed to connect to port forward: %s", err)
+	}
+	defer conn.Close()
+
+	if err := b.AdoptInternalPortForwarding(&SimpleDriver{BaseDriver: *prev}); err != nil {
+		t.Fatalf("Failed to adopt internal port forwarding: %s", err)
+	}
+	defer b.Shutdown()
+	if prev.InternalPortForwarding() {
+		t.Errorf("Internal port forwarding enabled on previous driver after adoption")
+	}
+	if !b.InternalPortForwarding() {
+		t.Fatalf("Internal port forwarding not enabled after adoption")
+	}
+	if b.Settings().PortForwarding != b.portForwarding().Settings() {
+		t.Errorf("Driver settings not shared with adopted service")
+	}
+	// Shutdown of the previous driver must not stop the adopted forwards
+	if err := prev.Shutdown(); err != nil {
+		t.Fatalf("Failed to shutdown previous driver: %s", err)
+	}
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("Failed to write to connection after adoption: %s", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("Connection interrupted by adoption: %v", err)
+	}
+	fwds, err := b.InternalPortFwds(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to list port forwards: %s", err)
+	}
+	if len(fwds) != 1 || fwds[0].Port != port {
+		t.Errorf("Port forward not available after adoption")
+	}
+}
+
+func settingsDriver(dir string) (*BaseDriver, error) {
+	l := logger("base-driver")
+	nat, err := settings.LoadNATSettings(path.Join(dir, "nat.json"), l)
+	if err != nil {
+		return nil, err
+	}
+	pfwds, err := settings.LoadPortForwardingSettings(path.Join(dir, "portforwarding.json"), l)
+	if err != nil {
+		return nil, err
+	}
+	return &BaseDriver{
+		logger: l,
+		settings: &settings.Settings{
+			NAT:            nat,
+			PortForwarding: pfwds,
+		},
+	}, nil
+}
+
 func createFiles(names []string) (string, error) {
 	dir, err := ioutil.TempDir("", "vagrant-vmware-utility")
 	if err != nil {
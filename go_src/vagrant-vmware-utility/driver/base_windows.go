@@ -4,12 +4,12 @@
 package driver
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	"os/exec"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
 	"golang.org/x/sys/windows/registry"
@@ -19,6 +19,9 @@ const VMWARE_REGISTRY_PATH = `SOFTWARE\VMware, Inc.`
 const VMNETLIB_REGISTRY_PATH = `SOFTWARE\VMware, Inc.\VMnetLib`
 const POWERSHELL_PATH = `%systemdrive%\Windows\System32\WindowsPowerShell\v1.0\powershell.exe`
 const REGINI_PATH = `%systemdrive%\Windows\System32\regini.exe`
+
+// Maximum duration allowed for changing registry key ownership
+const REGISTRY_OWNERSHIP_TIMEOUT = time.Minute
 const REGISTRY_OWNERSHIP_SCRIPT = `
 param(
     [Parameter(Mandatory=$true)]
@@ -59,20 +62,21 @@ $key.SetAccessControl($acl)
 `
 
 // Generate current list of vmnets
-func (b *BaseDriver) Vmnets() (*Vmnets, error) {
-	b.logger.Info("collecting vmnets")
+func (b *BaseDriver) Vmnets(ctx context.Context) (*Vmnets, error) {
+	logger := utility.ContextLogger(ctx, b.logger)
+	logger.Info("collecting vmnets")
 	access := b.registryAccess(registry.QUERY_VALUE | registry.ENUMERATE_SUB_KEYS)
 	configPath := VMNETLIB_REGISTRY_PATH + `\VMnetConfig`
 	regKey, err := registry.OpenKey(registry.LOCAL_MACHINE,
 		configPath, access)
 	if err != nil {
-		b.logger.Trace("vmnet list registry open", "path", configPath,
+		logger.Trace("vmnet list registry open", "path", configPath,
 			"error", err)
 		return nil, err
 	}
 	devices, err := regKey.ReadSubKeyNames(-1)
 	if err != nil {
-		b.logger.Trace("vmnet list subkeys", "path", configPath,
+		logger.Trace("vmnet list subkeys", "path", configPath,
 			"error", err)
 		return nil, err
 	}
@@ -83,14 +87,14 @@ func (b *BaseDriver) Vmnets() (*Vmnets, error) {
 		deviceKey, err := registry.OpenKey(registry.LOCAL_MACHINE,
 			basePath, access)
 		if err != nil {
-			b.logger.Trace("vmnet list registry open", "path", basePath,
+			logger.Trace("vmnet list registry open", "path", basePath,
 				"error", err)
 			return nil, err
 		}
 		natKey, err := registry.OpenKey(registry.LOCAL_MACHINE,
 			basePath+`\NAT`, access)
 		if err != nil {
-			b.logger.Trace("vmnet nat check", "path", basePath+`\NAT`, "error", err)
+			logger.Trace("vmnet nat check", "path", basePath+`\NAT`, "error", err)
 			vn.Type = "hostOnly"
 		} else {
 			natEnabled, _, err := natKey.GetIntegerValue("UseNAT")
@@ -103,7 +107,7 @@ func (b *BaseDriver) Vmnets() (*Vmnets, error) {
 		dhcpKey, err := registry.OpenKey(registry.LOCAL_MACHINE,
 			basePath+`\DHCP`, access)
 		if err != nil {
-			b.logger.Trace("vmnet dhcp check", "path", basePath+`\DHCP`, "error", err)
+			logger.Trace("vmnet dhcp check", "path", basePath+`\DHCP`, "error", err)
 			vn.Dhcp = "no"
 		} else {
 			dhcpEnabled, _, err := dhcpKey.GetIntegerValue("UseDHCP")
@@ -117,12 +121,12 @@ func (b *BaseDriver) Vmnets() (*Vmnets, error) {
 		if err == nil {
 			vn.Subnet = subAddr
 		} else {
-			b.logger.Trace("vmnet subnet ip", "path", basePath, "key", "IPSubnetAddress",
+			logger.Trace("vmnet subnet ip", "path", basePath, "key", "IPSubnetAddress",
 				"error", err)
 		}
 		subMask, _, err := deviceKey.GetStringValue("IPSubnetMask")
 		if err != nil && vn.Subnet != "" {
-			b.logger.Trace("vmnet subnet mask", "path", basePath, "key", "IPSubnetMask",
+			logger.Trace("vmnet subnet mask", "path", basePath, "key", "IPSubnetMask",
 				"error", err)
 			vn.Mask = "255.255.255.0"
 		} else if err == nil {
@@ -135,10 +139,11 @@ func (b *BaseDriver) Vmnets() (*Vmnets, error) {
 }
 
 // Generate current list of port forwards for given device
-func (b *BaseDriver) PortFwds(device string) (pfwds *PortFwds, err error) {
+func (b *BaseDriver) PortFwds(ctx context.Context, device string) (pfwds *PortFwds, err error) {
+	logger := utility.ContextLogger(ctx, b.logger)
 	pfwds = &PortFwds{}
 	if b.InternalPortForwarding() {
-		pfwds.PortForwards, err = b.InternalPortFwds()
+		pfwds.PortForwards, err = b.InternalPortFwds(ctx)
 		return
 	}
 
@@ -146,7 +151,7 @@ func (b *BaseDriver) PortFwds(device string) (pfwds *PortFwds, err error) {
 	if device != "" {
 		device = fmt.Sprintf("vmnet%s", device)
 		if err := b.supportPortFwds(device); err != nil {
-			b.logger.Trace("portforward check", "device", device, "supported", "false")
+			logger.Trace("portforward check", "device", device, "supported", "false")
 			return nil, err
 		}
 		devices = []string{device}
@@ -155,19 +160,19 @@ func (b *BaseDriver) PortFwds(device string) (pfwds *PortFwds, err error) {
 			VMNETLIB_REGISTRY_PATH+`\VMnetConfig`,
 			b.registryAccess(registry.QUERY_VALUE|registry.ENUMERATE_SUB_KEYS))
 		if err != nil {
-			b.logger.Trace("portforward registry open", "path", VMNETLIB_REGISTRY_PATH+`\VMnetConfig`,
+			logger.Trace("portforward registry open", "path", VMNETLIB_REGISTRY_PATH+`\VMnetConfig`,
 				"error", err)
 			return nil, err
 		}
 		allDevices, err := regKey.ReadSubKeyNames(-1)
 		if err != nil {
-			b.logger.Trace("portforward registry subkeys", "path", VMNETLIB_REGISTRY_PATH+`\VMnetConfig`,
+			logger.Trace("portforward registry subkeys", "path", VMNETLIB_REGISTRY_PATH+`\VMnetConfig`,
 				"error", err)
 			return nil, err
 		}
 		for _, dev := range allDevices {
 			if err := b.supportPortFwds(dev); err == nil {
-				b.logger.Trace("portforward supported device", "device", dev)
+				logger.Trace("portforward supported device", "device", dev)
 				devices = append(devices, dev)
 			}
 		}
@@ -176,12 +181,12 @@ func (b *BaseDriver) PortFwds(device string) (pfwds *PortFwds, err error) {
 	for _, device := range devices {
 		tcpFwds, err := b.buildFwdMap(device, "TCPForward")
 		if err != nil {
-			b.logger.Debug("failed to build tcp forward list", "device", device, "error", err)
+			logger.Debug("failed to build tcp forward list", "device", device, "error", err)
 			err = nil
 		}
 		udpFwds, err := b.buildFwdMap(device, "UDPForward")
 		if err != nil {
-			b.logger.Debug("failed to build udp forward list", "device", device, "error", err)
+			logger.Debug("failed to build udp forward list", "device", device, "error", err)
 			err = nil
 		}
 		slot, err := strconv.Atoi(strings.Replace(device, "vmnet", "", -1))
@@ -191,12 +196,12 @@ func (b *BaseDriver) PortFwds(device string) (pfwds *PortFwds, err error) {
 		for portKey, fwd := range tcpFwds {
 			hostPort, err := strconv.Atoi(portKey)
 			if err != nil {
-				b.logger.Trace("portforward host port conversion", "port", portKey, "error", err)
+				logger.Trace("portforward host port conversion", "port", portKey, "error", err)
 				return nil, err
 			}
 			guestPort, err := strconv.Atoi(fwd["port"])
 			if err != nil {
-				b.logger.Trace("portforward guest port conversion", "port", fwd["port"], "error", err)
+				logger.Trace("portforward guest port conversion", "port", fwd["port"], "error", err)
 				return nil, err
 			}
 
@@ -214,12 +219,12 @@ func (b *BaseDriver) PortFwds(device string) (pfwds *PortFwds, err error) {
 		for portKey, fwd := range udpFwds {
 			hostPort, err := strconv.Atoi(portKey)
 			if err != nil {
-				b.logger.Trace("portforward host port conversion", "port", portKey, "error", err)
+				logger.Trace("portforward host port conversion", "port", portKey, "error", err)
 				return nil, err
 			}
 			guestPort, err := strconv.Atoi(fwd["port"])
 			if err != nil {
-				b.logger.Trace("portforward guest port conversion", "port", fwd["port"], "error", err)
+				logger.Trace("portforward guest port conversion", "port", fwd["port"], "error", err)
 				return nil, err
 			}
 
@@ -240,7 +245,8 @@ func (b *BaseDriver) PortFwds(device string) (pfwds *PortFwds, err error) {
 }
 
 // Find installed VMware product information
-func (b *BaseDriver) VmwareInfo() (*VmwareInfo, error) {
+func (b *BaseDriver) VmwareInfo(ctx context.Context) (*VmwareInfo, error) {
+	logger := utility.ContextLogger(ctx, b.logger)
 	var access uint32
 	access = registry.QUERY_VALUE
 	if runtime.GOARCH == "amd64" {
@@ -249,12 +255,12 @@ func (b *BaseDriver) VmwareInfo() (*VmwareInfo, error) {
 	corePath := `SOFTWARE\VMware, Inc.`
 	coreKey, err := registry.OpenKey(registry.LOCAL_MACHINE, corePath, access)
 	if err != nil {
-		b.logger.Trace("vmware core info registry open", "path", corePath, "error", err)
+		logger.Trace("vmware core info registry open", "path", corePath, "error", err)
 		return nil, err
 	}
 	product, _, err := coreKey.GetStringValue("Core")
 	if err != nil {
-		b.logger.Trace("vmware core info registry read", "path", corePath, "key", "Core",
+		logger.Trace("vmware core info registry read", "path", corePath, "key", "Core",
 			"error", err)
 		return nil, err
 		product = `VMware Workstation`
@@ -262,18 +268,18 @@ func (b *BaseDriver) VmwareInfo() (*VmwareInfo, error) {
 	vmwarePath := corePath + `\` + product
 	regKey, err := registry.OpenKey(registry.LOCAL_MACHINE, vmwarePath, access)
 	if err != nil {
-		b.logger.Trace("vmware info registry open", "path", vmwarePath, "error", err)
+		logger.Trace("vmware info registry open", "path", vmwarePath, "error", err)
 		return nil, err
 	}
 	version, _, err := regKey.GetStringValue("ProductVersion")
 	if err != nil {
-		b.logger.Trace("vmware info registry read", "path", vmwarePath, "key", "ProductVersion",
+		logger.Trace("vmware info registry read", "path", vmwarePath, "key", "ProductVersion",
 			"error", err)
 		return nil, err
 	}
 	matches, err := utility.MatchPattern(`^(?P<version>\d+\.\d+\.\d+)(?P<build>\d+)?`, version)
 	if err != nil {
-		b.logger.Trace("vmware info version match", "version", version, "error", err)
+		logger.Trace("vmware info version match", "version", version, "error", err)
 		return nil, err
 	}
 	info := strings.Split(product, " ")
@@ -314,7 +320,9 @@ func (b *BaseDriver) registryTakeOwnership(root registry.Key, path string) bool
 	case registry.USERS:
 		o_prefix = "Users"
 	}
-	cmd := exec.Command(utility.ExpandPath(POWERSHELL_PATH),
+	ctx, cancel := context.WithTimeout(context.Background(), REGISTRY_OWNERSHIP_TIMEOUT)
+	defer cancel()
+	cmd := utility.CommandContext(ctx, utility.ExpandPath(POWERSHELL_PATH),
 		"-ExecutionPolicy", "Unrestricted",
 		"-NoProfile", "-Noninteractive",
 		"-Command", "& {"+REGISTRY_OWNERSHIP_SCRIPT+"}",
@@ -334,15 +342,13 @@ func (b *BaseDriver) supportPortFwds(device string) error {
 	_, err := registry.OpenKey(registry.LOCAL_MACHINE, VMNETLIB_REGISTRY_PATH+`\VMnetConfig\`+device, access)
 	if err != nil {
 		b.logger.Trace("portfwd invalid device", "device", device, "error", err)
-		return errors.New(fmt.Sprintf(
-			"Device does not exist: %s", device))
+		return NewError(ERROR_NOT_FOUND, "Device does not exist: %s", device)
 	}
 	// Next check that NAT is enabled on the device
 	_, err = registry.OpenKey(registry.LOCAL_MACHINE, VMNETLIB_REGISTRY_PATH+`\VMnetConfig\`+device+`\NAT`, access)
 	if err != nil {
 		b.logger.Trace("portfwd device nat disabled", "device", device, "error", err)
-		return errors.New(fmt.Sprintf(
-			"Device does not have NAT service enabled: %s", device))
+		return NewError(ERROR_INVALID_INPUT, "Device does not have NAT service enabled: %s", device)
 	}
 	return nil
 }
@@ -4,9 +4,11 @@
 package driver
 
 import (
+	"context"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
 
@@ -81,12 +83,43 @@ type PortFwdGuest struct {
 	Port int    `json:"port"`
 }
 
+type PortFwdStats struct {
+	ActiveConnections int64 `json:"active_connections"`
+	TotalConnections  int64 `json:"total_connections"`
+	BytesIn           int64 `json:"bytes_in"`
+	BytesOut          int64 `json:"bytes_out"`
+	DialFailures      int64 `json:"dial_failures"`
+}
+
+type PortFwdConnection struct {
+	Id       int64     `json:"id"`
+	Remote   string    `json:"remote"`
+	Started  time.Time `json:"started"`
+	BytesIn  int64     `json:"bytes_in"`
+	BytesOut int64     `json:"bytes_out"`
+}
+
+type PortFwdConnections struct {
+	Num         int                  `json:"num"`
+	Connections []*PortFwdConnection `json:"connections"`
+}
+
 type PortFwd struct {
-	Port        int           `json:"port"`
-	Protocol    string        `json:"protocol"`
-	Description string        `json:"description"`
-	Guest       *PortFwdGuest `json:"guest"`
-	SlotNumber  int           `json:"-"`
+	Port          int           `json:"port"`
+	Protocol      string        `json:"protocol"`
+	Description   string        `json:"description"`
+	Guest         *PortFwdGuest `json:"guest"`
+	HostPath      string        `json:"host_path,omitempty"`
+	Permissions   string        `json:"permissions,omitempty"`
+	ProxyProtocol string        `json:"proxy_protocol,omitempty"`
+	Stats         *PortFwdStats `json:"stats,omitempty"`
+	SlotNumber    int           `json:"-"`
+}
+
+// Port forward is bound to a host path (unix socket or
+// named pipe) instead of a host port
+func (p *PortFwd) IsPath() bool {
+	return p.Protocol == "unix" || p.Protocol == "npipe"
 }
 
 func (p *PortFwd) Matches(fwd *PortFwd) bool {
@@ -101,6 +134,63 @@ type PortFwds struct {
 	PortForwards []*PortFwd `json:"port_forwards"`
 }
 
+type PortRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// Parses a port range in the format of "MIN-MAX"
+func ParsePortRange(r string) (*PortRange, error) {
+	parts := strings.Split(r, "-")
+	if len(parts) != 2 {
+		return nil, NewError(ERROR_INVALID_INPUT, "invalid port range format '%s' (expected MIN-MAX)", r)
+	}
+	min, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return nil, NewError(ERROR_INVALID_INPUT, "invalid port range minimum '%s'", parts[0])
+	}
+	max, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return nil, NewError(ERROR_INVALID_INPUT, "invalid port range maximum '%s'", parts[1])
+	}
+	if min < 1 || max > 65535 || min > max {
+		return nil, NewError(ERROR_INVALID_INPUT, "invalid port range %d-%d", min, max)
+	}
+	return &PortRange{Min: min, Max: max}, nil
+}
+
+func (p *PortRange) Contains(port int) bool {
+	return port >= p.Min && port <= p.Max
+}
+
+type PortSuggestions struct {
+	Protocol string     `json:"protocol"`
+	Ports    []int      `json:"ports"`
+	Range    *PortRange `json:"range"`
+}
+
+// Reasons reported for port forwards when pruning
+const PRUNE_REASON_UNMANAGED = "unmanaged"
+const PRUNE_REASON_VM_RUNNING = "vm_running"
+const PRUNE_REASON_VM_NOT_RUNNING = "vm_not_running"
+const PRUNE_REASON_VMX_MISSING = "vmx_missing"
+
+type PruneOptions struct {
+	DryRun       bool
+	PruneMissing bool
+}
+
+type PortFwdPruneEntry struct {
+	*PortFwd
+	Reason string `json:"reason"`
+}
+
+type PruneResult struct {
+	DryRun bool                 `json:"dry_run"`
+	Pruned []*PortFwdPruneEntry `json:"pruned"`
+	Kept   []*PortFwdPruneEntry `json:"kept"`
+}
+
 type MacToIp struct {
 	Vmnet string `json:"vmnet"`
 	Mac   string `json:"mac"`
@@ -112,32 +202,76 @@ type MacToIps struct {
 	MacToIps []*MacToIp `json:"mactoips"`
 }
 
+// Features reported via capabilities
+const CAPABILITY_VMNET_CREATE = "vmnet_create"
+const CAPABILITY_VMNET_UPDATE = "vmnet_update"
+const CAPABILITY_VMNET_DELETE = "vmnet_delete"
+const CAPABILITY_VMNET_CUSTOM_SUBNET = "vmnet_custom_subnet"
+const CAPABILITY_DHCP_LEASE = "dhcp_lease"
+const CAPABILITY_DHCP_RESERVATION = "dhcp_reservation"
+const CAPABILITY_PORT_FORWARDING = "port_forwarding"
+const CAPABILITY_INTERNAL_PORT_FORWARDING = "internal_port_forwarding"
+const CAPABILITY_PORT_FORWARD_CONNECTIONS = "port_forward_connections"
+const CAPABILITY_PORT_FORWARD_PROXY_PROTOCOL = "port_forward_proxy_protocol"
+const CAPABILITY_PORT_FORWARD_UNIX_SOCKET = "port_forward_unix_socket"
+const CAPABILITY_PORT_FORWARD_NAMED_PIPE = "port_forward_named_pipe"
+
+type Capability struct {
+	Supported bool   `json:"supported"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+type Capabilities struct {
+	Driver   string                 `json:"driver"`
+	Features map[string]*Capability `json:"features"`
+}
+
+// Mark a feature as unsupported with the given reason
+func (c *Capabilities) Unsupported(feature, reason string) {
+	c.Features[feature] = &Capability{Supported: false, Reason: reason}
+}
+
 const FUSION_ADVANCED_MAJOR_MIN = 10
 
+// Default usable port range for suggestions. This matches
+// the default usable port range used by Vagrant.
+const DEFAULT_PORT_RANGE_MIN = 2200
+const DEFAULT_PORT_RANGE_MAX = 2250
+
 type Driver interface {
-	AddInternalPortForward(fwd *PortFwd) error
-	AddPortFwd(fwds []*PortFwd) error
-	AddVmnet(v *Vmnet) error
-	DeleteInternalPortForward(fwd *PortFwd) error
-	DeletePortFwd(fwds []*PortFwd) error
-	DeleteVmnet(v *Vmnet) error
+	AddInternalPortForward(ctx context.Context, fwd *PortFwd) error
+	AddPortFwd(ctx context.Context, fwds []*PortFwd) error
+	AddVmnet(ctx context.Context, v *Vmnet) error
+	AdoptInternalPortForwarding(from Driver) error
+	Capabilities() *Capabilities
+	CloseInternalPortFwdConnection(ctx context.Context, fwd *PortFwd, id int64) error
+	DeleteInternalPortForward(ctx context.Context, fwd *PortFwd) error
+	DeletePortFwd(ctx context.Context, fwds []*PortFwd) error
+	DeleteVmnet(ctx context.Context, v *Vmnet) error
+	DisableInternalPortForwarding() error
 	EnableInternalPortForwarding() error
-	InternalPortFwds() (fwds []*PortFwd, err error)
+	InternalPortFwdConnections(ctx context.Context, fwd *PortFwd) (conns *PortFwdConnections, err error)
+	InternalPortFwds(ctx context.Context) (fwds []*PortFwd, err error)
 	LoadNetworkingFile() (f utility.NetworkingFile, err error)
-	LookupDhcpAddress(device, mac string) (addr string, err error)
+	LookupDhcpAddress(ctx context.Context, device, mac string) (addr string, err error)
 	Path() (path *string, err error)
-	PortFwds(device string) (fwds *PortFwds, err error)
-	PrunePortFwds(fwds func(string) (*PortFwds, error), deleter func([]*PortFwd) error) error
-	ReserveDhcpAddress(slot int, mac, ip string) error
+	PortFwds(ctx context.Context, device string) (fwds *PortFwds, err error)
+	PrunePortFwds(ctx context.Context, fwds func(context.Context, string) (*PortFwds, error), deleter func(context.Context, []*PortFwd) error, opts *PruneOptions) (*PruneResult, error)
+	ReserveDhcpAddress(ctx context.Context, slot int, mac, ip string) error
+	SuggestPortFwds(ctx context.Context, pfwds func(context.Context, string) (*PortFwds, error), protocol string, preferred, count int, usable *PortRange) (*PortSuggestions, error)
 	Settings() *settings.Settings
-	UpdateVmnet(v *Vmnet) error
+	Shutdown() error
+	UpdateVmnet(ctx context.Context, v *Vmnet) error
 	Validated() bool
 	Validate() bool
 	ValidationReason() string
-	VerifyVmnet() error
-	Vmnets() (v *Vmnets, err error)
-	VmwareInfo() (info *VmwareInfo, err error)
+	VerifyVmnet(ctx context.Context) error
+	Vmnets(ctx context.Context) (v *Vmnets, err error)
+	VmwareInfo(ctx context.Context) (info *VmwareInfo, err error)
 	VmwarePaths() *utility.VmwarePaths
+
+	// Shared internal port forwarding service holder
+	pfwdService() *portForwardingService
 }
 
 func CreateDriver(vmxPath *string, b *BaseDriver, logger hclog.Logger) (Driver, error) {
@@ -158,7 +292,7 @@ func CreateDriver(vmxPath *string, b *BaseDriver, logger hclog.Logger) (Driver,
 		if err != nil {
 			return d, err
 		}
-		info, err := d.VmwareInfo()
+		info, err := d.VmwareInfo(context.Background())
 		if err != nil {
 			logger.Error("failed to get VMware information", "error", err)
 			return d, err
@@ -4,6 +4,8 @@
 package driver
 
 import (
+	"context"
+
 	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
 )
 
@@ -13,47 +15,51 @@ func (t *MockDriver) Path() (p *string, err error) {
 	return
 }
 
-func (t *MockDriver) Vmnets() (v *Vmnets, err error) {
+func (t *MockDriver) Vmnets(ctx context.Context) (v *Vmnets, err error) {
+	return
+}
+
+func (t *MockDriver) AddVmnet(ctx context.Context, v *Vmnet) (err error) {
 	return
 }
 
-func (t *MockDriver) AddVmnet(v *Vmnet) (err error) {
+func (t *MockDriver) UpdateVmnet(ctx context.Context, v *Vmnet) (err error) {
 	return
 }
 
-func (t *MockDriver) UpdateVmnet(v *Vmnet) (err error) {
+func (t *MockDriver) DeleteVmnet(ctx context.Context, v *Vmnet) (err error) {
 	return
 }
 
-func (t *MockDriver) DeleteVmnet(v *Vmnet) (err error) {
+func (t *MockDriver) PortFwds(ctx context.Context, device string) (fwds *PortFwds, err error) {
 	return
 }
 
-func (t *MockDriver) PortFwds(device string) (fwds *PortFwds, err error) {
+func (t *MockDriver) AddPortFwd(ctx context.Context, fwds []*PortFwd) (err error) {
 	return
 }
 
-func (t *MockDriver) AddPortFwd(fwds []*PortFwd) (err error) {
+func (t *MockDriver) DeletePortFwd(ctx context.Context, fwds []*PortFwd) (err error) {
 	return
 }
 
-func (t *MockDriver) DeletePortFwd(fwds []*PortFwd) (err error) {
+func (t *MockDriver) PrunePortFwds(ctx context.Context, fwds func(context.Context, string) (*PortFwds, error), deleter func(context.Context, []*PortFwd) error, opts *PruneOptions) (r *PruneResult, err error) {
 	return
 }
 
-func (t *MockDriver) PrunePortFwds(fwds func(string) (*PortFwds, error), deleter func([]*PortFwd) error) (err error) {
+func (t *MockDriver) Capabilities() (c *Capabilities) {
 	return
 }
 
-func (t *MockDriver) LookupDhcpAddress(device, mac string) (ip string, err error) {
+func (t *MockDriver) LookupDhcpAddress(ctx context.Context, device, mac string) (ip string, err error) {
 	return
 }
 
-func (t *MockDriver) ReserveDhcpAddress(slot int, mac, ip string) (err error) {
+func (t *MockDriver) ReserveDhcpAddress(ctx context.Context, slot int, mac, ip string) (err error) {
 	return
 }
 
-func (t *MockDriver) VmwareInfo() (info *VmwareInfo, err error) {
+func (t *MockDriver) VmwareInfo(ctx context.Context) (info *VmwareInfo, err error) {
 	return
 }
 
@@ -78,6 +84,22 @@ func (t *MockDriver) ValidationReason() (r string) {
 	return
 }
 
-func (t *MockDriver) VerifyVmnet() (err error) {
+func (t *MockDriver) VerifyVmnet(ctx context.Context) (err error) {
+	return
+}
+
+func (t *MockDriver) SuggestPortFwds(ctx context.Context, pfwds func(context.Context, string) (*PortFwds, error), protocol string, preferred, count int, usable *PortRange) (s *PortSuggestions, err error) {
+	return
+}
+
+func (t *MockDriver) InternalPortFwdConnections(ctx context.Context, fwd *PortFwd) (c *PortFwdConnections, err error) {
+	return
+}
+
+func (t *MockDriver) CloseInternalPortFwdConnection(ctx context.Context, fwd *PortFwd, id int64) (err error) {
+	return
+}
+
+func (t *MockDriver) Shutdown() (err error) {
 	return
 }
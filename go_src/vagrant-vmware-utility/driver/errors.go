@@ -0,0 +1,76 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+type ErrorCode string
+
+// Error codes used to classify driver failures. These values
+// are exposed via the API and must remain stable.
+const (
+	ERROR_NOT_FOUND               ErrorCode = "not_found"
+	ERROR_CONFLICT                ErrorCode = "conflict"
+	ERROR_UNSUPPORTED_ON_PLATFORM ErrorCode = "unsupported_on_platform"
+	ERROR_VMWARE_SERVICE_FAILURE  ErrorCode = "vmware_service_failure"
+	ERROR_VALIDATION_FAILED       ErrorCode = "validation_failed"
+	ERROR_INVALID_INPUT           ErrorCode = "invalid_input"
+	ERROR_TIMEOUT                 ErrorCode = "timeout"
+	ERROR_CANCELED                ErrorCode = "canceled"
+	ERROR_UNKNOWN                 ErrorCode = "unknown"
+)
+
+var ErrInternalPortForwardingDisabled = NewError(ERROR_UNSUPPORTED_ON_PLATFORM,
+	"internal port forwarding service is not enabled")
+
+type DriverError struct {
+	Code ErrorCode
+	Err  error
+}
+
+func (e *DriverError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DriverError) Unwrap() error {
+	return e.Err
+}
+
+// Create a new error with the given classification
+func NewError(code ErrorCode, format string, args ...interface{}) error {
+	return &DriverError{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// Classify an existing error. Errors which have already
+// been classified retain their original classification.
+func WrapError(code ErrorCode, err error) error {
+	if err == nil {
+		return nil
+	}
+	var dErr *DriverError
+	if errors.As(err, &dErr) {
+		return err
+	}
+	return &DriverError{Code: code, Err: err}
+}
+
+// Classification of the given error. Errors caused by a
+// timeout or cancellation are classified as such.
+func ErrorCodeFor(err error) ErrorCode {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ERROR_TIMEOUT
+	}
+	if errors.Is(err, context.Canceled) {
+		return ERROR_CANCELED
+	}
+	var dErr *DriverError
+	if errors.As(err, &dErr) {
+		return dErr.Code
+	}
+	return ERROR_UNKNOWN
+}
@@ -0,0 +1,58 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorCodeFor(t *testing.T) {
+	err := NewError(ERROR_NOT_FOUND, "device %s not found", "vmnet1")
+	if err.Error() != "device vmnet1 not found" {
+		t.Errorf("Invalid error message '%s'", err)
+	}
+	if c := ErrorCodeFor(err); c != ERROR_NOT_FOUND {
+		t.Errorf("Invalid error code %s != %s", c, ERROR_NOT_FOUND)
+	}
+	wrapped := fmt.Errorf("lookup failed: %w", err)
+	if c := ErrorCodeFor(wrapped); c != ERROR_NOT_FOUND {
+		t.Errorf("Invalid wrapped error code %s != %s", c, ERROR_NOT_FOUND)
+	}
+	if c := ErrorCodeFor(errors.New("failure")); c != ERROR_UNKNOWN {
+		t.Errorf("Invalid unclassified error code %s != %s", c, ERROR_UNKNOWN)
+	}
+}
+
+func TestWrapError(t *testing.T) {
+	if WrapError(ERROR_CONFLICT, nil) != nil {
+		t.Errorf("Expected nil error to remain nil")
+	}
+	base := errors.New("failure")
+	err := WrapError(ERROR_VMWARE_SERVICE_FAILURE, base)
+	if c := ErrorCodeFor(err); c != ERROR_VMWARE_SERVICE_FAILURE {
+		t.Errorf("Invalid error code %s != %s", c, ERROR_VMWARE_SERVICE_FAILURE)
+	}
+	if !errors.Is(err, base) {
+		t.Errorf("Expected wrapped error to match original error")
+	}
+	err = WrapError(ERROR_NOT_FOUND, err)
+	if c := ErrorCodeFor(err); c != ERROR_VMWARE_SERVICE_FAILURE {
+		t.Errorf("Expected existing classification to be retained, got %s", c)
+	}
+}
+
+func TestErrorCodeForContext(t *testing.T) {
+	err := WrapError(ERROR_VMWARE_SERVICE_FAILURE,
+		fmt.Errorf("Failed to start NAT service: %w", context.DeadlineExceeded))
+	if c := ErrorCodeFor(err); c != ERROR_TIMEOUT {
+		t.Errorf("Invalid error code %s != %s", c, ERROR_TIMEOUT)
+	}
+	err = fmt.Errorf("request failed: %w", context.Canceled)
+	if c := ErrorCodeFor(err); c != ERROR_CANCELED {
+		t.Errorf("Invalid error code %s != %s", c, ERROR_CANCELED)
+	}
+}
@@ -4,6 +4,7 @@
 package driver
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -11,6 +12,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
 
@@ -42,7 +44,14 @@ func NewSimpleDriver(vmxPath *string, b *BaseDriver, logger hclog.Logger) (s *Si
 	return
 }
 
-func (s *SimpleDriver) AddVmnet(vmnet *Vmnet) error {
+func (s *SimpleDriver) Capabilities() *Capabilities {
+	caps := s.BaseDriver.Capabilities()
+	caps.Driver = "simple"
+	return caps
+}
+
+func (s *SimpleDriver) AddVmnet(ctx context.Context, vmnet *Vmnet) error {
+	logger := utility.ContextLogger(ctx, s.logger)
 	netF, err := s.LoadNetworkingFile()
 	if err != nil {
 		return err
@@ -55,50 +64,51 @@ func (s *SimpleDriver) AddVmnet(vmnet *Vmnet) error {
 	}
 	device.Dhcp = vmnet.Dhcp == "yes"
 	device.Nat = vmnet.Type == "nat"
-	s.logger.Debug("vmnet create", "name", device.Name, "dhcp", device.Dhcp,
+	logger.Debug("vmnet create", "name", device.Name, "dhcp", device.Dhcp,
 		"nat", device.Nat, "subnet", device.HostonlySubnet, "mask",
 		device.HostonlyNetmask)
 	vmnet.Name = device.Name
-	return s.saveAndRestart(netF)
+	return s.saveAndRestart(ctx, netF)
 }
 
-func (s *SimpleDriver) UpdateVmnet(vmnet *Vmnet) error {
+func (s *SimpleDriver) UpdateVmnet(ctx context.Context, vmnet *Vmnet) error {
+	logger := utility.ContextLogger(ctx, s.logger)
 	netF, err := s.LoadNetworkingFile()
 	if err != nil {
 		return err
 	}
 	device := netF.GetDeviceByName(vmnet.Name)
 	if device == nil {
-		return errors.New(fmt.Sprintf(
-			"Device does not exist %s", vmnet.Name))
+		return NewError(ERROR_NOT_FOUND, "Device does not exist %s", vmnet.Name)
 	}
 	device.Dhcp = vmnet.Dhcp == "yes"
 	device.Nat = vmnet.Type == "nat"
 	device.HostonlyNetmask = vmnet.Mask
 	device.HostonlySubnet = vmnet.Subnet
-	s.logger.Debug("vmnet update", "name", device.Name, "dhcp", device.Dhcp,
+	logger.Debug("vmnet update", "name", device.Name, "dhcp", device.Dhcp,
 		"nat", device.Nat, "subnet", device.HostonlySubnet, "mask",
 		device.HostonlyNetmask)
-	return s.saveAndRestart(netF)
+	return s.saveAndRestart(ctx, netF)
 }
 
-func (s *SimpleDriver) DeleteVmnet(vmnet *Vmnet) error {
+func (s *SimpleDriver) DeleteVmnet(ctx context.Context, vmnet *Vmnet) error {
 	netF, err := s.LoadNetworkingFile()
 	if err != nil {
 		return err
 	}
 	err = netF.RemoveDeviceByName(vmnet.Name)
 	if err != nil {
-		return err
+		return WrapError(ERROR_NOT_FOUND, err)
 	}
-	return s.saveAndRestart(netF)
+	return s.saveAndRestart(ctx, netF)
 }
 
 // Lookup reserved DHCP address for MAC
-func (s *SimpleDriver) LookupDhcpAddress(device, mac string) (addr string, err error) {
+func (s *SimpleDriver) LookupDhcpAddress(ctx context.Context, device, mac string) (addr string, err error) {
+	logger := utility.ContextLogger(ctx, s.logger)
 	leases, err := utility.LoadDhcpLeaseFile(s.vmwarePaths.DhcpLeaseFile(device), s.logger)
 	if err != nil {
-		s.logger.Debug("dhcp leases file load failure", "error", err)
+		logger.Debug("dhcp leases file load failure", "error", err)
 		return addr, err
 	}
 	paddr, err := leases.IpForMac(mac)
@@ -111,10 +121,11 @@ func (s *SimpleDriver) LookupDhcpAddress(device, mac string) (addr string, err e
 	}
 	slotNumber := strings.Replace(device, "vmnet", "", -1)
 	slot, _ := strconv.Atoi(slotNumber)
-	return netF.LookupDhcpReservation(slot, mac)
+	addr, err = netF.LookupDhcpReservation(slot, mac)
+	return addr, WrapError(ERROR_NOT_FOUND, err)
 }
 
-func (s *SimpleDriver) ReserveDhcpAddress(slot int, mac, ip string) error {
+func (s *SimpleDriver) ReserveDhcpAddress(ctx context.Context, slot int, mac, ip string) error {
 	netF, err := s.LoadNetworkingFile()
 	if err != nil {
 		return err
@@ -123,15 +134,18 @@ func (s *SimpleDriver) ReserveDhcpAddress(slot int, mac, ip string) error {
 	if err != nil {
 		return err
 	}
-	return s.saveAndRestart(netF)
+	return s.saveAndRestart(ctx, netF)
 }
 
-func (s *SimpleDriver) AddPortFwd(pfwds []*PortFwd) error {
+func (s *SimpleDriver) AddPortFwd(ctx context.Context, pfwds []*PortFwd) error {
 	netF, err := s.LoadNetworkingFile()
 	if err != nil {
 		return err
 	}
 	for _, pfwd := range pfwds {
+		if err := s.validateVmwarePortFwd(pfwd); err != nil {
+			return err
+		}
 		description, err := s.validatePortFwdDescription(pfwd.Description)
 		if err != nil {
 			return err
@@ -145,13 +159,13 @@ func (s *SimpleDriver) AddPortFwd(pfwds []*PortFwd) error {
 			GuestPort:   pfwd.Guest.Port,
 		}
 		if err := netF.AddPortFwd(newPf); err != nil {
-			return err
+			return WrapError(ERROR_CONFLICT, err)
 		}
 	}
-	return s.saveAndRestart(netF)
+	return s.saveAndRestart(ctx, netF)
 }
 
-func (s *SimpleDriver) DeletePortFwd(pfwds []*PortFwd) error {
+func (s *SimpleDriver) DeletePortFwd(ctx context.Context, pfwds []*PortFwd) error {
 	netF, err := s.LoadNetworkingFile()
 	if err != nil {
 		return err
@@ -165,7 +179,7 @@ func (s *SimpleDriver) DeletePortFwd(pfwds []*PortFwd) error {
 			GuestIp:     pfwd.Guest.Ip,
 			GuestPort:   pfwd.Guest.Port,
 		}
-		err = s.clearNatConfPortFwd(
+		err = s.clearNatConfPortFwd(ctx,
 			fmt.Sprintf("vmnet%d", pfwd.SlotNumber), pfwd.Protocol, pfwd.Port)
 		if err != nil {
 			return err
@@ -174,10 +188,10 @@ func (s *SimpleDriver) DeletePortFwd(pfwds []*PortFwd) error {
 			return err
 		}
 	}
-	return s.saveAndRestart(netF)
+	return s.saveAndRestart(ctx, netF)
 }
 
-func (s *SimpleDriver) clearNatConfPortFwd(device, protocol string, iport int) error {
+func (s *SimpleDriver) clearNatConfPortFwd(ctx context.Context, device, protocol string, iport int) error {
 	port := strconv.Itoa(iport)
 	natconf, err := s.Natfile(device)
 	if err != nil {
@@ -196,7 +210,11 @@ func (s *SimpleDriver) clearNatConfPortFwd(device, protocol string, iport int) e
 			if err := section.DeleteEntry(entry); err != nil {
 				return err
 			}
-			if err := natconf.Save(); err != nil {
+			start := time.Now()
+			err := natconf.Save()
+			utility.Audit(ctx, "nat.write", map[string]interface{}{
+				"device": device, "section": sectionName, "removed_port": port}, start, err)
+			if err != nil {
 				return err
 			}
 			break
@@ -205,8 +223,11 @@ func (s *SimpleDriver) clearNatConfPortFwd(device, protocol string, iport int) e
 	return nil
 }
 
-func (s *SimpleDriver) saveAndRestart(netF utility.NetworkingFile) error {
+func (s *SimpleDriver) saveAndRestart(ctx context.Context, netF utility.NetworkingFile) error {
+	logger := utility.ContextLogger(ctx, s.logger)
+	start := time.Now()
 	path, err := netF.Save()
+	utility.Audit(ctx, "networking.write", map[string]interface{}{"path": path}, start, err)
 	if err != nil {
 		return err
 	}
@@ -219,22 +240,24 @@ func (s *SimpleDriver) saveAndRestart(netF utility.NetworkingFile) error {
 	}
 	backups, err := s.backupDhcpLeases(netF)
 	if err != nil {
-		s.logger.Warn("failed to restore DHCP leases", "error", err)
+		logger.Warn("failed to restore DHCP leases", "error", err)
 	}
-	if err := s.vmnet.Configure(path); err != nil {
-		s.logger.Debug("vmnet configure failed", "error", err)
-		return err
+	if err := s.vmnet.Configure(ctx, path); err != nil {
+		logger.Debug("vmnet configure failed", "error", err)
+		return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 	}
-	if err := s.vmnet.Stop(); err != nil {
-		s.logger.Debug("vmnet service stop failed (non-fatal)", "error", err)
+	if err := s.vmnet.Stop(ctx); err != nil {
+		logger.Debug("vmnet service stop failed (non-fatal)", "error", err)
 	}
 	err = s.restoreDhcpLeases(backups)
 	if err != nil {
-		s.logger.Warn("failed to restore DHCP leases", "error", err)
+		logger.Warn("failed to restore DHCP leases", "error", err)
 	}
-	if err := s.vmnet.Start(); err != nil {
-		s.logger.Debug("vmnet service start failed", "error", err)
-		return err
+	// Services must be started even if the request has been
+	// canceled so networking is not left unavailable
+	if err := s.vmnet.Start(context.WithoutCancel(ctx)); err != nil {
+		logger.Debug("vmnet service start failed", "error", err)
+		return WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 	}
 	return nil
 }
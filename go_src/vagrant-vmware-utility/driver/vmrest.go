@@ -44,7 +44,6 @@ type Client interface {
 type VmrestDriver struct {
 	BaseDriver
 	client      Client
-	ctx         context.Context
 	isBigSurMin bool
 	fallback    Driver
 	vmrest      *vmrest
@@ -54,9 +53,11 @@ type VmrestDriver struct {
 type vmrest struct {
 	access      sync.Mutex
 	activity    chan struct{}
+	cancel      context.CancelFunc
 	command     *exec.Cmd
 	config_path string
 	ctx         context.Context
+	done        chan struct{}
 	home        string
 	logger      hclog.Logger
 	path        string
@@ -78,6 +79,7 @@ const WINDOWS_VMREST_CONFIG = "vmrest.cfg"
 const VMREST_CONTENT_TYPE = "application/vnd.vmware.vmw.rest-v1+json"
 const VMREST_VAGRANT_DESC = "vagrant: managed port"
 const VMREST_KEEPALIVE_SECONDS = 300
+const VMREST_REQUEST_TIMEOUT = 2 * time.Minute
 
 const VMWARE_NETDEV_PREFIX = "vmnet"
 const VAGRANT_NETDEV_PREFIX = "vgtnet"
@@ -143,10 +145,21 @@ func (v *vmrest) Init() (err error) {
 	v.logger.Trace("process configuration", "home", v.home, "username", v.username,
 		"password", v.password, "port", v.port)
 	util.RegisterShutdownTask(v.Cleanup)
+	v.done = make(chan struct{})
 	go v.Runner()
 	return
 }
 
+// Stop the vmrest process and remove the generated configuration
+func (v *vmrest) Stop() {
+	v.logger.Debug("stopping vmrest process runner")
+	v.cancel()
+	if v.done != nil {
+		<-v.done
+	}
+	v.Cleanup()
+}
+
 func (v *vmrest) Cleanup() {
 	if v.isWindows() {
 		v.logger.Debug("vmrest configuration not removed on Windows platform")
@@ -161,7 +174,11 @@ func (v *vmrest) Cleanup() {
 }
 
 func (v *vmrest) Active() (url string) {
-	v.activity <- struct{}{}
+	select {
+	case v.activity <- struct{}{}:
+	case <-v.ctx.Done():
+		v.logger.Warn("activity request after process runner halted")
+	}
 	return v.buildURL()
 }
 
@@ -253,7 +270,8 @@ func (v *vmrest) Runner() {
 			if v.command != nil {
 				v.command.Process.Kill()
 			}
-			break
+			close(v.done)
+			return
 		}
 	}
 }
@@ -375,44 +393,55 @@ func (v *vmrest) stringgen(syms bool, l int) (string, error) {
 }
 
 func (v *vmrest) validate() error {
-	if !utility.FileExists(v.path) {
-		v.logger.Trace("missing vmrest executable", "path", v.path)
-		return errors.New("Failed to locate the vmrest executable")
+	_, err := ValidateVmrest(v.path, v.logger)
+	return err
+}
+
+// Validate the vmrest executable at the given path exists and
+// satisfies the version constraint. The detected version is returned.
+func ValidateVmrest(vmrestPath string, logger hclog.Logger) (string, error) {
+	if !utility.FileExists(vmrestPath) {
+		logger.Trace("missing vmrest executable", "path", vmrestPath)
+		return "", errors.New("Failed to locate the vmrest executable")
 	}
 
-	cmd := exec.Command(v.path, "-v")
+	ctx, cancel := context.WithTimeout(context.Background(), VMWARE_INFO_TIMEOUT)
+	defer cancel()
+	cmd := utility.CommandContext(ctx, vmrestPath, "-v")
 	_, o := utility.ExecuteWithOutput(cmd)
 	m, err := utility.MatchPattern(`vmrest (?P<version>[\d+.]+) `, o)
 	if err != nil {
-		v.logger.Trace("failed to determine vmrest version information", "output", o)
-		return errors.New("failed to determine vmrest version")
+		logger.Trace("failed to determine vmrest version information", "output", o)
+		return "", errors.New("failed to determine vmrest version")
 	}
-	v.logger.Trace("detected vmrest version", "version", m["version"])
+	logger.Trace("detected vmrest version", "version", m["version"])
 	constraint, err := version.NewConstraint(VMREST_VERSION_CONSTRAINT)
 	if err != nil {
-		v.logger.Warn("failed to parse vmrest constraint", "constraint", VMREST_VERSION_CONSTRAINT, "error", err)
-		return errors.New("failed to setup vmrest constraint for version check")
+		logger.Warn("failed to parse vmrest constraint", "constraint", VMREST_VERSION_CONSTRAINT, "error", err)
+		return m["version"], errors.New("failed to setup vmrest constraint for version check")
 	}
 	checkV, err := version.NewVersion(m["version"])
 	if err != nil {
-		v.logger.Warn("failed to parse vmrest version for check", "version", m["version"], "error", err)
-		return errors.New("failed to parse vmrest version for validation check")
+		logger.Warn("failed to parse vmrest version for check", "version", m["version"], "error", err)
+		return m["version"], errors.New("failed to parse vmrest version for validation check")
 	}
 
-	v.logger.Trace("validating vmrest version", "constraint", constraint, "version", checkV)
+	logger.Trace("validating vmrest version", "constraint", constraint, "version", checkV)
 
 	if !constraint.Check(checkV) {
-		v.logger.Warn("installed vmrest does not meet constraint requirements", "constraint", constraint, "version", checkV)
-		return errors.New("vmrest version is incompatible")
+		logger.Warn("installed vmrest does not meet constraint requirements", "constraint", constraint, "version", checkV)
+		return m["version"], errors.New("vmrest version is incompatible")
 	}
 
-	return nil
+	return m["version"], nil
 }
 
 func NewVmrest(ctx context.Context, vmrestPath string, logger hclog.Logger) (v *vmrest, err error) {
 	logger = logger.Named("process")
+	ctx, cancel := context.WithCancel(ctx)
 	v = &vmrest{
 		activity: make(chan struct{}),
+		cancel:   cancel,
 		ctx:      ctx,
 		logger:   logger,
 		path:     vmrestPath}
@@ -422,7 +451,7 @@ func NewVmrest(ctx context.Context, vmrestPath string, logger hclog.Logger) (v *
 
 func NewVmrestDriver(ctx context.Context, f Driver, logger hclog.Logger) (d Driver, err error) {
 	logger = logger.Named("vmrest")
-	i, err := f.VmwareInfo()
+	i, err := f.VmwareInfo(ctx)
 	if err != nil {
 		logger.Warn("failed to get vmware info", "error", err)
 		logger.Info("using fallback driver")
@@ -453,7 +482,6 @@ func NewVmrestDriver(ctx context.Context, f Driver, logger hclog.Logger) (d Driv
 	d = &VmrestDriver{
 		BaseDriver:  b,
 		client:      retryablehttp.NewClient().StandardClient(),
-		ctx:         ctx,
 		fallback:    f,
 		vmrest:      v,
 		isBigSurMin: utility.IsBigSurMin(),
@@ -461,7 +489,7 @@ func NewVmrestDriver(ctx context.Context, f Driver, logger hclog.Logger) (d Driv
 	// License detection is not always correct so we need to validate
 	// that networking functionality is available via the vmrest process
 	logger.Debug("validating that vmrest service provides networking functionality")
-	_, err = d.Vmnets()
+	_, err = d.Vmnets(ctx)
 	if err != nil {
 		logger.Error("vmrest driver failed to access networking functions, using fallback",
 			"status", "invalid", "error", err)
@@ -471,21 +499,38 @@ func NewVmrestDriver(ctx context.Context, f Driver, logger hclog.Logger) (d Driv
 	return
 }
 
-func (v *VmrestDriver) Vmnets() (vmns *Vmnets, err error) {
-	v.logger.Trace("requesting list of current vmnets")
-	r, err := v.Do("get", "vmnet", nil)
+func (v *VmrestDriver) Vmnets(ctx context.Context) (vmns *Vmnets, err error) {
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Trace("requesting list of current vmnets")
+	r, err := v.Do(ctx, "get", "vmnet", nil)
 	if err != nil {
-		v.logger.Error("vmnets list request failed", "error", err)
+		logger.Error("vmnets list request failed", "error", err)
 		return
 	}
 	vmns = &Vmnets{}
 	err = json.Unmarshal(r, vmns)
-	v.logger.Trace("current vmnets request list", "vmnets", vmns, "error", err)
+	logger.Trace("current vmnets request list", "vmnets", vmns, "error", err)
 	return
 }
 
-func (v *VmrestDriver) AddVmnet(vnet *Vmnet) (err error) {
-	v.logger.Trace("adding vmnet device", "vmnet", vnet)
+func (v *VmrestDriver) Capabilities() *Capabilities {
+	caps := v.BaseDriver.Capabilities()
+	caps.Driver = "vmrest"
+	// Big Sur and beyond use vmrest for vmnet management which
+	// only supports creating devices
+	if v.isBigSurMin {
+		caps.Unsupported(CAPABILITY_VMNET_UPDATE, "VMware does not support updating vmnet device")
+		caps.Unsupported(CAPABILITY_VMNET_DELETE, "VMware does not support deleting vmnet device")
+		caps.Unsupported(CAPABILITY_VMNET_CUSTOM_SUBNET,
+			"Networks with custom subnet/mask values are not supported on this platform")
+		caps.Unsupported(CAPABILITY_DHCP_RESERVATION, "DHCP reservations are not available on this platform")
+	}
+	return caps
+}
+
+func (v *VmrestDriver) AddVmnet(ctx context.Context, vnet *Vmnet) (err error) {
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Trace("adding vmnet device", "vmnet", vnet)
 
 	// Big Sur and beyond require using vmrest for vmnet management
 	if v.isBigSurMin {
@@ -493,62 +538,66 @@ func (v *VmrestDriver) AddVmnet(vnet *Vmnet) (err error) {
 		// we need to force an error since the subnet/mask is not available
 		// for modification via the vmnet framework
 		if vnet.Type != "bridged" && (vnet.Mask != "" || vnet.Subnet != "") {
-			return errors.New("Networks with custom subnet/mask values are not supported on this platform")
+			return NewError(ERROR_UNSUPPORTED_ON_PLATFORM,
+				"Networks with custom subnet/mask values are not supported on this platform")
 		}
 		// we need a name, so if one is not set provide one
 		if vnet.Name == "" {
-			if err = v.setVmnetName(vnet); err != nil {
+			if err = v.setVmnetName(ctx, vnet); err != nil {
 				return
 			}
 		}
 		var f []byte
 		f, err = json.Marshal(vnet)
 		if err != nil {
-			v.logger.Error("failed to encode vmnet", "vmnet", vnet, "error", err)
+			logger.Error("failed to encode vmnet", "vmnet", vnet, "error", err)
 			return
 		}
-		_, err = v.Do("post", "vmnets", bytes.NewBuffer(f))
+		_, err = v.Do(ctx, "post", "vmnets", bytes.NewBuffer(f))
 		if err != nil {
-			v.logger.Error("failed to create new network", "vmnet", vnet, "error", err)
+			logger.Error("failed to create new network", "vmnet", vnet, "error", err)
 		}
 		return
 	}
-	return v.fallback.AddVmnet(vnet)
+	return v.fallback.AddVmnet(ctx, vnet)
 }
 
-func (v *VmrestDriver) UpdateVmnet(vnet *Vmnet) (err error) {
-	v.logger.Trace("updating vmnet device (proxy to create request)", "vmnet", vnet)
+func (v *VmrestDriver) UpdateVmnet(ctx context.Context, vnet *Vmnet) (err error) {
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Trace("updating vmnet device (proxy to create request)", "vmnet", vnet)
 	// Big Sur and beyond require using vmrest for vmnet management and
 	// vmrest does not support updating existing vmnet devices
 	if v.isBigSurMin {
-		return errors.New("VMware does not support updating vmnet device")
+		return NewError(ERROR_UNSUPPORTED_ON_PLATFORM, "VMware does not support updating vmnet device")
 	}
-	return v.fallback.UpdateVmnet(vnet)
+	return v.fallback.UpdateVmnet(ctx, vnet)
 }
 
-func (v *VmrestDriver) DeleteVmnet(vnet *Vmnet) (err error) {
+func (v *VmrestDriver) DeleteVmnet(ctx context.Context, vnet *Vmnet) (err error) {
+	logger := utility.ContextLogger(ctx, v.logger)
 	// The vmrest interface does not provide any method for removing
 	// interfaces, only creating them. We can use the fallback driver
 	// here, but it may have no affect on platforms like Big Sur where
 	// the VMware vmnet implementation isn't actually being used
-	v.logger.Trace("deleting vmnet device", "vmnet", vnet)
+	logger.Trace("deleting vmnet device", "vmnet", vnet)
 	if v.isBigSurMin {
-		return errors.New("VMware does not support deleting vmnet device")
+		return NewError(ERROR_UNSUPPORTED_ON_PLATFORM, "VMware does not support deleting vmnet device")
 	}
-	return v.fallback.DeleteVmnet(vnet)
+	return v.fallback.DeleteVmnet(ctx, vnet)
 }
 
-func (v *VmrestDriver) PortFwds(slot string) (*PortFwds, error) {
+func (v *VmrestDriver) PortFwds(ctx context.Context, slot string) (*PortFwds, error) {
+	logger := utility.ContextLogger(ctx, v.logger)
 	f := &PortFwds{}
 	if v.InternalPortForwarding() {
 		var err error
-		f.PortForwards, err = v.InternalPortFwds()
+		f.PortForwards, err = v.InternalPortFwds(ctx)
 		return f, err
 	}
 
 	fwds := []*PortFwd{}
 	if v.InternalPortForwarding() {
-		iFwds, err := v.InternalPortFwds()
+		iFwds, err := v.InternalPortFwds(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -557,7 +606,7 @@ func (v *VmrestDriver) PortFwds(slot string) (*PortFwds, error) {
 		device := "vmnet" + slot
 		if slot == "" {
 			var nat *Vmnet
-			nat, err := v.detectNAT(v)
+			nat, err := v.detectNAT(ctx, v)
 			if err != nil {
 				return nil, err
 			}
@@ -566,24 +615,24 @@ func (v *VmrestDriver) PortFwds(slot string) (*PortFwds, error) {
 		}
 		slotNum, err := strconv.Atoi(string(device[len(device)-1]))
 		if err != nil {
-			v.logger.Error("failed to parse slot number from device", "device", device, "error", err)
+			logger.Error("failed to parse slot number from device", "device", device, "error", err)
 			return nil, errors.New("error parsing vmnet device name for slot")
 		}
-		v.logger.Trace("requesting list of port forwards", "device", device)
-		r, err := v.Do("get", "vmnet/"+device+"/portforward", nil)
+		logger.Trace("requesting list of port forwards", "device", device)
+		r, err := v.Do(ctx, "get", "vmnet/"+device+"/portforward", nil)
 		if err != nil {
-			v.logger.Error("port forwards list request failed", "error", err)
+			logger.Error("port forwards list request failed", "error", err)
 			return nil, err
 		}
 		tmp := map[string]interface{}{}
 		err = json.Unmarshal(r, &tmp)
 		if err != nil {
-			v.logger.Warn("failed initial port forward parsing", "error", err)
+			logger.Warn("failed initial port forward parsing", "error", err)
 			return nil, err
 		}
 		ifwds, ok := tmp["port_forwardings"].([]interface{})
 		if !ok {
-			v.logger.Warn("failed to convert port forwardings", "forwards", tmp["port_forwardings"])
+			logger.Warn("failed to convert port forwardings", "forwards", tmp["port_forwardings"])
 			return nil, errors.New("failed to parse port forwards")
 		}
 
@@ -606,125 +655,131 @@ func (v *VmrestDriver) PortFwds(slot string) (*PortFwds, error) {
 		for _, natFwd := range v.settings.NAT.PortFwds() {
 			nfwd := v.utilityToDriverFwd(natFwd)
 			if pfwd.Matches(nfwd) {
-				v.logger.Trace("updating port forward description", "portforward", pfwd, "description", nfwd.Description)
+				logger.Trace("updating port forward description", "portforward", pfwd, "description", nfwd.Description)
 				pfwd.Description = nfwd.Description
 			}
 		}
 		f.PortForwards = append(f.PortForwards, pfwd)
 	}
 
-	v.logger.Trace("current port forwards list", "portforwards", f)
+	logger.Trace("current port forwards list", "portforwards", f)
 	return f, nil
 }
 
-func (v *VmrestDriver) AddPortFwd(pfwds []*PortFwd) (err error) {
-	v.logger.Trace("adding port forwards", "portforwards", pfwds)
+func (v *VmrestDriver) AddPortFwd(ctx context.Context, pfwds []*PortFwd) (err error) {
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Trace("adding port forwards", "portforwards", pfwds)
 	for _, fwd := range pfwds {
 		fwd.Description, err = v.validatePortFwdDescription(fwd.Description)
 		if err != nil {
 			return err
 		}
-		v.logger.Trace("creating port forward", "portforward", fwd)
+		logger.Trace("creating port forward", "portforward", fwd)
 		// Check if we have the internal port forward service enabled, and if so
 		// add the port forward there. Otherwise, call up to the vmrest service
 		if v.InternalPortForwarding() {
-			if err = v.AddInternalPortForward(fwd); err != nil {
+			if err = v.AddInternalPortForward(ctx, fwd); err != nil {
 				return
 			}
 		} else {
+			if err = v.validateVmwarePortFwd(fwd); err != nil {
+				return
+			}
 			f := map[string]interface{}{
 				"guestIp":   fwd.Guest.Ip,
 				"guestPort": fwd.Guest.Port,
 				"desc":      VMREST_VAGRANT_DESC}
 			body, e := json.Marshal(f)
 			if e != nil {
-				v.logger.Error("failed to encode portforward request", "content", fwd.
+				logger.Error("failed to encode portforward request", "content", fwd.
 					Guest, "error", e)
 				return errors.New("failed to generate port forward request")
 			}
-			v.logger.Trace("new port forward request", "body", string(body))
-			_, err = v.Do("put", fmt.Sprintf("vmnet/vmnet%d/portforward/%s/%d",
+			logger.Trace("new port forward request", "body", string(body))
+			_, err = v.Do(ctx, "put", fmt.Sprintf("vmnet/vmnet%d/portforward/%s/%d",
 				fwd.SlotNumber, fwd.Protocol, fwd.Port), bytes.NewBuffer(body))
 			if err != nil {
-				v.logger.Error("failed to create port forward", "portforward", fwd, "error", err)
+				logger.Error("failed to create port forward", "portforward", fwd, "error", err)
 				return
 			}
 		}
-		v.logger.Info("port forward added", "portforward", fwd)
+		logger.Info("port forward added", "portforward", fwd)
 		ufwd := v.driverToUtilityFwd(fwd)
 		// Ensure port forward is not already stored
 		err = v.settings.NAT.Remove(ufwd)
 		if err != nil {
-			v.logger.Trace("failure encountered attempting to remove port forward", "portforward", ufwd, "error", err)
+			logger.Trace("failure encountered attempting to remove port forward", "portforward", ufwd, "error", err)
 		}
 		err = v.settings.NAT.Add(ufwd)
 		if err != nil {
-			v.logger.Trace("failed to store port forward in nat settings", "portforward", ufwd, "error", err)
+			logger.Trace("failed to store port forward in nat settings", "portforward", ufwd, "error", err)
 			return errors.New("failed to persist port forward information")
 		}
 		err = v.settings.NAT.Save()
 		if err != nil {
-			v.logger.Error("failed to save port forward nat settings", "error", err)
+			logger.Error("failed to save port forward nat settings", "error", err)
 			return errors.New("failed to store persistent port forward information")
 		}
 	}
-	v.logger.Trace("all port forwards added", "portforwards", pfwds)
+	logger.Trace("all port forwards added", "portforwards", pfwds)
 	return
 }
 
-func (v *VmrestDriver) DeletePortFwd(pfwds []*PortFwd) (err error) {
-	v.logger.Trace("removing port forwards", "portforwards", pfwds)
+func (v *VmrestDriver) DeletePortFwd(ctx context.Context, pfwds []*PortFwd) (err error) {
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Trace("removing port forwards", "portforwards", pfwds)
 	for _, fwd := range pfwds {
-		v.logger.Trace("deleting port forward", "portforward", fwd)
+		logger.Trace("deleting port forward", "portforward", fwd)
 		if v.InternalPortForwarding() {
-			if err = v.DeleteInternalPortForward(fwd); err != nil {
+			if err = v.DeleteInternalPortForward(ctx, fwd); err != nil {
 				return
 			}
 		} else {
-			_, err = v.Do("delete", fmt.Sprintf("vmnet/vmnet%d/portforward/%s/%d",
+			_, err = v.Do(ctx, "delete", fmt.Sprintf("vmnet/vmnet%d/portforward/%s/%d",
 				fwd.SlotNumber, fwd.Protocol, fwd.Port), nil)
 			if err != nil {
-				v.logger.Error("failed to delete port forward", "portforward", fwd, "error", err)
+				logger.Error("failed to delete port forward", "portforward", fwd, "error", err)
 				return
 			}
 		}
-		v.logger.Info("port forward removed", "portforward", fwd)
+		logger.Info("port forward removed", "portforward", fwd)
 		ufwd := v.driverToUtilityFwd(fwd)
 		err = v.settings.NAT.Remove(ufwd)
 		if err != nil {
-			v.logger.Error("failed to remove port forward from nat settings", "portforward", ufwd, "error", err)
+			logger.Error("failed to remove port forward from nat settings", "portforward", ufwd, "error", err)
 			return errors.New("failed to persist port forward removal information")
 		}
 		err = v.settings.NAT.Save()
 		if err != nil {
-			v.logger.Error("failed to save port forward nat settings", "error", err)
+			logger.Error("failed to save port forward nat settings", "error", err)
 			return errors.New("failed to store persistent port forward information")
 		}
 	}
-	v.logger.Trace("all port fowards removed", "portforwards", pfwds)
+	logger.Trace("all port fowards removed", "portforwards", pfwds)
 	return
 }
 
-func (v *VmrestDriver) LookupDhcpAddress(device string, mac string) (addr string, err error) {
-	return v.fallback.LookupDhcpAddress(device, mac)
+func (v *VmrestDriver) LookupDhcpAddress(ctx context.Context, device string, mac string) (addr string, err error) {
+	return v.fallback.LookupDhcpAddress(ctx, device, mac)
 }
 
-func (v *VmrestDriver) ReserveDhcpAddress(slot int, mac string, ip string) (err error) {
+func (v *VmrestDriver) ReserveDhcpAddress(ctx context.Context, slot int, mac string, ip string) (err error) {
+	logger := utility.ContextLogger(ctx, v.logger)
 	// Big Sur does not support dhcp address reservation
 	if v.isBigSurMin {
-		return errors.New("DHCP reservations are not available on this platform")
+		return NewError(ERROR_UNSUPPORTED_ON_PLATFORM, "DHCP reservations are not available on this platform")
 	}
-	v.logger.Trace("reserving dhcp address", "slot", slot, "mac", mac, "ip", ip)
+	logger.Trace("reserving dhcp address", "slot", slot, "mac", mac, "ip", ip)
 	body, err := json.Marshal(map[string]string{"IP": ip})
 	if err != nil {
-		v.logger.Error("failed to encode dhcp reservation request", "error", err)
+		logger.Error("failed to encode dhcp reservation request", "error", err)
 		return errors.New("failed to encode dhcp reservation request")
 	}
-	_, err = v.Do("put", fmt.Sprintf("vmnet/vmnet%d/mactoip/%s", slot, mac),
+	_, err = v.Do(ctx, "put", fmt.Sprintf("vmnet/vmnet%d/mactoip/%s", slot, mac),
 		bytes.NewBuffer(body))
 	if err != nil {
-		v.logger.Error("failed to create dhcp reservation", "error", err)
-		return errors.New("failed to create dhcp reservation")
+		logger.Error("failed to create dhcp reservation", "error", err)
+		return NewError(ErrorCodeFor(err), "failed to create dhcp reservation")
 	}
 	return
 }
@@ -735,19 +790,30 @@ func (v *VmrestDriver) LoadNetworkingFile() (utility.NetworkingFile, error) {
 	return v.fallback.LoadNetworkingFile()
 }
 
-func (v *VmrestDriver) VerifyVmnet() error {
-	return v.fallback.VerifyVmnet()
+func (v *VmrestDriver) VerifyVmnet(ctx context.Context) error {
+	return v.fallback.VerifyVmnet(ctx)
+}
+
+// Shutdown the driver. The vmrest process is stopped after
+// services provided by the base driver have been stopped.
+func (v *VmrestDriver) Shutdown() error {
+	err := v.BaseDriver.Shutdown()
+	v.vmrest.Stop()
+	return err
 }
 
 // Sends a request to the vmrest service
-func (v *VmrestDriver) Do(method, path string, body io.Reader) (r []byte, err error) {
-	v.logger.Info("starting remote request to vmware service")
+func (v *VmrestDriver) Do(ctx context.Context, method, path string, body io.Reader) (r []byte, err error) {
+	ctx, cancel := context.WithTimeout(ctx, VMREST_REQUEST_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Info("starting remote request to vmware service")
 	url := strings.Join(
 		[]string{
 			v.vmrest.Active(),
 			path}, "/")
 	method = strings.ToUpper(method)
-	req, err := http.NewRequest(method, url, body)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return
 	}
@@ -756,36 +822,44 @@ func (v *VmrestDriver) Do(method, path string, body io.Reader) (r []byte, err er
 	if body != nil {
 		req.Header.Add("Content-Type", VMREST_CONTENT_TYPE)
 	}
-	v.logger.Debug("sending request", "method", method, "url", url)
-	resp, err := v.client.Do(req.WithContext(v.ctx))
+	logger.Debug("sending request", "method", method, "url", url)
+	resp, err := v.client.Do(req)
 	if err != nil {
-		v.logger.Warn("request failed", "error", err)
+		logger.Warn("request failed", "error", err)
+		err = WrapError(ERROR_VMWARE_SERVICE_FAILURE, err)
 		return
 	}
 	defer resp.Body.Close()
 	r, err = ioutil.ReadAll(resp.Body)
-	v.logger.Debug("received response", "code", resp.StatusCode, "status", resp.Status, "body", string(r), "error", err)
+	logger.Debug("received response", "code", resp.StatusCode, "status", resp.Status, "body", string(r), "error", err)
 	if resp.StatusCode > 299 {
+		code := ERROR_VMWARE_SERVICE_FAILURE
+		switch resp.StatusCode {
+		case http.StatusNotFound:
+			code = ERROR_NOT_FOUND
+		case http.StatusConflict:
+			code = ERROR_CONFLICT
+		}
 		result := map[string]interface{}{}
 		err = json.Unmarshal(r, &result)
 		if err != nil {
-			err = errors.New("unknown error encountered with vmrest process")
+			err = NewError(code, "unknown error encountered with vmrest process")
 			return
 		}
 		msg, ok := result["Message"].(string)
 		if !ok {
-			err = errors.New("unknown error encountered with vmrest process")
+			err = NewError(code, "unknown error encountered with vmrest process")
 			return
 		}
-		err = errors.New("failure encountered: " + msg)
+		err = NewError(code, "failure encountered: %s", msg)
 	}
 	return
 }
 
 // Finds a free vmnet device. Currently very stupid and does not
 // match on missing devices
-func (v *VmrestDriver) setVmnetName(vnet *Vmnet) (err error) {
-	vmns, err := v.Vmnets()
+func (v *VmrestDriver) setVmnetName(ctx context.Context, vnet *Vmnet) (err error) {
+	vmns, err := v.Vmnets(ctx)
 	names := []string{}
 	for _, n := range vmns.Vmnets {
 		names = append(names, n.Name)
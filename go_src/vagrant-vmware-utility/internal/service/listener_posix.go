@@ -0,0 +1,55 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build !windows
+// +build !windows
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	hclog "github.com/hashicorp/go-hclog"
+
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/settings"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+// Default file mode applied to unix sockets
+const DEFAULT_SOCKET_MODE = 0600
+
+// Validate the host address and permissions of a path based forward
+func validatePath(addr *settings.Address, permissions string) error {
+	if addr.Type != "unix" {
+		return fmt.Errorf("%s port forwards are not supported on this platform", addr.Type)
+	}
+	if _, err := socketMode(permissions); err != nil {
+		return err
+	}
+	return validateSocketPath(addr.Host)
+}
+
+// Create a listener for path based host addresses. The permissions
+// value is an octal file mode applied to the socket.
+func listenPath(addr *settings.Address, permissions string, logger hclog.Logger) (net.Listener, error) {
+	if err := validatePath(addr, permissions); err != nil {
+		return nil, err
+	}
+	mode, _ := socketMode(permissions)
+	return utility.ListenUnix(addr.Host, mode, -1, logger)
+}
+
+// File mode of the socket from the permissions value
+func socketMode(permissions string) (os.FileMode, error) {
+	if permissions == "" {
+		return os.FileMode(DEFAULT_SOCKET_MODE), nil
+	}
+	m, err := strconv.ParseUint(permissions, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid socket permissions '%s' (expected octal file mode)", permissions)
+	}
+	return os.FileMode(m).Perm(), nil
+}
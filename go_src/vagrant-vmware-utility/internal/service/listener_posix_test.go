@@ -0,0 +1,106 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build !windows
+// +build !windows
+
+package service
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/settings"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+func TestForwardUnixSocket(t *testing.T) {
+	guest, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to create guest listener: %s", err)
+	}
+	defer guest.Close()
+	go func() {
+		for {
+			c, err := guest.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(c, c)
+		}
+	}()
+
+	dir := testSocketDirectory(t)
+	sock := path.Join(dir, "guest.sock")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &Forward{
+		Ctx: ctx,
+		Fwd: &settings.Forward{
+			Host:        &settings.Address{Host: sock, Type: "unix"},
+			Guest:       &settings.Address{Host: "127.0.0.1", Port: guest.Addr().(*net.TCPAddr).Port, Type: "tcp"},
+			Permissions: "0660",
+		},
+		cancel: cancel,
+		logger: testLogger(),
+	}
+	if err := f.Activate(); err != nil {
+		t.Fatalf("Failed to activate forward: %s", err)
+	}
+	defer f.Deactivate()
+
+	info, err := os.Stat(sock)
+	if err != nil {
+		t.Fatalf("Failed to stat socket: %s", err)
+	}
+	if info.Mode().Perm() != 0660 {
+		t.Errorf("Invalid socket permissions %o != 660", info.Mode().Perm())
+	}
+
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		t.Fatalf("Failed to connect to forward: %s", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("Failed to write to forward: %s", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("Failed to read from forward: %s", err)
+	}
+	if string(buf) != "ping" {
+		t.Errorf("Invalid response from forward '%s'", buf)
+	}
+}
+
+func TestPortForwardingAddInvalidPath(t *testing.T) {
+	dir := testSocketDirectory(t)
+	p := testPortForwarding(t)
+	guest := &settings.Address{Host: "127.0.0.1", Port: 22, Type: "tcp"}
+	for _, fwd := range []*settings.Forward{
+		{Host: &settings.Address{Type: "unix"}, Guest: guest},
+		{Host: &settings.Address{Host: path.Join(t.TempDir(), "guest.sock"), Type: "unix"}, Guest: guest},
+		{Host: &settings.Address{Host: path.Join(dir, "..", "guest.sock"), Type: "unix"}, Guest: guest},
+		{Host: &settings.Address{Host: path.Join(dir, "guest.sock"), Type: "npipe"}, Guest: guest},
+		{Host: &settings.Address{Host: path.Join(dir, "guest.sock"), Type: "unix"}, Guest: guest, Permissions: "rw"},
+	} {
+		if err := p.Add(fwd); err == nil {
+			t.Errorf("Expected invalid port forward to be rejected: %s %s", fwd.Host.Type, fwd.Host.Host)
+		}
+	}
+	if len(p.s.Forwards) != 0 || len(p.Fwds()) != 0 {
+		t.Errorf("Invalid port forward was persisted")
+	}
+}
+
+func testSocketDirectory(t *testing.T) string {
+	dir := t.TempDir()
+	utility.SetSocketDirectory(dir)
+	t.Cleanup(func() { utility.SetSocketDirectory("") })
+	return dir
+}
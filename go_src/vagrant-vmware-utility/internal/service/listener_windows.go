@@ -0,0 +1,63 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"golang.org/x/sys/windows"
+
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/settings"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+// Default security descriptor applied to named pipes. Access is
+// restricted to the SYSTEM user and Administrators group.
+const DEFAULT_PIPE_SDDL = utility.PIPE_BASE_SDDL
+
+// Validate the host address and permissions of a path based forward
+func validatePath(addr *settings.Address, permissions string) error {
+	switch addr.Type {
+	case "unix":
+		return validateSocketPath(addr.Host)
+	case "npipe":
+		prefix := utility.PIPE_PREFIX
+		if len(addr.Host) <= len(prefix) || !strings.EqualFold(addr.Host[:len(prefix)], prefix) {
+			return fmt.Errorf("invalid named pipe path '%s' (expected %sNAME)", addr.Host, prefix)
+		}
+		if permissions != "" {
+			if _, err := windows.SecurityDescriptorFromString(permissions); err != nil {
+				return fmt.Errorf("invalid named pipe permissions '%s': %s", permissions, err)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%s port forwards are not supported on this platform", addr.Type)
+	}
+}
+
+// Create a listener for path based host addresses. The permissions
+// value is an SDDL security descriptor applied to named pipes.
+func listenPath(addr *settings.Address, permissions string, logger hclog.Logger) (net.Listener, error) {
+	if err := validatePath(addr, permissions); err != nil {
+		return nil, err
+	}
+	switch addr.Type {
+	case "unix":
+		if permissions != "" {
+			logger.Warn("socket permissions are not supported on this platform", "path", addr.Host)
+		}
+		return net.Listen("unix", addr.Host)
+	case "npipe":
+		if permissions == "" {
+			permissions = DEFAULT_PIPE_SDDL
+		}
+		return utility.ListenPipe(addr.Host, permissions, logger)
+	default:
+		return nil, fmt.Errorf("%s port forwards are not supported on this platform", addr.Type)
+	}
+}
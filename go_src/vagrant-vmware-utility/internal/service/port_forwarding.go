@@ -6,24 +6,141 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"net"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
 
 	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/settings"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
 )
 
+// Snapshot of traffic statistics for a forward
+type ForwardStats struct {
+	ActiveConnections int64
+	TotalConnections  int64
+	BytesIn           int64
+	BytesOut          int64
+	DialFailures      int64
+}
+
+// Single client connection being streamed through a forward
+type Connection struct {
+	Id      int64
+	Remote  string
+	Started time.Time
+
+	bytesIn  atomic.Int64
+	bytesOut atomic.Int64
+	cancel   context.CancelFunc
+}
+
+func (c *Connection) BytesIn() int64 {
+	return c.bytesIn.Load()
+}
+
+func (c *Connection) BytesOut() int64 {
+	return c.bytesOut.Load()
+}
+
+// Forcibly close the connection
+func (c *Connection) Close() {
+	c.cancel()
+}
+
 type Forward struct {
 	Active bool
 	Ctx    context.Context
 	Fwd    *settings.Forward
 
-	cancel context.CancelFunc
-	l      sync.Mutex
-	logger hclog.Logger
+	cancel       context.CancelFunc
+	closers      []io.Closer
+	cl           sync.Mutex
+	connections  map[int64]*Connection
+	l            sync.Mutex
+	logger       hclog.Logger
+	totalConns   atomic.Int64
+	bytesIn      atomic.Int64
+	bytesOut     atomic.Int64
+	dialFailures atomic.Int64
+}
+
+func (f *Forward) String() string {
+	return fmt.Sprintf("%s %s -> %s", f.Fwd.Host.Type, f.Fwd.Host, f.Fwd.Guest)
+}
+
+func (f *Forward) Stats() ForwardStats {
+	f.cl.Lock()
+	active := len(f.connections)
+	f.cl.Unlock()
+
+	return ForwardStats{
+		ActiveConnections: int64(active),
+		TotalConnections:  f.totalConns.Load(),
+		BytesIn:           f.bytesIn.Load(),
+		BytesOut:          f.bytesOut.Load(),
+		DialFailures:      f.dialFailures.Load(),
+	}
+}
+
+// List of currently active connections
+func (f *Forward) Connections() []*Connection {
+	f.cl.Lock()
+	defer f.cl.Unlock()
+
+	conns := make([]*Connection, 0, len(f.connections))
+	for _, c := range f.connections {
+		conns = append(conns, c)
+	}
+	sort.Slice(conns, func(i, j int) bool {
+		return conns[i].Id < conns[j].Id
+	})
+	return conns
+}
+
+// Forcibly close an active connection
+func (f *Forward) CloseConnection(id int64) error {
+	f.cl.Lock()
+	c, ok := f.connections[id]
+	f.cl.Unlock()
+	if !ok {
+		return fmt.Errorf("connection %d not found", id)
+	}
+	f.logger.Debug("closing connection", "fwd", f, "connection", id, "source", c.Remote)
+	c.Close()
+	return nil
+}
+
+func (f *Forward) track(remote net.Addr, cancel context.CancelFunc) *Connection {
+	c := &Connection{
+		Id:      f.totalConns.Add(1),
+		Started: time.Now(),
+		cancel:  cancel,
+	}
+	// Unix sockets and named pipes may not provide a remote address
+	if remote != nil {
+		c.Remote = remote.String()
+	}
+	f.cl.Lock()
+	defer f.cl.Unlock()
+	if f.connections == nil {
+		f.connections = map[int64]*Connection{}
+	}
+	f.connections[c.Id] = c
+	return c
+}
+
+func (f *Forward) untrack(c *Connection) {
+	f.cl.Lock()
+	defer f.cl.Unlock()
+	delete(f.connections, c.Id)
 }
 
 func (f *Forward) Deactivate() error {
@@ -31,6 +148,12 @@ func (f *Forward) Deactivate() error {
 	defer f.l.Unlock()
 
 	f.cancel()
+	// Listeners are closed before returning so the host
+	// address is available once the forward is deactivated
+	for _, c := range f.closers {
+		c.Close()
+	}
+	f.closers = nil
 	f.Active = false
 	return nil
 }
@@ -41,52 +164,27 @@ func (f *Forward) Activate() error {
 	if f.Active {
 		return errors.New("port forward is already active")
 	}
+	if err := ValidateForward(f.Fwd); err != nil {
+		return err
+	}
 	f.Active = true
 
+	if f.Fwd.Host.IsPath() {
+		l, err := listenPath(f.Fwd.Host, f.Fwd.Permissions, f.logger)
+		if err != nil {
+			f.logger.Error("failed to setup host listener", "type", f.Fwd.Host.Type, "host", f.Fwd.Host, "error", err)
+			return err
+		}
+		f.serve(l, f.Fwd.Host.Type)
+	}
+
 	if strings.Contains(f.Fwd.Host.Type, "tcp") {
 		l, err := net.Listen("tcp", f.Fwd.Host.String())
 		if err != nil {
 			f.logger.Error("failed to setup host listener", "type", "tcp", "host", f.Fwd.Host, "error", err)
 			return err
 		}
-
-		go func() {
-			<-f.Ctx.Done()
-			l.Close()
-		}()
-
-		f.logger.Debug("activated port forward", "type", "tcp", "fwd", f)
-
-		go func() {
-			for {
-				conn, err := l.Accept()
-				if err != nil {
-					f.logger.Error("failed to accept incoming connection", "type", "tcp", "fwd", f, "error", err)
-					f.cancel()
-					return
-				}
-
-				target, err := net.Dial("tcp", f.Fwd.Guest.String())
-				if err != nil {
-					f.logger.Warn("failed to connect to guest", "type", "tcp", "guest", f.Fwd.Guest)
-					continue
-				}
-
-				ctx, completed := context.WithCancel(f.Ctx)
-				f.logger.Debug("initializing new connection stream", "type", "tcp", "fwd", f, "source", conn.RemoteAddr())
-				go f.stream(conn, target, completed, "tcp", "outgoing")
-				go f.stream(target, conn, completed, "tcp", "incoming")
-
-				go func() {
-					select {
-					case <-ctx.Done():
-					case <-f.Ctx.Done():
-					}
-					conn.Close()
-					target.Close()
-				}()
-			}
-		}()
+		f.serve(l, "tcp")
 	}
 
 	if strings.Contains(f.Fwd.Host.Type, "udp") {
@@ -103,10 +201,12 @@ func (f *Forward) Activate() error {
 		target, err := net.Dial("udp", f.Fwd.Guest.String())
 		if err != nil {
 			f.logger.Error("failed to connect to guest", "type", "udp", "guest", f.Fwd.Guest, "error", err)
+			f.dialFailures.Add(1)
 			conn.Close()
 			return err
 		}
 
+		f.closers = append(f.closers, conn)
 		ctx, completed := context.WithCancel(f.Ctx)
 		go func() {
 			select {
@@ -117,7 +217,7 @@ func (f *Forward) Activate() error {
 		}()
 
 		f.logger.Debug("initializing connection stream", "type", "udp", "fwd", f)
-		go f.stream(conn, target, completed, "udp", "incoming")
+		go f.stream(conn, target, completed, "udp", "incoming", &f.bytesIn)
 
 		f.logger.Debug("activated port forward", "type", "udp", "fwd", f)
 	}
@@ -125,15 +225,129 @@ func (f *Forward) Activate() error {
 	return nil
 }
 
-func (f *Forward) stream(incoming io.ReadCloser, outgoing io.WriteCloser, complete context.CancelFunc, kind, direction string) {
+// Accept connections on the given listener and stream
+// them to the guest
+func (f *Forward) serve(l net.Listener, kind string) {
+	f.closers = append(f.closers, l)
+	go func() {
+		<-f.Ctx.Done()
+		l.Close()
+	}()
+
+	f.logger.Debug("activated port forward", "type", kind, "fwd", f)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				f.logger.Error("failed to accept incoming connection", "type", kind, "fwd", f, "error", err)
+				f.cancel()
+				return
+			}
+
+			// Guest side of the forward is always TCP
+			target, err := net.Dial("tcp", f.Fwd.Guest.String())
+			if err != nil {
+				f.logger.Warn("failed to connect to guest", "type", kind, "guest", f.Fwd.Guest)
+				f.dialFailures.Add(1)
+				conn.Close()
+				continue
+			}
+
+			if f.Fwd.ProxyProtocol != "" {
+				if err := f.sendProxyHeader(conn, target); err != nil {
+					f.logger.Warn("failed to send PROXY protocol header", "type", kind, "guest", f.Fwd.Guest,
+						"error", err)
+					f.dialFailures.Add(1)
+					conn.Close()
+					target.Close()
+					continue
+				}
+			}
+
+			ctx, completed := context.WithCancel(f.Ctx)
+			c := f.track(conn.RemoteAddr(), completed)
+			f.logger.Debug("initializing new connection stream", "type", kind, "fwd", f, "source", conn.RemoteAddr(),
+				"connection", c.Id)
+			go f.stream(conn, target, completed, kind, "outgoing", &f.bytesIn, &c.bytesIn)
+			go f.stream(target, conn, completed, kind, "incoming", &f.bytesOut, &c.bytesOut)
+
+			go func() {
+				select {
+				case <-ctx.Done():
+				case <-f.Ctx.Done():
+				}
+				conn.Close()
+				target.Close()
+				f.untrack(c)
+			}()
+		}
+	}()
+}
+
+func (f *Forward) stream(incoming io.ReadCloser, outgoing io.WriteCloser, complete context.CancelFunc, kind, direction string, counters ...*atomic.Int64) {
 	defer incoming.Close()
 	defer outgoing.Close()
 
-	n, err := io.Copy(outgoing, incoming)
+	n, err := io.Copy(&countingWriter{w: outgoing, counters: counters}, incoming)
 	f.logger.Debug("connection stream complete", "direction", direction, "type", kind, "fwd", f, "bytes", n, "error", err)
 	complete()
 }
 
+// Write the PROXY protocol header to the guest connection so
+// the guest can see the original client address
+func (f *Forward) sendProxyHeader(conn, target net.Conn) error {
+	header, err := ProxyProtocolHeader(f.Fwd.ProxyProtocol, conn.RemoteAddr(), conn.LocalAddr())
+	if err != nil {
+		return err
+	}
+	f.logger.Trace("sending PROXY protocol header", "fwd", f, "version", f.Fwd.ProxyProtocol,
+		"source", conn.RemoteAddr())
+	_, err = target.Write(header)
+	return err
+}
+
+// Writer wrapper that updates the given counters with
+// the number of bytes written
+type countingWriter struct {
+	w        io.Writer
+	counters []*atomic.Int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	for _, ctr := range c.counters {
+		ctr.Add(int64(n))
+	}
+	return n, err
+}
+
+// Validate the forward configuration. Forwards must be validated
+// before they are persisted so an invalid forward is not loaded
+// again on every start.
+func ValidateForward(fwd *settings.Forward) error {
+	if err := ValidateProxyProtocol(fwd.ProxyProtocol, fwd.Host.Type); err != nil {
+		return err
+	}
+	if fwd.Host.IsPath() {
+		return validatePath(fwd.Host, fwd.Permissions)
+	}
+	return nil
+}
+
+// Unix sockets must be created directly within the socket directory
+// so the service never creates or modifies files in other locations
+func validateSocketPath(path string) error {
+	if path == "" {
+		return errors.New("unix port forward requires a host path")
+	}
+	dir := filepath.Clean(utility.SocketDirectory())
+	if !filepath.IsAbs(path) || filepath.Clean(path) != path || filepath.Dir(path) != dir {
+		return fmt.Errorf("unix socket path '%s' must be located within the socket directory %s", path, dir)
+	}
+	return nil
+}
+
 type PortForwarding struct {
 	forwards []*Forward
 
@@ -188,6 +402,12 @@ func (p *PortForwarding) Start() error {
 			p.logger.Trace("port forward marked as active", "fwd", f)
 			continue
 		}
+		// Invalid persisted forwards are skipped so they do not
+		// prevent the remaining forwards from being started
+		if err := ValidateForward(f.Fwd); err != nil {
+			p.logger.Error("skipping invalid port forward", "fwd", f, "error", err)
+			continue
+		}
 		if err := f.Activate(); err != nil {
 			return err
 		}
@@ -216,6 +436,17 @@ func (p *PortForwarding) Add(fwd *settings.Forward) error {
 
 	p.logger.Debug("adding new port forward", "fwd", fwd)
 
+	if err := ValidateForward(fwd); err != nil {
+		p.logger.Error("invalid port forward", "fwd", fwd, "error", err)
+		return err
+	}
+	persisted := false
+	for _, f := range p.s.Forwards {
+		if f.Equal(fwd) {
+			persisted = true
+		}
+	}
+
 	err := p.s.Add(fwd)
 	if err != nil {
 		p.logger.Error("failed to add port forward", "fwd", fwd, "error", err)
@@ -236,6 +467,14 @@ func (p *PortForwarding) Add(fwd *settings.Forward) error {
 	err = f.Activate()
 	if err != nil {
 		p.logger.Error("failed to activate new port forward", "fwd", fwd, "error", err)
+		f.Deactivate()
+		// Only remove the forward from the settings if it was added
+		// here so a previously persisted forward is retained
+		if !persisted {
+			if dErr := p.s.Delete(fwd); dErr != nil {
+				p.logger.Error("failed to remove port forward from settings", "fwd", fwd, "error", dErr)
+			}
+		}
 		return err
 	}
 
@@ -268,6 +507,37 @@ func (p *PortForwarding) Remove(fwd *settings.Forward) error {
 	return nil
 }
 
+// Settings the port forwards are persisted within
+func (p *PortForwarding) Settings() *settings.PortForwarding {
+	return p.s
+}
+
 func (p *PortForwarding) Fwds() []*Forward {
 	return p.forwards
 }
+
+// Find the forward bound to the given host port
+func (p *PortForwarding) Lookup(protocol string, port int) (*Forward, error) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	for _, f := range p.forwards {
+		if f.Fwd.Host.Port == port && strings.Contains(f.Fwd.Host.Type, protocol) {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("port forward %s/%d not found", protocol, port)
+}
+
+// Find the forward bound to the given host path
+func (p *PortForwarding) LookupPath(protocol, path string) (*Forward, error) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	for _, f := range p.forwards {
+		if f.Fwd.Host.IsPath() && f.Fwd.Host.Type == protocol && f.Fwd.Host.Host == path {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("port forward %s %s not found", protocol, path)
+}
@@ -0,0 +1,222 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package service
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/settings"
+)
+
+func TestForwardConnectionTracking(t *testing.T) {
+	guest, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to create guest listener: %s", err)
+	}
+	defer guest.Close()
+	go func() {
+		for {
+			c, err := guest.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(c, c)
+		}
+	}()
+
+	f := testForward(t, guest.Addr().(*net.TCPAddr).Port)
+	defer f.Deactivate()
+
+	conn, err := net.Dial("tcp", f.Fwd.Host.String())
+	if err != nil {
+		t.Fatalf("Failed to connect to forward: %s", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("Failed to write to forward: %s", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("Failed to read from forward: %s", err)
+	}
+
+	conns := f.Connections()
+	if len(conns) != 1 {
+		t.Fatalf("Expected 1 active connection but found %d", len(conns))
+	}
+	stats := f.Stats()
+	if stats.ActiveConnections != 1 || stats.TotalConnections != 1 {
+		t.Errorf("Invalid connection counts - active: %d total: %d", stats.ActiveConnections, stats.TotalConnections)
+	}
+	// Byte counts are updated after the copied data is written
+	waitFor(t, func() bool {
+		stats := f.Stats()
+		return stats.BytesIn == 4 && stats.BytesOut == 4
+	})
+
+	if err := f.CloseConnection(conns[0].Id); err != nil {
+		t.Fatalf("Failed to close connection: %s", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(buf); err == nil {
+		t.Errorf("Expected connection to be closed")
+	}
+	waitFor(t, func() bool { return f.Stats().ActiveConnections == 0 })
+}
+
+func TestForwardDialFailure(t *testing.T) {
+	// Grab a free port and release it so the guest dial fails
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to locate free port: %s", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	f := testForward(t, port)
+	defer f.Deactivate()
+
+	conn, err := net.Dial("tcp", f.Fwd.Host.String())
+	if err != nil {
+		t.Fatalf("Failed to connect to forward: %s", err)
+	}
+	defer conn.Close()
+	waitFor(t, func() bool { return f.Stats().DialFailures == 1 })
+	if f.Stats().ActiveConnections != 0 {
+		t.Errorf("Expected no active connections after dial failure")
+	}
+}
+
+func TestPortForwardingAddInvalid(t *testing.T) {
+	p := testPortForwarding(t)
+	fwd := &settings.Forward{
+		Host:          &settings.Address{Host: "127.0.0.1", Port: 2222, Type: "udp"},
+		Guest:         &settings.Address{Host: "127.0.0.1", Port: 22, Type: "tcp"},
+		ProxyProtocol: PROXY_PROTOCOL_V1,
+	}
+	if err := p.Add(fwd); err == nil {
+		t.Fatalf("Expected invalid port forward to be rejected")
+	}
+	if len(p.s.Forwards) != 0 || len(p.Fwds()) != 0 {
+		t.Errorf("Invalid port forward was persisted")
+	}
+}
+
+func TestPortForwardingAddActivationFailure(t *testing.T) {
+	p := testPortForwarding(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %s", err)
+	}
+	defer l.Close()
+	fwd := &settings.Forward{
+		Host:  &settings.Address{Host: "127.0.0.1", Port: l.Addr().(*net.TCPAddr).Port, Type: "tcp"},
+		Guest: &settings.Address{Host: "127.0.0.1", Port: 22, Type: "tcp"},
+	}
+	if err := p.Add(fwd); err == nil {
+		t.Fatalf("Expected port forward activation to fail")
+	}
+	if err := p.s.Reload(); err != nil {
+		t.Fatalf("Failed to reload settings: %s", err)
+	}
+	if len(p.s.Forwards) != 0 || len(p.Fwds()) != 0 {
+		t.Errorf("Port forward which failed activation was persisted")
+	}
+}
+
+func TestPortForwardingLookupPath(t *testing.T) {
+	p := testPortForwarding(t)
+	sock := &Forward{Fwd: &settings.Forward{
+		Host:  &settings.Address{Host: "/tmp/vagrant.sock", Type: "unix"},
+		Guest: &settings.Address{Host: "127.0.0.1", Port: 22, Type: "tcp"},
+	}}
+	port := &Forward{Fwd: &settings.Forward{
+		Host:  &settings.Address{Host: "0.0.0.0", Port: 2222, Type: "tcp"},
+		Guest: &settings.Address{Host: "127.0.0.1", Port: 22, Type: "tcp"},
+	}}
+	p.forwards = append(p.forwards, port, sock)
+
+	f, err := p.LookupPath("unix", "/tmp/vagrant.sock")
+	if err != nil {
+		t.Fatalf("Failed to lookup path forward: %s", err)
+	}
+	if f != sock {
+		t.Errorf("Invalid forward located for path")
+	}
+	if _, err := p.LookupPath("npipe", "/tmp/vagrant.sock"); err == nil {
+		t.Errorf("Expected lookup with mismatched type to fail")
+	}
+	if _, err := p.LookupPath("unix", "/tmp/other.sock"); err == nil {
+		t.Errorf("Expected lookup of unknown path to fail")
+	}
+	if f, err := p.Lookup("tcp", 2222); err != nil || f != port {
+		t.Errorf("Failed to lookup port forward: %v", err)
+	}
+}
+
+func testPortForwarding(t *testing.T) *PortForwarding {
+	s, err := settings.LoadPortForwardingSettings(filepath.Join(t.TempDir(), "portforwarding.json"), testLogger())
+	if err != nil {
+		t.Fatalf("Failed to load port forwarding settings: %s", err)
+	}
+	p, err := NewPortForwarding(&settings.Settings{PortForwarding: s}, testLogger())
+	if err != nil {
+		t.Fatalf("Failed to create port forwarding service: %s", err)
+	}
+	return p
+}
+
+func testForward(t *testing.T, guestPort int) *Forward {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to locate free port: %s", err)
+	}
+	hostPort := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &Forward{
+		Ctx: ctx,
+		Fwd: &settings.Forward{
+			Host:  &settings.Address{Host: "127.0.0.1", Port: hostPort, Type: "tcp"},
+			Guest: &settings.Address{Host: "127.0.0.1", Port: guestPort, Type: "tcp"},
+		},
+		cancel: cancel,
+		logger: testLogger(),
+	}
+	if err := f.Activate(); err != nil {
+		t.Fatalf("Failed to activate forward: %s", err)
+	}
+	return f
+}
+
+func waitFor(t *testing.T, check func() bool) {
+	for i := 0; i < 50; i++ {
+		if check() {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("Timeout waiting for expected state")
+}
+
+func testLogger() hclog.Logger {
+	level := hclog.Error
+	if os.Getenv("DEBUG") != "" {
+		level = hclog.Trace
+	}
+	return hclog.New(
+		&hclog.LoggerOptions{
+			Output: hclog.DefaultOutput,
+			Level:  level,
+			Name:   "vagrant-vmware-pfwd-test"})
+}
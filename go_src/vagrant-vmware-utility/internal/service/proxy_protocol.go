@@ -0,0 +1,87 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Supported PROXY protocol versions
+const PROXY_PROTOCOL_V1 = "v1"
+const PROXY_PROTOCOL_V2 = "v2"
+
+var proxyV2Signature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+
+// Validate the PROXY protocol version for a forward of the given type
+func ValidateProxyProtocol(version, kind string) error {
+	switch version {
+	case "":
+		return nil
+	case PROXY_PROTOCOL_V1, PROXY_PROTOCOL_V2:
+		if kind != "tcp" {
+			return fmt.Errorf("PROXY protocol is only supported for tcp port forwards (type: %s)", kind)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown PROXY protocol version '%s' (expected %s or %s)",
+			version, PROXY_PROTOCOL_V1, PROXY_PROTOCOL_V2)
+	}
+}
+
+// Build a PROXY protocol header describing a connection from
+// source to destination
+func ProxyProtocolHeader(version string, source, destination net.Addr) ([]byte, error) {
+	src, ok := source.(*net.TCPAddr)
+	if !ok {
+		return nil, errors.New("PROXY protocol source must be a TCP address")
+	}
+	dst, ok := destination.(*net.TCPAddr)
+	if !ok {
+		return nil, errors.New("PROXY protocol destination must be a TCP address")
+	}
+
+	srcIP, dstIP := src.IP.To4(), dst.IP.To4()
+	v4 := srcIP != nil && dstIP != nil
+	if !v4 {
+		srcIP, dstIP = src.IP.To16(), dst.IP.To16()
+		if srcIP == nil || dstIP == nil {
+			return nil, errors.New("invalid address for PROXY protocol header")
+		}
+	}
+
+	switch version {
+	case PROXY_PROTOCOL_V1:
+		family := "TCP6"
+		if v4 {
+			family = "TCP4"
+		}
+		return []byte(fmt.Sprintf("PROXY %s %s %s %d %d\r\n",
+			family, srcIP, dstIP, src.Port, dst.Port)), nil
+	case PROXY_PROTOCOL_V2:
+		var b bytes.Buffer
+		b.Write(proxyV2Signature)
+		// Version 2, PROXY command
+		b.WriteByte(0x21)
+		if v4 {
+			// AF_INET, STREAM
+			b.WriteByte(0x11)
+			binary.Write(&b, binary.BigEndian, uint16(12))
+		} else {
+			// AF_INET6, STREAM
+			b.WriteByte(0x21)
+			binary.Write(&b, binary.BigEndian, uint16(36))
+		}
+		b.Write(srcIP)
+		b.Write(dstIP)
+		binary.Write(&b, binary.BigEndian, uint16(src.Port))
+		binary.Write(&b, binary.BigEndian, uint16(dst.Port))
+		return b.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unknown PROXY protocol version '%s'", version)
+	}
+}
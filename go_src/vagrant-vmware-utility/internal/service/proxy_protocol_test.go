@@ -0,0 +1,65 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package service
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestProxyProtocolHeaderV1(t *testing.T) {
+	src := &net.TCPAddr{IP: net.ParseIP("192.168.1.10"), Port: 51234}
+	dst := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 2222}
+	header, err := ProxyProtocolHeader(PROXY_PROTOCOL_V1, src, dst)
+	if err != nil {
+		t.Fatalf("Unexpected error building header - %s", err)
+	}
+	expected := "PROXY TCP4 192.168.1.10 127.0.0.1 51234 2222\r\n"
+	if string(header) != expected {
+		t.Errorf("Invalid v1 header '%s' != '%s'", header, expected)
+	}
+
+	src = &net.TCPAddr{IP: net.ParseIP("fe80::1"), Port: 51234}
+	dst = &net.TCPAddr{IP: net.ParseIP("::1"), Port: 2222}
+	header, err = ProxyProtocolHeader(PROXY_PROTOCOL_V1, src, dst)
+	if err != nil {
+		t.Fatalf("Unexpected error building header - %s", err)
+	}
+	expected = "PROXY TCP6 fe80::1 ::1 51234 2222\r\n"
+	if string(header) != expected {
+		t.Errorf("Invalid v1 header '%s' != '%s'", header, expected)
+	}
+}
+
+func TestProxyProtocolHeaderV2(t *testing.T) {
+	src := &net.TCPAddr{IP: net.ParseIP("192.168.1.10"), Port: 51234}
+	dst := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 2222}
+	header, err := ProxyProtocolHeader(PROXY_PROTOCOL_V2, src, dst)
+	if err != nil {
+		t.Fatalf("Unexpected error building header - %s", err)
+	}
+	expected := append([]byte{}, proxyV2Signature...)
+	expected = append(expected, 0x21, 0x11, 0x00, 0x0C,
+		192, 168, 1, 10, 127, 0, 0, 1,
+		0xC8, 0x22, 0x08, 0xAE)
+	if !bytes.Equal(header, expected) {
+		t.Errorf("Invalid v2 header %v != %v", header, expected)
+	}
+}
+
+func TestValidateProxyProtocol(t *testing.T) {
+	if err := ValidateProxyProtocol("", "udp"); err != nil {
+		t.Errorf("Unexpected error for disabled PROXY protocol - %s", err)
+	}
+	if err := ValidateProxyProtocol(PROXY_PROTOCOL_V2, "tcp"); err != nil {
+		t.Errorf("Unexpected error for tcp PROXY protocol - %s", err)
+	}
+	if err := ValidateProxyProtocol(PROXY_PROTOCOL_V1, "udp"); err == nil {
+		t.Errorf("Expected error for udp PROXY protocol")
+	}
+	if err := ValidateProxyProtocol("v3", "tcp"); err == nil {
+		t.Errorf("Expected error for unknown PROXY protocol version")
+	}
+}
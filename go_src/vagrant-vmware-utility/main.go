@@ -21,11 +21,18 @@ var Commands map[string]cli.CommandFactory
 // Pretend to do stuff
 func main() {
 	defer cleanPanic()
-	s := make(chan os.Signal)
-	signal.Notify(s, os.Interrupt, syscall.SIGTERM)
+	s := make(chan os.Signal, 1)
+	signal.Notify(s, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 	go func() {
-		<-s
-		util.RunShutdownTasks()
+		for sig := range s {
+			// A hangup reloads the configuration when reloading is
+			// supported and is otherwise handled as a termination
+			if sig == syscall.SIGHUP && util.RunReloadTasks() {
+				continue
+			}
+			util.RunShutdownTasks()
+			return
+		}
 	}()
 
 	os.Exit(realMain())
@@ -4,15 +4,16 @@
 package server
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net"
 	"net/http"
-	"regexp"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/driver"
 	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
@@ -20,166 +21,355 @@ import (
 	hclog "github.com/hashicorp/go-hclog"
 )
 
+// Default time allowed for inflight requests to complete
+// when the API is stopped
+const DEFAULT_DRAIN_TIMEOUT = 30 * time.Second
+
 type Api struct {
-	listener   net.Listener
-	router     *RegexpHandler
-	inflight   int
-	stopChan   chan bool
-	reqTracker sync.WaitGroup
+	listeners  []net.Listener
+	server     *http.Server
+	router     *Router
+	handler    *ApiHandler
+	certs      *certificateStore
+	inflight   atomic.Int64
+	ready      atomic.Bool
+	halted     atomic.Bool
 	actionSync sync.Mutex
-	Halted     bool
+	configLock sync.RWMutex
 	Address    string
 	Port       int
+	// Local socket listener configuration. The local socket
+	// is not enabled when unset.
+	Socket *SocketConfig
+	// Disable the TCP listener
+	DisableTcp bool
+	// Time allowed for inflight requests to complete when stopping
+	DrainTimeout time.Duration
+	// Minimum TLS version accepted by the TCP listener
+	TlsMinVersion uint16
+	// Reloads the service configuration. Reloading is
+	// not supported when unset.
+	Reloader func() (*ReloadResult, error)
+	// Closed once the API has been halted
 	HaltedChan chan bool
+	PortRange  *driver.PortRange
 	logger     hclog.Logger
 	Driver     driver.Driver
 }
 
-func Create(bindAddr string, bindPort int, driver driver.Driver, logger hclog.Logger) (*Api, error) {
+func Create(bindAddr string, bindPort int, drv driver.Driver, logger hclog.Logger) (*Api, error) {
 	logger = logger.Named("api")
 	srv := &Api{
-		Address:    bindAddr,
-		Driver:     driver,
-		Port:       bindPort,
-		Halted:     true,
-		HaltedChan: make(chan bool),
-		stopChan:   make(chan bool),
-		inflight:   0,
-		logger:     logger,
-	}
-
-	router := NewRegexpHandler(srv, logger)
-	srv.router = router
+		Address:       bindAddr,
+		Driver:        drv,
+		Port:          bindPort,
+		DrainTimeout:  DEFAULT_DRAIN_TIMEOUT,
+		TlsMinVersion: tls.VersionTLS12,
+		HaltedChan:    make(chan bool),
+		logger:        logger,
+		PortRange: &driver.PortRange{
+			Min: driver.DEFAULT_PORT_RANGE_MIN,
+			Max: driver.DEFAULT_PORT_RANGE_MAX,
+		},
+	}
+	srv.halted.Store(true)
+
+	srv.handler = NewApiHandler(srv, logger)
 	return srv, nil
 }
 
-func (a *Api) defineRoutes(r *RegexpHandler) error {
+// Result of reloading the service configuration
+type ReloadResult struct {
+	// Settings which were modified and applied
+	Applied []string `json:"applied"`
+	// Settings which were modified but are not applied
+	// until the service is restarted
+	RestartRequired []string `json:"restart_required"`
+}
+
+type apiRoute struct {
+	method     string
+	path       string
+	handler    http.HandlerFunc
+	permission utility.Permission
+	middleware []Middleware
+}
+
+func (a *Api) defineRoutes(h *ApiHandler) (*Router, error) {
 	a.logger.Trace("registering routes")
-	routes := map[string]func(http.ResponseWriter, *http.Request){
+	r := NewRouter(API_V2_PREFIX, a.logger)
+	r.NotFound = http.HandlerFunc(h.handleNotFound)
+	r.MethodNotAllowed = http.HandlerFunc(h.handleMethodNotAllowed)
+	r.Use(h.requestId, h.identifyClient, h.logRequests, h.contentType, h.requireRequester)
+
+	valid := h.requireValidDriver
+	locked := h.lockNetwork
+	read := utility.PERMISSION_READ
+	portFwd := utility.PERMISSION_PORT_FORWARD
+	admin := utility.PERMISSION_ADMIN
+	routes := []apiRoute{
 		// VMware Host Adapter Management
-		`/vmnet/vmnet(?P<vnet_slot>\d+)/portforward`:                           r.handleVmnetDeviceForward,
-		`/vmnet/vmnet(?P<vnet_slot>\d+)/dhcpreserve/(?P<mac>[^/]+)/(?P<ip>.+)`: r.handleVmnetDhcpReserve,
-		`/vmnet/(?P<vnet_name>vmnet\d+)/dhcplease/(?P<mac>.+)`:                 r.handleVmnetDhcpLease,
-		`/vmnet/(?P<vnet_name>vmnet\d+)`:                                       r.handleVmnetDevice,
-		`/vmnet/verify`:                                                        r.handleVmnetVerify,
-		`/vmnet`:                                                               r.handleVmnet,
-		// VMware Guest Network Adapter Management
-		`/vms/(?P<vm_id>[^/]+)/nic/(?P<adapter_id>.+)`: r.handleVmNicAdapter,
-		`/vms/(?P<vm_id>[^/]+)/nic`:                    r.handleVmNic,
-		`/vms/(?P<vm_id>[^/]+)/ip`:                     r.handleVmIp,
+		{"GET", `/vmnet/vmnet(?P<vnet_slot>\d+)/portforward`, h.listDevicePortFwds, read, []Middleware{valid}},
+		{"PUT", `/vmnet/vmnet(?P<vnet_slot>\d+)/portforward`, h.applyPortFwd, portFwd, []Middleware{valid, locked}},
+		{"DELETE", `/vmnet/vmnet(?P<vnet_slot>\d+)/portforward`, h.deletePortFwd, portFwd, []Middleware{valid, locked}},
+		{"PUT", `/vmnet/vmnet(?P<vnet_slot>\d+)/dhcpreserve/(?P<mac>[^/]+)/(?P<ip>.+)`, h.reserveVmnetDhcpAddress, admin, []Middleware{valid, locked}},
+		{"GET", `/vmnet/(?P<vnet_name>vmnet\d+)/dhcplease/(?P<mac>.+)`, h.getVmnetDhcpLease, read, []Middleware{valid}},
+		{"POST", `/vmnet/verify`, h.verifyVmnet, admin, []Middleware{valid, locked}},
+		{"GET", `/vmnet/(?P<vnet_name>vmnet\d+)`, h.getVmnetDevice, read, []Middleware{valid}},
+		{"PUT", `/vmnet/(?P<vnet_name>vmnet\d+)`, h.updateVmnetDevice, admin, []Middleware{valid, locked}},
+		{"DELETE", `/vmnet/(?P<vnet_name>vmnet\d+)`, h.deleteVmnetDevice, admin, []Middleware{valid, locked}},
+		{"GET", `/vmnet`, h.listVmnetDevices, read, []Middleware{valid}},
+		{"POST", `/vmnet`, h.createVmnetDevice, admin, []Middleware{valid, locked}},
 		// Custom Rest API Paths
-		`/portforwards`: r.handlePortForwards,
-		`/vmware/paths`: r.handleVmwarePaths,
-		`/vmware/info`:  r.handleVmwareInfo,
-		`/status`:       r.handleStatus,
-		`/version`:      r.handleVersion,
-		`/`:             r.handleRoot,
+		{"DELETE", `/portforwards/(?P<protocol>tcp|udp)/(?P<port>\d+)/connections/(?P<connection_id>\d+)`, h.closePortFwdConnection, portFwd, []Middleware{valid}},
+		{"GET", `/portforwards/(?P<protocol>tcp|udp)/(?P<port>\d+)/connections`, h.listPortFwdConnections, read, []Middleware{valid}},
+		{"DELETE", `/portforwards/(?P<protocol>unix|npipe)/connections/(?P<connection_id>\d+)`, h.closePortFwdConnection, portFwd, []Middleware{valid}},
+		{"GET", `/portforwards/(?P<protocol>unix|npipe)/connections`, h.listPortFwdConnections, read, []Middleware{valid}},
+		{"GET", `/portforwards/suggest`, h.suggestPortFwds, read, []Middleware{valid}},
+		{"GET", `/portforwards`, h.listAllPortFwds, read, []Middleware{valid}},
+		{"DELETE", `/portforwards`, h.prunePortFwds, portFwd, []Middleware{valid, locked}},
+		{"GET", `/vmware/paths`, h.getVmwarePaths, read, []Middleware{valid}},
+		{"GET", `/vmware/info`, h.getVmwareInfo, read, []Middleware{valid}},
+		{"GET", `/capabilities`, h.handleCapabilities, read, []Middleware{valid}},
+		{"GET", `/status`, h.handleStatus, read, []Middleware{valid}},
+		{"POST", `/admin/reload`, h.handleReload, admin, nil},
+		{"GET", `/version`, h.handleVersion, read, []Middleware{valid}},
+		{"GET", `/`, h.handleRoot, read, []Middleware{valid}},
+	}
+	// VMware Guest Network Adapter Management (not implemented)
+	for _, method := range []string{"GET", "POST", "PUT", "DELETE"} {
+		permission := admin
+		if method == "GET" {
+			permission = read
+		}
+		routes = append(routes, []apiRoute{
+			{method, `/vms/(?P<vm_id>[^/]+)/nic/(?P<adapter_id>.+)`, h.handleVmNicAdapter, permission, []Middleware{valid}},
+			{method, `/vms/(?P<vm_id>[^/]+)/nic`, h.handleVmNic, permission, []Middleware{valid}},
+			{method, `/vms/(?P<vm_id>[^/]+)/ip`, h.handleVmIp, permission, []Middleware{valid}},
+		}...)
 	}
 
-	for path, handler := range routes {
-		pattern, err := regexp.Compile(`^` + path + `$`)
-		if err != nil {
-			a.logger.Error("Failed to compile route path %s - %s", path, err)
-			return err
+	for _, rt := range routes {
+		middleware := []Middleware{h.authorize(rt.permission)}
+		if rt.method != "GET" {
+			middleware = append(middleware, h.idempotent)
+		}
+		middleware = append(middleware, rt.middleware...)
+		if err := r.Handle(rt.method, rt.path, rt.handler, middleware...); err != nil {
+			a.logger.Error("failed to register route", "method", rt.method, "path", rt.path, "error", err)
+			return nil, err
 		}
-		a.router.HandleFunc(pattern, handler)
 	}
-	return nil
+	return r, nil
 }
 
 func (a *Api) Start() error {
 	a.logger.Debug("start api service requested")
 	a.actionSync.Lock()
 	defer a.actionSync.Unlock()
-	if err := a.defineRoutes(a.router); err != nil {
-		return err
+	if !a.halted.Load() {
+		return errors.New("Server process is currently running")
 	}
-	a.logger.Info("api service start", "host", a.Address, "port", a.Port)
-	tlsConfig, err := a.loadTlsConfig()
+	router, err := a.defineRoutes(a.handler)
 	if err != nil {
 		return err
 	}
-	listener, err := tls.Listen("tcp", fmt.Sprintf("%s:%d", a.Address, a.Port), tlsConfig)
+	a.router = router
+	listeners, err := a.listen()
 	if err != nil {
 		return err
 	}
-	a.listener = listener
-	a.Halted = false
-	go a.consume()
+	a.listeners = listeners
+	a.server = &http.Server{
+		Handler:           http.HandlerFunc(a.RequestHandler),
+		ReadHeaderTimeout: 30 * time.Second,
+		ErrorLog:          a.logger.StandardLogger(&hclog.StandardLoggerOptions{InferLevels: true}),
+		ConnContext:       localConnContext,
+	}
+	a.HaltedChan = make(chan bool)
+	a.halted.Store(false)
+	for _, listener := range listeners {
+		go a.serve(a.server, listener)
+	}
+	a.ready.Store(true)
 	a.logger.Debug("api ready for message consumption")
 	return nil
 }
 
+// Stop the API. New connections are no longer accepted and inflight
+// requests are allowed to complete until the drain timeout is reached.
+// Any requests remaining after the timeout are canceled. Once requests
+// have been drained the driver is shut down.
 func (a *Api) Stop() error {
 	a.logger.Debug("stop api service requested")
 	a.actionSync.Lock()
 	defer a.actionSync.Unlock()
-	if a.Halted {
+	if a.halted.Load() {
 		return errors.New("Server process is currently halted")
 	}
-	a.logger.Debug("sending stop notification to consumer")
-	a.stopChan <- true
-	return nil
+	defer a.halt()
+
+	a.ready.Store(false)
+	timeout := a.drainTimeout()
+	a.logger.Info("draining inflight requests", "inflight", a.Inflight(), "timeout", timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := a.server.Shutdown(ctx)
+	if err != nil {
+		a.logger.Warn("inflight requests did not complete before drain timeout",
+			"inflight", a.Inflight(), "error", err)
+		if err := a.server.Close(); err != nil {
+			a.logger.Error("failed to close api server", "error", err)
+		}
+	} else {
+		a.logger.Debug("inflight requests drained")
+	}
+
+	a.logger.Debug("shutting down driver")
+	if dErr := a.driver().Shutdown(); dErr != nil {
+		a.logger.Error("driver shutdown failure", "error", dErr)
+		if err == nil {
+			err = dErr
+		}
+	}
+	return err
 }
 
-func (a *Api) consume() {
-	defer func() {
-		a.Halted = true
-		a.logger.Debug("sending halt notification")
-		a.HaltedChan <- true
-	}()
+// Create the configured listeners. Mutual TLS is required
+// for the TCP listener and its certificates are reloaded
+// when rotated.
+func (a *Api) listen() ([]net.Listener, error) {
+	listeners := []net.Listener{}
+	if !a.DisableTcp {
+		a.logger.Info("api service start", "host", a.Address, "port", a.Port)
+		certs := newCertificateStore(a.TlsMinVersion, a.logger)
+		if err := certs.Load(); err != nil {
+			return nil, err
+		}
+		a.certs = certs
+		listener, err := tls.Listen("tcp", fmt.Sprintf("%s:%d", a.Address, a.Port), certs.tlsConfig())
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+	if a.Socket != nil {
+		a.logger.Info("api service start", "socket", a.Socket.Path)
+		listener, err := listenLocal(a.Socket, a.logger)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+	if len(listeners) < 1 {
+		return nil, errors.New("No listeners are enabled")
+	}
+	return listeners, nil
+}
 
-	go http.Serve(a.listener, http.HandlerFunc(a.RequestHandler))
-	select {
-	case <-a.stopChan:
-		a.logger.Debug("stop notification received - closing")
-		a.listener.Close()
-		a.logger.Trace("wait for inflight requests to complete")
-		a.reqTracker.Wait()
-		a.logger.Trace("api consumer halted")
+func (a *Api) serve(srv *http.Server, listener net.Listener) {
+	err := srv.Serve(listener)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		a.logger.Error("api server failure", "error", err)
 	}
+	a.logger.Trace("api server no longer accepting connections")
+}
+
+func (a *Api) halt() {
+	a.halted.Store(true)
+	a.logger.Debug("sending halt notification")
+	close(a.HaltedChan)
 }
 
 func (a *Api) RequestHandler(writ http.ResponseWriter, req *http.Request) {
-	a.reqTracker.Add(1)
-	a.inflight++
-	defer func() {
-		a.inflight--
-		a.reqTracker.Done()
-		a.logger.Debug("completed request", "request-id", fmt.Sprintf("%p", req), "headers", writ.Header())
-	}()
-	a.logger.Debug("starting request", "request-id", fmt.Sprintf("%p", req))
+	a.inflight.Add(1)
+	defer a.inflight.Add(-1)
 	a.router.ServeHTTP(writ, req)
 }
 
+// Number of requests currently being processed
 func (a *Api) Inflight() int {
-	return a.inflight
+	return int(a.inflight.Load())
 }
 
-func (a *Api) loadTlsConfig() (*tls.Config, error) {
-	paths, err := utility.GetCertificatePaths()
-	if err != nil {
-		return nil, err
-	}
-	cert, err := tls.LoadX509KeyPair(paths.Certificate, paths.PrivateKey)
-	if err != nil {
-		return nil, err
+// API is accepting requests
+func (a *Api) Ready() bool {
+	return a.ready.Load()
+}
+
+// API has been halted
+func (a *Api) Halted() bool {
+	return a.halted.Load()
+}
+
+// Server certificate used by the TCP listener. No certificate
+// is returned when the TCP listener is not enabled.
+func (a *Api) Certificate() *x509.Certificate {
+	if a.certs == nil {
+		return nil
 	}
-	pool := x509.NewCertPool()
-	certPem, err := ioutil.ReadFile(paths.Certificate)
+	return a.certs.Certificate()
+}
+
+// Update the time allowed for inflight requests to
+// complete while the API is running
+func (a *Api) SetDrainTimeout(timeout time.Duration) {
+	a.configLock.Lock()
+	defer a.configLock.Unlock()
+	a.DrainTimeout = timeout
+}
+
+func (a *Api) drainTimeout() time.Duration {
+	a.configLock.RLock()
+	defer a.configLock.RUnlock()
+	return a.DrainTimeout
+}
+
+// Update the usable host port range for port forward
+// suggestions while the API is running
+func (a *Api) SetPortRange(r *driver.PortRange) {
+	a.configLock.Lock()
+	defer a.configLock.Unlock()
+	a.PortRange = r
+}
+
+func (a *Api) portRange() *driver.PortRange {
+	a.configLock.RLock()
+	defer a.configLock.RUnlock()
+	return a.PortRange
+}
+
+// Replace the driver used by the API. The network lock is held
+// while the replacement is built so no requests modify the network
+// while the driver is being replaced. The replacement is provided
+// the current driver and the current driver is retained when an
+// error is returned.
+func (a *Api) ReplaceDriver(replace func(current driver.Driver) (driver.Driver, error)) error {
+	a.handler.netLock <- struct{}{}
+	defer func() { <-a.handler.netLock }()
+	drv, err := replace(a.driver())
 	if err != nil {
-		return nil, err
-	}
-	if !pool.AppendCertsFromPEM(certPem) {
-		return nil, errors.New("failed to properly load certificate")
+		return err
 	}
-	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		ClientAuth:   tls.RequireAndVerifyClientCert,
-		ClientCAs:    pool,
-		ServerName:   "127.0.0.1",
-		RootCAs:      pool,
+	a.configLock.Lock()
+	defer a.configLock.Unlock()
+	a.Driver = drv
+	return nil
+}
+
+func (a *Api) driver() driver.Driver {
+	a.configLock.RLock()
+	defer a.configLock.RUnlock()
+	return a.Driver
+}
+
+// Reload the service configuration
+func (a *Api) Reload() (*ReloadResult, error) {
+	if a.Reloader == nil {
+		return nil, errors.New("Configuration reload is not supported")
 	}
-	tlsConfig.BuildNameToCertificate()
-	return tlsConfig, nil
+	return a.Reloader()
 }
@@ -0,0 +1,158 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package server
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/driver"
+)
+
+type shutdownDriver struct {
+	driver.Driver
+	shutdown int
+}
+
+func (s *shutdownDriver) Validated() bool {
+	return true
+}
+
+func (s *shutdownDriver) Shutdown() error {
+	s.shutdown++
+	return nil
+}
+
+func startTestApi(t *testing.T, drv driver.Driver, handler http.HandlerFunc) (*Api, string) {
+	a, err := Create("127.0.0.1", 0, drv, hclog.NewNullLogger())
+	if err != nil {
+		t.Fatalf("Failed to create api: %s", err)
+	}
+	a.router = NewRouter("", hclog.NewNullLogger())
+	if err := a.router.Handle("GET", `/slow`, handler); err != nil {
+		t.Fatalf("Failed to register route: %s", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %s", err)
+	}
+	a.server = &http.Server{Handler: http.HandlerFunc(a.RequestHandler)}
+	a.halted.Store(false)
+	a.ready.Store(true)
+	go a.serve(a.server, listener)
+	return a, "http://" + listener.Addr().String()
+}
+
+func TestApiStopDrainTimeout(t *testing.T) {
+	drv := &shutdownDriver{}
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	a, url := startTestApi(t, drv, func(writ http.ResponseWriter, req *http.Request) {
+		close(started)
+		<-req.Context().Done()
+		close(canceled)
+	})
+	a.DrainTimeout = 50 * time.Millisecond
+	go http.Get(url + "/slow")
+	<-started
+	if a.Inflight() != 1 {
+		t.Errorf("Invalid inflight count %d != 1", a.Inflight())
+	}
+
+	if err := a.Stop(); err == nil {
+		t.Errorf("Expected error when drain timeout is exceeded")
+	}
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Inflight request was not canceled")
+	}
+	select {
+	case <-a.HaltedChan:
+	default:
+		t.Errorf("Expected halt notification")
+	}
+	if a.Ready() || !a.Halted() {
+		t.Errorf("Invalid state after stop - ready: %t halted: %t", a.Ready(), a.Halted())
+	}
+	if drv.shutdown != 1 {
+		t.Errorf("Expected driver to be shutdown once, was %d", drv.shutdown)
+	}
+	if err := a.Stop(); err == nil {
+		t.Errorf("Expected error stopping halted api")
+	}
+}
+
+func TestApiStopDrains(t *testing.T) {
+	drv := &shutdownDriver{}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	a, url := startTestApi(t, drv, func(writ http.ResponseWriter, req *http.Request) {
+		close(started)
+		<-release
+		writ.WriteHeader(200)
+	})
+	result := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(url + "/slow")
+		if err != nil {
+			result <- 0
+			return
+		}
+		resp.Body.Close()
+		result <- resp.StatusCode
+	}()
+	<-started
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	if err := a.Stop(); err != nil {
+		t.Errorf("Unexpected stop error: %s", err)
+	}
+	if code := <-result; code != 200 {
+		t.Errorf("Inflight request was not completed, status %d", code)
+	}
+	if drv.shutdown != 1 {
+		t.Errorf("Expected driver to be shutdown once, was %d", drv.shutdown)
+	}
+}
+
+func TestApiReplaceDriver(t *testing.T) {
+	original := &shutdownDriver{}
+	a, err := Create("127.0.0.1", 0, original, hclog.NewNullLogger())
+	if err != nil {
+		t.Fatalf("Failed to create api: %s", err)
+	}
+	err = a.ReplaceDriver(func(current driver.Driver) (driver.Driver, error) {
+		return nil, errors.New("replacement failure")
+	})
+	if err == nil || a.driver() != original {
+		t.Errorf("Driver replaced after failure")
+	}
+
+	replacement := &shutdownDriver{}
+	err = a.ReplaceDriver(func(current driver.Driver) (driver.Driver, error) {
+		if current != original {
+			t.Errorf("Replacement not provided the current driver")
+		}
+		if len(a.handler.netLock) != 1 {
+			t.Errorf("Network lock not held while replacing driver")
+		}
+		return replacement, nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to replace driver: %s", err)
+	}
+	if a.driver() != replacement {
+		t.Errorf("Driver was not replaced")
+	}
+	if len(a.handler.netLock) != 0 {
+		t.Errorf("Network lock not released after replacing driver")
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+// Minimum time between checks for modified certificate files
+const CERTIFICATE_CHECK_INTERVAL = 5 * time.Second
+
+// Cipher suites allowed for TLS 1.2 connections. TLS 1.3
+// cipher suites are not configurable and are always allowed.
+var TLS_CIPHER_SUITES = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// Parse the minimum TLS version. Only TLS 1.2 and
+// TLS 1.3 are supported.
+func ParseTlsVersion(v string) (uint16, error) {
+	switch v {
+	case "1.2", "":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unsupported TLS version '%s' (expected 1.2 or 1.3)", v)
+}
+
+// Provides the TLS configuration for the API. Certificate files
+// are checked for modifications during connection handshakes and
+// the configuration is reloaded when the certificates have been
+// rotated so the API does not need to be restarted.
+type certificateStore struct {
+	config     *tls.Config
+	leaf       *x509.Certificate
+	modified   time.Time
+	expires    time.Time
+	checked    time.Time
+	minVersion uint16
+	lock       sync.Mutex
+	logger     hclog.Logger
+}
+
+func newCertificateStore(minVersion uint16, logger hclog.Logger) *certificateStore {
+	if minVersion < tls.VersionTLS12 {
+		minVersion = tls.VersionTLS12
+	}
+	return &certificateStore{
+		minVersion: minVersion,
+		logger:     logger.Named("certificates")}
+}
+
+// TLS configuration used by the listener
+func (s *certificateStore) tlsConfig() *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: s.configForClient,
+		MinVersion:         s.minVersion}
+}
+
+// Server certificate currently in use
+func (s *certificateStore) Certificate() *x509.Certificate {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.leaf
+}
+
+// Load the certificates
+func (s *certificateStore) Load() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.load()
+}
+
+func (s *certificateStore) configForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := time.Now()
+	if now.Sub(s.checked) >= CERTIFICATE_CHECK_INTERVAL {
+		s.checked = now
+		expired := !s.expires.IsZero() && now.After(s.expires)
+		if modified, err := s.lastModified(); err == nil && (expired || !modified.Equal(s.modified)) {
+			s.logger.Info("reloading certificates", "overlap_expired", expired)
+			// Files may be observed while a rotation is in progress
+			// so the existing configuration is retained on failure
+			// and the reload is attempted on the next check
+			if err := s.load(); err != nil {
+				s.logger.Error("certificate reload failure", "error", err)
+			}
+		}
+	}
+	if s.config == nil {
+		return nil, errors.New("certificates are not loaded")
+	}
+	return s.config, nil
+}
+
+func (s *certificateStore) load() error {
+	paths, err := utility.GetCertificatePaths()
+	if err != nil {
+		return err
+	}
+	modified, err := s.lastModified()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(paths.Certificate, paths.PrivateKey)
+	if err != nil {
+		return err
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	signingPath, _ := paths.Signing()
+	signing, err := utility.ReadCertificate(signingPath)
+	if err != nil {
+		return err
+	}
+	pool.AddCert(signing)
+	// The certificate authority is included in the chain
+	// presented to clients when it signs the server certificate
+	if !signing.Equal(leaf) {
+		cert.Certificate = append(cert.Certificate, signing.Raw)
+	}
+	// Client certificates signed by the previous signing
+	// certificate are accepted until the overlap ends
+	previous, expires, err := utility.ReadPreviousCertificate()
+	if err != nil {
+		s.logger.Warn("previous certificate load failure", "error", err)
+	}
+	if previous != nil {
+		s.logger.Debug("accepting previous certificate", "until", expires)
+		pool.AddCert(previous)
+	} else {
+		expires = time.Time{}
+	}
+	tlsConfig := &tls.Config{
+		Certificates:     []tls.Certificate{cert},
+		ClientAuth:       tls.RequireAndVerifyClientCert,
+		ClientCAs:        pool,
+		MinVersion:       s.minVersion,
+		CipherSuites:     TLS_CIPHER_SUITES,
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384},
+	}
+	s.config = tlsConfig
+	s.leaf = leaf
+	s.modified = modified
+	s.expires = expires
+	s.checked = time.Now()
+	return nil
+}
+
+// Latest modification time of the certificate files
+func (s *certificateStore) lastModified() (time.Time, error) {
+	paths, err := utility.GetCertificatePaths()
+	if err != nil {
+		return time.Time{}, err
+	}
+	var modified time.Time
+	for _, p := range []string{paths.Certificate, paths.PrivateKey, paths.CaCertificate, paths.PreviousCertificate} {
+		info, err := os.Stat(p)
+		if err != nil {
+			if os.IsNotExist(err) && (p == paths.CaCertificate || p == paths.PreviousCertificate) {
+				continue
+			}
+			return time.Time{}, err
+		}
+		if info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+	}
+	return modified, nil
+}
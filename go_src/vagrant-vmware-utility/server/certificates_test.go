@@ -0,0 +1,202 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+func TestCertificateStoreRotation(t *testing.T) {
+	utility.SetCertificateDirectory(t.TempDir())
+	t.Cleanup(func() { utility.SetCertificateDirectory("") })
+	if err := utility.GenerateCertificate(nil); err != nil {
+		t.Fatalf("Failed to generate certificates: %s", err)
+	}
+	paths, err := utility.GetCertificatePaths()
+	if err != nil {
+		t.Fatalf("Failed to get certificate paths: %s", err)
+	}
+	original, err := utility.ReadCertificate(paths.ClientCertificate)
+	if err != nil {
+		t.Fatalf("Failed to read client certificate: %s", err)
+	}
+
+	s := newCertificateStore(tls.VersionTLS12, hclog.NewNullLogger())
+	if err := s.Load(); err != nil {
+		t.Fatalf("Failed to load certificates: %s", err)
+	}
+	verify := func(cert *x509.Certificate) error {
+		s.checked = time.Time{}
+		config, err := s.configForClient(nil)
+		if err != nil {
+			t.Fatalf("Failed to get configuration: %s", err)
+		}
+		_, err = cert.Verify(x509.VerifyOptions{
+			Roots:     config.ClientCAs,
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+		return err
+	}
+	if err := verify(original); err != nil {
+		t.Errorf("Original client certificate not accepted: %s", err)
+	}
+
+	server := s.Certificate()
+	if err := utility.RotateCertificate(time.Hour, nil); err != nil {
+		t.Fatalf("Failed to rotate certificates: %s", err)
+	}
+	rotated, err := utility.ReadCertificate(paths.ClientCertificate)
+	if err != nil {
+		t.Fatalf("Failed to read client certificate: %s", err)
+	}
+	if err := verify(rotated); err != nil {
+		t.Errorf("Rotated client certificate not accepted: %s", err)
+	}
+	if err := verify(original); err != nil {
+		t.Errorf("Original client certificate not accepted during overlap: %s", err)
+	}
+	if s.Certificate().Equal(server) {
+		t.Errorf("Server certificate was not reloaded")
+	}
+
+	if err := utility.RotateCertificate(0, nil); err != nil {
+		t.Fatalf("Failed to rotate certificates: %s", err)
+	}
+	if err := verify(rotated); err == nil {
+		t.Errorf("Replaced client certificate accepted without overlap")
+	}
+}
+
+func TestCertificateStoreSplitCa(t *testing.T) {
+	utility.SetCertificateDirectory(t.TempDir())
+	t.Cleanup(func() { utility.SetCertificateDirectory("") })
+	for _, keyType := range []utility.KeyType{utility.KEY_TYPE_ECDSA, utility.KEY_TYPE_ED25519} {
+		opts := &utility.CertificateOptions{KeyType: keyType, SplitCa: true}
+		if err := utility.GenerateCertificate(opts); err != nil {
+			t.Fatalf("Failed to generate %s certificates: %s", keyType, err)
+		}
+		paths, err := utility.GetCertificatePaths()
+		if err != nil {
+			t.Fatalf("Failed to get certificate paths: %s", err)
+		}
+		server, err := utility.ReadCertificate(paths.Certificate)
+		if err != nil {
+			t.Fatalf("Failed to read server certificate: %s", err)
+		}
+		if server.IsCA {
+			t.Errorf("Server certificate is a CA with split CA (%s)", keyType)
+		}
+		if utility.CertificateKeyType(server) != keyType {
+			t.Errorf("Invalid server key type %s != %s", utility.CertificateKeyType(server), keyType)
+		}
+		if current := utility.CurrentCertificateOptions(); *current != *opts {
+			t.Errorf("Invalid current certificate options %#v != %#v", current, opts)
+		}
+
+		s := newCertificateStore(tls.VersionTLS13, hclog.NewNullLogger())
+		if err := s.Load(); err != nil {
+			t.Fatalf("Failed to load certificates: %s", err)
+		}
+		listener, err := tls.Listen("tcp", "127.0.0.1:0", s.tlsConfig())
+		if err != nil {
+			t.Fatalf("Failed to listen: %s", err)
+		}
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				conn.(*tls.Conn).Handshake()
+				conn.Close()
+			}
+		}()
+
+		clientCert, err := tls.LoadX509KeyPair(paths.ClientCertificate, paths.ClientKey)
+		if err != nil {
+			t.Fatalf("Failed to load client certificate: %s", err)
+		}
+		ca, err := utility.ReadCertificate(paths.CaCertificate)
+		if err != nil {
+			t.Fatalf("Failed to read certificate authority: %s", err)
+		}
+		pool := x509.NewCertPool()
+		pool.AddCert(ca)
+		dial := func(maxVersion uint16) error {
+			conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{
+				Certificates: []tls.Certificate{clientCert},
+				RootCAs:      pool,
+				ServerName:   "127.0.0.1",
+				MaxVersion:   maxVersion})
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			return conn.Handshake()
+		}
+		if err := dial(tls.VersionTLS13); err != nil {
+			t.Errorf("Failed to connect with %s certificates: %s", keyType, err)
+		}
+		if err := dial(tls.VersionTLS12); err == nil {
+			t.Errorf("Connected with TLS 1.2 when TLS 1.3 is required")
+		}
+		listener.Close()
+	}
+	if err := utility.GenerateCertificate(nil); err != nil {
+		t.Fatalf("Failed to generate certificates: %s", err)
+	}
+	paths, _ := utility.GetCertificatePaths()
+	if utility.FileExists(paths.CaCertificate) {
+		t.Errorf("Certificate authority retained without split CA")
+	}
+}
+
+func TestParseTlsVersion(t *testing.T) {
+	for v, expected := range map[string]uint16{"": tls.VersionTLS12, "1.2": tls.VersionTLS12, "1.3": tls.VersionTLS13} {
+		if version, err := ParseTlsVersion(v); err != nil || version != expected {
+			t.Errorf("Invalid TLS version for '%s': %d (%v)", v, version, err)
+		}
+	}
+	for _, v := range []string{"1.0", "1.1", "tls13"} {
+		if _, err := ParseTlsVersion(v); err == nil {
+			t.Errorf("Expected error for TLS version '%s'", v)
+		}
+	}
+}
+
+func TestStatusCertificateWarning(t *testing.T) {
+	a := &Api{certs: &certificateStore{leaf: &x509.Certificate{
+		NotAfter: time.Now().Add(24 * time.Hour * 30)}}}
+	h := NewApiHandler(a, hclog.NewNullLogger())
+	rec := httptest.NewRecorder()
+	h.handleStatus(rec, httptest.NewRequest("GET", "/status", nil))
+	status := map[string]string{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to decode status: %s", err)
+	}
+	if status["certificate_expires"] == "" {
+		t.Errorf("Certificate expiry not included in status")
+	}
+	if status["warning"] == "" {
+		t.Errorf("Expiry warning not included in status")
+	}
+
+	a.certs.leaf.NotAfter = time.Now().Add(utility.CERTIFICATE_RENEWAL_WINDOW * 2)
+	rec = httptest.NewRecorder()
+	h.handleStatus(rec, httptest.NewRequest("GET", "/status", nil))
+	status = map[string]string{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to decode status: %s", err)
+	}
+	if _, ok := status["warning"]; ok {
+		t.Errorf("Unexpected expiry warning: %s", status["warning"])
+	}
+}
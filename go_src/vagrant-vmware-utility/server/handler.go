@@ -0,0 +1,468 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/driver"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/settings"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/version"
+)
+
+const API_CONTENT_TYPE = "application/vnd.hashicorp.vagrant.vmware.rest-v1+json"
+const REQUEST_ID_HEADER = "X-Request-Id"
+const MAX_REQUEST_ID_LENGTH = 128
+
+type ApiHandler struct {
+	logger      hclog.Logger
+	audit       hclog.Logger
+	api         *Api
+	netLock     chan struct{}
+	idempotency *idempotencyStore
+}
+
+func NewApiHandler(api *Api, logger hclog.Logger) *ApiHandler {
+	logger = logger.Named("handler")
+	return &ApiHandler{
+		api:         api,
+		logger:      logger,
+		audit:       logger.Named("audit"),
+		netLock:     make(chan struct{}, 1),
+		idempotency: newIdempotencyStore(DEFAULT_IDEMPOTENCY_TTL)}
+}
+
+// Records the status code and size of the response
+type statusRecorder struct {
+	http.ResponseWriter
+	code  int
+	bytes int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.code = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+	return n, err
+}
+
+// Attach an identifier to the request. A valid identifier provided
+// by the client is used, otherwise a new identifier is generated. The
+// identifier is included in the response and in the request logger.
+func (r *ApiHandler) requestId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writ http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(REQUEST_ID_HEADER)
+		if !validRequestId(id) {
+			if id != "" {
+				r.logger.Debug("ignoring invalid request id", "request-id", id)
+			}
+			id = generateRequestId()
+		}
+		writ.Header().Set(REQUEST_ID_HEADER, id)
+		ctx := utility.ContextWithRequestId(req.Context(), id, r.logger)
+		next.ServeHTTP(writ, req.WithContext(ctx))
+	})
+}
+
+// Identify the client using the certificate presented during the
+// TLS handshake, or the user connected to the local socket. The
+// identity is attached to the request context and included in the
+// request logger. Requests without a client certificate are not
+// identified and will fail authorization.
+func (r *ApiHandler) identifyClient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writ http.ResponseWriter, req *http.Request) {
+		var identity *utility.ClientIdentity
+		if user, ok := localPeer(req.Context()); ok && r.api.Socket != nil {
+			identity = &utility.ClientIdentity{
+				Name: LOCAL_CLIENT_PREFIX + user,
+				Role: r.api.Socket.Role,
+			}
+		} else if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+			var err error
+			identity, err = utility.IdentityFromCertificate(req.TLS.PeerCertificates[0])
+			if err != nil {
+				r.requestLogger(req).Warn("invalid client certificate", "error", err)
+				r.error(writ, "invalid client certificate", 403)
+				return
+			}
+		} else {
+			next.ServeHTTP(writ, req)
+			return
+		}
+		ctx := utility.ContextWithClient(req.Context(), identity)
+		ctx = hclog.WithContext(ctx, r.requestLogger(req),
+			"client", identity.Name, "role", string(identity.Role))
+		next.ServeHTTP(writ, req.WithContext(ctx))
+	})
+}
+
+// Require the client to be allowed the given permission. Requests
+// which are denied, and requests which modify state, are recorded
+// in the audit log with the identity of the client.
+func (r *ApiHandler) authorize(permission utility.Permission) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writ http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			args := map[string]interface{}{
+				"method":     req.Method,
+				"path":       req.URL.Path,
+				"permission": string(permission),
+			}
+			identity := utility.Client(req.Context())
+			if identity == nil || !identity.Allowed(permission) {
+				client, role := "", ""
+				if identity != nil {
+					client, role = identity.Name, string(identity.Role)
+				}
+				r.audit.Warn("request denied",
+					"request-id", utility.RequestId(req.Context()),
+					"client", client,
+					"role", role,
+					"permission", string(permission),
+					"method", req.Method,
+					"path", req.URL.Path)
+				utility.AuditResult(req.Context(), "api.request", args, start,
+					utility.AUDIT_RESULT_DENIED, nil)
+				r.errorCode(writ, "client is not authorized for this request", ERROR_FORBIDDEN)
+				return
+			}
+			if permission == utility.PERMISSION_READ {
+				next.ServeHTTP(writ, req)
+				return
+			}
+			rec := &statusRecorder{ResponseWriter: writ, code: 200}
+			next.ServeHTTP(rec, req)
+			args["status"] = rec.code
+			var err error
+			if rec.code >= 400 {
+				err = fmt.Errorf("request failed with status %d", rec.code)
+			}
+			utility.Audit(req.Context(), "api.request", args, start, err)
+		})
+	}
+}
+
+// Log the start of requests and write an access log entry on completion
+func (r *ApiHandler) logRequests(next http.Handler) http.Handler {
+	access := r.logger.Named("access")
+	return http.HandlerFunc(func(writ http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		r.requestLogger(req).Debug("request start", "method", req.Method, "path", req.URL.Path)
+		rec := &statusRecorder{ResponseWriter: writ, code: 200}
+		next.ServeHTTP(rec, req)
+		client := ""
+		if identity := utility.Client(req.Context()); identity != nil {
+			client = identity.Name
+		}
+		access.Info("request",
+			"request-id", utility.RequestId(req.Context()),
+			"client", client,
+			"method", req.Method,
+			"path", req.URL.Path,
+			"status", rec.code,
+			"bytes", rec.bytes,
+			"duration", time.Since(start),
+			"remote", req.RemoteAddr)
+	})
+}
+
+// Logger which includes the request identifier
+func (r *ApiHandler) requestLogger(req *http.Request) hclog.Logger {
+	return utility.ContextLogger(req.Context(), r.logger)
+}
+
+func validRequestId(id string) bool {
+	if id == "" || len(id) > MAX_REQUEST_ID_LENGTH {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') &&
+			!(c >= '0' && c <= '9') && !strings.ContainsRune("-_.:", c) {
+			return false
+		}
+	}
+	return true
+}
+
+func generateRequestId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Set the content type used for API responses
+func (r *ApiHandler) contentType(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writ http.ResponseWriter, req *http.Request) {
+		writ.Header().Set("Content-Type", API_CONTENT_TYPE)
+		next.ServeHTTP(writ, req)
+	})
+}
+
+// Reject requests which are not from a valid requester
+func (r *ApiHandler) requireRequester(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writ http.ResponseWriter, req *http.Request) {
+		if r.invalidRequester(writ, req) {
+			return
+		}
+		next.ServeHTTP(writ, req)
+	})
+}
+
+// Reject requests when the driver has not been validated
+func (r *ApiHandler) requireValidDriver(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writ http.ResponseWriter, req *http.Request) {
+		if !r.api.driver().Validated() {
+			r.invalidDriver(writ)
+			return
+		}
+		next.ServeHTTP(writ, req)
+	})
+}
+
+// Serialize requests which modify host networking. Requests
+// which are canceled while waiting are not processed.
+func (r *ApiHandler) lockNetwork(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writ http.ResponseWriter, req *http.Request) {
+		select {
+		case r.netLock <- struct{}{}:
+		case <-req.Context().Done():
+			r.requestLogger(req).Debug("request canceled waiting for network lock",
+				"error", req.Context().Err())
+			r.errorCode(writ, "request canceled", driver.ERROR_CANCELED)
+			return
+		}
+		defer func() { <-r.netLock }()
+		next.ServeHTTP(writ, req)
+	})
+}
+
+// Error codes for failures originating within the API
+const ERROR_FORBIDDEN driver.ErrorCode = "forbidden"
+const ERROR_NOT_IMPLEMENTED driver.ErrorCode = "not_implemented"
+const ERROR_INTERNAL driver.ErrorCode = "internal"
+const ERROR_METHOD_NOT_ALLOWED driver.ErrorCode = "method_not_allowed"
+
+// HTTP status codes used for error codes
+var errorStatuses = map[driver.ErrorCode]int{
+	driver.ERROR_NOT_FOUND:               404,
+	driver.ERROR_CONFLICT:                409,
+	driver.ERROR_UNSUPPORTED_ON_PLATFORM: 501,
+	driver.ERROR_VMWARE_SERVICE_FAILURE:  502,
+	driver.ERROR_VALIDATION_FAILED:       500,
+	driver.ERROR_INVALID_INPUT:           400,
+	driver.ERROR_TIMEOUT:                 504,
+	driver.ERROR_CANCELED:                503,
+	driver.ERROR_UNKNOWN:                 500,
+	ERROR_FORBIDDEN:                      403,
+	ERROR_NOT_IMPLEMENTED:                501,
+	ERROR_INTERNAL:                       500,
+	ERROR_METHOD_NOT_ALLOWED:             405,
+	ERROR_IDEMPOTENCY_KEY_MISMATCH:       422,
+}
+
+// Error codes used for errors generated within the API
+var statusErrorCodes = map[int]driver.ErrorCode{
+	400: driver.ERROR_INVALID_INPUT,
+	403: ERROR_FORBIDDEN,
+	404: driver.ERROR_NOT_FOUND,
+	405: ERROR_METHOD_NOT_ALLOWED,
+	500: ERROR_INTERNAL,
+	501: ERROR_NOT_IMPLEMENTED,
+}
+
+type StandardResponse struct {
+	Code      int              `json:"code"`
+	Message   string           `json:"message"`
+	ErrorCode driver.ErrorCode `json:"error_code"`
+}
+
+func (r *ApiHandler) respond(writ http.ResponseWriter, body interface{}, code int) {
+	writ.WriteHeader(code)
+	if body != nil {
+		if err := json.NewEncoder(writ).Encode(body); err != nil {
+			r.logger.Error("error encoding response body", "error", err)
+			http.Error(writ, http.StatusText(500), 500)
+		}
+	}
+}
+
+func (r *ApiHandler) notFound(writ http.ResponseWriter) {
+	r.error(writ, "not found", 404)
+}
+
+func (r *ApiHandler) handleNotFound(writ http.ResponseWriter, req *http.Request) {
+	r.notFound(writ)
+}
+
+func (r *ApiHandler) handleMethodNotAllowed(writ http.ResponseWriter, req *http.Request) {
+	r.error(writ, "method not allowed", 405)
+}
+
+func (r *ApiHandler) invalidDriver(writ http.ResponseWriter) {
+	r.errorCode(writ, "Validation failure: "+r.api.driver().ValidationReason(), driver.ERROR_VALIDATION_FAILED)
+}
+
+func (r *ApiHandler) invalidRequester(writ http.ResponseWriter, req *http.Request) bool {
+	invalid := false
+	validOrigin := fmt.Sprintf("https://%s:%d", r.api.Address, r.api.Port)
+	if len(req.Header["X-Requested-With"]) != 1 || req.Header["X-Requested-With"][0] != "Vagrant" {
+		invalid = true
+	}
+	// Requests on the local socket are not subject to the origin
+	// check as they cannot originate from a browser
+	if _, local := localPeer(req.Context()); !local &&
+		(len(req.Header["Origin"]) != 1 || req.Header["Origin"][0] != validOrigin) {
+		invalid = true
+	}
+	if invalid {
+		r.error(writ, "invalid client requester", 403)
+	}
+	return invalid
+}
+
+func (r *ApiHandler) error(writ http.ResponseWriter, msg string, code int) {
+	errCode, ok := statusErrorCodes[code]
+	if !ok {
+		errCode = driver.ERROR_UNKNOWN
+	}
+	r.respondError(writ, msg, code, errCode)
+}
+
+// Respond with the status code for the given error code
+func (r *ApiHandler) errorCode(writ http.ResponseWriter, msg string, errCode driver.ErrorCode) {
+	code, ok := errorStatuses[errCode]
+	if !ok {
+		code = 400
+	}
+	r.respondError(writ, msg, code, errCode)
+}
+
+// Respond with the classification of an error returned by the driver
+func (r *ApiHandler) driverError(writ http.ResponseWriter, err error) {
+	r.errorCode(writ, err.Error(), driver.ErrorCodeFor(err))
+}
+
+func (r *ApiHandler) respondError(writ http.ResponseWriter, msg string, code int, errCode driver.ErrorCode) {
+	r.logger.Debug("request error", "code", code, "error-code", errCode, "message", msg)
+	response := StandardResponse{
+		Code:      code,
+		Message:   msg,
+		ErrorCode: errCode}
+	r.respond(writ, response, code)
+}
+
+// VMware VM Network Adapter handler
+func (r *ApiHandler) handleVmNicAdapter(writ http.ResponseWriter, req *http.Request) {
+	r.error(writ, "not implemented", 501)
+}
+
+// VMware VM Network handler
+func (r *ApiHandler) handleVmNic(writ http.ResponseWriter, req *http.Request) {
+	r.error(writ, "not implemented", 501)
+}
+
+// VMware VM IP handler
+func (r *ApiHandler) handleVmIp(writ http.ResponseWriter, req *http.Request) {
+	r.error(writ, "not implemented", 501)
+}
+
+// API root handler
+func (r *ApiHandler) handleRoot(writ http.ResponseWriter, req *http.Request) {
+	logger := r.requestLogger(req)
+	logger.Debug("api document request")
+	doc, err := openApiDocument()
+	if err != nil {
+		logger.Error("failed to load api document", "error", err)
+		r.error(writ, err.Error(), 500)
+		return
+	}
+	r.respond(writ, doc, 200)
+}
+
+// Custom handlers
+func (r *ApiHandler) handleStatus(writ http.ResponseWriter, req *http.Request) {
+	status := "running"
+	if !r.api.Ready() {
+		status = "stopping"
+	}
+	response := map[string]string{
+		"status":   status,
+		"ready":    strconv.FormatBool(r.api.Ready()),
+		"inflight": strconv.Itoa(r.api.Inflight()),
+	}
+	if cert := r.api.Certificate(); cert != nil {
+		response["certificate_expires"] = cert.NotAfter.UTC().Format(time.RFC3339)
+		if remaining := time.Until(cert.NotAfter); remaining < utility.CERTIFICATE_RENEWAL_WINDOW {
+			response["warning"] = fmt.Sprintf(
+				"certificate expires in %d days, rotate certificates with `certificate rotate`",
+				int(remaining.Hours()/24))
+		}
+	}
+	if repairs := settings.Repairs(); len(repairs) > 0 {
+		msgs := []string{}
+		for _, repair := range repairs {
+			msg := fmt.Sprintf("invalid %s settings file moved to %s", repair.Kind, repair.Backup)
+			if repair.Action == settings.REPAIR_RESTORED {
+				msg += " and restored from " + repair.Restored
+			} else {
+				msg += " and reset"
+			}
+			msgs = append(msgs, msg)
+		}
+		response["settings_repaired"] = strings.Join(msgs, "; ")
+	}
+	r.respond(writ, response, 200)
+}
+
+// Reload the service configuration. Settings which cannot be
+// applied while running are reported as requiring a restart.
+func (r *ApiHandler) handleReload(writ http.ResponseWriter, req *http.Request) {
+	logger := r.requestLogger(req)
+	logger.Info("configuration reload request")
+	if r.api.Reloader == nil {
+		r.errorCode(writ, "configuration reload is not supported", ERROR_NOT_IMPLEMENTED)
+		return
+	}
+	start := time.Now()
+	result, err := r.api.Reload()
+	var args map[string]interface{}
+	if result != nil {
+		args = map[string]interface{}{
+			"applied":          result.Applied,
+			"restart_required": result.RestartRequired}
+	}
+	utility.Audit(req.Context(), "config.reload", args, start, err)
+	if err != nil {
+		logger.Error("configuration reload failure", "error", err)
+		r.errorCode(writ, err.Error(), ERROR_INTERNAL)
+		return
+	}
+	r.respond(writ, result, 200)
+}
+
+func (r *ApiHandler) handleVersion(writ http.ResponseWriter, req *http.Request) {
+	response := map[string]string{"version": version.VERSION}
+	r.respond(writ, response, 200)
+}
+
+func (r *ApiHandler) handleCapabilities(writ http.ResponseWriter, req *http.Request) {
+	logger := r.requestLogger(req)
+	logger.Debug("capabilities request")
+	r.respond(writ, r.api.driver().Capabilities(), 200)
+}
@@ -0,0 +1,251 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/driver"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/settings"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+func TestRequestId(t *testing.T) {
+	h := NewApiHandler(&Api{}, hclog.NewNullLogger())
+	var seen string
+	handler := h.requestId(http.HandlerFunc(func(writ http.ResponseWriter, req *http.Request) {
+		seen = utility.RequestId(req.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/status", nil)
+	req.Header.Set(REQUEST_ID_HEADER, "vagrant-up-1234")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if seen != "vagrant-up-1234" {
+		t.Errorf("Provided request id not used: '%s'", seen)
+	}
+	if id := rec.Header().Get(REQUEST_ID_HEADER); id != seen {
+		t.Errorf("Invalid response request id '%s' != '%s'", id, seen)
+	}
+
+	for _, invalid := range []string{"", "bad id", "bad\nid"} {
+		req = httptest.NewRequest("GET", "/status", nil)
+		req.Header.Set(REQUEST_ID_HEADER, invalid)
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if seen == "" || seen == invalid {
+			t.Errorf("Expected generated request id for '%s', received '%s'", invalid, seen)
+		}
+		if id := rec.Header().Get(REQUEST_ID_HEADER); id != seen {
+			t.Errorf("Invalid response request id '%s' != '%s'", id, seen)
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	h := NewApiHandler(&Api{}, hclog.NewNullLogger())
+	called := false
+	handler := h.identifyClient(h.authorize(utility.PERMISSION_PORT_FORWARD)(
+		http.HandlerFunc(func(writ http.ResponseWriter, req *http.Request) {
+			called = true
+			if client := utility.Client(req.Context()); client == nil || client.Name != "agent" {
+				t.Errorf("Client identity not attached to request")
+			}
+		})))
+
+	for _, c := range []struct {
+		role   string
+		status int
+	}{
+		{"admin", 200},
+		{"port-forward", 200},
+		{"read-only", 403},
+		{"unknown", 403},
+	} {
+		called = false
+		req := httptest.NewRequest("DELETE", "/portforwards", nil)
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{
+			Subject: pkix.Name{CommonName: "agent", OrganizationalUnit: []string{c.role}}}}}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != c.status {
+			t.Errorf("Invalid status for role %s: %d != %d", c.role, rec.Code, c.status)
+		}
+		if called != (c.status == 200) {
+			t.Errorf("Invalid handler call for role %s", c.role)
+		}
+	}
+
+	called = false
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("DELETE", "/portforwards", nil))
+	if rec.Code != 403 || called {
+		t.Errorf("Expected request without client certificate to be denied")
+	}
+}
+
+func TestHandleReload(t *testing.T) {
+	a := &Api{}
+	h := NewApiHandler(a, hclog.NewNullLogger())
+
+	rec := httptest.NewRecorder()
+	h.handleReload(rec, httptest.NewRequest("POST", "/admin/reload", nil))
+	if rec.Code != 501 {
+		t.Errorf("Expected unsupported reload to be not implemented, received %d", rec.Code)
+	}
+
+	a.Reloader = func() (*ReloadResult, error) {
+		return &ReloadResult{
+			Applied:         []string{"level"},
+			RestartRequired: []string{"port"}}, nil
+	}
+	rec = httptest.NewRecorder()
+	h.handleReload(rec, httptest.NewRequest("POST", "/admin/reload", nil))
+	if rec.Code != 200 {
+		t.Fatalf("Invalid reload status %d", rec.Code)
+	}
+	result := &ReloadResult{}
+	if err := json.NewDecoder(rec.Body).Decode(result); err != nil {
+		t.Fatalf("Failed to decode reload result: %s", err)
+	}
+	if len(result.Applied) != 1 || result.Applied[0] != "level" {
+		t.Errorf("Invalid applied settings %v", result.Applied)
+	}
+	if len(result.RestartRequired) != 1 || result.RestartRequired[0] != "port" {
+		t.Errorf("Invalid restart required settings %v", result.RestartRequired)
+	}
+
+	a.Reloader = func() (*ReloadResult, error) {
+		return nil, errors.New("Failed to parse configuration")
+	}
+	rec = httptest.NewRecorder()
+	h.handleReload(rec, httptest.NewRequest("POST", "/admin/reload", nil))
+	if rec.Code != 500 {
+		t.Errorf("Expected failed reload to be an error, received %d", rec.Code)
+	}
+}
+
+type reserveDriver struct {
+	driver.Driver
+	err error
+}
+
+func (d *reserveDriver) ReserveDhcpAddress(ctx context.Context, slot int, mac, ip string) error {
+	return d.err
+}
+
+func TestReserveVmnetDhcpAddressErrors(t *testing.T) {
+	drv := &reserveDriver{}
+	h := NewApiHandler(&Api{Driver: drv}, hclog.NewNullLogger())
+	r := NewRouter("", hclog.NewNullLogger())
+	if err := r.Handle("PUT", `/vmnet/vmnet(?P<vnet_slot>[^/]+)/dhcpreserve/(?P<mac>[^/]+)/(?P<ip>.+)`,
+		h.reserveVmnetDhcpAddress); err != nil {
+		t.Fatalf("Failed to register route: %s", err)
+	}
+
+	for _, c := range []struct {
+		slot   string
+		err    error
+		status int
+		code   driver.ErrorCode
+	}{
+		{"x", nil, 400, driver.ERROR_INVALID_INPUT},
+		{"1", driver.NewError(driver.ERROR_NOT_FOUND, "missing"), 404, driver.ERROR_NOT_FOUND},
+		{"1", driver.NewError(driver.ERROR_TIMEOUT, "slow"), 504, driver.ERROR_TIMEOUT},
+		{"1", errors.New("failure"), 500, driver.ERROR_UNKNOWN},
+	} {
+		drv.err = c.err
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("PUT", "/vmnet/vmnet"+c.slot+"/dhcpreserve/00:0c:29:00:00:01/10.0.0.2", nil))
+		resp := StandardResponse{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %s", err)
+		}
+		if rec.Code != c.status || resp.ErrorCode != c.code {
+			t.Errorf("Invalid response for slot %s error %v: %d %s", c.slot, c.err, rec.Code, resp.ErrorCode)
+		}
+	}
+}
+
+type connectionsDriver struct {
+	driver.Driver
+	fwd *driver.PortFwd
+}
+
+func (d *connectionsDriver) InternalPortFwdConnections(ctx context.Context, fwd *driver.PortFwd) (*driver.PortFwdConnections, error) {
+	d.fwd = fwd
+	return &driver.PortFwdConnections{Connections: []*driver.PortFwdConnection{}}, nil
+}
+
+func TestListPortFwdConnectionsPath(t *testing.T) {
+	drv := &connectionsDriver{}
+	h := NewApiHandler(&Api{Driver: drv}, hclog.NewNullLogger())
+	r := NewRouter("", hclog.NewNullLogger())
+	for _, path := range []string{`/portforwards/(?P<protocol>tcp|udp)/(?P<port>\d+)/connections`,
+		`/portforwards/(?P<protocol>unix|npipe)/connections`} {
+		if err := r.Handle("GET", path, h.listPortFwdConnections); err != nil {
+			t.Fatalf("Failed to register route: %s", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/portforwards/unix/connections?path=%2Ftmp%2Fvagrant.sock", nil))
+	if rec.Code != 200 {
+		t.Fatalf("Invalid status %d", rec.Code)
+	}
+	if drv.fwd == nil || drv.fwd.Protocol != "unix" || drv.fwd.HostPath != "/tmp/vagrant.sock" {
+		t.Errorf("Invalid port forward provided to driver: %#v", drv.fwd)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/portforwards/tcp/2222/connections", nil))
+	if rec.Code != 200 {
+		t.Fatalf("Invalid status %d", rec.Code)
+	}
+	if drv.fwd.Protocol != "tcp" || drv.fwd.Port != 2222 || drv.fwd.HostPath != "" {
+		t.Errorf("Invalid port forward provided to driver: %#v", drv.fwd)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/portforwards/npipe/connections", nil))
+	resp := StandardResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %s", err)
+	}
+	if rec.Code != 400 || resp.ErrorCode != driver.ERROR_INVALID_INPUT {
+		t.Errorf("Expected missing path to be invalid input, received %d %s", rec.Code, resp.ErrorCode)
+	}
+}
+
+func TestStatusSettingsRepaired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nat.json")
+	if err := os.WriteFile(path, []byte("{invalid"), 0644); err != nil {
+		t.Fatalf("Failed to write nat file: %s", err)
+	}
+	if _, err := settings.LoadNATSettings(path, hclog.NewNullLogger()); err != nil {
+		t.Fatalf("Failed to load invalid nat file: %s", err)
+	}
+	h := NewApiHandler(&Api{}, hclog.NewNullLogger())
+	rec := httptest.NewRecorder()
+	h.handleStatus(rec, httptest.NewRequest("GET", "/status", nil))
+	status := map[string]string{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to decode status: %s", err)
+	}
+	if !strings.Contains(status["settings_repaired"], path) {
+		t.Errorf("Settings repair not included in status: %q", status["settings_repaired"])
+	}
+}
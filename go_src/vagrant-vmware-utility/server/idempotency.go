@@ -0,0 +1,239 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/driver"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+const IDEMPOTENCY_KEY_HEADER = "Idempotency-Key"
+const IDEMPOTENCY_REPLAYED_HEADER = "Idempotency-Replayed"
+const MAX_IDEMPOTENCY_KEY_LENGTH = 255
+
+// Time a completed response is stored for replay
+const DEFAULT_IDEMPOTENCY_TTL = time.Hour
+
+// Maximum number of stored responses
+const MAX_IDEMPOTENCY_ENTRIES = 1024
+
+// Maximum size of request or response bodies for idempotent requests
+const MAX_IDEMPOTENCY_BODY_SIZE = 1024 * 1024
+
+// Error code used when an idempotency key is reused for a different request
+const ERROR_IDEMPOTENCY_KEY_MISMATCH driver.ErrorCode = "idempotency_key_mismatch"
+
+type idempotentResponse struct {
+	fingerprint string
+	done        chan struct{}
+	code        int
+	header      http.Header
+	body        []byte
+	stored      bool
+	expires     time.Time
+}
+
+// Responses of requests with idempotency keys. Keys are
+// scoped to the client which made the request.
+type idempotencyStore struct {
+	ttl       time.Duration
+	responses map[string]*idempotentResponse
+	m         sync.Mutex
+}
+
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	return &idempotencyStore{
+		ttl:       ttl,
+		responses: map[string]*idempotentResponse{},
+	}
+}
+
+// Get the response for the key. If no response exists, a new
+// pending response is registered and true is returned to
+// indicate the request should be processed.
+func (s *idempotencyStore) acquire(key, fingerprint string) (*idempotentResponse, bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	now := time.Now()
+	if resp, ok := s.responses[key]; ok {
+		select {
+		case <-resp.done:
+			if now.After(resp.expires) {
+				delete(s.responses, key)
+			} else {
+				return resp, false
+			}
+		default:
+			return resp, false
+		}
+	}
+	s.prune(now)
+	resp := &idempotentResponse{
+		fingerprint: fingerprint,
+		done:        make(chan struct{}),
+	}
+	s.responses[key] = resp
+	return resp, true
+}
+
+// Complete a pending response. Responses which should not
+// be stored are removed so the request may be retried.
+func (s *idempotencyStore) complete(key string, resp *idempotentResponse) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	resp.expires = time.Now().Add(s.ttl)
+	if !resp.stored {
+		delete(s.responses, key)
+	}
+	close(resp.done)
+}
+
+// Remove expired responses. If the store is full the
+// completed response closest to expiry is removed.
+func (s *idempotencyStore) prune(now time.Time) {
+	var oldestKey string
+	var oldest *idempotentResponse
+	for key, resp := range s.responses {
+		select {
+		case <-resp.done:
+		default:
+			continue
+		}
+		if now.After(resp.expires) {
+			delete(s.responses, key)
+			continue
+		}
+		if oldest == nil || resp.expires.Before(oldest.expires) {
+			oldestKey, oldest = key, resp
+		}
+	}
+	if len(s.responses) >= MAX_IDEMPOTENCY_ENTRIES && oldest != nil {
+		delete(s.responses, oldestKey)
+	}
+}
+
+// Records the response while writing it to the client
+type responseCapture struct {
+	http.ResponseWriter
+	code     int
+	header   http.Header
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (c *responseCapture) WriteHeader(code int) {
+	c.code = code
+	c.header = c.ResponseWriter.Header().Clone()
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *responseCapture) Write(b []byte) (int, error) {
+	if c.header == nil {
+		c.WriteHeader(200)
+	}
+	if c.body.Len()+len(b) > MAX_IDEMPOTENCY_BODY_SIZE {
+		c.overflow = true
+	} else {
+		c.body.Write(b)
+	}
+	return c.ResponseWriter.Write(b)
+}
+
+// Process requests with an idempotency key only once. The response
+// is stored and replayed for duplicate requests, including requests
+// received while the original request is still being processed.
+// Requests with a key are not canceled when the client disconnects
+// so a retried request receives the result of the original request.
+func (r *ApiHandler) idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writ http.ResponseWriter, req *http.Request) {
+		key := req.Header.Get(IDEMPOTENCY_KEY_HEADER)
+		if key == "" {
+			next.ServeHTTP(writ, req)
+			return
+		}
+		logger := r.requestLogger(req)
+		if len(key) > MAX_IDEMPOTENCY_KEY_LENGTH {
+			r.errorCode(writ, "idempotency key is too long", driver.ERROR_INVALID_INPUT)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(req.Body, MAX_IDEMPOTENCY_BODY_SIZE+1))
+		if err != nil {
+			r.errorCode(writ, "failed to read request body", driver.ERROR_INVALID_INPUT)
+			return
+		}
+		if len(body) > MAX_IDEMPOTENCY_BODY_SIZE {
+			r.errorCode(writ, "request body is too large", driver.ERROR_INVALID_INPUT)
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		client := ""
+		if identity := utility.Client(req.Context()); identity != nil {
+			client = identity.Name
+		}
+		sum := sha256.Sum256(append([]byte(req.Method+" "+req.URL.Path+"\n"), body...))
+		fingerprint := hex.EncodeToString(sum[:])
+		storeKey := client + "\n" + key
+
+		resp, acquired := r.idempotency.acquire(storeKey, fingerprint)
+		if !acquired {
+			if resp.fingerprint != fingerprint {
+				logger.Debug("idempotency key reused for different request", "key", key)
+				r.errorCode(writ, "idempotency key was used for a different request",
+					ERROR_IDEMPOTENCY_KEY_MISMATCH)
+				return
+			}
+			select {
+			case <-resp.done:
+			case <-req.Context().Done():
+				logger.Debug("request canceled waiting for idempotent request", "key", key)
+				r.errorCode(writ, "request canceled", driver.ERROR_CANCELED)
+				return
+			}
+			r.replay(writ, resp, logger, key)
+			return
+		}
+
+		capture := &responseCapture{ResponseWriter: writ, code: 200}
+		defer func() {
+			resp.code = capture.code
+			resp.header = capture.header
+			resp.body = capture.body.Bytes()
+			// Server failures are not stored so the request can be
+			// retried. Duplicate requests already waiting receive
+			// the failure response.
+			resp.stored = capture.header != nil && !capture.overflow && capture.code < 500
+			r.idempotency.complete(storeKey, resp)
+		}()
+		ctx := context.WithoutCancel(req.Context())
+		next.ServeHTTP(capture, req.WithContext(ctx))
+	})
+}
+
+func (r *ApiHandler) replay(writ http.ResponseWriter, resp *idempotentResponse, logger hclog.Logger, key string) {
+	logger.Debug("replaying idempotent response", "key", key, "status", resp.code)
+	if resp.header == nil {
+		r.errorCode(writ, "original request did not complete", driver.ERROR_CONFLICT)
+		return
+	}
+	for name, values := range resp.header {
+		if name == REQUEST_ID_HEADER {
+			continue
+		}
+		writ.Header()[name] = values
+	}
+	writ.Header().Set(IDEMPOTENCY_REPLAYED_HEADER, "true")
+	writ.WriteHeader(resp.code)
+	writ.Write(resp.body)
+}
@@ -0,0 +1,142 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+func idempotentRequest(h http.Handler, key, client, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/vmnet", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IDEMPOTENCY_KEY_HEADER, key)
+	}
+	req = req.WithContext(utility.ContextWithClient(req.Context(),
+		&utility.ClientIdentity{Name: client, Role: utility.ROLE_ADMIN}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	h := NewApiHandler(&Api{}, hclog.NewNullLogger())
+	var calls atomic.Int64
+	handler := h.idempotent(http.HandlerFunc(func(writ http.ResponseWriter, req *http.Request) {
+		n := calls.Add(1)
+		writ.Header().Set("Content-Type", API_CONTENT_TYPE)
+		writ.WriteHeader(200)
+		writ.Write([]byte(`{"name":"vmnet` + strconv.FormatInt(n, 10) + `"}`))
+	}))
+
+	first := idempotentRequest(handler, "key-1", "vagrant", `{"type":"nat"}`)
+	second := idempotentRequest(handler, "key-1", "vagrant", `{"type":"nat"}`)
+	if calls.Load() != 1 {
+		t.Errorf("Handler called %d times", calls.Load())
+	}
+	if second.Body.String() != first.Body.String() || second.Code != first.Code {
+		t.Errorf("Invalid replayed response %d '%s'", second.Code, second.Body.String())
+	}
+	if second.Header().Get(IDEMPOTENCY_REPLAYED_HEADER) != "true" {
+		t.Errorf("Replayed header not set")
+	}
+	if second.Header().Get("Content-Type") != API_CONTENT_TYPE {
+		t.Errorf("Headers not replayed")
+	}
+
+	if rec := idempotentRequest(handler, "key-1", "vagrant", `{"type":"hostonly"}`); rec.Code != 422 {
+		t.Errorf("Expected key mismatch, received %d", rec.Code)
+	}
+	idempotentRequest(handler, "key-1", "other", `{"type":"nat"}`)
+	idempotentRequest(handler, "", "vagrant", `{"type":"nat"}`)
+	if calls.Load() != 3 {
+		t.Errorf("Expected requests from other clients and without keys to be processed, calls: %d", calls.Load())
+	}
+}
+
+func TestIdempotentInflight(t *testing.T) {
+	h := NewApiHandler(&Api{}, hclog.NewNullLogger())
+	var calls atomic.Int64
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := h.idempotent(http.HandlerFunc(func(writ http.ResponseWriter, req *http.Request) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		writ.WriteHeader(200)
+		writ.Write([]byte("created"))
+	}))
+
+	var wg sync.WaitGroup
+	results := make([]*httptest.ResponseRecorder, 3)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0] = idempotentRequest(handler, "key-1", "vagrant", "body")
+	}()
+	<-started
+	for i := 1; i < len(results); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = idempotentRequest(handler, "key-1", "vagrant", "body")
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Errorf("Handler called %d times", calls.Load())
+	}
+	for i, rec := range results {
+		if rec.Code != 200 || rec.Body.String() != "created" {
+			t.Errorf("Invalid response %d: %d '%s'", i, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestIdempotentServerError(t *testing.T) {
+	h := NewApiHandler(&Api{}, hclog.NewNullLogger())
+	var calls atomic.Int64
+	handler := h.idempotent(http.HandlerFunc(func(writ http.ResponseWriter, req *http.Request) {
+		if calls.Add(1) == 1 {
+			writ.WriteHeader(502)
+			return
+		}
+		writ.WriteHeader(200)
+	}))
+	if rec := idempotentRequest(handler, "key-1", "vagrant", "body"); rec.Code != 502 {
+		t.Errorf("Invalid status %d != 502", rec.Code)
+	}
+	if rec := idempotentRequest(handler, "key-1", "vagrant", "body"); rec.Code != 200 {
+		t.Errorf("Expected failed request to be retried, received %d", rec.Code)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Handler called %d times", calls.Load())
+	}
+}
+
+func TestIdempotencyStoreExpiry(t *testing.T) {
+	s := newIdempotencyStore(time.Millisecond)
+	resp, acquired := s.acquire("key", "a")
+	if !acquired {
+		t.Fatalf("Expected new response")
+	}
+	resp.stored = true
+	s.complete("key", resp)
+	time.Sleep(5 * time.Millisecond)
+	if _, acquired := s.acquire("key", "a"); !acquired {
+		t.Errorf("Expected expired response to be removed")
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package server
+
+import (
+	"context"
+	"net"
+	"os"
+
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+// Default permissions of the local socket
+const DEFAULT_SOCKET_MODE os.FileMode = 0600
+
+// Prefix used for the names of clients connected via the local socket
+const LOCAL_CLIENT_PREFIX = "local/"
+
+// Configuration of the local socket listener. The local socket is
+// a Unix domain socket on Linux and macOS and a named pipe on Windows.
+type SocketConfig struct {
+	// Path of the Unix domain socket or name of the named pipe
+	Path string
+	// Permissions of the Unix domain socket (unused on Windows)
+	Mode os.FileMode
+	// Group owner of the Unix domain socket (unused on Windows)
+	Group string
+	// Users allowed to connect. On Linux and macOS the credentials
+	// of the peer are checked, and when empty access is controlled
+	// by the socket permissions. On Windows the users are granted
+	// access to the named pipe in addition to SYSTEM and Administrators.
+	Users []string
+	// Role assigned to clients connected via the local socket
+	Role utility.Role
+}
+
+const localPeerKey routerContextKey = "local-peer"
+
+// Connection accepted on the local socket
+type localConn struct {
+	net.Conn
+	user string
+}
+
+// Attach the user of connections accepted on the local
+// socket to the context of requests on the connection
+func localConnContext(ctx context.Context, c net.Conn) context.Context {
+	if lc, ok := c.(*localConn); ok {
+		return context.WithValue(ctx, localPeerKey, lc.user)
+	}
+	return ctx
+}
+
+// User connected via the local socket. Returns false if the
+// request was not received on the local socket.
+func localPeer(ctx context.Context) (string, bool) {
+	user, ok := ctx.Value(localPeerKey).(string)
+	return user, ok
+}
@@ -0,0 +1,104 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build !windows
+// +build !windows
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+type unixListener struct {
+	*utility.UnixListener
+	allowed map[uint32]bool
+	logger  hclog.Logger
+}
+
+// Create a Unix domain socket listener. A stale socket left at the
+// path is removed. A socket in use or any other existing file is an
+// error.
+func listenLocal(config *SocketConfig, logger hclog.Logger) (net.Listener, error) {
+	logger = logger.Named("socket")
+	allowed := map[uint32]bool{}
+	for _, name := range config.Users {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid socket user '%s': %w", name, err)
+		}
+		uid, err := strconv.ParseUint(u.Uid, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid uid for socket user '%s': %w", name, err)
+		}
+		allowed[uint32(uid)] = true
+	}
+	gid := -1
+	if config.Group != "" {
+		g, err := user.LookupGroup(config.Group)
+		if err != nil {
+			return nil, fmt.Errorf("invalid socket group '%s': %w", config.Group, err)
+		}
+		gid, err = strconv.Atoi(g.Gid)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gid for socket group '%s': %w", config.Group, err)
+		}
+	}
+
+	// Permissions are applied before the socket is accessible
+	// at the path and a socket in use is never removed
+	l, err := utility.ListenUnix(config.Path, config.Mode, gid, logger)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("listening on local socket", "path", config.Path, "mode", config.Mode,
+		"group", config.Group, "users", config.Users)
+	return &unixListener{
+		UnixListener: l,
+		allowed:      allowed,
+		logger:       logger,
+	}, nil
+}
+
+// Accept connections from allowed peers. Connections from
+// other peers are closed.
+func (l *unixListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.AcceptUnix()
+		if err != nil {
+			return nil, err
+		}
+		uid, err := peerUid(conn)
+		if err != nil {
+			l.logger.Warn("failed to read peer credentials", "error", err)
+			conn.Close()
+			continue
+		}
+		if !l.permitted(uid) {
+			l.logger.Warn("connection rejected", "uid", uid)
+			conn.Close()
+			continue
+		}
+		name := strconv.FormatUint(uint64(uid), 10)
+		if u, err := user.LookupId(name); err == nil {
+			name = u.Username
+		}
+		return &localConn{Conn: conn, user: name}, nil
+	}
+}
+
+// Root and the user running the API are always permitted. If no
+// users are configured, access is controlled by socket permissions.
+func (l *unixListener) permitted(uid uint32) bool {
+	if len(l.allowed) == 0 || uid == 0 || uid == uint32(os.Getuid()) {
+		return true
+	}
+	return l.allowed[uid]
+}
@@ -0,0 +1,116 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build !windows
+// +build !windows
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+)
+
+func TestApiLocalSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.sock")
+	drv := &shutdownDriver{}
+	a, err := Create("127.0.0.1", 0, drv, hclog.NewNullLogger())
+	if err != nil {
+		t.Fatalf("Failed to create api: %s", err)
+	}
+	a.DisableTcp = true
+	a.Socket = &SocketConfig{
+		Path: path,
+		Mode: DEFAULT_SOCKET_MODE,
+		Role: utility.ROLE_READ_ONLY,
+	}
+	if err := a.Start(); err != nil {
+		t.Fatalf("Failed to start api: %s", err)
+	}
+	defer a.Stop()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat socket: %s", err)
+	}
+	if info.Mode().Perm() != DEFAULT_SOCKET_MODE {
+		t.Errorf("Invalid socket permissions %o != %o", info.Mode().Perm(), DEFAULT_SOCKET_MODE)
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	request := func(method, path string) *http.Response {
+		req, _ := http.NewRequest(method, "http://localhost"+path, nil)
+		req.Header.Set("X-Requested-With", "Vagrant")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %s", err)
+		}
+		return resp
+	}
+
+	resp := request("GET", "/status")
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("Invalid status code %d != 200", resp.StatusCode)
+	}
+	status := map[string]string{}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode response: %s", err)
+	}
+	if status["status"] != "running" {
+		t.Errorf("Invalid status '%s'", status["status"])
+	}
+
+	resp = request("DELETE", "/portforwards")
+	resp.Body.Close()
+	if resp.StatusCode != 403 {
+		t.Errorf("Expected read-only socket client to be denied, received %d", resp.StatusCode)
+	}
+}
+
+func TestListenLocalExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.sock")
+	if err := os.WriteFile(path, []byte("data"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %s", err)
+	}
+	_, err := listenLocal(&SocketConfig{Path: path, Mode: DEFAULT_SOCKET_MODE}, hclog.NewNullLogger())
+	if err == nil {
+		t.Errorf("Expected error when path is not a socket")
+	}
+}
+
+func TestListenLocalSocketInUse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.sock")
+	l, err := listenLocal(&SocketConfig{Path: path, Mode: DEFAULT_SOCKET_MODE}, hclog.NewNullLogger())
+	if err != nil {
+		t.Fatalf("Failed to create socket: %s", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+	if _, err := listenLocal(&SocketConfig{Path: path, Mode: DEFAULT_SOCKET_MODE}, hclog.NewNullLogger()); err == nil {
+		t.Errorf("Expected error when socket is in use")
+	}
+	if _, err := os.Lstat(path); err != nil {
+		t.Errorf("Socket in use was removed: %s", err)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+	"golang.org/x/sys/windows"
+)
+
+type pipeListener struct {
+	*utility.PipeListener
+	logger hclog.Logger
+}
+
+// Create a named pipe listener. Access to the named pipe is always
+// granted to SYSTEM and Administrators in addition to the users.
+func listenLocal(config *SocketConfig, logger hclog.Logger) (net.Listener, error) {
+	logger = logger.Named("pipe")
+	path := config.Path
+	if !strings.HasPrefix(path, utility.PIPE_PREFIX) {
+		path = utility.PIPE_PREFIX + path
+	}
+	sddl := utility.PIPE_BASE_SDDL
+	for _, name := range config.Users {
+		sid, _, _, err := windows.LookupSID("", name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pipe user '%s': %w", name, err)
+		}
+		sddl += fmt.Sprintf("(A;;GRGW;;;%s)", sid.String())
+	}
+	l, err := utility.ListenPipe(path, sddl, logger)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("listening on named pipe", "path", path, "users", config.Users)
+	return &pipeListener{PipeListener: l, logger: logger}, nil
+}
+
+// Wait for a client to connect to the named pipe. Clients
+// which cannot be identified are disconnected.
+func (l *pipeListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.AcceptPipe()
+		if err != nil {
+			return nil, err
+		}
+		user, err := conn.ClientUser()
+		if err != nil {
+			l.logger.Warn("failed to identify pipe client", "error", err)
+			conn.Close()
+			continue
+		}
+		return &localConn{Conn: conn, user: user}, nil
+	}
+}
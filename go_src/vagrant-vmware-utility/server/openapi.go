@@ -0,0 +1,31 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package server
+
+import (
+	_ "embed"
+	"encoding/json"
+
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/version"
+)
+
+// Prefix for versioned API routes. Routes are also available
+// without the prefix for existing clients.
+const API_V2_PREFIX = "/v2"
+
+//go:embed openapi.json
+var openApiSpec []byte
+
+// Load the OpenAPI document describing the API with the
+// version set to the current utility version
+func openApiDocument() (map[string]interface{}, error) {
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(openApiSpec, &doc); err != nil {
+		return nil, err
+	}
+	if info, ok := doc["info"].(map[string]interface{}); ok {
+		info["version"] = version.VERSION
+	}
+	return doc, nil
+}
@@ -6,6 +6,7 @@ package server
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strconv"
@@ -13,78 +14,183 @@ import (
 	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/driver"
 )
 
-func (r *RegexpHandler) handleVmnetDeviceForward(writ http.ResponseWriter, req *http.Request) {
-	params := r.pathParams(req.URL.Path)
-	r.logger.Trace("portforward parameters", "params", params)
-
-	switch req.Method {
-	case "GET":
-		r.logger.Debug("portforward list", "slot", params["vnet_slot"])
-		r.listPortFwds(writ, params["vnet_slot"])
-	case "PUT":
-		r.netLock.Lock()
-		defer r.netLock.Unlock()
-		r.logger.Debug("portforward request", "slot", params["vnet_slot"])
-		r.applyPortFwd(writ, req, params["vnet_slot"])
-	case "DELETE":
-		r.netLock.Lock()
-		defer r.netLock.Unlock()
-		r.logger.Debug("portforward delete", "slot", params["vnet_slot"])
-		r.deletePortFwd(writ, req, params["vnet_slot"])
-
-	default:
-		r.notFound(writ)
+// Port forward identified by the request. Path based forwards
+// are identified by the path query parameter, all others by
+// the port within the request path.
+func connectionPortFwd(req *http.Request) (*driver.PortFwd, error) {
+	params := PathParams(req)
+	fwd := &driver.PortFwd{Protocol: params["protocol"]}
+	if fwd.IsPath() {
+		fwd.HostPath = req.URL.Query().Get("path")
+		if fwd.HostPath == "" {
+			return nil, errors.New("host path is required for " + fwd.Protocol + " port forwards")
+		}
+		return fwd, nil
 	}
+	port, err := strconv.Atoi(params["port"])
+	if err != nil {
+		return nil, errors.New("invalid port '" + params["port"] + "'")
+	}
+	fwd.Port = port
+	return fwd, nil
 }
 
-func (r *RegexpHandler) handlePortForwards(writ http.ResponseWriter, req *http.Request) {
-	switch req.Method {
-	case "GET":
-		r.logger.Debug("full portforward list")
-		r.listPortFwds(writ, "")
-	case "DELETE":
-		r.netLock.Lock()
-		defer r.netLock.Unlock()
-		r.logger.Debug("prune inactive portforwards")
-		r.prunePortFwds(writ)
-	default:
-		r.notFound(writ)
+func (r *ApiHandler) listPortFwdConnections(writ http.ResponseWriter, req *http.Request) {
+	logger := r.requestLogger(req)
+	fwd, err := connectionPortFwd(req)
+	if err != nil {
+		logger.Debug("portforward parse failed", "error", err)
+		r.errorCode(writ, err.Error(), driver.ERROR_INVALID_INPUT)
+		return
+	}
+	logger.Debug("portforward connections list", "protocol", fwd.Protocol, "port", fwd.Port,
+		"path", fwd.HostPath)
+	conns, err := r.api.driver().InternalPortFwdConnections(req.Context(), fwd)
+	if err != nil {
+		logger.Debug("portforward connections error", "error", err)
+		r.driverError(writ, err)
+		return
 	}
+	r.respond(writ, conns, 200)
 }
 
-func (r *RegexpHandler) prunePortFwds(writ http.ResponseWriter) {
-	err := r.api.Driver.PrunePortFwds(r.api.Driver.PortFwds, r.api.Driver.DeletePortFwd)
+func (r *ApiHandler) closePortFwdConnection(writ http.ResponseWriter, req *http.Request) {
+	logger := r.requestLogger(req)
+	connectionId := PathParams(req)["connection_id"]
+	fwd, err := connectionPortFwd(req)
 	if err != nil {
-		r.logger.Debug("portforward prune failed", "error", err)
+		logger.Debug("portforward parse failed", "error", err)
+		r.errorCode(writ, err.Error(), driver.ERROR_INVALID_INPUT)
+		return
+	}
+	logger.Debug("portforward connection close", "protocol", fwd.Protocol, "port", fwd.Port,
+		"path", fwd.HostPath, "connection", connectionId)
+	id, err := strconv.ParseInt(connectionId, 10, 64)
+	if err != nil {
+		logger.Debug("portforward connection id parse failed", "connection", connectionId, "error", err)
 		r.error(writ, err.Error(), 400)
 		return
 	}
+	err = r.api.driver().CloseInternalPortFwdConnection(req.Context(), fwd, id)
+	if err != nil {
+		logger.Debug("portforward connection close failure", "error", err)
+		r.driverError(writ, err)
+		return
+	}
 	r.respond(writ, nil, 204)
 }
 
-func (r *RegexpHandler) listPortFwds(writ http.ResponseWriter, slotNumber string) {
-	portfwds, err := r.api.Driver.PortFwds(slotNumber)
+func (r *ApiHandler) suggestPortFwds(writ http.ResponseWriter, req *http.Request) {
+	logger := r.requestLogger(req)
+	logger.Debug("portforward suggestion request")
+	query := req.URL.Query()
+	protocol := query.Get("protocol")
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	preferred := 0
+	if v := query.Get("preferred"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil {
+			logger.Debug("portforward suggestion preferred parse failed", "preferred", v, "error", err)
+			r.error(writ, "invalid preferred port value", 400)
+			return
+		}
+		preferred = p
+	}
+	count := 1
+	if v := query.Get("count"); v != "" {
+		c, err := strconv.Atoi(v)
+		if err != nil || c < 1 {
+			logger.Debug("portforward suggestion count parse failed", "count", v, "error", err)
+			r.error(writ, "invalid count value", 400)
+			return
+		}
+		count = c
+	}
+	drv := r.api.driver()
+	suggestions, err := drv.SuggestPortFwds(req.Context(), drv.PortFwds, protocol, preferred, count, r.api.portRange())
+	if err != nil {
+		logger.Debug("portforward suggestion failed", "error", err)
+		r.driverError(writ, err)
+		return
+	}
+	logger.Trace("portforward suggestions", "suggestions", suggestions)
+	r.respond(writ, suggestions, 200)
+}
+
+func (r *ApiHandler) prunePortFwds(writ http.ResponseWriter, req *http.Request) {
+	logger := r.requestLogger(req)
+	logger.Debug("prune inactive portforwards")
+	query := req.URL.Query()
+	// NOTE: port forwards with missing VMX paths have always been
+	// pruned so this must be explicitly disabled
+	opts := &driver.PruneOptions{PruneMissing: true}
+	for name, opt := range map[string]*bool{"dry_run": &opts.DryRun, "prune_missing": &opts.PruneMissing} {
+		v := query.Get(name)
+		if v == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			logger.Debug("portforward prune option parse failed", "option", name, "value", v, "error", err)
+			r.error(writ, "invalid "+name+" value", 400)
+			return
+		}
+		*opt = b
+	}
+	drv := r.api.driver()
+	result, err := drv.PrunePortFwds(req.Context(), drv.PortFwds, drv.DeletePortFwd, opts)
 	if err != nil {
-		r.logger.Debug("portforward error", "error", err)
-		r.error(writ, err.Error(), 400)
+		logger.Debug("portforward prune failed", "error", err)
+		r.driverError(writ, err)
 		return
 	}
-	r.logger.Trace("full portforward list", "fwds", portfwds)
+	logger.Debug("portforward prune complete", "dry-run", result.DryRun, "pruned", len(result.Pruned),
+		"kept", len(result.Kept))
+	r.respond(writ, result, 200)
+}
+
+func (r *ApiHandler) listAllPortFwds(writ http.ResponseWriter, req *http.Request) {
+	logger := r.requestLogger(req)
+	logger.Debug("full portforward list")
+	r.listPortFwds(writ, req, "")
+}
+
+func (r *ApiHandler) listDevicePortFwds(writ http.ResponseWriter, req *http.Request) {
+	logger := r.requestLogger(req)
+	slotNumber := PathParams(req)["vnet_slot"]
+	logger.Debug("portforward list", "slot", slotNumber)
+	r.listPortFwds(writ, req, slotNumber)
+}
+
+func (r *ApiHandler) listPortFwds(writ http.ResponseWriter, req *http.Request, slotNumber string) {
+	logger := r.requestLogger(req)
+	portfwds, err := r.api.driver().PortFwds(req.Context(), slotNumber)
+	if err != nil {
+		logger.Debug("portforward error", "error", err)
+		r.driverError(writ, err)
+		return
+	}
+	logger.Trace("full portforward list", "fwds", portfwds)
 	r.respond(writ, portfwds, 200)
 }
 
-func (r *RegexpHandler) applyPortFwd(writ http.ResponseWriter, req *http.Request, slotNumber string) {
+func (r *ApiHandler) applyPortFwd(writ http.ResponseWriter, req *http.Request) {
+	logger := r.requestLogger(req)
+	slotNumber := PathParams(req)["vnet_slot"]
+	logger.Debug("portforward request", "slot", slotNumber)
 	var portFwds []driver.PortFwd
 	var buf bytes.Buffer
 	tr := io.TeeReader(req.Body, &buf)
 	err := json.NewDecoder(tr).Decode(&portFwds)
 	if err != nil {
-		r.logger.Debug("portforward parse failed", "error", err)
-		r.logger.Debug("portforward re-parse attempt as non-collection")
+		logger.Debug("portforward parse failed", "error", err)
+		logger.Debug("portforward re-parse attempt as non-collection")
 		var pfwd driver.PortFwd
 		err = json.NewDecoder(bytes.NewReader(buf.Bytes())).Decode(&pfwd)
 		if err != nil {
-			r.logger.Debug("portforward re-parse failed", "error", err)
+			logger.Debug("portforward re-parse failed", "error", err)
 			r.error(writ, err.Error(), 400)
 			return
 		} else {
@@ -93,39 +199,42 @@ func (r *RegexpHandler) applyPortFwd(writ http.ResponseWriter, req *http.Request
 	}
 	slotNum, err := strconv.Atoi(slotNumber)
 	if err != nil {
-		r.logger.Debug("portforward slot parse failed", "slot", slotNumber, "error", err)
+		logger.Debug("portforward slot parse failed", "slot", slotNumber, "error", err)
 		r.error(writ, err.Error(), 400)
 		return
 	}
-	r.logger.Debug("apply port forwards", "port-forwards", portFwds)
+	logger.Debug("apply port forwards", "port-forwards", portFwds)
 	pfwds := []*driver.PortFwd{}
 	for i := 0; i < len(portFwds); i++ {
 		fwd := &portFwds[i]
 		fwd.SlotNumber = slotNum
 		pfwds = append(pfwds, fwd)
 	}
-	r.logger.Debug("adding port forwards", "fwds", pfwds)
-	err = r.api.Driver.AddPortFwd(pfwds)
+	logger.Debug("adding port forwards", "fwds", pfwds)
+	err = r.api.driver().AddPortFwd(req.Context(), pfwds)
 	if err != nil {
-		r.logger.Debug("portforward apply failure", "error", err)
-		r.error(writ, err.Error(), 400)
+		logger.Debug("portforward apply failure", "error", err)
+		r.driverError(writ, err)
 		return
 	}
 	r.respond(writ, portFwds, 200)
 }
 
-func (r *RegexpHandler) deletePortFwd(writ http.ResponseWriter, req *http.Request, slotNumber string) {
+func (r *ApiHandler) deletePortFwd(writ http.ResponseWriter, req *http.Request) {
+	logger := r.requestLogger(req)
+	slotNumber := PathParams(req)["vnet_slot"]
+	logger.Debug("portforward delete", "slot", slotNumber)
 	var portFwds []driver.PortFwd
 	var buf bytes.Buffer
 	tr := io.TeeReader(req.Body, &buf)
 	err := json.NewDecoder(tr).Decode(&portFwds)
 	if err != nil {
-		r.logger.Debug("portforward parse failed", "error", err)
-		r.logger.Debug("portforward re-parse attempt as non-collection")
+		logger.Debug("portforward parse failed", "error", err)
+		logger.Debug("portforward re-parse attempt as non-collection")
 		var pfwd driver.PortFwd
 		err = json.NewDecoder(bytes.NewReader(buf.Bytes())).Decode(&pfwd)
 		if err != nil {
-			r.logger.Debug("portforward re-parse failed", "error", err)
+			logger.Debug("portforward re-parse failed", "error", err)
 			r.error(writ, err.Error(), 400)
 			return
 		} else {
@@ -134,21 +243,21 @@ func (r *RegexpHandler) deletePortFwd(writ http.ResponseWriter, req *http.Reques
 	}
 	slotNum, err := strconv.Atoi(slotNumber)
 	if err != nil {
-		r.logger.Debug("portforward slot parse failed", "slot", slotNumber, "error", err)
+		logger.Debug("portforward slot parse failed", "slot", slotNumber, "error", err)
 		r.error(writ, err.Error(), 400)
 		return
 	}
-	r.logger.Debug("apply port forwards", "port-forwards", portFwds)
+	logger.Debug("apply port forwards", "port-forwards", portFwds)
 	pfwds := []*driver.PortFwd{}
 	for i := 0; i < len(portFwds); i++ {
 		fwd := &portFwds[i]
 		fwd.SlotNumber = slotNum
 		pfwds = append(pfwds, fwd)
 	}
-	err = r.api.Driver.DeletePortFwd(pfwds)
+	err = r.api.driver().DeletePortFwd(req.Context(), pfwds)
 	if err != nil {
-		r.logger.Debug("portforward delete failure", "error", err)
-		r.error(writ, err.Error(), 400)
+		logger.Debug("portforward delete failure", "error", err)
+		r.driverError(writ, err)
 		return
 	}
 	r.respond(writ, nil, 204)
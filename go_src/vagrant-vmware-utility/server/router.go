@@ -0,0 +1,158 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+// Wraps a handler to provide additional behavior
+type Middleware func(http.Handler) http.Handler
+
+type routerContextKey string
+
+const pathParamsKey routerContextKey = "path-params"
+
+type route struct {
+	path     string
+	pattern  *regexp.Regexp
+	handlers map[string]http.Handler
+}
+
+// Router matches request paths against routes in the order they
+// were registered and dispatches to the handler registered for
+// the request method.
+type Router struct {
+	// Handler used when no route matches the request path
+	NotFound http.Handler
+	// Handler used when a route matches the request path but no
+	// handler is registered for the request method. The Allow
+	// header is set before this handler is called.
+	MethodNotAllowed http.Handler
+
+	prefix     string
+	routes     []*route
+	middleware []Middleware
+	logger     hclog.Logger
+}
+
+// Create a new router. If prefix is provided, routes will match
+// paths both with and without the prefix.
+func NewRouter(prefix string, logger hclog.Logger) *Router {
+	return &Router{
+		NotFound: http.NotFoundHandler(),
+		MethodNotAllowed: http.HandlerFunc(func(writ http.ResponseWriter, req *http.Request) {
+			http.Error(writ, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}),
+		prefix: prefix,
+		logger: logger.Named("router"),
+	}
+}
+
+// Add middleware applied to all requests, including those which
+// do not match a route
+func (r *Router) Use(middleware ...Middleware) {
+	r.middleware = append(r.middleware, middleware...)
+}
+
+// Register a handler for the method on the given path. The path is a
+// regular expression and named groups are available via PathParams.
+// Middleware is applied in the order provided.
+func (r *Router) Handle(method, path string, handler http.HandlerFunc, middleware ...Middleware) error {
+	var rt *route
+	for _, existing := range r.routes {
+		if existing.path == path {
+			rt = existing
+			break
+		}
+	}
+	if rt == nil {
+		expr := `^` + path + `$`
+		if r.prefix != "" {
+			expr = `^(?:` + regexp.QuoteMeta(r.prefix) + `)?` + path + `$`
+		}
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			r.logger.Error("failed to compile route path", "path", path, "error", err)
+			return err
+		}
+		rt = &route{
+			path:     path,
+			pattern:  pattern,
+			handlers: map[string]http.Handler{},
+		}
+		r.routes = append(r.routes, rt)
+	}
+	if _, ok := rt.handlers[method]; ok {
+		return fmt.Errorf("handler already registered for %s %s", method, path)
+	}
+	var h http.Handler = handler
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	rt.handlers[method] = h
+	r.logger.Trace("registered route", "method", method, "path", path)
+	return nil
+}
+
+func (r *Router) ServeHTTP(writ http.ResponseWriter, req *http.Request) {
+	var h http.Handler = http.HandlerFunc(r.dispatch)
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		h = r.middleware[i](h)
+	}
+	h.ServeHTTP(writ, req)
+}
+
+func (r *Router) dispatch(writ http.ResponseWriter, req *http.Request) {
+	for _, rt := range r.routes {
+		match := rt.pattern.FindStringSubmatch(req.URL.Path)
+		if match == nil {
+			continue
+		}
+		handler, ok := rt.handlers[req.Method]
+		if !ok {
+			allow := strings.Join(rt.methods(), ", ")
+			r.logger.Debug("method not allowed", "method", req.Method, "path", req.URL.Path, "allow", allow)
+			writ.Header().Set("Allow", allow)
+			r.MethodNotAllowed.ServeHTTP(writ, req)
+			return
+		}
+		params := map[string]string{}
+		for i, name := range rt.pattern.SubexpNames() {
+			if i == 0 || name == "" {
+				continue
+			}
+			params[name] = match[i]
+		}
+		ctx := context.WithValue(req.Context(), pathParamsKey, params)
+		handler.ServeHTTP(writ, req.WithContext(ctx))
+		return
+	}
+	r.NotFound.ServeHTTP(writ, req)
+}
+
+func (rt *route) methods() []string {
+	methods := []string{}
+	for method := range rt.handlers {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// Named parameters extracted from the request path
+func PathParams(req *http.Request) map[string]string {
+	params, ok := req.Context().Value(pathParamsKey).(map[string]string)
+	if !ok {
+		return map[string]string{}
+	}
+	return params
+}
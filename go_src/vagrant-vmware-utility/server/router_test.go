@@ -0,0 +1,118 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+func testRouter(t *testing.T) *Router {
+	r := NewRouter("/v2", hclog.NewNullLogger())
+	respond := func(body string) http.HandlerFunc {
+		return func(writ http.ResponseWriter, req *http.Request) {
+			writ.Write([]byte(body + ":" + PathParams(req)["name"]))
+		}
+	}
+	if err := r.Handle("POST", `/vmnet/verify`, respond("verify")); err != nil {
+		t.Fatalf("Failed to register route: %s", err)
+	}
+	if err := r.Handle("GET", `/vmnet/(?P<name>[^/]+)`, respond("get")); err != nil {
+		t.Fatalf("Failed to register route: %s", err)
+	}
+	if err := r.Handle("DELETE", `/vmnet/(?P<name>[^/]+)`, respond("delete")); err != nil {
+		t.Fatalf("Failed to register route: %s", err)
+	}
+	return r
+}
+
+func TestRouterMatch(t *testing.T) {
+	r := testRouter(t)
+	for _, c := range []struct {
+		method, path, body string
+	}{
+		{"POST", "/vmnet/verify", "verify:"},
+		{"GET", "/vmnet/vmnet2", "get:vmnet2"},
+		{"GET", "/v2/vmnet/vmnet3", "get:vmnet3"},
+		{"DELETE", "/vmnet/vmnet2", "delete:vmnet2"},
+	} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(c.method, c.path, nil))
+		if rec.Code != 200 {
+			t.Errorf("Invalid status for %s %s: %d", c.method, c.path, rec.Code)
+		}
+		if rec.Body.String() != c.body {
+			t.Errorf("Invalid body for %s %s: '%s' != '%s'", c.method, c.path, rec.Body.String(), c.body)
+		}
+	}
+}
+
+func TestRouterMethodNotAllowed(t *testing.T) {
+	r := testRouter(t)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("PUT", "/vmnet/vmnet2", nil))
+	if rec.Code != 405 {
+		t.Errorf("Invalid status code %d != 405", rec.Code)
+	}
+	if allow := rec.Header().Get("Allow"); allow != "DELETE, GET" {
+		t.Errorf("Invalid Allow header '%s'", allow)
+	}
+
+	// The verify route only allows POST and must not fall
+	// through to the device route
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/vmnet/verify", nil))
+	if rec.Code != 405 {
+		t.Errorf("Invalid status code %d != 405", rec.Code)
+	}
+	if allow := rec.Header().Get("Allow"); allow != "POST" {
+		t.Errorf("Invalid Allow header '%s'", allow)
+	}
+}
+
+func TestRouterNotFound(t *testing.T) {
+	r := testRouter(t)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/unknown", nil))
+	if rec.Code != 404 {
+		t.Errorf("Invalid status code %d != 404", rec.Code)
+	}
+}
+
+func TestRouterMiddleware(t *testing.T) {
+	r := NewRouter("", hclog.NewNullLogger())
+	order := []string{}
+	mw := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(writ http.ResponseWriter, req *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(writ, req)
+			})
+		}
+	}
+	r.Use(mw("global"))
+	err := r.Handle("GET", `/status`, func(writ http.ResponseWriter, req *http.Request) {
+		order = append(order, "handler")
+	}, mw("first"), mw("second"))
+	if err != nil {
+		t.Fatalf("Failed to register route: %s", err)
+	}
+	if err := r.Handle("GET", `/status`, func(http.ResponseWriter, *http.Request) {}); err == nil {
+		t.Errorf("Expected error registering duplicate route")
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/status", nil))
+	expected := []string{"global", "first", "second", "handler"}
+	if len(order) != len(expected) {
+		t.Fatalf("Invalid middleware order %v != %v", order, expected)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("Invalid middleware order %v != %v", order, expected)
+			break
+		}
+	}
+}
@@ -20,44 +20,32 @@ type VagrantVmwareValidate struct {
 	Features []LicenseFeature `json:"features"`
 }
 
-func (r *RegexpHandler) handleVmwareInfo(writ http.ResponseWriter, req *http.Request) {
-	switch req.Method {
-	case "GET":
-		r.logger.Debug("vmware info")
-		r.getVmwareInfo(writ)
-	default:
-		r.notFound(writ)
-	}
-}
-
-func (r *RegexpHandler) handleVmwarePaths(writ http.ResponseWriter, req *http.Request) {
-	switch req.Method {
-	case "GET":
-		r.logger.Debug("vmware paths")
-		paths, err := utility.LoadVmwarePaths(r.logger)
-		if err != nil {
-			r.error(writ, err.Error(), 400)
-			return
-		}
-		r.respond(writ, paths, 200)
-	default:
-		r.notFound(writ)
+func (r *ApiHandler) getVmwarePaths(writ http.ResponseWriter, req *http.Request) {
+	logger := r.requestLogger(req)
+	logger.Debug("vmware paths")
+	paths, err := utility.LoadVmwarePaths(r.logger)
+	if err != nil {
+		r.driverError(writ, err)
+		return
 	}
+	r.respond(writ, paths, 200)
 }
 
-func (r *RegexpHandler) getVmwareInfo(writ http.ResponseWriter) {
-	info, err := r.api.Driver.VmwareInfo()
+func (r *ApiHandler) getVmwareInfo(writ http.ResponseWriter, req *http.Request) {
+	logger := r.requestLogger(req)
+	logger.Debug("vmware info")
+	info, err := r.api.driver().VmwareInfo(req.Context())
 	if err != nil {
-		r.logger.Debug("vmware info error", "error", err)
-		r.error(writ, err.Error(), 400)
+		logger.Debug("vmware info error", "error", err)
+		r.driverError(writ, err)
 		return
 	}
-	r.logger.Trace("vmware version info", "version", info.Version, "product", info.Product,
+	logger.Trace("vmware version info", "version", info.Version, "product", info.Product,
 		"type", info.Type, "build", info.Build)
 	r.respond(writ, info, 200)
 }
 
-func (r *RegexpHandler) hasValidFeature(vmware *driver.VmwareInfo, vagrant *VagrantVmwareValidate) bool {
+func (r *ApiHandler) hasValidFeature(vmware *driver.VmwareInfo, vagrant *VagrantVmwareValidate) bool {
 	vmwareVersionParts := strings.Split(vmware.Version, ".")
 	if len(vmwareVersionParts) < 1 {
 		r.logger.Trace("failed to split vmware version", "vmware-version", vmware.Version)
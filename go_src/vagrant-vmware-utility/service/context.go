@@ -0,0 +1,26 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Maximum durations allowed for operations performed by the
+// VMware helper executables
+const VNETLIB_TIMEOUT = 2 * time.Minute
+const VMNET_CLI_TIMEOUT = 5 * time.Minute
+const VMRUN_TIMEOUT = 30 * time.Second
+
+// Error for a failed command. If the command failed due to the
+// context being canceled or timing out, the cause is included.
+func commandError(ctx context.Context, msg string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+	return errors.New(msg)
+}
@@ -7,6 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"os/exec"
+	"regexp"
+	"strconv"
 
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
@@ -14,9 +16,20 @@ import (
 
 const LAUNCHCTL_PATH = `/bin/launchctl`
 
+var launchctlPidPattern = regexp.MustCompile(`"PID"\s*=\s*(\d+);`)
+
 type Launchctl interface {
 	Load(servicePath string) (err error)
 	Unload(servicePath string) (err error)
+	Status(label string) (status *LaunchctlStatus, err error)
+	Restart(label string) (err error)
+}
+
+// Current state of a launchd job
+type LaunchctlStatus struct {
+	Loaded  bool
+	Running bool
+	Pid     int
 }
 
 type LaunchctlExe struct {
@@ -65,6 +78,46 @@ func (l *LaunchctlExe) Unload(servicePath string) error {
 	return nil
 }
 
+func (l *LaunchctlExe) Status(label string) (*LaunchctlStatus, error) {
+	l.logger.Debug("checking service status", "label", label)
+	lcmd, err := l.launchctl("list", label)
+	if err != nil {
+		l.logger.Debug("command generation failure", "error", err)
+		return nil, err
+	}
+	status := &LaunchctlStatus{}
+	exitCode, out := utility.ExecuteWithOutput(lcmd)
+	if exitCode != 0 {
+		// Job is not loaded
+		l.logger.Debug("service status not found", "label", label, "exitcode", exitCode, "output", out)
+		return status, nil
+	}
+	status.Loaded = true
+	if match := launchctlPidPattern.FindStringSubmatch(out); match != nil {
+		status.Pid, _ = strconv.Atoi(match[1])
+		status.Running = status.Pid > 0
+	}
+	l.logger.Debug("service status", "label", label, "running", status.Running, "pid", status.Pid)
+	return status, nil
+}
+
+func (l *LaunchctlExe) Restart(label string) error {
+	l.logger.Debug("restarting service", "label", label)
+	lcmd, err := l.launchctl("kickstart", "-k", "system/"+label)
+	if err != nil {
+		l.logger.Debug("command generation failure", "error", err)
+		return err
+	}
+	exitCode, out := utility.ExecuteWithOutput(lcmd)
+	if exitCode != 0 {
+		l.logger.Debug("service restart failure", "exitcode", exitCode, "output", out)
+		return errors.New(fmt.Sprintf(
+			"Failed to restart service: %s", label))
+	}
+	l.logger.Debug("service restarted", "label", label)
+	return nil
+}
+
 func (l *LaunchctlExe) launchctl(args ...string) (*exec.Cmd, error) {
 	if !utility.RootOwned(LAUNCHCTL_PATH, true) {
 		return nil, errors.New("Failed to locate valid launchctl executable")
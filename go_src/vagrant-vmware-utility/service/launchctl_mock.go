@@ -4,10 +4,14 @@
 package service
 
 type LaunchctlMock struct {
-	LoadResponses   []error
-	LoadRequests    []string
-	UnloadResponses []error
-	UnloadRequests  []string
+	LoadResponses    []error
+	LoadRequests     []string
+	UnloadResponses  []error
+	UnloadRequests   []string
+	StatusResponses  []*LaunchctlStatus
+	StatusRequests   []string
+	RestartResponses []error
+	RestartRequests  []string
 }
 
 func (l *LaunchctlMock) Load(p string) (err error) {
@@ -27,3 +31,22 @@ func (l *LaunchctlMock) Unload(p string) (err error) {
 	l.UnloadRequests = append(l.UnloadRequests, p)
 	return
 }
+
+func (l *LaunchctlMock) Status(label string) (status *LaunchctlStatus, err error) {
+	status = &LaunchctlStatus{}
+	if len(l.StatusResponses) > 0 {
+		status = l.StatusResponses[0]
+		l.StatusResponses = l.StatusResponses[1:]
+	}
+	l.StatusRequests = append(l.StatusRequests, label)
+	return
+}
+
+func (l *LaunchctlMock) Restart(label string) (err error) {
+	if len(l.RestartResponses) > 0 {
+		err = l.RestartResponses[0]
+		l.RestartResponses = l.RestartResponses[1:]
+	}
+	l.RestartRequests = append(l.RestartRequests, label)
+	return
+}
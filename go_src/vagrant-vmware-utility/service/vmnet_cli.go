@@ -4,24 +4,25 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"io"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path"
 	"runtime"
+	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
 )
 
 type VmnetCli interface {
-	Start() (err error)
-	Stop() (err error)
-	Status() bool
-	Restart() (err error)
-	Configure(path string) (err error)
+	Start(ctx context.Context) (err error)
+	Stop(ctx context.Context) (err error)
+	Status(ctx context.Context) bool
+	Restart(ctx context.Context) (err error)
+	Configure(ctx context.Context, path string) (err error)
 }
 
 type VmnetCliExe struct {
@@ -41,51 +42,67 @@ func NewVmnetCli(path string, services VmwareServices, logger hclog.Logger) (Vmn
 		logger:   logger}, nil
 }
 
-func (v *VmnetCliExe) Start() (err error) {
-	if v.Status() {
-		v.logger.Debug("start ignored - service running")
+func (v *VmnetCliExe) Start(ctx context.Context) (err error) {
+	defer utility.AuditDeferred(ctx, "vmnet-cli.start", nil, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VMNET_CLI_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	if v.Status(ctx) {
+		logger.Debug("start ignored - service running")
 		return nil
 	}
 	v.Services.WrapOpenServices(func() {
-		err = v.start()
+		err = v.start(ctx)
 	})
 	return err
 }
 
-func (v *VmnetCliExe) Stop() (err error) {
+func (v *VmnetCliExe) Stop(ctx context.Context) (err error) {
+	defer utility.AuditDeferred(ctx, "vmnet-cli.stop", nil, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VMNET_CLI_TIMEOUT)
+	defer cancel()
 	v.Services.WrapOpenServices(func() {
-		err = v.stop()
+		err = v.stop(ctx)
 	})
 	return err
 }
 
-func (v *VmnetCliExe) Status() bool {
-	cmd := exec.Command(v.ExePath, "--status")
+func (v *VmnetCliExe) Status(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, VMNET_CLI_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	cmd := utility.CommandContext(ctx, v.ExePath, "--status")
 	if utility.Execute(cmd) == 0 {
-		v.logger.Debug("service status", "state", "running")
+		logger.Debug("service status", "state", "running")
 		return true
 	}
-	v.logger.Debug("service status", "state", "stopped")
+	logger.Debug("service status", "state", "stopped")
 	return false
 }
 
-func (v *VmnetCliExe) Restart() (err error) {
+func (v *VmnetCliExe) Restart(ctx context.Context) (err error) {
+	defer utility.AuditDeferred(ctx, "vmnet-cli.restart", nil, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VMNET_CLI_TIMEOUT)
+	defer cancel()
 	v.Services.WrapOpenServices(func() {
-		err = v.stop()
+		err = v.stop(ctx)
 		if err != nil {
 			return
 		}
-		err = v.start()
+		err = v.start(ctx)
 	})
 	return err
 }
 
-func (v *VmnetCliExe) Configure(path string) (err error) {
-	cmd := exec.Command(v.ExePath)
-	cmd.Args = []string{v.ExePath, "--configure"}
+func (v *VmnetCliExe) Configure(ctx context.Context, path string) (err error) {
+	defer utility.AuditDeferred(ctx, "vmnet-cli.configure", map[string]interface{}{"path": path}, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VMNET_CLI_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	cmd := utility.CommandContext(ctx, v.ExePath, "--configure")
 	if runtime.GOOS == "linux" {
 		if path == "" {
-			v.logger.Debug("received empty path for configure, ignoring")
+			logger.Debug("received empty path for configure, ignoring")
 			return
 		}
 		cpy, err := v.copyFile(path)
@@ -93,46 +110,48 @@ func (v *VmnetCliExe) Configure(path string) (err error) {
 			return err
 		}
 		defer os.Remove(cpy)
-		v.logger.Debug("configure via migrate settings", "path", cpy)
-		cmd = exec.Command(v.ExePath, "--migrate-network-settings", cpy)
+		logger.Debug("configure via migrate settings", "path", cpy)
+		cmd = utility.CommandContext(ctx, v.ExePath, "--migrate-network-settings", cpy)
 	}
 	v.Services.WrapOpenServices(func() {
-		_ = v.stop()
-		v.logger.Debug("configuring service")
+		_ = v.stop(ctx)
+		logger.Debug("configuring service")
 		exitCode, out := utility.ExecuteWithOutput(cmd)
-		if exitCode != 0 {
-			v.logger.Debug("service configure failed", "exitcode", exitCode)
-			v.logger.Trace("service failure", "output", out)
-			err = errors.New("Failed to configure vmnet service")
+		if exitCode != 0 || ctx.Err() != nil {
+			logger.Debug("service configure failed", "exitcode", exitCode)
+			logger.Trace("service failure", "output", out)
+			err = commandError(ctx, "Failed to configure vmnet service")
 		}
 	})
 	return err
 }
 
-func (v *VmnetCliExe) stop() (err error) {
-	v.logger.Debug("stopping service")
-	cmd := exec.Command(v.ExePath, "--stop")
+func (v *VmnetCliExe) stop(ctx context.Context) (err error) {
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("stopping service")
+	cmd := utility.CommandContext(ctx, v.ExePath, "--stop")
 	exitCode, out := utility.ExecuteWithOutput(cmd)
-	if exitCode != 0 {
-		v.logger.Debug("service stop failed", "exitcode", exitCode)
-		v.logger.Trace("service failure", "output", out)
-		err = errors.New("Failed to stop vmnet service")
+	if exitCode != 0 || ctx.Err() != nil {
+		logger.Debug("service stop failed", "exitcode", exitCode)
+		logger.Trace("service failure", "output", out)
+		err = commandError(ctx, "Failed to stop vmnet service")
 	}
 	// Ensure things are dead
-	cmd = exec.Command("/usr/bin/pkill", "vmnet-natd", "vmnet-bridge", "vmnet-dhcpd")
+	cmd = utility.CommandContext(ctx, "/usr/bin/pkill", "vmnet-natd", "vmnet-bridge", "vmnet-dhcpd")
 	exitCode, _ = utility.ExecuteWithOutput(cmd)
-	v.logger.Trace("service orphan cleanup", "exitcode", exitCode)
+	logger.Trace("service orphan cleanup", "exitcode", exitCode)
 	return err
 }
 
-func (v *VmnetCliExe) start() (err error) {
-	v.logger.Debug("starting service")
-	cmd := exec.Command(v.ExePath, "--start")
+func (v *VmnetCliExe) start(ctx context.Context) (err error) {
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("starting service")
+	cmd := utility.CommandContext(ctx, v.ExePath, "--start")
 	exitCode, out := utility.ExecuteWithOutput(cmd)
-	if exitCode != 0 {
-		v.logger.Debug("service start failed", "exitcode", exitCode)
-		v.logger.Trace("service failure", "output", out)
-		err = errors.New("Failed to start vmnet service")
+	if exitCode != 0 || ctx.Err() != nil {
+		logger.Debug("service start failed", "exitcode", exitCode)
+		logger.Trace("service failure", "output", out)
+		err = commandError(ctx, "Failed to start vmnet service")
 	}
 	return err
 }
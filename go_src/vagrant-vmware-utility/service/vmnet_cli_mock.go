@@ -3,6 +3,8 @@
 
 package service
 
+import "context"
+
 type VmnetCliMock struct {
 	StartResponses     []error
 	StopResponses      []error
@@ -13,7 +15,7 @@ type VmnetCliMock struct {
 	ConfigureRequests []string
 }
 
-func (v *VmnetCliMock) Start() (err error) {
+func (v *VmnetCliMock) Start(ctx context.Context) (err error) {
 	if len(v.StartResponses) > 0 {
 		err = v.StartResponses[0]
 		v.StartResponses = v.StartResponses[1:]
@@ -21,7 +23,7 @@ func (v *VmnetCliMock) Start() (err error) {
 	return
 }
 
-func (v *VmnetCliMock) Stop() (err error) {
+func (v *VmnetCliMock) Stop(ctx context.Context) (err error) {
 	if len(v.StopResponses) > 0 {
 		err = v.StopResponses[0]
 		v.StopResponses = v.StopResponses[1:]
@@ -29,7 +31,7 @@ func (v *VmnetCliMock) Stop() (err error) {
 	return
 }
 
-func (v *VmnetCliMock) Status() (s bool) {
+func (v *VmnetCliMock) Status(ctx context.Context) (s bool) {
 	s = true
 	if len(v.StatusResponses) > 0 {
 		s = v.StatusResponses[0]
@@ -38,7 +40,7 @@ func (v *VmnetCliMock) Status() (s bool) {
 	return s
 }
 
-func (v *VmnetCliMock) Restart() (err error) {
+func (v *VmnetCliMock) Restart(ctx context.Context) (err error) {
 	if len(v.RestartResponses) > 0 {
 		err = v.RestartResponses[0]
 		v.RestartResponses = v.RestartResponses[1:]
@@ -46,7 +48,7 @@ func (v *VmnetCliMock) Restart() (err error) {
 	return
 }
 
-func (v *VmnetCliMock) Configure(path string) (err error) {
+func (v *VmnetCliMock) Configure(ctx context.Context, path string) (err error) {
 	if len(v.ConfigureResponses) > 0 {
 		err = v.ConfigureResponses[0]
 		v.ConfigureResponses = v.ConfigureResponses[1:]
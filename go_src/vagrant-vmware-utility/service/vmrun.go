@@ -4,8 +4,8 @@
 package service
 
 import (
+	"context"
 	"errors"
-	"os/exec"
 	"strings"
 
 	hclog "github.com/hashicorp/go-hclog"
@@ -13,7 +13,7 @@ import (
 )
 
 type Vmrun interface {
-	RunningVms() ([]*Vm, error)
+	RunningVms(ctx context.Context) ([]*Vm, error)
 }
 
 type VmrunExe struct {
@@ -36,20 +36,23 @@ func NewVmrun(path string, logger hclog.Logger) (Vmrun, error) {
 		logger:  logger}, nil
 }
 
-func (v *VmrunExe) RunningVms() ([]*Vm, error) {
+func (v *VmrunExe) RunningVms(ctx context.Context) ([]*Vm, error) {
+	ctx, cancel := context.WithTimeout(ctx, VMRUN_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
 	result := []*Vm{}
-	cmd := exec.Command(v.exePath, "list")
+	cmd := utility.CommandContext(ctx, v.exePath, "list")
 	exitCode, out := utility.ExecuteWithOutput(cmd)
-	if exitCode != 0 {
-		v.logger.Debug("vmrun list failed", "exitcode", exitCode)
-		v.logger.Trace("vmrun list failed", "output", out)
-		return result, errors.New("Failed to list running VMs")
+	if exitCode != 0 || ctx.Err() != nil {
+		logger.Debug("vmrun list failed", "exitcode", exitCode)
+		logger.Trace("vmrun list failed", "output", out)
+		return result, commandError(ctx, "Failed to list running VMs")
 	}
 	for _, line := range strings.Split(out, "\n") {
 		line = strings.TrimSpace(line)
-		v.logger.Trace("vmrun path check", "path", line)
+		logger.Trace("vmrun path check", "path", line)
 		if utility.FileExists(line) {
-			v.logger.Trace("vmrun path valid", "path", line)
+			logger.Trace("vmrun path valid", "path", line)
 			result = append(result, &Vm{Path: line, vmrun: v})
 		}
 	}
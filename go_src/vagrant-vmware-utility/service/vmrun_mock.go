@@ -3,6 +3,8 @@
 
 package service
 
+import "context"
+
 type VmrunMock struct {
 	Responses []*VmrunResponse
 }
@@ -25,7 +27,7 @@ func (v *VmrunMock) GenerateResponse() {
 	v.Responses = append(v.Responses, r)
 }
 
-func (v *VmrunMock) RunningVms() (r []*Vm, err error) {
+func (v *VmrunMock) RunningVms(ctx context.Context) (r []*Vm, err error) {
 	if len(v.Responses) > 0 {
 		result := v.Responses[0]
 		v.Responses = v.Responses[1:]
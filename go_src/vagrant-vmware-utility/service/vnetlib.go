@@ -4,8 +4,10 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
 
@@ -13,26 +15,26 @@ import (
 )
 
 type Vnetlib interface {
-	CreateDevice(newName string) (devName string, err error)
-	DeleteDevice(devName string) (err error)
-	SetSubnetAddress(devName string, addr string) (err error)
-	SetSubnetMask(devName string, mask string) (err error)
-	SetNAT(devName string, enable bool) error
-	UpdateDeviceNAT(devName string) (err error)
-	StatusNAT(devName string) bool
-	StartNAT(devName string) (err error)
-	StopNAT(devName string) (err error)
-	SetDHCP(devName string, enable bool) error
-	StatusDHCP(devName string) bool
-	StartDHCP(devName string) (err error)
-	StopDHCP(devName string) (err error)
-	LookupReservedAddress(device, mac string) (addr string, err error)
-	ReserveAddress(device, mac, ip string) (err error)
-	EnableDevice(devName string) (err error)
-	DisableDevice(devName string) (err error)
-	UpdateDevice(devName string) (err error)
-	DeletePortFwd(device, protocol, hostPort string) (err error)
-	GetUnusedDevice() (devName string, err error)
+	CreateDevice(ctx context.Context, newName string) (devName string, err error)
+	DeleteDevice(ctx context.Context, devName string) (err error)
+	SetSubnetAddress(ctx context.Context, devName string, addr string) (err error)
+	SetSubnetMask(ctx context.Context, devName string, mask string) (err error)
+	SetNAT(ctx context.Context, devName string, enable bool) error
+	UpdateDeviceNAT(ctx context.Context, devName string) (err error)
+	StatusNAT(ctx context.Context, devName string) bool
+	StartNAT(ctx context.Context, devName string) (err error)
+	StopNAT(ctx context.Context, devName string) (err error)
+	SetDHCP(ctx context.Context, devName string, enable bool) error
+	StatusDHCP(ctx context.Context, devName string) bool
+	StartDHCP(ctx context.Context, devName string) (err error)
+	StopDHCP(ctx context.Context, devName string) (err error)
+	LookupReservedAddress(ctx context.Context, device, mac string) (addr string, err error)
+	ReserveAddress(ctx context.Context, device, mac, ip string) (err error)
+	EnableDevice(ctx context.Context, devName string) (err error)
+	DisableDevice(ctx context.Context, devName string) (err error)
+	UpdateDevice(ctx context.Context, devName string) (err error)
+	DeletePortFwd(ctx context.Context, device, protocol, hostPort string) (err error)
+	GetUnusedDevice(ctx context.Context) (devName string, err error)
 }
 
 type VnetlibExe struct {
@@ -53,274 +55,350 @@ func NewVnetlib(path string, services VmwareServices, logger hclog.Logger) (Vnet
 }
 
 // Device modifications
-func (v *VnetlibExe) CreateDevice(newName string) (devName string, err error) {
+func (v *VnetlibExe) CreateDevice(ctx context.Context, newName string) (devName string, err error) {
+	defer utility.AuditDeferred(ctx, "vnetlib.create_device", map[string]interface{}{"name": newName}, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
 	if newName == "" {
-		devName, err = v.GetUnusedDevice()
+		devName, err = v.GetUnusedDevice(ctx)
 		if err != nil {
 			return devName, err
 		}
 	} else {
 		devName = newName
 	}
-	v.logger.Debug("create new device", "device", devName)
+	logger.Debug("create new device", "device", devName)
 	v.Services.WrapOpenServices(func() {
-		exitCode, out := v.addDevice(devName)
-		if exitCode == 0 {
-			v.logger.Debug("create device failed", "device", devName, "exitcode", exitCode)
-			v.logger.Trace("create device failed", "device", devName, "output", out)
-			err = errors.New("Failed to create new device")
+		exitCode, out := v.addDevice(ctx, devName)
+		if exitCode == 0 || ctx.Err() != nil {
+			logger.Debug("create device failed", "device", devName, "exitcode", exitCode)
+			logger.Trace("create device failed", "device", devName, "output", out)
+			err = commandError(ctx, "Failed to create new device")
 		}
 	})
 	return devName, err
 }
 
-func (v *VnetlibExe) DeleteDevice(devName string) (err error) {
-	v.logger.Debug("delete device", "device", devName)
+func (v *VnetlibExe) DeleteDevice(ctx context.Context, devName string) (err error) {
+	defer utility.AuditDeferred(ctx, "vnetlib.delete_device", map[string]interface{}{"device": devName}, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("delete device", "device", devName)
 	v.Services.WrapOpenServices(func() {
-		exitCode, out := v.removeDevice(devName)
-		if exitCode == 0 {
-			v.logger.Debug("delete device failed", "device", devName, "exitcode", exitCode)
-			v.logger.Debug("delete device failed", "device", devName, "output", out)
-			err = errors.New("Failed to delete device")
+		exitCode, out := v.removeDevice(ctx, devName)
+		if exitCode == 0 || ctx.Err() != nil {
+			logger.Debug("delete device failed", "device", devName, "exitcode", exitCode)
+			logger.Debug("delete device failed", "device", devName, "output", out)
+			err = commandError(ctx, "Failed to delete device")
 		}
 	})
 	return err
 }
 
-func (v *VnetlibExe) SetSubnetAddress(devName string, addr string) (err error) {
-	v.logger.Debug("set subnet address", "device", devName, "address", addr)
+func (v *VnetlibExe) SetSubnetAddress(ctx context.Context, devName string, addr string) (err error) {
+	defer utility.AuditDeferred(ctx, "vnetlib.set_subnet_address", map[string]interface{}{"device": devName, "address": addr}, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("set subnet address", "device", devName, "address", addr)
 	v.Services.WrapOpenServices(func() {
-		exitCode, out := v.setSubnetAddr(devName, addr)
-		if exitCode == 0 {
-			v.logger.Debug("set subnet address failed", "device", devName, "address", addr, "exitcode", exitCode)
-			v.logger.Trace("set subnet address failed", "device", devName, "address", addr, "output", out)
-			err = errors.New("Failed to set subnet address")
+		exitCode, out := v.setSubnetAddr(ctx, devName, addr)
+		if exitCode == 0 || ctx.Err() != nil {
+			logger.Debug("set subnet address failed", "device", devName, "address", addr, "exitcode", exitCode)
+			logger.Trace("set subnet address failed", "device", devName, "address", addr, "output", out)
+			err = commandError(ctx, "Failed to set subnet address")
 		}
 	})
 	return err
 }
 
-func (v *VnetlibExe) SetSubnetMask(devName string, mask string) (err error) {
-	v.logger.Debug("set subnet mask", "device", devName, "mask", mask)
+func (v *VnetlibExe) SetSubnetMask(ctx context.Context, devName string, mask string) (err error) {
+	defer utility.AuditDeferred(ctx, "vnetlib.set_subnet_mask", map[string]interface{}{"device": devName, "mask": mask}, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("set subnet mask", "device", devName, "mask", mask)
 	v.Services.WrapOpenServices(func() {
-		exitCode, out := v.setSubnetMask(devName, mask)
-		if exitCode == 0 {
-			v.logger.Debug("set subnet mask failed", "device", devName, "mask", mask, "exitcode", exitCode)
-			v.logger.Trace("set subnet mask failed", "device", devName, "mask", mask, "output", out)
-			err = errors.New("Failed to set subnet mask")
+		exitCode, out := v.setSubnetMask(ctx, devName, mask)
+		if exitCode == 0 || ctx.Err() != nil {
+			logger.Debug("set subnet mask failed", "device", devName, "mask", mask, "exitcode", exitCode)
+			logger.Trace("set subnet mask failed", "device", devName, "mask", mask, "output", out)
+			err = commandError(ctx, "Failed to set subnet mask")
 		}
 	})
 	return err
 }
 
-func (v *VnetlibExe) SetNAT(devName string, enable bool) error {
-	v.logger.Debug("set NAT", "device", devName, "enable", enable)
+func (v *VnetlibExe) SetNAT(ctx context.Context, devName string, enable bool) (err error) {
+	defer utility.AuditDeferred(ctx, "vnetlib.set_nat", map[string]interface{}{"device": devName, "enable": enable}, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("set NAT", "device", devName, "enable", enable)
 	var exitCode int
 	var out string
 	v.Services.WrapOpenServices(func() {
 		if enable {
-			exitCode, out = v.enableNat(devName)
+			exitCode, out = v.enableNat(ctx, devName)
 		} else {
-			exitCode, out = v.disableNat(devName)
+			exitCode, out = v.disableNat(ctx, devName)
 		}
 	})
-	if exitCode == 0 {
-		v.logger.Debug("set NAT failed", "device", devName, "enable", enable, "exitcode", exitCode)
-		v.logger.Trace("set NAT failed", "device", devName, "output", out)
-		return errors.New("Failed to set NAT")
+	if exitCode == 0 || ctx.Err() != nil {
+		logger.Debug("set NAT failed", "device", devName, "enable", enable, "exitcode", exitCode)
+		logger.Trace("set NAT failed", "device", devName, "output", out)
+		return commandError(ctx, "Failed to set NAT")
 	}
 	return nil
 }
 
-func (v *VnetlibExe) SetDHCP(devName string, enable bool) error {
-	v.logger.Debug("set DHCP", "device", devName, "enable", enable)
+func (v *VnetlibExe) SetDHCP(ctx context.Context, devName string, enable bool) (err error) {
+	defer utility.AuditDeferred(ctx, "vnetlib.set_dhcp", map[string]interface{}{"device": devName, "enable": enable}, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("set DHCP", "device", devName, "enable", enable)
 	var exitCode int
 	var out string
 	v.Services.WrapOpenServices(func() {
 		if enable {
-			exitCode, out = v.enableDhcp(devName)
+			exitCode, out = v.enableDhcp(ctx, devName)
 		} else {
-			exitCode, out = v.disableDhcp(devName)
+			exitCode, out = v.disableDhcp(ctx, devName)
 		}
 	})
-	if exitCode == 0 {
-		v.logger.Debug("set DHCP failed", "device", devName, "enable", enable, "exitcode", exitCode)
-		v.logger.Trace("set DHCP failed", "device", devName, "output", out)
-		return errors.New("Failed to set DHCP")
+	if exitCode == 0 || ctx.Err() != nil {
+		logger.Debug("set DHCP failed", "device", devName, "enable", enable, "exitcode", exitCode)
+		logger.Trace("set DHCP failed", "device", devName, "output", out)
+		return commandError(ctx, "Failed to set DHCP")
 	}
 	return nil
 }
 
-func (v *VnetlibExe) LookupReservedAddress(device, mac string) (addr string, err error) {
-	v.logger.Debug("looking up dhcp reserved address", "device", device, "mac", mac)
-	exitCode, output := v.lookupReservedAddress(device, mac)
+func (v *VnetlibExe) LookupReservedAddress(ctx context.Context, device, mac string) (addr string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("looking up dhcp reserved address", "device", device, "mac", mac)
+	exitCode, output := v.lookupReservedAddress(ctx, device, mac)
 	if exitCode != 0 {
-		v.logger.Debug("dhcp address lookup failed", "device", device, "mac", mac, "error", output)
-		err = errors.New(fmt.Sprintf("No entry found for MAC %s", mac))
+		logger.Debug("dhcp address lookup failed", "device", device, "mac", mac, "error", output)
+		err = commandError(ctx, fmt.Sprintf("No entry found for MAC %s", mac))
 	} else {
 		addr = output
 	}
 	return addr, err
 }
 
-func (v *VnetlibExe) ReserveAddress(device, mac, ip string) (err error) {
-	v.logger.Debug("reserve dhcp address", "device", device, "mac", mac, "address", ip)
+func (v *VnetlibExe) ReserveAddress(ctx context.Context, device, mac, ip string) (err error) {
+	defer utility.AuditDeferred(ctx, "vnetlib.reserve_address", map[string]interface{}{"device": device, "mac": mac, "ip": ip}, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("reserve dhcp address", "device", device, "mac", mac, "address", ip)
 	v.Services.WrapOpenServices(func() {
-		exitCode, out := v.reserveAddress(device, mac, ip)
-		if exitCode == 0 {
-			v.logger.Debug("reserve dhcp address failed", "device", device,
+		exitCode, out := v.reserveAddress(ctx, device, mac, ip)
+		if exitCode == 0 || ctx.Err() != nil {
+			logger.Debug("reserve dhcp address failed", "device", device,
 				"mac", mac, "address", ip)
-			v.logger.Trace("reserve dhcp address failed", "device", device,
+			logger.Trace("reserve dhcp address failed", "device", device,
 				"output", out)
-			err = errors.New("Failed to reserve DHCP IP address")
+			err = commandError(ctx, "Failed to reserve DHCP IP address")
 		}
 	})
 	return err
 }
 
-func (v *VnetlibExe) EnableDevice(devName string) (err error) {
-	v.logger.Debug("enable device", "device", devName)
+func (v *VnetlibExe) EnableDevice(ctx context.Context, devName string) (err error) {
+	defer utility.AuditDeferred(ctx, "vnetlib.enable_device", map[string]interface{}{"device": devName}, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("enable device", "device", devName)
 	v.Services.WrapOpenServices(func() {
-		exitCode, out := v.enableDevice(devName)
-		if exitCode == 0 {
-			v.logger.Debug("enable device failed", "device", devName, "exitcode", exitCode)
-			v.logger.Trace("enable device failed", "device", devName, "output", out)
-			err = errors.New("Failed to enable device")
+		exitCode, out := v.enableDevice(ctx, devName)
+		if exitCode == 0 || ctx.Err() != nil {
+			logger.Debug("enable device failed", "device", devName, "exitcode", exitCode)
+			logger.Trace("enable device failed", "device", devName, "output", out)
+			err = commandError(ctx, "Failed to enable device")
 		}
 	})
 	return err
 }
 
-func (v *VnetlibExe) DisableDevice(devName string) (err error) {
-	v.logger.Debug("disable device", "device", devName)
+func (v *VnetlibExe) DisableDevice(ctx context.Context, devName string) (err error) {
+	defer utility.AuditDeferred(ctx, "vnetlib.disable_device", map[string]interface{}{"device": devName}, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("disable device", "device", devName)
 	v.Services.WrapOpenServices(func() {
-		exitCode, out := v.disableDevice(devName)
-		if exitCode == 0 {
-			v.logger.Debug("disable device failed", "device", devName, "exitcode", exitCode)
-			v.logger.Trace("disable device failed", "device", devName, "output", out)
-			err = errors.New("Failed to disable device")
+		exitCode, out := v.disableDevice(ctx, devName)
+		if exitCode == 0 || ctx.Err() != nil {
+			logger.Debug("disable device failed", "device", devName, "exitcode", exitCode)
+			logger.Trace("disable device failed", "device", devName, "output", out)
+			err = commandError(ctx, "Failed to disable device")
 		}
 	})
 	return err
 }
 
-func (v *VnetlibExe) UpdateDevice(devName string) (err error) {
-	v.logger.Debug("update device", "device", devName)
+func (v *VnetlibExe) UpdateDevice(ctx context.Context, devName string) (err error) {
+	defer utility.AuditDeferred(ctx, "vnetlib.update_device", map[string]interface{}{"device": devName}, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("update device", "device", devName)
 	v.Services.WrapOpenServices(func() {
-		exitCode, out := v.updateDevice(devName)
-		if exitCode == 0 {
-			v.logger.Debug("update device failed", "device", devName, "exitcode", exitCode)
-			v.logger.Trace("update device failed", "device", devName, "output", out)
-			err = errors.New("Failed to update device")
+		exitCode, out := v.updateDevice(ctx, devName)
+		if exitCode == 0 || ctx.Err() != nil {
+			logger.Debug("update device failed", "device", devName, "exitcode", exitCode)
+			logger.Trace("update device failed", "device", devName, "output", out)
+			err = commandError(ctx, "Failed to update device")
 		}
 	})
 	return err
 }
 
-func (v *VnetlibExe) UpdateDeviceNAT(devName string) (err error) {
-	v.logger.Debug("update device NAT", "device", devName)
+func (v *VnetlibExe) UpdateDeviceNAT(ctx context.Context, devName string) (err error) {
+	defer utility.AuditDeferred(ctx, "vnetlib.update_device_nat", map[string]interface{}{"device": devName}, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("update device NAT", "device", devName)
 	v.Services.WrapOpenServices(func() {
-		exitCode, out := v.updateNat(devName)
-		if exitCode == 0 {
-			v.logger.Debug("update device NAT failed", "device", devName, "exitcode", exitCode)
-			v.logger.Trace("update device NAT failed", "device", devName, "output", out)
-			err = errors.New("Failed to update device NAT")
+		exitCode, out := v.updateNat(ctx, devName)
+		if exitCode == 0 || ctx.Err() != nil {
+			logger.Debug("update device NAT failed", "device", devName, "exitcode", exitCode)
+			logger.Trace("update device NAT failed", "device", devName, "output", out)
+			err = commandError(ctx, "Failed to update device NAT")
 		}
 	})
 	return err
 }
 
-func (v *VnetlibExe) DeletePortFwd(device, protocol, hostPort string) (err error) {
-	v.logger.Debug("delete port fwd", "device", device, "port", hostPort, "protocol", protocol)
+func (v *VnetlibExe) DeletePortFwd(ctx context.Context, device, protocol, hostPort string) (err error) {
+	defer utility.AuditDeferred(ctx, "vnetlib.delete_port_forward", map[string]interface{}{"device": device, "protocol": protocol, "port": hostPort}, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("delete port fwd", "device", device, "port", hostPort, "protocol", protocol)
 	v.Services.WrapOpenServices(func() {
-		exitCode, out := v.deletePortFwd(device, protocol, hostPort)
-		if exitCode == 0 {
-			v.logger.Debug("delete port fwd", "device", device, "host-port", hostPort, "exitcode", exitCode)
-			v.logger.Trace("delete port fwd", "device", device, "host-port", hostPort, "output", out)
-			err = errors.New("Failed to delete port forward")
+		exitCode, out := v.deletePortFwd(ctx, device, protocol, hostPort)
+		if exitCode == 0 || ctx.Err() != nil {
+			logger.Debug("delete port fwd", "device", device, "host-port", hostPort, "exitcode", exitCode)
+			logger.Trace("delete port fwd", "device", device, "host-port", hostPort, "output", out)
+			err = commandError(ctx, "Failed to delete port forward")
 		}
 	})
 	return err
 }
 
 // Service modfications
-func (v *VnetlibExe) StatusNAT(devName string) bool {
-	v.logger.Debug("service NAT status")
-	exitCode, _ := v.statusNat(devName)
-	v.logger.Trace("service NAT status", "exitcode", exitCode)
-	return exitCode == 1
+func (v *VnetlibExe) StatusNAT(ctx context.Context, devName string) bool {
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("service NAT status")
+	exitCode, _ := v.statusNat(ctx, devName)
+	logger.Trace("service NAT status", "exitcode", exitCode)
+	return exitCode == 1 && ctx.Err() == nil
 }
 
-func (v *VnetlibExe) StatusDHCP(devName string) bool {
-	v.logger.Debug("service DHCP status")
-	exitCode, _ := v.statusDhcp(devName)
-	v.logger.Trace("service DHCP status", "exitcode", exitCode)
-	return exitCode == 1
+func (v *VnetlibExe) StatusDHCP(ctx context.Context, devName string) bool {
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("service DHCP status")
+	exitCode, _ := v.statusDhcp(ctx, devName)
+	logger.Trace("service DHCP status", "exitcode", exitCode)
+	return exitCode == 1 && ctx.Err() == nil
 }
 
-func (v *VnetlibExe) StartNAT(devName string) (err error) {
-	v.logger.Debug("service NAT start")
+func (v *VnetlibExe) StartNAT(ctx context.Context, devName string) (err error) {
+	defer utility.AuditDeferred(ctx, "vnetlib.start_nat", map[string]interface{}{"device": devName}, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("service NAT start")
 	v.Services.WrapOpenServices(func() {
-		exitCode, out := v.startNat(devName)
-		if exitCode == 0 {
-			v.logger.Debug("service NAT start failed", "device", devName, "exitcode", exitCode)
-			v.logger.Trace("service NAT start failed", "device", devName, "output", out)
-			err = errors.New("Failed to start NAT service")
+		exitCode, out := v.startNat(ctx, devName)
+		if exitCode == 0 || ctx.Err() != nil {
+			logger.Debug("service NAT start failed", "device", devName, "exitcode", exitCode)
+			logger.Trace("service NAT start failed", "device", devName, "output", out)
+			err = commandError(ctx, "Failed to start NAT service")
 		}
 	})
 	return nil
 }
 
-func (v *VnetlibExe) StartDHCP(devName string) (err error) {
-	v.logger.Debug("service DHCP start")
+func (v *VnetlibExe) StartDHCP(ctx context.Context, devName string) (err error) {
+	defer utility.AuditDeferred(ctx, "vnetlib.start_dhcp", map[string]interface{}{"device": devName}, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("service DHCP start")
 	v.Services.WrapOpenServices(func() {
-		exitCode, out := v.startDhcp(devName)
-		if exitCode == 0 {
-			v.logger.Debug("service DHCP start failed", "device", devName, "exitcode", exitCode)
-			v.logger.Trace("service DHCP start failed", "device", devName, "output", out)
-			err = errors.New("Failed to start DHCP service")
+		exitCode, out := v.startDhcp(ctx, devName)
+		if exitCode == 0 || ctx.Err() != nil {
+			logger.Debug("service DHCP start failed", "device", devName, "exitcode", exitCode)
+			logger.Trace("service DHCP start failed", "device", devName, "output", out)
+			err = commandError(ctx, "Failed to start DHCP service")
 		}
 	})
 	return err
 }
 
-func (v *VnetlibExe) StopNAT(devName string) (err error) {
-	v.logger.Debug("service NAT stop")
+func (v *VnetlibExe) StopNAT(ctx context.Context, devName string) (err error) {
+	defer utility.AuditDeferred(ctx, "vnetlib.stop_nat", map[string]interface{}{"device": devName}, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("service NAT stop")
 	v.Services.WrapOpenServices(func() {
-		exitCode, out := v.stopNat(devName)
-		if exitCode == 0 {
-			v.logger.Debug("service NAT stop failed", "device", devName, "exitcode", exitCode)
-			v.logger.Trace("service NAT stop failed", "device", devName, "output", out)
-			err = errors.New("Failed to stop NAT service")
+		exitCode, out := v.stopNat(ctx, devName)
+		if exitCode == 0 || ctx.Err() != nil {
+			logger.Debug("service NAT stop failed", "device", devName, "exitcode", exitCode)
+			logger.Trace("service NAT stop failed", "device", devName, "output", out)
+			err = commandError(ctx, "Failed to stop NAT service")
 		}
 	})
 	return err
 }
 
-func (v *VnetlibExe) StopDHCP(devName string) (err error) {
-	v.logger.Debug("service DHCP stop")
+func (v *VnetlibExe) StopDHCP(ctx context.Context, devName string) (err error) {
+	defer utility.AuditDeferred(ctx, "vnetlib.stop_dhcp", map[string]interface{}{"device": devName}, time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("service DHCP stop")
 	v.Services.WrapOpenServices(func() {
-		exitCode, out := v.stopDhcp(devName)
-		if exitCode == 0 {
-			v.logger.Debug("service DHCP stop failed", "device", devName, "exitcode", exitCode)
-			v.logger.Trace("service DHCP stop failed", "device", devName, "output", out)
-			err = errors.New("Failed to stop DHCP service")
+		exitCode, out := v.stopDhcp(ctx, devName)
+		if exitCode == 0 || ctx.Err() != nil {
+			logger.Debug("service DHCP stop failed", "device", devName, "exitcode", exitCode)
+			logger.Trace("service DHCP stop failed", "device", devName, "output", out)
+			err = commandError(ctx, "Failed to stop DHCP service")
 		}
 	})
 	return err
 }
 
 // Helpers
-func (v *VnetlibExe) GetUnusedDevice() (devName string, err error) {
-	v.logger.Debug("request unused device name")
-	exitCode, devName := v.getUnusedDevice()
-	if exitCode == 0 {
-		v.logger.Debug("unused device name request failed", "exitcode", exitCode, "output", devName)
-		return devName, errors.New("Failed to generate new device name")
+func (v *VnetlibExe) GetUnusedDevice(ctx context.Context) (devName string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, VNETLIB_TIMEOUT)
+	defer cancel()
+	logger := utility.ContextLogger(ctx, v.logger)
+	logger.Debug("request unused device name")
+	exitCode, devName := v.getUnusedDevice(ctx)
+	if exitCode == 0 || ctx.Err() != nil {
+		logger.Debug("unused device name request failed", "exitcode", exitCode, "output", devName)
+		return devName, commandError(ctx, "Failed to generate new device name")
 	}
 	return devName, err
 }
 
-func (v *VnetlibExe) runcmd(args ...string) (exitCode int, output string) {
-	return utility.ExecuteWithOutput(v.buildCommand(args...))
+func (v *VnetlibExe) runcmd(ctx context.Context, args ...string) (exitCode int, output string) {
+	return utility.ExecuteWithOutput(v.buildCommand(ctx, args...))
 }
@@ -3,6 +3,8 @@
 
 package service
 
+import "context"
+
 type VnetlibMock struct {
 	CreateDeviceResponses          []*CreateDeviceResponse
 	CreateDeviceRequests           []string
@@ -97,7 +99,7 @@ type GetUnusedDeviceResponse struct {
 	Error   error
 }
 
-func (v *VnetlibMock) CreateDevice(newName string) (devName string, err error) {
+func (v *VnetlibMock) CreateDevice(ctx context.Context, newName string) (devName string, err error) {
 	if len(v.CreateDeviceResponses) > 0 {
 		r := v.CreateDeviceResponses[0]
 		v.CreateDeviceResponses = v.CreateDeviceResponses[1:]
@@ -109,7 +111,7 @@ func (v *VnetlibMock) CreateDevice(newName string) (devName string, err error) {
 	return
 }
 
-func (v *VnetlibMock) DeleteDevice(devName string) (err error) {
+func (v *VnetlibMock) DeleteDevice(ctx context.Context, devName string) (err error) {
 	if len(v.DeleteDeviceResponses) > 0 {
 		err = v.DeleteDeviceResponses[0]
 		v.DeleteDeviceResponses = v.DeleteDeviceResponses[1:]
@@ -119,7 +121,7 @@ func (v *VnetlibMock) DeleteDevice(devName string) (err error) {
 	return
 }
 
-func (v *VnetlibMock) SetSubnetAddress(devName string, addr string) (err error) {
+func (v *VnetlibMock) SetSubnetAddress(ctx context.Context, devName string, addr string) (err error) {
 	if len(v.SetSubnetAddressResponses) > 0 {
 		err = v.SetSubnetAddressResponses[0]
 		v.SetSubnetAddressResponses = v.SetSubnetAddressResponses[1:]
@@ -129,7 +131,7 @@ func (v *VnetlibMock) SetSubnetAddress(devName string, addr string) (err error)
 	return
 }
 
-func (v *VnetlibMock) SetSubnetMask(devName string, mask string) (err error) {
+func (v *VnetlibMock) SetSubnetMask(ctx context.Context, devName string, mask string) (err error) {
 	if len(v.SetSubnetMaskResponses) > 0 {
 		err = v.SetSubnetMaskResponses[0]
 		v.SetSubnetMaskResponses = v.SetSubnetMaskResponses[1:]
@@ -139,7 +141,7 @@ func (v *VnetlibMock) SetSubnetMask(devName string, mask string) (err error) {
 	return
 }
 
-func (v *VnetlibMock) SetNAT(devName string, enable bool) (err error) {
+func (v *VnetlibMock) SetNAT(ctx context.Context, devName string, enable bool) (err error) {
 	if len(v.SetNATResponses) > 0 {
 		err = v.SetNATResponses[0]
 		v.SetNATResponses = v.SetNATResponses[1:]
@@ -149,7 +151,7 @@ func (v *VnetlibMock) SetNAT(devName string, enable bool) (err error) {
 	return
 }
 
-func (v *VnetlibMock) UpdateDeviceNAT(devName string) (err error) {
+func (v *VnetlibMock) UpdateDeviceNAT(ctx context.Context, devName string) (err error) {
 	if len(v.UpdateDeviceNATResponses) > 0 {
 		err = v.UpdateDeviceNATResponses[0]
 		v.UpdateDeviceNATResponses = v.UpdateDeviceNATResponses[1:]
@@ -159,7 +161,7 @@ func (v *VnetlibMock) UpdateDeviceNAT(devName string) (err error) {
 	return
 }
 
-func (v *VnetlibMock) StatusNAT(devName string) (s bool) {
+func (v *VnetlibMock) StatusNAT(ctx context.Context, devName string) (s bool) {
 	s = true
 	if len(v.StatusNATResponses) > 0 {
 		s = v.StatusNATResponses[0]
@@ -170,7 +172,7 @@ func (v *VnetlibMock) StatusNAT(devName string) (s bool) {
 	return
 }
 
-func (v *VnetlibMock) StartNAT(devName string) (err error) {
+func (v *VnetlibMock) StartNAT(ctx context.Context, devName string) (err error) {
 	if len(v.StartNATResponses) > 0 {
 		err = v.StartNATResponses[0]
 		v.StartNATResponses = v.StartNATResponses[1:]
@@ -180,7 +182,7 @@ func (v *VnetlibMock) StartNAT(devName string) (err error) {
 	return
 }
 
-func (v *VnetlibMock) StopNAT(devName string) (err error) {
+func (v *VnetlibMock) StopNAT(ctx context.Context, devName string) (err error) {
 	if len(v.StopNATResponses) > 0 {
 		err = v.StopNATResponses[0]
 		v.StopNATResponses = v.StopNATResponses[1:]
@@ -190,7 +192,7 @@ func (v *VnetlibMock) StopNAT(devName string) (err error) {
 	return
 }
 
-func (v *VnetlibMock) SetDHCP(devName string, enable bool) (err error) {
+func (v *VnetlibMock) SetDHCP(ctx context.Context, devName string, enable bool) (err error) {
 	if len(v.SetDHCPResponses) > 0 {
 		err = v.SetDHCPResponses[0]
 		v.SetDHCPResponses = v.SetDHCPResponses[1:]
@@ -200,7 +202,7 @@ func (v *VnetlibMock) SetDHCP(devName string, enable bool) (err error) {
 	return
 }
 
-func (v *VnetlibMock) StatusDHCP(devName string) (s bool) {
+func (v *VnetlibMock) StatusDHCP(ctx context.Context, devName string) (s bool) {
 	s = true
 	if len(v.StatusDHCPResponses) > 0 {
 		s = v.StatusDHCPResponses[0]
@@ -211,7 +213,7 @@ func (v *VnetlibMock) StatusDHCP(devName string) (s bool) {
 	return
 }
 
-func (v *VnetlibMock) StartDHCP(devName string) (err error) {
+func (v *VnetlibMock) StartDHCP(ctx context.Context, devName string) (err error) {
 	if len(v.StartDHCPResponses) > 0 {
 		err = v.StartDHCPResponses[0]
 		v.StartDHCPResponses = v.StartDHCPResponses[1:]
@@ -221,7 +223,7 @@ func (v *VnetlibMock) StartDHCP(devName string) (err error) {
 	return
 }
 
-func (v *VnetlibMock) StopDHCP(devName string) (err error) {
+func (v *VnetlibMock) StopDHCP(ctx context.Context, devName string) (err error) {
 	if len(v.StopDHCPResponses) > 0 {
 		err = v.StopDHCPResponses[0]
 		v.StopDHCPResponses = v.StopDHCPResponses[1:]
@@ -231,7 +233,7 @@ func (v *VnetlibMock) StopDHCP(devName string) (err error) {
 	return
 }
 
-func (v *VnetlibMock) LookupReservedAddress(device, mac string) (addr string, err error) {
+func (v *VnetlibMock) LookupReservedAddress(ctx context.Context, device, mac string) (addr string, err error) {
 	if len(v.LookupReservedAddressResponses) > 0 {
 		r := v.LookupReservedAddressResponses[0]
 		v.LookupReservedAddressResponses = v.LookupReservedAddressResponses[1:]
@@ -243,7 +245,7 @@ func (v *VnetlibMock) LookupReservedAddress(device, mac string) (addr string, er
 	return
 }
 
-func (v *VnetlibMock) ReserveAddress(device, mac, ip string) (err error) {
+func (v *VnetlibMock) ReserveAddress(ctx context.Context, device, mac, ip string) (err error) {
 	if len(v.ReserveAddressResponses) > 0 {
 		err = v.ReserveAddressResponses[0]
 		v.ReserveAddressResponses = v.ReserveAddressResponses[1:]
@@ -253,7 +255,7 @@ func (v *VnetlibMock) ReserveAddress(device, mac, ip string) (err error) {
 	return
 }
 
-func (v *VnetlibMock) EnableDevice(devName string) (err error) {
+func (v *VnetlibMock) EnableDevice(ctx context.Context, devName string) (err error) {
 	if len(v.EnableDeviceResponses) > 0 {
 		err = v.EnableDeviceResponses[0]
 		v.EnableDeviceResponses = v.EnableDeviceResponses[1:]
@@ -262,7 +264,7 @@ func (v *VnetlibMock) EnableDevice(devName string) (err error) {
 
 	return
 }
-func (v *VnetlibMock) DisableDevice(devName string) (err error) {
+func (v *VnetlibMock) DisableDevice(ctx context.Context, devName string) (err error) {
 	if len(v.DisableDeviceResponses) > 0 {
 		err = v.DisableDeviceResponses[0]
 		v.DisableDeviceResponses = v.DisableDeviceResponses[1:]
@@ -272,7 +274,7 @@ func (v *VnetlibMock) DisableDevice(devName string) (err error) {
 	return
 }
 
-func (v *VnetlibMock) UpdateDevice(devName string) (err error) {
+func (v *VnetlibMock) UpdateDevice(ctx context.Context, devName string) (err error) {
 	if len(v.UpdateDeviceResponses) > 0 {
 		err = v.UpdateDeviceResponses[0]
 		v.UpdateDeviceResponses = v.UpdateDeviceResponses[1:]
@@ -282,7 +284,7 @@ func (v *VnetlibMock) UpdateDevice(devName string) (err error) {
 	return
 }
 
-func (v *VnetlibMock) DeletePortFwd(device, protocol, hostPort string) (err error) {
+func (v *VnetlibMock) DeletePortFwd(ctx context.Context, device, protocol, hostPort string) (err error) {
 	if len(v.DeletePortFwdResponses) > 0 {
 		err = v.DeletePortFwdResponses[0]
 		v.DeletePortFwdResponses = v.DeletePortFwdResponses[1:]
@@ -292,7 +294,7 @@ func (v *VnetlibMock) DeletePortFwd(device, protocol, hostPort string) (err erro
 	return
 }
 
-func (v *VnetlibMock) GetUnusedDevice() (devName string, err error) {
+func (v *VnetlibMock) GetUnusedDevice(ctx context.Context) (devName string, err error) {
 	if len(v.GetUnusedDeviceResponses) > 0 {
 		r := v.GetUnusedDeviceResponses[0]
 		v.GetUnusedDeviceResponses = v.GetUnusedDeviceResponses[1:]
@@ -6,6 +6,7 @@
 package service
 
 import (
+	"context"
 	"os/exec"
 
 	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
@@ -14,88 +15,88 @@ import (
 const DHCP_RESERVED_ADDRESS = `IP:\s+(?P<address>[^\s]+)\s`
 const UNUSED_VNET_PATTERN = `vmnet:\s+(?P<device_name>[^\s]+)\s`
 
-func (v *VnetlibExe) deletePortFwd(device, protocol, port string) (int, string) {
-	return v.runcmd("setnatportfwd", device, protocol, port)
+func (v *VnetlibExe) deletePortFwd(ctx context.Context, device, protocol, port string) (int, string) {
+	return v.runcmd(ctx, "setnatportfwd", device, protocol, port)
 }
 
-func (v *VnetlibExe) addDevice(name string) (int, string) {
-	return v.runcmd("addadapter", name)
+func (v *VnetlibExe) addDevice(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "addadapter", name)
 }
 
-func (v *VnetlibExe) removeDevice(name string) (int, string) {
-	return v.runcmd("removeadapter", name)
+func (v *VnetlibExe) removeDevice(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "removeadapter", name)
 }
 
-func (v *VnetlibExe) setSubnetAddr(name, addr string) (int, string) {
-	return v.runcmd("setsubnetaddr", name, addr)
+func (v *VnetlibExe) setSubnetAddr(ctx context.Context, name, addr string) (int, string) {
+	return v.runcmd(ctx, "setsubnetaddr", name, addr)
 }
 
-func (v *VnetlibExe) setSubnetMask(name, mask string) (int, string) {
-	return v.runcmd("setsubnetmask", name, mask)
+func (v *VnetlibExe) setSubnetMask(ctx context.Context, name, mask string) (int, string) {
+	return v.runcmd(ctx, "setsubnetmask", name, mask)
 }
 
-func (v *VnetlibExe) enableNat(name string) (int, string) {
-	return v.runcmd("setnatusage", name, "yes")
+func (v *VnetlibExe) enableNat(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "setnatusage", name, "yes")
 }
 
-func (v *VnetlibExe) disableNat(name string) (int, string) {
-	return v.runcmd("setnatusage", name, "no")
+func (v *VnetlibExe) disableNat(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "setnatusage", name, "no")
 }
 
-func (v *VnetlibExe) enableDhcp(name string) (int, string) {
-	return v.runcmd("setdhcpusage", name, "yes")
+func (v *VnetlibExe) enableDhcp(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "setdhcpusage", name, "yes")
 }
 
-func (v *VnetlibExe) disableDhcp(name string) (int, string) {
-	return v.runcmd("setdhcpusage", name, "no")
+func (v *VnetlibExe) disableDhcp(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "setdhcpusage", name, "no")
 }
 
-func (v *VnetlibExe) enableDevice(name string) (int, string) {
-	return v.runcmd("enablehostonlyadap", name)
+func (v *VnetlibExe) enableDevice(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "enablehostonlyadap", name)
 }
 
-func (v *VnetlibExe) disableDevice(name string) (int, string) {
-	return v.runcmd("disablehostonlyadap", name)
+func (v *VnetlibExe) disableDevice(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "disablehostonlyadap", name)
 }
 
-func (v *VnetlibExe) updateDevice(name string) (int, string) {
-	return v.runcmd("udpateadapterfromconfig", name)
+func (v *VnetlibExe) updateDevice(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "udpateadapterfromconfig", name)
 }
 
-func (v *VnetlibExe) updateNat(name string) (int, string) {
-	return v.runcmd("updatenatfromconfig", name)
+func (v *VnetlibExe) updateNat(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "updatenatfromconfig", name)
 }
 
-func (v *VnetlibExe) statusNat(name string) (int, string) {
-	return v.runcmd("servicestatus", name, "nat")
+func (v *VnetlibExe) statusNat(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "servicestatus", name, "nat")
 }
 
-func (v *VnetlibExe) statusDhcp(name string) (int, string) {
-	return v.runcmd("servicestatus", name, "dhcp")
+func (v *VnetlibExe) statusDhcp(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "servicestatus", name, "dhcp")
 }
 
-func (v *VnetlibExe) startNat(name string) (int, string) {
-	return v.runcmd("servicestart", name, "nat")
+func (v *VnetlibExe) startNat(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "servicestart", name, "nat")
 }
 
-func (v *VnetlibExe) startDhcp(name string) (int, string) {
-	return v.runcmd("servicestart", name, "dhcp")
+func (v *VnetlibExe) startDhcp(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "servicestart", name, "dhcp")
 }
 
-func (v *VnetlibExe) stopNat(name string) (int, string) {
-	return v.runcmd("servicestop", name, "nat")
+func (v *VnetlibExe) stopNat(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "servicestop", name, "nat")
 }
 
-func (v *VnetlibExe) stopDhcp(name string) (int, string) {
-	return v.runcmd("servicestop", name, "dhcp")
+func (v *VnetlibExe) stopDhcp(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "servicestop", name, "dhcp")
 }
 
-func (v *VnetlibExe) reserveAddress(device, mac, ip string) (int, string) {
-	return v.runcmd("setdhcpmac2ip", device, mac, ip)
+func (v *VnetlibExe) reserveAddress(ctx context.Context, device, mac, ip string) (int, string) {
+	return v.runcmd(ctx, "setdhcpmac2ip", device, mac, ip)
 }
 
-func (v *VnetlibExe) lookupReservedAddress(device, mac string) (int, string) {
-	exitCode, out := v.runcmd("getdhcpmac2ip", device, mac)
+func (v *VnetlibExe) lookupReservedAddress(ctx context.Context, device, mac string) (int, string) {
+	exitCode, out := v.runcmd(ctx, "getdhcpmac2ip", device, mac)
 	if exitCode != 0 {
 		return exitCode, out
 	}
@@ -108,8 +109,8 @@ func (v *VnetlibExe) lookupReservedAddress(device, mac string) (int, string) {
 	return 0, matches["address"]
 }
 
-func (v *VnetlibExe) getUnusedDevice() (int, string) {
-	cmd := v.buildCommand("getunusedvnet")
+func (v *VnetlibExe) getUnusedDevice(ctx context.Context) (int, string) {
+	cmd := v.buildCommand(ctx, "getunusedvnet")
 	exitCode, out := utility.ExecuteWithOutput(cmd)
 	if exitCode != 1 {
 		return exitCode, out
@@ -122,6 +123,6 @@ func (v *VnetlibExe) getUnusedDevice() (int, string) {
 	return 1, matches["device_name"]
 }
 
-func (v *VnetlibExe) buildCommand(args ...string) *exec.Cmd {
-	return exec.Command(v.ExePath, args...)
+func (v *VnetlibExe) buildCommand(ctx context.Context, args ...string) *exec.Cmd {
+	return utility.CommandContext(ctx, v.ExePath, args...)
 }
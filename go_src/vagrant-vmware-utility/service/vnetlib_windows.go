@@ -4,6 +4,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os/exec"
@@ -11,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
 	"golang.org/x/sys/windows/registry"
 	"golang.org/x/sys/windows/svc"
 	"golang.org/x/sys/windows/svc/mgr"
@@ -23,7 +25,7 @@ const VMWARE_DHCP_SERVICE = `VMnetDHCP`
 // Windows registry path to the VMnet configurations
 const VMNETCONFIG_REGISTRY_PATH = `SOFTWARE\VMware, Inc.\VMnetLib\VMnetConfig`
 
-func (v *VnetlibExe) deletePortFwd(device, protocol, port string) (int, string) {
+func (v *VnetlibExe) deletePortFwd(ctx context.Context, device, protocol, port string) (int, string) {
 	var protoKey string
 	if protocol == "tcp" {
 		protoKey = "TCPForward"
@@ -52,53 +54,53 @@ func (v *VnetlibExe) deletePortFwd(device, protocol, port string) (int, string)
 	return 1, ""
 }
 
-func (v *VnetlibExe) addDevice(name string) (int, string) {
-	return v.runcmd("add", "adapter", name)
+func (v *VnetlibExe) addDevice(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "add", "adapter", name)
 }
 
-func (v *VnetlibExe) removeDevice(name string) (int, string) {
+func (v *VnetlibExe) removeDevice(ctx context.Context, name string) (int, string) {
 	return -1, "not implemented"
 }
 
-func (v *VnetlibExe) setSubnetAddr(name, addr string) (int, string) {
-	return v.runcmd("set", "vnet", name, "addr", addr)
+func (v *VnetlibExe) setSubnetAddr(ctx context.Context, name, addr string) (int, string) {
+	return v.runcmd(ctx, "set", "vnet", name, "addr", addr)
 }
 
-func (v *VnetlibExe) setSubnetMask(name, mask string) (int, string) {
-	return v.runcmd("set", "vnet", name, "mask", mask)
+func (v *VnetlibExe) setSubnetMask(ctx context.Context, name, mask string) (int, string) {
+	return v.runcmd(ctx, "set", "vnet", name, "mask", mask)
 }
 
-func (v *VnetlibExe) enableNat(name string) (int, string) {
-	return v.runcmd("add", "nat", name)
+func (v *VnetlibExe) enableNat(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "add", "nat", name)
 }
 
-func (v *VnetlibExe) disableNat(name string) (int, string) {
-	return v.runcmd("remove", "nat", name)
+func (v *VnetlibExe) disableNat(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "remove", "nat", name)
 }
 
-func (v *VnetlibExe) enableDhcp(name string) (int, string) {
-	return v.runcmd("add", "dhcp", name)
+func (v *VnetlibExe) enableDhcp(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "add", "dhcp", name)
 }
 
-func (v *VnetlibExe) disableDhcp(name string) (int, string) {
-	return v.runcmd("remove", "dhcp", name)
+func (v *VnetlibExe) disableDhcp(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "remove", "dhcp", name)
 }
 
 // TODO: Test these enable/disable
-func (v *VnetlibExe) enableDevice(name string) (int, string) {
-	return v.runcmd("enable", "adapter", name)
+func (v *VnetlibExe) enableDevice(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "enable", "adapter", name)
 }
 
-func (v *VnetlibExe) disableDevice(name string) (int, string) {
-	return v.runcmd("disable", "adapter", name)
+func (v *VnetlibExe) disableDevice(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "disable", "adapter", name)
 }
 
-func (v *VnetlibExe) updateDevice(name string) (int, string) {
-	return v.runcmd("update", "adapter", name)
+func (v *VnetlibExe) updateDevice(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "update", "adapter", name)
 }
 
-func (v *VnetlibExe) updateNat(name string) (int, string) {
-	return v.runcmd("update", "nat", name)
+func (v *VnetlibExe) updateNat(ctx context.Context, name string) (int, string) {
+	return v.runcmd(ctx, "update", "nat", name)
 }
 
 // Creating an IP to MAC mapping always returns 0 and never
@@ -107,14 +109,14 @@ func (v *VnetlibExe) updateNat(name string) (int, string) {
 // The mapping will be added to the Registry but not actually
 // used until the config has been rewritten so force that as
 // well before leaving.
-func (v *VnetlibExe) reserveAddress(device, mac, ip string) (int, string) {
-	_, _ = v.runcmd("set", "dhcp", device, "addipmac", ip, mac)
-	_, _ = v.runcmd("update", "dhcp", device)
+func (v *VnetlibExe) reserveAddress(ctx context.Context, device, mac, ip string) (int, string) {
+	_, _ = v.runcmd(ctx, "set", "dhcp", device, "addipmac", ip, mac)
+	_, _ = v.runcmd(ctx, "update", "dhcp", device)
 	return 1, ""
 }
 
 // This needs to iterate the registry entries
-func (v *VnetlibExe) lookupReservedAddress(device, mac string) (int, string) {
+func (v *VnetlibExe) lookupReservedAddress(ctx context.Context, device, mac string) (int, string) {
 	keyPath := VMNETCONFIG_REGISTRY_PATH + `\` + device + `\DHCP\FixedIPtoMac`
 	regKey, err := registry.OpenKey(registry.LOCAL_MACHINE, keyPath,
 		v.registryAccess(registry.ALL_ACCESS))
@@ -146,7 +148,7 @@ func (v *VnetlibExe) lookupReservedAddress(device, mac string) (int, string) {
 	return -1, "no address found"
 }
 
-func (v *VnetlibExe) statusNat(name string) (int, string) {
+func (v *VnetlibExe) statusNat(ctx context.Context, name string) (int, string) {
 	running, _ := v.serviceRunning(VMWARE_NAT_SERVICE)
 	if running {
 		return 1, ""
@@ -154,7 +156,7 @@ func (v *VnetlibExe) statusNat(name string) (int, string) {
 	return -1, ""
 }
 
-func (v *VnetlibExe) statusDhcp(name string) (int, string) {
+func (v *VnetlibExe) statusDhcp(ctx context.Context, name string) (int, string) {
 	running, _ := v.serviceRunning(VMWARE_DHCP_SERVICE)
 	if running {
 		return 1, ""
@@ -162,7 +164,7 @@ func (v *VnetlibExe) statusDhcp(name string) (int, string) {
 	return -1, ""
 }
 
-func (v *VnetlibExe) startNat(name string) (int, string) {
+func (v *VnetlibExe) startNat(ctx context.Context, name string) (int, string) {
 	srv, err := v.getService(VMWARE_NAT_SERVICE)
 	if err != nil {
 		v.logger.Trace("start nat get service", "name", VMWARE_NAT_SERVICE,
@@ -179,7 +181,7 @@ func (v *VnetlibExe) startNat(name string) (int, string) {
 	return 1, ""
 }
 
-func (v *VnetlibExe) startDhcp(name string) (int, string) {
+func (v *VnetlibExe) startDhcp(ctx context.Context, name string) (int, string) {
 	srv, err := v.getService(VMWARE_DHCP_SERVICE)
 	if err != nil {
 		v.logger.Trace("start dhcp get service", "name", VMWARE_DHCP_SERVICE,
@@ -196,7 +198,7 @@ func (v *VnetlibExe) startDhcp(name string) (int, string) {
 	return 1, ""
 }
 
-func (v *VnetlibExe) stopNat(name string) (int, string) {
+func (v *VnetlibExe) stopNat(ctx context.Context, name string) (int, string) {
 	srv, err := v.getService(VMWARE_NAT_SERVICE)
 	if err != nil {
 		v.logger.Trace("stop nat get service", "name", VMWARE_NAT_SERVICE,
@@ -210,7 +212,7 @@ func (v *VnetlibExe) stopNat(name string) (int, string) {
 			"error", err)
 		return -1, err.Error()
 	}
-	err = v.waitForState(srv, svc.Stopped)
+	err = v.waitForState(ctx, srv, svc.Stopped)
 	if err != nil {
 		v.logger.Trace("stop nat service", "error", err)
 		return -1, "Failed to transition NAT service to stopped state"
@@ -218,7 +220,7 @@ func (v *VnetlibExe) stopNat(name string) (int, string) {
 	return 1, ""
 }
 
-func (v *VnetlibExe) stopDhcp(name string) (int, string) {
+func (v *VnetlibExe) stopDhcp(ctx context.Context, name string) (int, string) {
 	srv, err := v.getService(VMWARE_DHCP_SERVICE)
 	if err != nil {
 		v.logger.Trace("stop dhcp get service", "name", VMWARE_DHCP_SERVICE,
@@ -232,7 +234,7 @@ func (v *VnetlibExe) stopDhcp(name string) (int, string) {
 			"error", err)
 		return -1, err.Error()
 	}
-	err = v.waitForState(srv, svc.Stopped)
+	err = v.waitForState(ctx, srv, svc.Stopped)
 	if err != nil {
 		v.logger.Trace("stop dhcp service", "error", err)
 		return -1, "Failed to transition DHCP service to stopped state"
@@ -240,7 +242,7 @@ func (v *VnetlibExe) stopDhcp(name string) (int, string) {
 	return 1, ""
 }
 
-func (v *VnetlibExe) getUnusedDevice() (int, string) {
+func (v *VnetlibExe) getUnusedDevice(ctx context.Context) (int, string) {
 	regKey, _, err := registry.CreateKey(registry.LOCAL_MACHINE,
 		VMNETCONFIG_REGISTRY_PATH, v.registryAccess(registry.QUERY_VALUE|registry.ENUMERATE_SUB_KEYS))
 	if err != nil {
@@ -296,9 +298,9 @@ func (v *VnetlibExe) getUnusedDevice() (int, string) {
 	return -1, "unknown"
 }
 
-func (v *VnetlibExe) buildCommand(args ...string) *exec.Cmd {
+func (v *VnetlibExe) buildCommand(ctx context.Context, args ...string) *exec.Cmd {
 	args = append([]string{"--"}, args...)
-	return exec.Command(v.ExePath, args...)
+	return utility.CommandContext(ctx, v.ExePath, args...)
 }
 
 func (v *VnetlibExe) getService(name string) (*mgr.Service, error) {
@@ -338,7 +340,7 @@ func (v *VnetlibExe) registryAccess(access uint32) uint32 {
 	return access
 }
 
-func (v *VnetlibExe) waitForState(srv *mgr.Service, desiredState svc.State) error {
+func (v *VnetlibExe) waitForState(ctx context.Context, srv *mgr.Service, desiredState svc.State) error {
 	waitInterval := 1 * time.Second
 	for i := 0; i < SERVICE_STATUS_TIMEOUT; i++ {
 		currentStatus, err := srv.Query()
@@ -352,7 +354,11 @@ func (v *VnetlibExe) waitForState(srv *mgr.Service, desiredState svc.State) erro
 		}
 		v.logger.Trace("service not in desired state", "name", srv.Name, "current", currentStatus.State,
 			"desired", desiredState)
-		time.Sleep(waitInterval)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(waitInterval):
+		}
 	}
 	return errors.New("Service failed to reach desired state")
 }
@@ -0,0 +1,491 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/utility"
+	"github.com/hashicorp/vagrant-vmware-desktop/go_src/vagrant-vmware-utility/version"
+)
+
+// Current version of the settings file format
+const SETTINGS_VERSION = 1
+
+// Kinds of settings files
+const (
+	SETTINGS_KIND_NAT             = "nat"
+	SETTINGS_KIND_PORT_FORWARDING = "portforwarding"
+)
+
+// Time format used in the name of settings file backups
+const SETTINGS_BACKUP_TIME_FORMAT = "2006-01-02T15-04-05.000"
+
+// Extension of settings file backups
+const SETTINGS_BACKUP_EXT = ".bak"
+
+// Extension of settings files moved aside by a repair
+const SETTINGS_INVALID_EXT = ".invalid"
+
+// Actions taken when repairing a settings file
+const (
+	REPAIR_NONE     = "none"
+	REPAIR_UPGRADED = "upgraded"
+	REPAIR_RESTORED = "restored"
+	REPAIR_RESET    = "reset"
+)
+
+// Settings file was written using a version newer than supported
+var ErrUnsupportedVersion = errors.New("Settings file version is not supported")
+
+// Contents of a settings file which cannot be loaded
+type InvalidSettingsError struct {
+	Err error
+}
+
+func (e *InvalidSettingsError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidSettingsError) Unwrap() error {
+	return e.Err
+}
+
+// Envelope identifying the format of a settings file so it can
+// be upgraded. The version and kind are stored alongside the
+// settings data at the top level of the file so releases before
+// versioning ignore them and continue to load the settings data.
+// Files without a version are version 0.
+type Envelope struct {
+	Version int
+	Kind    string
+	Data    json.RawMessage
+}
+
+func (e *Envelope) UnmarshalJSON(data []byte) error {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*e = Envelope{Data: json.RawMessage(bytes.TrimSpace(data))}
+	v, ok := fields["version"]
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal(v, &e.Version); err != nil {
+		return fmt.Errorf("Invalid settings file version: %w", err)
+	}
+	if k, ok := fields["kind"]; ok {
+		if err := json.Unmarshal(k, &e.Kind); err != nil {
+			return fmt.Errorf("Invalid settings file kind: %w", err)
+		}
+	}
+	delete(fields, "version")
+	delete(fields, "kind")
+	content, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	e.Data = content
+	return nil
+}
+
+func (e Envelope) MarshalJSON() ([]byte, error) {
+	fields := map[string]json.RawMessage{}
+	if len(e.Data) > 0 {
+		if err := json.Unmarshal(e.Data, &fields); err != nil {
+			return nil, fmt.Errorf("Settings data is not an object: %w", err)
+		}
+	}
+	var err error
+	if fields["version"], err = json.Marshal(e.Version); err != nil {
+		return nil, err
+	}
+	if fields["kind"], err = json.Marshal(e.Kind); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// Migration upgrades settings data from the previous version
+type Migration func(data json.RawMessage) (json.RawMessage, error)
+
+// Migrations for each kind of settings file keyed by the
+// version the migration upgrades the data to
+var Migrations = map[string]map[int]Migration{
+	SETTINGS_KIND_NAT:             {1: migrateUnversioned},
+	SETTINGS_KIND_PORT_FORWARDING: {1: migrateUnversioned},
+}
+
+// Status of a settings file
+type FileStatus struct {
+	Path     string   `json:"path"`
+	Kind     string   `json:"kind"`
+	Exists   bool     `json:"exists"`
+	Valid    bool     `json:"valid"`
+	Outdated bool     `json:"outdated"`
+	Version  int      `json:"version"`
+	Entries  int      `json:"entries"`
+	Backups  []string `json:"backups"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// Result of repairing a settings file
+type RepairResult struct {
+	Path     string `json:"path"`
+	Kind     string `json:"kind"`
+	Action   string `json:"action"`
+	Backup   string `json:"backup,omitempty"`
+	Restored string `json:"restored_from,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Invalid settings files repaired while loading settings
+var loadRepairs = struct {
+	sync.Mutex
+	results []*RepairResult
+}{}
+
+// Invalid settings files which have been repaired while
+// loading settings
+func Repairs() []*RepairResult {
+	loadRepairs.Lock()
+	defer loadRepairs.Unlock()
+	return append([]*RepairResult{}, loadRepairs.results...)
+}
+
+// Parse the contents of a settings file. Contents without
+// a version are treated as unversioned data.
+func ParseEnvelope(data []byte) (*Envelope, error) {
+	env := &Envelope{}
+	if err := json.Unmarshal(data, env); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// Upgrade the envelope to the current version by applying
+// each migration after the current version in order
+func (e *Envelope) Migrate(kind string) error {
+	if e.Version > SETTINGS_VERSION || e.Version < 0 {
+		return fmt.Errorf("%w (found version %d, supported version %d)",
+			ErrUnsupportedVersion, e.Version, SETTINGS_VERSION)
+	}
+	if e.Version > 0 && e.Kind != kind {
+		return fmt.Errorf("Settings file contains %q settings, expected %q", e.Kind, kind)
+	}
+	for v := e.Version + 1; v <= SETTINGS_VERSION; v++ {
+		m, ok := Migrations[kind][v]
+		if !ok {
+			return fmt.Errorf("No migration available for %s settings to version %d", kind, v)
+		}
+		data, err := m(e.Data)
+		if err != nil {
+			return fmt.Errorf("Failed to migrate %s settings to version %d: %w", kind, v, err)
+		}
+		e.Data = data
+		e.Version = v
+	}
+	e.Kind = kind
+	return nil
+}
+
+// Inspect the settings file without modifying it
+func Inspect(path, kind string) *FileStatus {
+	status, _ := inspect(path, kind)
+	return status
+}
+
+// Repair the settings file. Outdated files are upgraded. Invalid
+// files are moved aside and replaced by the newest valid backup
+// or by empty settings when no valid backup exists. Files using
+// a newer version are never modified.
+func Repair(path, kind string, logger hclog.Logger) (*RepairResult, error) {
+	result := &RepairResult{Path: path, Kind: kind, Action: REPAIR_NONE}
+	status, err := inspect(path, kind)
+	if errors.Is(err, ErrUnsupportedVersion) {
+		return nil, fmt.Errorf("Settings file %s cannot be repaired: %w", path, err)
+	}
+	if !status.Exists || (status.Valid && !status.Outdated) {
+		return result, nil
+	}
+	if status.Valid {
+		backup, err := readSettingsFile(path, kind, &json.RawMessage{}, logger)
+		if err != nil {
+			return nil, err
+		}
+		result.Action = REPAIR_UPGRADED
+		result.Backup = backup
+		return result, nil
+	}
+
+	invalid := path + "." + time.Now().UTC().Format(SETTINGS_BACKUP_TIME_FORMAT) + SETTINGS_INVALID_EXT
+	if err := os.Rename(path, invalid); err != nil {
+		return nil, fmt.Errorf("Failed to move invalid settings file: %w", err)
+	}
+	logger.Warn("moved invalid settings file", "path", path, "invalid-path", invalid)
+	result.Backup = invalid
+
+	for _, backup := range status.Backups {
+		env, err := loadEnvelope(backup, kind)
+		if err != nil {
+			logger.Debug("skipping invalid settings backup", "backup", backup, "error", err)
+			continue
+		}
+		if err := writeSettingsFile(path, kind, env.Data); err != nil {
+			return nil, err
+		}
+		logger.Info("restored settings file from backup", "path", path, "backup", backup)
+		result.Action = REPAIR_RESTORED
+		result.Restored = backup
+		return result, nil
+	}
+
+	if err := writeSettingsFile(path, kind, emptySettings(kind)); err != nil {
+		return nil, err
+	}
+	logger.Warn("no valid settings backup found, reset settings file", "path", path)
+	result.Action = REPAIR_RESET
+	return result, nil
+}
+
+// Backups of the settings file from newest to oldest
+func Backups(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*" + SETTINGS_BACKUP_EXT)
+	if err != nil {
+		return nil, err
+	}
+	backups := []string{}
+	for _, m := range matches {
+		name := strings.TrimSuffix(strings.TrimPrefix(m, path+"."), SETTINGS_BACKUP_EXT)
+		idx := strings.LastIndex(name, ".v")
+		if idx < 0 {
+			continue
+		}
+		if _, err := strconv.Atoi(name[idx+2:]); err != nil {
+			continue
+		}
+		if _, err := time.Parse(SETTINGS_BACKUP_TIME_FORMAT, name[:idx]); err == nil {
+			backups = append(backups, m)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups, nil
+}
+
+func inspect(path, kind string) (*FileStatus, error) {
+	status := &FileStatus{Path: path, Kind: kind, Backups: []string{}}
+	if backups, err := Backups(path); err == nil {
+		status.Backups = backups
+	}
+	if _, err := os.Stat(path); err != nil {
+		if !os.IsNotExist(err) {
+			status.Exists = true
+			status.Error = err.Error()
+		}
+		return status, err
+	}
+	status.Exists = true
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		status.Error = err.Error()
+		return status, err
+	}
+	env, err := ParseEnvelope(raw)
+	if err != nil {
+		status.Error = err.Error()
+		return status, err
+	}
+	status.Version = env.Version
+	if err := env.Migrate(kind); err != nil {
+		status.Error = err.Error()
+		return status, err
+	}
+	status.Entries, err = countEntries(kind, env.Data)
+	if err != nil {
+		status.Error = err.Error()
+		return status, err
+	}
+	status.Valid = true
+	status.Outdated = status.Version < SETTINGS_VERSION
+	return status, nil
+}
+
+// Read the settings file and decode the data into v. Files using
+// an older version are upgraded in place after the original file
+// has been backed up. The path of the backup is returned when the
+// file was upgraded.
+func readSettingsFile(path, kind string, v interface{}, logger hclog.Logger) (string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	env, err := ParseEnvelope(raw)
+	if err != nil {
+		return "", &InvalidSettingsError{
+			Err: fmt.Errorf("Failed to parse settings file %s: %w", path, err)}
+	}
+	version := env.Version
+	if err := env.Migrate(kind); err != nil {
+		err = fmt.Errorf("Failed to load settings file %s: %w", path, err)
+		if errors.Is(err, ErrUnsupportedVersion) {
+			return "", err
+		}
+		return "", &InvalidSettingsError{Err: err}
+	}
+	if err := json.Unmarshal(env.Data, v); err != nil {
+		return "", &InvalidSettingsError{
+			Err: fmt.Errorf("Failed to decode settings file %s: %w", path, err)}
+	}
+	if version == env.Version {
+		return "", nil
+	}
+
+	backup := fmt.Sprintf("%s.%s.v%d%s", path,
+		time.Now().UTC().Format(SETTINGS_BACKUP_TIME_FORMAT), version, SETTINGS_BACKUP_EXT)
+	if err := os.WriteFile(backup, raw, 0644); err != nil {
+		return "", fmt.Errorf("Failed to backup settings file %s before upgrade: %w", path, err)
+	}
+	if err := writeSettingsFile(path, kind, env.Data); err != nil {
+		return "", err
+	}
+	logger.Info("upgraded settings file", "path", path, "from", version,
+		"to", env.Version, "backup", backup)
+	return backup, nil
+}
+
+// Load the settings file and decode the data into v. Invalid
+// files are repaired so the settings remain usable and the repair
+// is recorded. Files using a newer version are never modified.
+func loadSettingsFile(path, kind string, v interface{}, logger hclog.Logger) error {
+	_, err := readSettingsFile(path, kind, v, logger)
+	var invalid *InvalidSettingsError
+	if !errors.As(err, &invalid) {
+		return err
+	}
+	logger.Error("invalid settings file, repairing", "path", path, "error", err)
+	result, rErr := Repair(path, kind, logger)
+	if rErr != nil {
+		logger.Error("failed to repair settings file", "path", path, "error", rErr)
+		return repairError(err)
+	}
+	if _, rErr := readSettingsFile(path, kind, v, logger); rErr != nil {
+		logger.Error("failed to load repaired settings file", "path", path, "error", rErr)
+		return repairError(rErr)
+	}
+	result.Reason = err.Error()
+	logger.Error("repaired invalid settings file", "path", path, "action", result.Action,
+		"invalid-path", result.Backup, "restored-from", result.Restored)
+	loadRepairs.Lock()
+	loadRepairs.results = append(loadRepairs.results, result)
+	loadRepairs.Unlock()
+	return nil
+}
+
+// Add instructions for repairing the settings file to an
+// error loading the settings file. Files using a newer version
+// cannot be repaired so no instructions are added.
+func repairError(err error) error {
+	if errors.Is(err, ErrUnsupportedVersion) {
+		return err
+	}
+	return fmt.Errorf("%w (run `%s settings repair` to repair the settings files)",
+		err, version.APP_NAME)
+}
+
+// Write the settings data to the settings file. The file is
+// written to a temporary file first and then moved into place.
+func writeSettingsFile(path, kind string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(Envelope{
+		Version: SETTINGS_VERSION,
+		Kind:    kind,
+		Data:    data}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if _, err := f.Write(content); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Load a settings file and upgrade it in memory only
+func loadEnvelope(path, kind string) (*Envelope, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	env, err := ParseEnvelope(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := env.Migrate(kind); err != nil {
+		return nil, err
+	}
+	if _, err := countEntries(kind, env.Data); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// Decode the settings data and return the number of entries
+func countEntries(kind string, data json.RawMessage) (int, error) {
+	switch kind {
+	case SETTINGS_KIND_NAT:
+		info := &NatInfo{}
+		if err := json.Unmarshal(data, info); err != nil {
+			return 0, err
+		}
+		return len(info.Fwds), nil
+	case SETTINGS_KIND_PORT_FORWARDING:
+		pf := &PortForwarding{}
+		if err := json.Unmarshal(data, pf); err != nil {
+			return 0, err
+		}
+		return len(pf.Forwards), nil
+	}
+	return 0, fmt.Errorf("Unknown settings kind %q", kind)
+}
+
+func emptySettings(kind string) interface{} {
+	if kind == SETTINGS_KIND_NAT {
+		return &NatInfo{Fwds: []*utility.PortFwd{}}
+	}
+	return &PortForwarding{Forwards: []*Forward{}}
+}
+
+// Unversioned files contain the settings data without an envelope
+func migrateUnversioned(data json.RawMessage) (json.RawMessage, error) {
+	if len(data) == 0 || data[0] != '{' {
+		return nil, errors.New("Unversioned settings data is not an object")
+	}
+	return data, nil
+}
@@ -0,0 +1,211 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const legacyNat = `{"fwds": [{"hostport": 2222, "protocol": "tcp", "description": "vagrant: a-path"}]}`
+
+func TestSettingsFileUpgrade(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nat.json")
+	if err := os.WriteFile(path, []byte(legacyNat), 0644); err != nil {
+		t.Fatalf("Failed to write nat file: %s", err)
+	}
+	nat, err := LoadNATSettings(path, defaultSettingsLogger())
+	if err != nil {
+		t.Fatalf("Failed to load nat settings: %s", err)
+	}
+	if len(nat.PortFwds()) != 1 || nat.PortFwds()[0].Description != "vagrant: a-path" {
+		t.Fatalf("Port forwards not loaded from unversioned file")
+	}
+
+	data, _ := os.ReadFile(path)
+	env := &Envelope{}
+	if err := json.Unmarshal(data, env); err != nil {
+		t.Fatalf("Failed to parse upgraded nat file: %s", err)
+	}
+	if env.Version != SETTINGS_VERSION || env.Kind != SETTINGS_KIND_NAT {
+		t.Errorf("Invalid upgraded nat file version %d kind %q", env.Version, env.Kind)
+	}
+	// Releases before versioning decode the file directly
+	info := &NatInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		t.Fatalf("Failed to decode upgraded nat file: %s", err)
+	}
+	if len(info.Fwds) != 1 || info.Fwds[0].Description != "vagrant: a-path" {
+		t.Errorf("Port forwards not at top level of upgraded nat file: %s", data)
+	}
+	backups, _ := Backups(path)
+	if len(backups) != 1 {
+		t.Fatalf("Invalid number of nat file backups %d != 1", len(backups))
+	}
+	data, _ = os.ReadFile(backups[0])
+	if string(data) != legacyNat {
+		t.Errorf("Invalid nat file backup contents '%s'", data)
+	}
+
+	// Reloading the upgraded file does not create another backup
+	if err := nat.Reload(); err != nil {
+		t.Fatalf("Failed to reload nat settings: %s", err)
+	}
+	if backups, _ = Backups(path); len(backups) != 1 {
+		t.Errorf("Invalid number of nat file backups %d != 1", len(backups))
+	}
+}
+
+func TestSettingsFileNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "portforwarding.json")
+	content := `{"version": 99, "kind": "portforwarding", "forwards": []}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write port forwarding file: %s", err)
+	}
+	_, err := LoadPortForwardingSettings(path, defaultSettingsLogger())
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected unsupported version error but received: %v", err)
+	} else if strings.Contains(err.Error(), "settings repair") {
+		t.Errorf("Unexpected repair instructions for newer version: %s", err)
+	}
+	if _, err := Repair(path, SETTINGS_KIND_PORT_FORWARDING, defaultSettingsLogger()); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected unsupported version error but received: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != content {
+		t.Errorf("Settings file using newer version was modified")
+	}
+}
+
+func TestSettingsFileInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nat.json")
+	if err := os.WriteFile(path, []byte("{invalid"), 0644); err != nil {
+		t.Fatalf("Failed to write nat file: %s", err)
+	}
+	status := Inspect(path, SETTINGS_KIND_NAT)
+	if !status.Exists || status.Valid || status.Error == "" {
+		t.Errorf("Expected invalid nat file status: %#v", status)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "{invalid" {
+		t.Errorf("Invalid nat file was modified by inspection")
+	}
+
+	nat, err := LoadNATSettings(path, defaultSettingsLogger())
+	if err != nil {
+		t.Fatalf("Failed to load invalid nat file: %s", err)
+	}
+	if len(nat.PortFwds()) != 0 {
+		t.Errorf("Expected empty nat settings after reset")
+	}
+	var result *RepairResult
+	for _, r := range Repairs() {
+		if r.Path == path {
+			result = r
+		}
+	}
+	if result == nil {
+		t.Fatalf("Repair of invalid nat file not recorded")
+	}
+	if result.Action != REPAIR_RESET || result.Reason == "" {
+		t.Errorf("Invalid repair result: %#v", result)
+	}
+	if data, _ := os.ReadFile(result.Backup); string(data) != "{invalid" {
+		t.Errorf("Invalid nat file was not kept: %s", result.Backup)
+	}
+	if status := Inspect(path, SETTINGS_KIND_NAT); !status.Valid {
+		t.Errorf("Expected valid nat file after repair: %#v", status)
+	}
+}
+
+func TestSettingsFileInvalidRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "portforwarding.json")
+	legacy := `{"forwards": [{"host": {"host": "0.0.0.0", "port": 2222, "type": "tcp"}, ` +
+		`"guest": {"host": "192.168.1.2", "port": 22, "type": "tcp"}, "description": "ssh"}]}`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write port forwarding file: %s", err)
+	}
+	if _, err := LoadPortForwardingSettings(path, defaultSettingsLogger()); err != nil {
+		t.Fatalf("Failed to load port forwarding settings: %s", err)
+	}
+	if err := os.WriteFile(path, []byte(`{"version": 1, "kind": "portforwarding", "forwards": 5}`), 0644); err != nil {
+		t.Fatalf("Failed to write port forwarding file: %s", err)
+	}
+	pf, err := LoadPortForwardingSettings(path, defaultSettingsLogger())
+	if err != nil {
+		t.Fatalf("Failed to load invalid port forwarding file: %s", err)
+	}
+	if len(pf.Forwards) != 1 || pf.Forwards[0].Description != "ssh" {
+		t.Errorf("Port forwards not restored from backup")
+	}
+	found := false
+	for _, r := range Repairs() {
+		if r.Path == path && r.Action == REPAIR_RESTORED {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Restore of invalid port forwarding file not recorded")
+	}
+}
+
+func TestSettingsFileRepair(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nat.json")
+	if err := os.WriteFile(path, []byte(legacyNat), 0644); err != nil {
+		t.Fatalf("Failed to write nat file: %s", err)
+	}
+	if status := Inspect(path, SETTINGS_KIND_NAT); !status.Valid || !status.Outdated || status.Entries != 1 {
+		t.Fatalf("Invalid unversioned nat file status: %#v", status)
+	}
+	result, err := Repair(path, SETTINGS_KIND_NAT, defaultSettingsLogger())
+	if err != nil {
+		t.Fatalf("Failed to repair nat file: %s", err)
+	}
+	if result.Action != REPAIR_UPGRADED || result.Backup == "" {
+		t.Errorf("Invalid repair result: %#v", result)
+	}
+
+	if err := os.WriteFile(path, []byte("{invalid"), 0644); err != nil {
+		t.Fatalf("Failed to write nat file: %s", err)
+	}
+	result, err = Repair(path, SETTINGS_KIND_NAT, defaultSettingsLogger())
+	if err != nil {
+		t.Fatalf("Failed to repair nat file: %s", err)
+	}
+	if result.Action != REPAIR_RESTORED {
+		t.Errorf("Expected nat file to be restored from backup: %#v", result)
+	}
+	if data, _ := os.ReadFile(result.Backup); string(data) != "{invalid" {
+		t.Errorf("Invalid nat file was not kept: %s", result.Backup)
+	}
+	nat, err := LoadNATSettings(path, defaultSettingsLogger())
+	if err != nil {
+		t.Fatalf("Failed to load repaired nat settings: %s", err)
+	}
+	if len(nat.PortFwds()) != 1 {
+		t.Errorf("Port forwards not restored from backup")
+	}
+}
+
+func TestSettingsFileRepairReset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "portforwarding.json")
+	if err := os.WriteFile(path, []byte(`{"version": 1, "kind": "nat", "fwds": []}`), 0644); err != nil {
+		t.Fatalf("Failed to write port forwarding file: %s", err)
+	}
+	result, err := Repair(path, SETTINGS_KIND_PORT_FORWARDING, defaultSettingsLogger())
+	if err != nil {
+		t.Fatalf("Failed to repair port forwarding file: %s", err)
+	}
+	if result.Action != REPAIR_RESET {
+		t.Errorf("Expected port forwarding file to be reset: %#v", result)
+	}
+	status := Inspect(path, SETTINGS_KIND_PORT_FORWARDING)
+	if !status.Valid || status.Outdated || status.Entries != 0 {
+		t.Errorf("Invalid repaired port forwarding file status: %#v", status)
+	}
+}
@@ -4,11 +4,9 @@
 package settings
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
-	"path"
 	"sync"
 
 	hclog "github.com/hashicorp/go-hclog"
@@ -109,21 +107,10 @@ func (n *NAT) Reload() error {
 		return nil
 	}
 	var info NatInfo
-	data, err := os.ReadFile(n.Path)
-	if err != nil {
-		n.logger.Error("failed to read nat file", "error", err, "path", n.Path)
+	if err := loadSettingsFile(n.Path, SETTINGS_KIND_NAT, &info, n.logger); err != nil {
+		n.logger.Error("failed to load nat data", "error", err, "path", n.Path)
 		return err
 	}
-	if err := json.Unmarshal(data, &info); err != nil {
-		n.logger.Error("failed to load nat data", "error", err)
-		if renameErr := os.Rename(n.Path, n.Path+".invalid"); renameErr != nil {
-			n.logger.Error("failed to move invalid nat settings file", "error", renameErr)
-			return err
-		}
-		n.logger.Warn("moved invalid nat settings file, clearing", "invalid-path", n.Path+".invalid")
-
-		return nil
-	}
 	n.info = info
 	return nil
 }
@@ -131,32 +118,7 @@ func (n *NAT) Reload() error {
 func (n *NAT) Save() error {
 	n.access.Lock()
 	defer n.access.Unlock()
-	if !n.exists() {
-		if err := os.MkdirAll(path.Dir(n.Path), 0755); err != nil {
-			n.logger.Error("failed to create parent directory", "error", err, "path", n.Path)
-			return err
-		}
-	}
-	data, err := json.MarshalIndent(n.info, "", "  ")
-	if err != nil {
-		n.logger.Error("failed to dump nat data", "error", err)
-		return err
-	}
-	f, err := os.CreateTemp(path.Dir(n.Path), "nat")
-	if err != nil {
-		n.logger.Error("failed to create file", "error", err, "path", f.Name())
-		return err
-	}
-	defer f.Close()
-	if _, err := f.Write(data); err != nil {
-		n.logger.Error("failed to write file", "error", err, "path", f.Name())
-		return err
-	}
-	if err := f.Close(); err != nil {
-		n.logger.Error("failed closing new file", "error", err, "path", f.Name())
-		return err
-	}
-	if err := os.Rename(f.Name(), n.Path); err != nil {
+	if err := writeSettingsFile(n.Path, SETTINGS_KIND_NAT, n.info); err != nil {
 		n.logger.Error("failed to save file", "error", err, "path", n.Path)
 		return err
 	}
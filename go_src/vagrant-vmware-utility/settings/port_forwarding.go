@@ -4,11 +4,8 @@
 package settings
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"path"
 	"sync"
 
 	hclog "github.com/hashicorp/go-hclog"
@@ -25,9 +22,18 @@ func (a *Address) Network() string {
 }
 
 func (a *Address) String() string {
+	if a.IsPath() {
+		return a.Host
+	}
 	return fmt.Sprintf("%s:%d", a.Host, a.Port)
 }
 
+// Address is a filesystem path (unix socket or named pipe)
+// instead of a host and port
+func (a *Address) IsPath() bool {
+	return a.Type == "unix" || a.Type == "npipe"
+}
+
 func (a *Address) Equal(a1 *Address) bool {
 	return a.Host == a1.Host &&
 		a.Port == a1.Port &&
@@ -35,9 +41,13 @@ func (a *Address) Equal(a1 *Address) bool {
 }
 
 type Forward struct {
-	Host        *Address `json:"host"`
-	Guest       *Address `json:"guest"`
-	Description string   `json:"description"`
+	Host          *Address `json:"host"`
+	Guest         *Address `json:"guest"`
+	Description   string   `json:"description"`
+	ProxyProtocol string   `json:"proxy_protocol,omitempty"`
+	// Access permissions for unix sockets (octal file mode) or
+	// named pipes (SDDL security descriptor)
+	Permissions string `json:"permissions,omitempty"`
 }
 
 func (f *Forward) Equal(f1 *Forward) bool {
@@ -110,33 +120,7 @@ func (p *PortForwarding) Save(presave func() error) error {
 		}
 	}
 
-	if !p.exists() {
-		if err := os.MkdirAll(path.Dir(p.Path), 0755); err != nil {
-			p.logger.Error("failed to create parent directory", "error", err, "path", p.Path)
-			return err
-		}
-	}
-	data, err := json.MarshalIndent(p, "", "  ")
-	if err != nil {
-		p.logger.Error("failed to dump forward data", "error", err)
-		return err
-	}
-
-	f, err := ioutil.TempFile(path.Dir(p.Path), "pfwds")
-	if err != nil {
-		p.logger.Error("failed to create file", "error", err, "path", f.Name())
-		return err
-	}
-	defer f.Close()
-	if _, err := f.Write(data); err != nil {
-		p.logger.Error("failed to write file", "error", err, "path", f.Name())
-		return err
-	}
-	if err := f.Close(); err != nil {
-		p.logger.Error("failed closing new file", "error", err, "path", f.Name())
-		return err
-	}
-	if err := os.Rename(f.Name(), p.Path); err != nil {
+	if err := writeSettingsFile(p.Path, SETTINGS_KIND_PORT_FORWARDING, p); err != nil {
 		p.logger.Error("failed to save file", "error", err, "path", p.Path)
 		return err
 	}
@@ -153,12 +137,7 @@ func (p *PortForwarding) Reload() error {
 		p.Forwards = []*Forward{}
 		return nil
 	}
-	data, err := ioutil.ReadFile(p.Path)
-	if err != nil {
-		p.logger.Error("failed to read settings", "error", err)
-		return err
-	}
-	if err := json.Unmarshal(data, p); err != nil {
+	if err := loadSettingsFile(p.Path, SETTINGS_KIND_PORT_FORWARDING, p, p.logger); err != nil {
 		p.logger.Error("failed to load settings", "error", err)
 		return err
 	}
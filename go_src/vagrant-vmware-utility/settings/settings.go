@@ -15,15 +15,24 @@ type Settings struct {
 	PortForwarding *PortForwarding
 }
 
+// Paths of the settings files keyed by the kind of settings
+func Paths() map[string]string {
+	dir := utility.DirectoryFor("settings")
+	return map[string]string{
+		SETTINGS_KIND_NAT:             path.Join(dir, "nat.json"),
+		SETTINGS_KIND_PORT_FORWARDING: path.Join(dir, "portforwarding.json")}
+}
+
 func BuildSettings(logger hclog.Logger) (*Settings, error) {
 	logger = logger.Named("settings")
-	npath := path.Join(utility.DirectoryFor("settings"), "nat.json")
+	paths := Paths()
+	npath := paths[SETTINGS_KIND_NAT]
 	logger.Trace("building nat settings", "path", npath)
 	nat, err := LoadNATSettings(npath, logger)
 	if err != nil {
 		return nil, err
 	}
-	ppath := path.Join(utility.DirectoryFor("settings"), "portforwarding.json")
+	ppath := paths[SETTINGS_KIND_PORT_FORWARDING]
 	logger.Trace("building port forwarding settings", "path", ppath)
 	pfwds, err := LoadPortForwardingSettings(ppath, logger)
 	if err != nil {
@@ -0,0 +1,25 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package util
+
+var ReloadTasks = []func(){}
+
+func RegisterReloadTask(f func()) {
+	L.Lock()
+	defer L.Unlock()
+	ReloadTasks = append(ReloadTasks, f)
+}
+
+// Run registered reload tasks in the order of registration.
+// Returns false when no reload tasks have been registered.
+func RunReloadTasks() bool {
+	L.Lock()
+	tasks := make([]func(), len(ReloadTasks))
+	copy(tasks, ReloadTasks)
+	L.Unlock()
+	for _, task := range tasks {
+		task()
+	}
+	return len(tasks) > 0
+}
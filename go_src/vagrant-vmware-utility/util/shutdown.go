@@ -17,8 +17,15 @@ func RegisterShutdownTask(f func()) {
 	ShutdownTasks = append(ShutdownTasks, f)
 }
 
+// Run registered shutdown tasks. Tasks are run in the reverse
+// order of registration so tasks registered later, which may
+// depend on earlier tasks, are run first.
 func RunShutdownTasks() {
-	for _, f := range ShutdownTasks {
-		f()
+	L.Lock()
+	tasks := make([]func(), len(ShutdownTasks))
+	copy(tasks, ShutdownTasks)
+	L.Unlock()
+	for i := len(tasks) - 1; i >= 0; i-- {
+		tasks[i]()
 	}
 }
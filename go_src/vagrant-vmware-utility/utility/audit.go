@@ -0,0 +1,226 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package utility
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+// Name of the audit log file within the audit directory
+const AUDIT_LOG_FILE = "audit.log"
+
+// Client recorded for actions which were not requested by a client
+const AUDIT_SYSTEM_CLIENT = "system"
+
+// Results recorded for audited actions
+const (
+	AUDIT_RESULT_SUCCESS = "success"
+	AUDIT_RESULT_FAILURE = "failure"
+	AUDIT_RESULT_DENIED  = "denied"
+)
+
+// Maximum size of a single audit record when reading the audit log
+const MAX_AUDIT_RECORD_SIZE = 1024 * 1024
+
+// Single entry within the audit log
+type AuditRecord struct {
+	Time       time.Time              `json:"time"`
+	RequestId  string                 `json:"request_id,omitempty"`
+	Client     string                 `json:"client"`
+	Role       string                 `json:"role,omitempty"`
+	Action     string                 `json:"action"`
+	Arguments  map[string]interface{} `json:"arguments,omitempty"`
+	Result     string                 `json:"result"`
+	Error      string                 `json:"error,omitempty"`
+	DurationMs int64                  `json:"duration_ms"`
+}
+
+// Append only audit log stored as JSON lines
+type AuditLog struct {
+	path   string
+	file   *os.File
+	m      sync.Mutex
+	logger hclog.Logger
+}
+
+var auditLog *AuditLog
+var auditLock sync.RWMutex
+
+// Default path of the audit log
+func AuditLogPath() string {
+	return filepath.Join(DirectoryFor("audit"), AUDIT_LOG_FILE)
+}
+
+// Open the audit log at the given path. The log is created
+// if it does not exist and is only ever appended.
+func OpenAuditLog(path string, logger hclog.Logger) (*AuditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLog{
+		path:   path,
+		file:   f,
+		logger: logger.Named("audit"),
+	}, nil
+}
+
+// Write a record to the audit log
+func (a *AuditLog) Record(record *AuditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	a.m.Lock()
+	defer a.m.Unlock()
+	if _, err := a.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return a.file.Sync()
+}
+
+func (a *AuditLog) Path() string {
+	return a.path
+}
+
+func (a *AuditLog) Close() error {
+	a.m.Lock()
+	defer a.m.Unlock()
+	return a.file.Close()
+}
+
+// Set the audit log used for recording actions. Actions are
+// not recorded when no audit log is set.
+func SetAuditLog(l *AuditLog) {
+	auditLock.Lock()
+	defer auditLock.Unlock()
+	auditLog = l
+}
+
+// Record an action in the audit log. The client and request are
+// extracted from the context. The result is determined by the error
+// and the duration is measured from the start time.
+func Audit(ctx context.Context, action string, args map[string]interface{}, start time.Time, err error) {
+	result := AUDIT_RESULT_SUCCESS
+	if err != nil {
+		result = AUDIT_RESULT_FAILURE
+	}
+	AuditResult(ctx, action, args, start, result, err)
+}
+
+// Record an action in the audit log. Intended to be deferred
+// with a pointer to the named error result of the action.
+func AuditDeferred(ctx context.Context, action string, args map[string]interface{}, start time.Time, err *error) {
+	Audit(ctx, action, args, start, *err)
+}
+
+// Record an action in the audit log with the given result
+func AuditResult(ctx context.Context, action string, args map[string]interface{}, start time.Time, result string, err error) {
+	auditLock.RLock()
+	l := auditLog
+	auditLock.RUnlock()
+	if l == nil {
+		return
+	}
+	record := &AuditRecord{
+		Time:       start.UTC(),
+		RequestId:  RequestId(ctx),
+		Client:     AUDIT_SYSTEM_CLIENT,
+		Action:     action,
+		Arguments:  args,
+		Result:     result,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if identity := Client(ctx); identity != nil {
+		record.Client = identity.Name
+		record.Role = string(identity.Role)
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if rErr := l.Record(record); rErr != nil {
+		l.logger.Error("failed to write audit record", "action", action, "error", rErr)
+	}
+}
+
+// Filter applied when reading the audit log
+type AuditFilter struct {
+	// Only include records at or after this time
+	Since time.Time
+	// Only include records from this client
+	Client string
+	// Only include records with actions starting with this value
+	Action string
+	// Only include records for this request
+	RequestId string
+	// Only include records which did not succeed
+	Failed bool
+	// Maximum number of records returned. The most
+	// recent records are returned.
+	Limit int
+}
+
+func (f *AuditFilter) match(r *AuditRecord) bool {
+	if !f.Since.IsZero() && r.Time.Before(f.Since) {
+		return false
+	}
+	if f.Client != "" && r.Client != f.Client {
+		return false
+	}
+	if f.Action != "" && !strings.HasPrefix(r.Action, f.Action) {
+		return false
+	}
+	if f.RequestId != "" && r.RequestId != f.RequestId {
+		return false
+	}
+	if f.Failed && r.Result == AUDIT_RESULT_SUCCESS {
+		return false
+	}
+	return true
+}
+
+// Read records from the audit log matching the filter. Lines which
+// cannot be parsed, such as a partially written final line, are skipped.
+func ReadAuditLog(path string, filter *AuditFilter) ([]*AuditRecord, error) {
+	if filter == nil {
+		filter = &AuditFilter{}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	records := []*AuditRecord{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), MAX_AUDIT_RECORD_SIZE)
+	for scanner.Scan() {
+		record := &AuditRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			continue
+		}
+		if !filter.match(record) {
+			continue
+		}
+		records = append(records, record)
+		if filter.Limit > 0 && len(records) > filter.Limit {
+			records = records[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
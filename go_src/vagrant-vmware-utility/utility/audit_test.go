@@ -0,0 +1,86 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package utility
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", AUDIT_LOG_FILE)
+	l, err := OpenAuditLog(path, hclog.NewNullLogger())
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %s", err)
+	}
+	SetAuditLog(l)
+	defer SetAuditLog(nil)
+	defer l.Close()
+
+	ctx := ContextWithRequestId(context.Background(), "req-1", hclog.NewNullLogger())
+	ctx = ContextWithClient(ctx, &ClientIdentity{Name: "ci", Role: ROLE_PORT_FORWARD})
+	start := time.Now().Add(-time.Second)
+	Audit(ctx, "portforward.add", map[string]interface{}{"port": 2222}, start, nil)
+	Audit(context.Background(), "vmnet-cli.start", nil, start, errors.New("start failed"))
+	AuditResult(ctx, "api.request", nil, start, AUDIT_RESULT_DENIED, nil)
+
+	// Partially written records are skipped
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %s", err)
+	}
+	f.WriteString(`{"time":"`)
+	f.Close()
+
+	records, err := ReadAuditLog(path, nil)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %s", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Invalid number of records %d != 3", len(records))
+	}
+	r := records[0]
+	if r.Client != "ci" || r.Role != "port-forward" || r.RequestId != "req-1" ||
+		r.Result != AUDIT_RESULT_SUCCESS || r.Arguments["port"] != float64(2222) {
+		t.Errorf("Invalid record: %#v", r)
+	}
+	if r.DurationMs < 1000 {
+		t.Errorf("Invalid duration %d", r.DurationMs)
+	}
+	r = records[1]
+	if r.Client != AUDIT_SYSTEM_CLIENT || r.Result != AUDIT_RESULT_FAILURE || r.Error != "start failed" {
+		t.Errorf("Invalid record: %#v", r)
+	}
+
+	for _, c := range []struct {
+		filter  *AuditFilter
+		actions []string
+	}{
+		{&AuditFilter{Client: "ci"}, []string{"portforward.add", "api.request"}},
+		{&AuditFilter{Action: "vmnet"}, []string{"vmnet-cli.start"}},
+		{&AuditFilter{Failed: true}, []string{"vmnet-cli.start", "api.request"}},
+		{&AuditFilter{Limit: 1}, []string{"api.request"}},
+		{&AuditFilter{Since: time.Now().Add(time.Hour)}, []string{}},
+	} {
+		records, err := ReadAuditLog(path, c.filter)
+		if err != nil {
+			t.Fatalf("Failed to read audit log: %s", err)
+		}
+		if len(records) != len(c.actions) {
+			t.Errorf("Invalid records for filter %#v: %d != %d", c.filter, len(records), len(c.actions))
+			continue
+		}
+		for i, action := range c.actions {
+			if records[i].Action != action {
+				t.Errorf("Invalid record action for filter %#v: %s != %s", c.filter, records[i].Action, action)
+			}
+		}
+	}
+}
@@ -4,8 +4,13 @@
 package utility
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
@@ -15,167 +20,668 @@ import (
 	"net"
 	"os"
 	"path"
+	"strings"
+	"sync"
 	"time"
 )
 
 // number of years until certificate expiry
 const CERTIFICATE_EXPIRES_IN = 10
 
+// certificates expiring within this window should be regenerated
+const CERTIFICATE_RENEWAL_WINDOW = 90 * 24 * time.Hour
+
+// default time the previous certificate is trusted after rotation
+const CERTIFICATE_OVERLAP = 7 * 24 * time.Hour
+
+// PEM header of the previous certificate recording the end of its overlap window
+const CERTIFICATE_OVERLAP_HEADER = "Overlap-Until"
+
+type KeyType string
+
+// Types of keys which may be generated for certificates
+const (
+	KEY_TYPE_RSA     KeyType = "rsa"
+	KEY_TYPE_ECDSA   KeyType = "ecdsa"
+	KEY_TYPE_ED25519 KeyType = "ed25519"
+)
+
+// size of generated RSA keys
+const RSA_KEY_SIZE = 4096
+
+var certificateDirectory string
+var certificateLock sync.Mutex
+
+type ClientCertificatePaths struct {
+	Certificate string
+	PrivateKey  string
+}
+
 type CertificatePaths struct {
-	Certificate       string
-	PrivateKey        string
-	ClientCertificate string
-	ClientKey         string
+	Certificate         string
+	PrivateKey          string
+	ClientCertificate   string
+	ClientKey           string
+	PreviousCertificate string
+	CaCertificate       string
+	CaKey               string
+}
+
+// Options used when generating certificates
+type CertificateOptions struct {
+	// Type of key generated for each certificate
+	KeyType KeyType
+	// Sign the server and client certificates with a dedicated
+	// certificate authority instead of the server certificate
+	SplitCa bool
+}
+
+// Certificate and key used for signing client certificates. The
+// certificate authority is used when present, otherwise the server
+// certificate signs client certificates.
+func (p *CertificatePaths) Signing() (string, string) {
+	if FileExists(p.CaCertificate) {
+		return p.CaCertificate, p.CaKey
+	}
+	return p.Certificate, p.PrivateKey
+}
+
+// Parse the given key type
+func ParseKeyType(v string) (KeyType, error) {
+	for _, k := range []KeyType{KEY_TYPE_RSA, KEY_TYPE_ECDSA, KEY_TYPE_ED25519} {
+		if string(k) == strings.ToLower(v) {
+			return k, nil
+		}
+	}
+	return "", errors.New(fmt.Sprintf(
+		"unknown key type '%s' (expected %s)", v, strings.Join(KeyTypeNames(), ", ")))
+}
+
+// Names of all supported key types
+func KeyTypeNames() []string {
+	return []string{string(KEY_TYPE_RSA), string(KEY_TYPE_ECDSA), string(KEY_TYPE_ED25519)}
 }
 
-func GenerateCertificate() error {
+// Key type of the given certificate
+func CertificateKeyType(cert *x509.Certificate) KeyType {
+	switch cert.PublicKeyAlgorithm {
+	case x509.ECDSA:
+		return KEY_TYPE_ECDSA
+	case x509.Ed25519:
+		return KEY_TYPE_ED25519
+	}
+	return KEY_TYPE_RSA
+}
+
+// Options matching the currently installed certificates. Default
+// options are returned when no certificates are installed.
+func CurrentCertificateOptions() *CertificateOptions {
+	opts := &CertificateOptions{KeyType: KEY_TYPE_RSA}
 	paths, err := GetCertificatePaths()
 	if err != nil {
-		return errors.New(fmt.Sprintf(
-			"path generation failed: %s", err))
+		return opts
 	}
-	serialMax := new(big.Int).Lsh(big.NewInt(1), 128)
-	serial, err := rand.Int(rand.Reader, serialMax)
-	if err != nil {
-		return errors.New(fmt.Sprintf(
-			"setup failure encountered: %s", err))
+	if cert, err := ReadCertificate(paths.Certificate); err == nil {
+		opts.KeyType = CertificateKeyType(cert)
+	}
+	opts.SplitCa = FileExists(paths.CaCertificate)
+	return opts
+}
+
+// Generate the server and default client certificates. When no
+// options are provided RSA keys are generated and the server
+// certificate signs the client certificate.
+func GenerateCertificate(opts *CertificateOptions) error {
+	if opts == nil {
+		opts = &CertificateOptions{KeyType: KEY_TYPE_RSA}
 	}
-	clientSerial, err := rand.Int(rand.Reader, serialMax)
+	paths, err := GetCertificatePaths()
 	if err != nil {
 		return errors.New(fmt.Sprintf(
-			"setup failure encountered: %s", err))
+			"path generation failed: %s", err))
+	}
+	expires := time.Now().Add(((time.Hour * 24) * 365) * CERTIFICATE_EXPIRES_IN)
+
+	var caBytes []byte
+	var caKey, signerKey crypto.Signer
+	var signer *x509.Certificate
+	if opts.SplitCa {
+		caKey, err = generateKey(opts.KeyType)
+		if err != nil {
+			return errors.New(fmt.Sprintf(
+				"certificate authority key generation failed: %s", err))
+		}
+		serial, err := newSerial()
+		if err != nil {
+			return err
+		}
+		caCert := x509.Certificate{
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+			MaxPathLenZero:        true,
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+			NotBefore:             time.Now(),
+			NotAfter:              expires,
+			SerialNumber:          serial,
+			Subject: pkix.Name{
+				CommonName:   "Vagrant VMware Utility CA",
+				Organization: []string{"HashiCorp"},
+			},
+		}
+		caBytes, err = x509.CreateCertificate(rand.Reader, &caCert, &caCert, caKey.Public(), caKey)
+		if err != nil {
+			return errors.New(fmt.Sprintf(
+				"certificate authority generation failed: %s", err))
+		}
+		if signer, err = x509.ParseCertificate(caBytes); err != nil {
+			return errors.New(fmt.Sprintf(
+				"cert parse failure: %s", err))
+		}
+		signerKey = caKey
 	}
-	privateKey, err := rsa.GenerateKey(rand.Reader, 4096)
+
+	privateKey, err := generateKey(opts.KeyType)
 	if err != nil {
 		return errors.New(fmt.Sprintf(
 			"private key generation failed: %s", err))
 	}
-	expires := time.Now().Add(((time.Hour * 24) * 365) * CERTIFICATE_EXPIRES_IN)
+	serial, err := newSerial()
+	if err != nil {
+		return err
+	}
 	cert := x509.Certificate{
 		BasicConstraintsValid: true,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
 		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
 		IsCA:                  true,
-		KeyUsage: x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature |
-			x509.KeyUsageCertSign | x509.KeyUsageDataEncipherment,
-		NotBefore:    time.Now(),
-		NotAfter:     expires,
-		SerialNumber: serial,
+		KeyUsage:              keyUsage(privateKey, x509.KeyUsageDigitalSignature|x509.KeyUsageCertSign),
+		NotBefore:             time.Now(),
+		NotAfter:              expires,
+		SerialNumber:          serial,
 		Subject: pkix.Name{
 			Organization: []string{"HashiCorp"},
 		},
 	}
-	certBytes, err := x509.CreateCertificate(rand.Reader, &cert, &cert,
-		&privateKey.PublicKey, privateKey)
+	if opts.SplitCa {
+		// The server certificate is only a leaf when
+		// signed by the certificate authority
+		cert.IsCA = false
+		cert.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		cert.KeyUsage = keyUsage(privateKey, x509.KeyUsageDigitalSignature)
+		cert.Subject.CommonName = "127.0.0.1"
+	} else {
+		signer, signerKey = &cert, privateKey
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, &cert, signer,
+		privateKey.Public(), signerKey)
 	if err != nil {
 		return errors.New(fmt.Sprintf(
 			"certificate generation failed: %s", err))
 	}
+	if !opts.SplitCa {
+		if signer, err = x509.ParseCertificate(certBytes); err != nil {
+			return errors.New(fmt.Sprintf(
+				"cert parse failure: %s", err))
+		}
+	}
 
-	if err := os.RemoveAll(paths.Certificate); err != nil {
+	clientKey, err := generateKey(opts.KeyType)
+	if err != nil {
 		return errors.New(fmt.Sprintf(
-			"certificate path cleanup error: %s", err))
+			"client key generation failed: %s", err))
 	}
-	if err := os.RemoveAll(paths.PrivateKey); err != nil {
+	clientSerial, err := newSerial()
+	if err != nil {
+		return err
+	}
+	clientCert := x509.Certificate{
+		BasicConstraintsValid: true,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		IsCA:                  false,
+		KeyUsage:              keyUsage(clientKey, x509.KeyUsageDigitalSignature),
+		NotBefore:             time.Now(),
+		NotAfter:              expires,
+		SerialNumber:          clientSerial,
+		Subject: pkix.Name{
+			CommonName:         DEFAULT_CLIENT_NAME,
+			Organization:       []string{"Vagrant"},
+			OrganizationalUnit: []string{string(ROLE_ADMIN)},
+		},
+	}
+	clientCertBytes, err := x509.CreateCertificate(rand.Reader, &clientCert, signer,
+		clientKey.Public(), signerKey)
+	if err != nil {
+		return errors.New(fmt.Sprintf(
+			"client certificate generation failed: %s", err))
+	}
+
+	// The certificate authority is written first so the new
+	// server and client certificates are trusted once written
+	if opts.SplitCa {
+		if err := writeCertificate(paths.CaCertificate, paths.CaKey, 0600, caBytes, caKey); err != nil {
+			return errors.New(fmt.Sprintf(
+				"certificate authority write failure: %s", err))
+		}
+	}
+	if err := writeCertificate(paths.Certificate, paths.PrivateKey, 0600, certBytes, privateKey); err != nil {
 		return errors.New(fmt.Sprintf(
-			"private key path cleanup error: %s", err))
+			"certificate write failure: %s", err))
 	}
-	if err := os.RemoveAll(paths.ClientCertificate); err != nil {
+	if err := writeCertificate(paths.ClientCertificate, paths.ClientKey, 0644, clientCertBytes, clientKey); err != nil {
 		return errors.New(fmt.Sprintf(
-			"client certificate path cleanup error: %s", err))
+			"client certificate write failure: %s", err))
+	}
+	if !opts.SplitCa {
+		for _, p := range []string{paths.CaCertificate, paths.CaKey} {
+			if err := os.RemoveAll(p); err != nil {
+				return errors.New(fmt.Sprintf(
+					"certificate authority path cleanup error: %s", err))
+			}
+		}
 	}
-	if err := os.RemoveAll(paths.ClientKey); err != nil {
+	// Certificates signed by a previous server certificate
+	// are no longer trusted once new certificates are generated
+	if err := os.RemoveAll(paths.PreviousCertificate); err != nil {
 		return errors.New(fmt.Sprintf(
-			"client key path cleanup error: %s", err))
+			"previous certificate path cleanup error: %s", err))
 	}
 
-	clientKey, err := rsa.GenerateKey(rand.Reader, 4096)
+	return nil
+}
+
+// Rotate the server and client certificates. The current signing
+// certificate is retained for the overlap window so client certificates
+// it signed continue to be accepted while clients pick up the new
+// certificates. Named client certificates are reissued using their
+// existing name and role. Only the most recently replaced signing
+// certificate is retained. When no options are provided the options
+// of the current certificates are used.
+func RotateCertificate(overlap time.Duration, opts *CertificateOptions) error {
+	paths, err := GetCertificatePaths()
 	if err != nil {
 		return errors.New(fmt.Sprintf(
+			"path generation failed: %s", err))
+	}
+	signingPath, _ := paths.Signing()
+	current, err := ReadCertificate(signingPath)
+	if err != nil {
+		return errors.New(fmt.Sprintf(
+			"no valid certificate to rotate: %s", err))
+	}
+	if opts == nil {
+		opts = CurrentCertificateOptions()
+	}
+	identities, err := ClientIdentities()
+	if err != nil {
+		return errors.New(fmt.Sprintf(
+			"client certificate load failure: %s", err))
+	}
+	if err := GenerateCertificate(opts); err != nil {
+		return err
+	}
+	if overlap > 0 {
+		until := time.Now().Add(overlap)
+		if until.After(current.NotAfter) {
+			until = current.NotAfter
+		}
+		block := &pem.Block{
+			Type:    "CERTIFICATE",
+			Headers: map[string]string{CERTIFICATE_OVERLAP_HEADER: until.UTC().Format(time.RFC3339)},
+			Bytes:   current.Raw,
+		}
+		if err := writePemFile(paths.PreviousCertificate, 0644, block); err != nil {
+			return errors.New(fmt.Sprintf(
+				"previous certificate write failure: %s", err))
+		}
+	}
+	for _, identity := range identities {
+		if identity.Name == DEFAULT_CLIENT_NAME {
+			continue
+		}
+		if _, err := GenerateClientCertificate(identity.Name, identity.Role, opts.KeyType); err != nil {
+			return errors.New(fmt.Sprintf(
+				"client certificate %s reissue failure: %s", identity.Name, err))
+		}
+	}
+	return nil
+}
+
+// Read the signing certificate replaced by the most recent rotation
+// and the end of its overlap window. No certificate is returned when
+// there is no previous certificate or its overlap window has ended.
+func ReadPreviousCertificate() (*x509.Certificate, time.Time, error) {
+	paths, err := GetCertificatePaths()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	certPem, err := os.ReadFile(paths.PreviousCertificate)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, time.Time{}, nil
+		}
+		return nil, time.Time{}, errors.New(fmt.Sprintf(
+			"previous certificate read failure: %s", err))
+	}
+	block, _ := pem.Decode(certPem)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, time.Time{}, errors.New(fmt.Sprintf(
+			"certificate decode failure: %s", paths.PreviousCertificate))
+	}
+	until, err := time.Parse(time.RFC3339, block.Headers[CERTIFICATE_OVERLAP_HEADER])
+	if err != nil {
+		return nil, time.Time{}, errors.New(fmt.Sprintf(
+			"invalid certificate overlap: %s", err))
+	}
+	if time.Now().After(until) {
+		return nil, until, nil
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, time.Time{}, errors.New(fmt.Sprintf(
+			"cert parse failure: %s", err))
+	}
+	return cert, until, nil
+}
+
+// SHA-256 fingerprint of the certificate
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// Write the certificate and its private key
+func writeCertificate(certPath, keyPath string, keyMode os.FileMode, certBytes []byte, key crypto.Signer) error {
+	keyBlock, err := encodeKey(key)
+	if err != nil {
+		return err
+	}
+	if err := writePemFile(keyPath, keyMode, keyBlock); err != nil {
+		return err
+	}
+	return writePemFile(certPath, 0644, &pem.Block{Type: "CERTIFICATE", Bytes: certBytes})
+}
+
+// Write the PEM block to a temporary file and move it into place so
+// readers never observe a partially written file
+func writePemFile(filePath string, mode os.FileMode, block *pem.Block) error {
+	tmp, err := os.CreateTemp(path.Dir(filePath), "."+path.Base(filePath))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if err := pem.Encode(tmp, block); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
+
+// Generate a named client certificate with the given role. The
+// client certificate is signed by the certificate authority, or
+// the server certificate when no certificate authority exists, so
+// it will be trusted by the API. When no key type is provided the
+// key type of the signing certificate is used.
+func GenerateClientCertificate(name string, role Role, keyType KeyType) (*ClientCertificatePaths, error) {
+	if !ValidClientName(name) {
+		return nil, errors.New(fmt.Sprintf(
+			"invalid client name '%s'", name))
+	}
+	if _, err := ParseRole(string(role)); err != nil {
+		return nil, err
+	}
+	paths, err := GetCertificatePaths()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf(
+			"path generation failed: %s", err))
+	}
+	parentCert, privateKey, err := loadSigningCertificate(paths)
+	if err != nil {
+		return nil, err
+	}
+	if keyType == "" {
+		keyType = CertificateKeyType(parentCert)
+	}
+	clientPaths, err := GetClientCertificatePaths(name)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf(
+			"path generation failed: %s", err))
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+	clientKey, err := generateKey(keyType)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf(
 			"client key generation failed: %s", err))
 	}
+	expires := time.Now().Add(((time.Hour * 24) * 365) * CERTIFICATE_EXPIRES_IN)
+	if expires.After(parentCert.NotAfter) {
+		expires = parentCert.NotAfter
+	}
 	clientCert := x509.Certificate{
 		BasicConstraintsValid: true,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
-		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
 		IsCA:                  false,
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		KeyUsage:              keyUsage(clientKey, x509.KeyUsageDigitalSignature),
 		NotBefore:             time.Now(),
 		NotAfter:              expires,
-		SerialNumber:          clientSerial,
+		SerialNumber:          serial,
 		Subject: pkix.Name{
-			Organization: []string{"Vagrant"},
+			CommonName:         name,
+			Organization:       []string{"Vagrant"},
+			OrganizationalUnit: []string{string(role)},
 		},
 	}
-	parentCert, err := x509.ParseCertificate(certBytes)
-	if err != nil {
-		return errors.New(fmt.Sprintf(
-			"cert parse failure: %s", err))
-	}
 	clientCertBytes, err := x509.CreateCertificate(rand.Reader, &clientCert, parentCert,
-		&clientKey.PublicKey, privateKey)
+		clientKey.Public(), privateKey)
 	if err != nil {
-		return errors.New(fmt.Sprintf(
+		return nil, errors.New(fmt.Sprintf(
 			"client certificate generation failed: %s", err))
 	}
 
-	certFile, err := os.Create(paths.Certificate)
+	if err := writeCertificate(clientPaths.Certificate, clientPaths.PrivateKey, 0600, clientCertBytes, clientKey); err != nil {
+		return nil, errors.New(fmt.Sprintf(
+			"client certificate write failure: %s", err))
+	}
+
+	return clientPaths, nil
+}
+
+// Load the identities of all named client certificates
+func ClientIdentities() ([]*ClientIdentity, error) {
+	basePath := path.Join(CertificateDirectory(), "clients")
+	entries, err := os.ReadDir(basePath)
 	if err != nil {
-		return errors.New(fmt.Sprintf(
-			"certificate write failure: %s", err))
+		if os.IsNotExist(err) {
+			return []*ClientIdentity{}, nil
+		}
+		return nil, err
 	}
-	defer certFile.Close()
-	if err := pem.Encode(certFile, &pem.Block{Type: "CERTIFICATE", Bytes: certBytes}); err != nil {
-		return errors.New(fmt.Sprintf(
-			"certificate encoding failure: %s", err))
+	identities := []*ClientIdentity{}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".crt" {
+			continue
+		}
+		cert, err := ReadCertificate(path.Join(basePath, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		identity, err := IdentityFromCertificate(cert)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf(
+				"invalid client certificate %s: %s", entry.Name(), err))
+		}
+		identities = append(identities, identity)
 	}
-	keyFile, err := os.OpenFile(paths.PrivateKey, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	return identities, nil
+}
+
+func loadSigningCertificate(paths *CertificatePaths) (*x509.Certificate, crypto.Signer, error) {
+	certPath, keyPath := paths.Signing()
+	cert, err := ReadCertificate(certPath)
 	if err != nil {
-		return errors.New(fmt.Sprintf(
-			"private key write failure: %s", err))
+		return nil, nil, err
 	}
-	defer keyFile.Close()
-	if err := pem.Encode(keyFile, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}); err != nil {
-		return errors.New(fmt.Sprintf(
-			"private key write failure: %s", err))
+	keyPem, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, nil, errors.New(fmt.Sprintf(
+			"private key read failure: %s", err))
+	}
+	block, _ := pem.Decode(keyPem)
+	if block == nil {
+		return nil, nil, errors.New("private key decode failure")
 	}
+	key, err := parseKey(block)
+	if err != nil {
+		return nil, nil, errors.New(fmt.Sprintf(
+			"private key parse failure: %s", err))
+	}
+	return cert, key, nil
+}
 
-	clientCertFile, err := os.Create(paths.ClientCertificate)
+func newSerial() (*big.Int, error) {
+	serialMax := new(big.Int).Lsh(big.NewInt(1), 128)
+	serial, err := rand.Int(rand.Reader, serialMax)
 	if err != nil {
-		return errors.New(fmt.Sprintf(
-			"client certificate write failure: %s", err))
+		return nil, errors.New(fmt.Sprintf(
+			"setup failure encountered: %s", err))
 	}
-	defer clientCertFile.Close()
-	if err := pem.Encode(clientCertFile, &pem.Block{Type: "CERTIFICATE", Bytes: clientCertBytes}); err != nil {
-		return errors.New(fmt.Sprintf(
-			"client certificate encoding failure: %s", err))
+	return serial, nil
+}
+
+func generateKey(keyType KeyType) (crypto.Signer, error) {
+	switch keyType {
+	case KEY_TYPE_RSA, "":
+		return rsa.GenerateKey(rand.Reader, RSA_KEY_SIZE)
+	case KEY_TYPE_ECDSA:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KEY_TYPE_ED25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	}
+	return nil, errors.New(fmt.Sprintf(
+		"unknown key type '%s'", keyType))
+}
+
+// Key encipherment is only valid for RSA keys
+func keyUsage(key crypto.Signer, usage x509.KeyUsage) x509.KeyUsage {
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		usage |= x509.KeyUsageKeyEncipherment
 	}
-	clientKeyFile, err := os.Create(paths.ClientKey)
+	return usage
+}
+
+// RSA keys are encoded as PKCS #1 for compatibility with
+// existing clients and all other keys are encoded as PKCS #8
+func encodeKey(key crypto.Signer) (*pem.Block, error) {
+	if rsaKey, ok := key.(*rsa.PrivateKey); ok {
+		return &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}, nil
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
 	if err != nil {
-		return errors.New(fmt.Sprintf(
-			"client key write failure: %s", err))
+		return nil, err
 	}
-	defer clientKeyFile.Close()
-	if err := pem.Encode(clientKeyFile, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(clientKey)}); err != nil {
-		return errors.New(fmt.Sprintf(
-			"client key write failure: %s", err))
+	return &pem.Block{Type: "PRIVATE KEY", Bytes: der}, nil
+}
+
+func parseKey(block *pem.Block) (crypto.Signer, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
 	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+	return signer, nil
+}
 
-	return nil
+// Read and parse the PEM encoded certificate at the given path
+func ReadCertificate(certPath string) (*x509.Certificate, error) {
+	certPem, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf(
+			"certificate read failure: %s", err))
+	}
+	block, _ := pem.Decode(certPem)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New(fmt.Sprintf(
+			"certificate decode failure: %s", certPath))
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf(
+			"cert parse failure: %s", err))
+	}
+	return cert, nil
+}
+
+// Set the directory certificates are stored within. The
+// default directory is used when set to an empty value.
+func SetCertificateDirectory(dir string) {
+	certificateLock.Lock()
+	defer certificateLock.Unlock()
+	certificateDirectory = dir
+}
+
+// Directory certificates are stored within
+func CertificateDirectory() string {
+	certificateLock.Lock()
+	defer certificateLock.Unlock()
+	if certificateDirectory != "" {
+		return certificateDirectory
+	}
+	return DirectoryFor("certificates")
 }
 
 // Paths are based on platform. If the platform can't be detected
 // then we just use the executable's directory as the base and create
-// a certificate directory within.
+// a certificate directory within. A custom directory may be set
+// using SetCertificateDirectory.
 func GetCertificatePaths() (*CertificatePaths, error) {
-	basePath := DirectoryFor("certificates")
+	basePath := CertificateDirectory()
 	if err := os.MkdirAll(basePath, 0755); err != nil {
 		return nil, err
 	}
 	return &CertificatePaths{
-		Certificate:       path.Join(basePath, "vagrant-utility.crt"),
-		PrivateKey:        path.Join(basePath, "vagrant-utility.key"),
-		ClientCertificate: path.Join(basePath, "vagrant-utility.client.crt"),
-		ClientKey:         path.Join(basePath, "vagrant-utility.client.key"),
+		Certificate:         path.Join(basePath, "vagrant-utility.crt"),
+		PrivateKey:          path.Join(basePath, "vagrant-utility.key"),
+		ClientCertificate:   path.Join(basePath, "vagrant-utility.client.crt"),
+		ClientKey:           path.Join(basePath, "vagrant-utility.client.key"),
+		PreviousCertificate: path.Join(basePath, "vagrant-utility.previous.crt"),
+		CaCertificate:       path.Join(basePath, "vagrant-utility.ca.crt"),
+		CaKey:               path.Join(basePath, "vagrant-utility.ca.key"),
+	}, nil
+}
+
+// Paths for a named client certificate. Named client
+// certificates are stored within the clients directory
+// of the certificate directory.
+func GetClientCertificatePaths(name string) (*ClientCertificatePaths, error) {
+	basePath := path.Join(CertificateDirectory(), "clients")
+	if err := os.MkdirAll(basePath, 0755); err != nil {
+		return nil, err
+	}
+	return &ClientCertificatePaths{
+		Certificate: path.Join(basePath, name+".crt"),
+		PrivateKey:  path.Join(basePath, name+".key"),
 	}, nil
 }
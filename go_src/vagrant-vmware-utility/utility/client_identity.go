@@ -0,0 +1,135 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package utility
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type Role string
+
+// Roles which may be assigned to client certificates. The role
+// is stored as the organizational unit of the client certificate.
+const (
+	// Full access to all API routes
+	ROLE_ADMIN Role = "admin"
+	// Read access and management of port forwards
+	ROLE_PORT_FORWARD Role = "port-forward"
+	// Read access only
+	ROLE_READ_ONLY Role = "read-only"
+)
+
+type Permission string
+
+// Permissions required by API routes
+const (
+	PERMISSION_READ         Permission = "read"
+	PERMISSION_PORT_FORWARD Permission = "port-forward"
+	PERMISSION_ADMIN        Permission = "admin"
+)
+
+// Name used for the client certificate generated alongside
+// the server certificate, and for client certificates which
+// were generated before named clients were supported
+const DEFAULT_CLIENT_NAME = "vagrant"
+
+var rolePermissions = map[Role][]Permission{
+	ROLE_ADMIN:        {PERMISSION_READ, PERMISSION_PORT_FORWARD, PERMISSION_ADMIN},
+	ROLE_PORT_FORWARD: {PERMISSION_READ, PERMISSION_PORT_FORWARD},
+	ROLE_READ_ONLY:    {PERMISSION_READ},
+}
+
+var clientNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,63}$`)
+
+// Identity of a client extracted from its certificate
+type ClientIdentity struct {
+	Name   string
+	Role   Role
+	Serial string
+}
+
+// Check if the client is allowed the given permission
+func (c *ClientIdentity) Allowed(p Permission) bool {
+	for _, allowed := range rolePermissions[c.Role] {
+		if allowed == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Build the identity of a client from its certificate. Certificates
+// without a role were generated before roles were supported and are
+// given the admin role.
+func IdentityFromCertificate(cert *x509.Certificate) (*ClientIdentity, error) {
+	identity := &ClientIdentity{
+		Name: cert.Subject.CommonName,
+		Role: ROLE_ADMIN,
+	}
+	if cert.SerialNumber != nil {
+		identity.Serial = cert.SerialNumber.Text(16)
+	}
+	if identity.Name == "" {
+		identity.Name = DEFAULT_CLIENT_NAME
+	}
+	switch len(cert.Subject.OrganizationalUnit) {
+	case 0:
+	case 1:
+		role, err := ParseRole(cert.Subject.OrganizationalUnit[0])
+		if err != nil {
+			return nil, err
+		}
+		identity.Role = role
+	default:
+		return nil, fmt.Errorf("client certificate contains multiple roles")
+	}
+	return identity, nil
+}
+
+// Validate the given role name
+func ParseRole(name string) (Role, error) {
+	role := Role(name)
+	if _, ok := rolePermissions[role]; !ok {
+		return "", fmt.Errorf("invalid role '%s' (valid roles: %s)", name,
+			strings.Join(RoleNames(), ", "))
+	}
+	return role, nil
+}
+
+// Names of all available roles
+func RoleNames() []string {
+	names := []string{}
+	for role := range rolePermissions {
+		names = append(names, string(role))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate the given client name
+func ValidClientName(name string) bool {
+	return clientNamePattern.MatchString(name)
+}
+
+const clientIdentityKey contextKey = "client-identity"
+
+// Attach the client identity to the context
+func ContextWithClient(ctx context.Context, identity *ClientIdentity) context.Context {
+	return context.WithValue(ctx, clientIdentityKey, identity)
+}
+
+// Client identity attached to the context. Returns nil
+// if no identity is attached.
+func Client(ctx context.Context) *ClientIdentity {
+	if ctx == nil {
+		return nil
+	}
+	identity, _ := ctx.Value(clientIdentityKey).(*ClientIdentity)
+	return identity
+}
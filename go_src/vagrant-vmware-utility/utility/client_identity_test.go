@@ -0,0 +1,82 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package utility
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+)
+
+func TestIdentityFromCertificate(t *testing.T) {
+	cert := &x509.Certificate{
+		SerialNumber: big.NewInt(255),
+		Subject: pkix.Name{
+			CommonName:         "monitoring",
+			OrganizationalUnit: []string{"read-only"},
+		},
+	}
+	identity, err := IdentityFromCertificate(cert)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if identity.Name != "monitoring" || identity.Role != ROLE_READ_ONLY || identity.Serial != "ff" {
+		t.Errorf("Invalid identity: %#v", identity)
+	}
+
+	legacy, err := IdentityFromCertificate(&x509.Certificate{
+		Subject: pkix.Name{Organization: []string{"Vagrant"}}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if legacy.Name != DEFAULT_CLIENT_NAME || legacy.Role != ROLE_ADMIN {
+		t.Errorf("Invalid legacy identity: %#v", legacy)
+	}
+
+	for _, ou := range [][]string{{"superuser"}, {"admin", "read-only"}} {
+		cert.Subject.OrganizationalUnit = ou
+		if _, err := IdentityFromCertificate(cert); err == nil {
+			t.Errorf("Expected error for roles %v", ou)
+		}
+	}
+}
+
+func TestClientIdentityAllowed(t *testing.T) {
+	for _, c := range []struct {
+		role    Role
+		allowed []Permission
+		denied  []Permission
+	}{
+		{ROLE_ADMIN, []Permission{PERMISSION_READ, PERMISSION_PORT_FORWARD, PERMISSION_ADMIN}, nil},
+		{ROLE_PORT_FORWARD, []Permission{PERMISSION_READ, PERMISSION_PORT_FORWARD}, []Permission{PERMISSION_ADMIN}},
+		{ROLE_READ_ONLY, []Permission{PERMISSION_READ}, []Permission{PERMISSION_PORT_FORWARD, PERMISSION_ADMIN}},
+		{Role("unknown"), nil, []Permission{PERMISSION_READ}},
+	} {
+		identity := &ClientIdentity{Name: "test", Role: c.role}
+		for _, p := range c.allowed {
+			if !identity.Allowed(p) {
+				t.Errorf("Role %s should be allowed %s", c.role, p)
+			}
+		}
+		for _, p := range c.denied {
+			if identity.Allowed(p) {
+				t.Errorf("Role %s should not be allowed %s", c.role, p)
+			}
+		}
+	}
+}
+
+func TestValidClientName(t *testing.T) {
+	for _, name := range []string{"monitoring", "ci-agent.01", "a_b"} {
+		if !ValidClientName(name) {
+			t.Errorf("Expected valid client name '%s'", name)
+		}
+	}
+	for _, name := range []string{"", "-agent", "../escape", "a/b", "has space"} {
+		if ValidClientName(name) {
+			t.Errorf("Expected invalid client name '%s'", name)
+		}
+	}
+}
@@ -4,11 +4,24 @@
 package utility
 
 import (
+	"context"
 	"os"
 	"os/exec"
 	"syscall"
+	"time"
 )
 
+// Time allowed for a command's output to be closed after the
+// command has been killed due to its context being done
+const COMMAND_WAIT_DELAY = 5 * time.Second
+
+// Creates a command which is killed when the context is done.
+func CommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = COMMAND_WAIT_DELAY
+	return cmd
+}
+
 // Runs the given command and returns the exit code.
 func Execute(cmd *exec.Cmd) int {
 	exitCode := 1
@@ -0,0 +1,30 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package utility
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCommandContextTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sleep command not available")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	exitCode, _ := ExecuteWithOutput(CommandContext(ctx, "sleep", "10"))
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Command was not killed when context expired")
+	}
+	if exitCode == 0 {
+		t.Errorf("Expected non-zero exit code for killed command")
+	}
+	if ctx.Err() == nil {
+		t.Errorf("Expected context to be expired")
+	}
+}
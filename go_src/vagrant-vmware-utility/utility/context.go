@@ -0,0 +1,47 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package utility
+
+import (
+	"context"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+type contextKey string
+
+const requestIdKey contextKey = "request-id"
+
+// Attach a request ID to the context. A request scoped logger
+// which includes the request ID is also attached to the context
+// using the given logger.
+func ContextWithRequestId(ctx context.Context, id string, logger hclog.Logger) context.Context {
+	ctx = context.WithValue(ctx, requestIdKey, id)
+	return hclog.WithContext(ctx, logger, "request-id", id)
+}
+
+// Request ID attached to the context. Returns an empty string
+// if no request ID is attached.
+func RequestId(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIdKey).(string)
+	return id
+}
+
+// Logger scoped to the request attached to the context. The
+// arguments of the request logger are applied to the given logger
+// so the name of the given logger is retained. If no request logger
+// is attached, the given logger is returned.
+func ContextLogger(ctx context.Context, logger hclog.Logger) hclog.Logger {
+	if ctx == nil {
+		return logger
+	}
+	rl := hclog.FromContext(ctx)
+	if rl == hclog.L() {
+		return logger
+	}
+	return logger.With(rl.ImpliedArgs()...)
+}
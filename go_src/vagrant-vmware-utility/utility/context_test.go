@@ -0,0 +1,40 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package utility
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+func TestContextLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	base := hclog.New(&hclog.LoggerOptions{Output: buf, Level: hclog.Debug})
+	ctx := ContextWithRequestId(context.Background(), "abc123", base.Named("api"))
+	if id := RequestId(ctx); id != "abc123" {
+		t.Errorf("Invalid request id '%s' != 'abc123'", id)
+	}
+	ContextLogger(ctx, base.Named("driver")).Debug("test message")
+	out := buf.String()
+	if !strings.Contains(out, "driver: test message") {
+		t.Errorf("Logger name not retained: %s", out)
+	}
+	if !strings.Contains(out, "request-id=abc123") {
+		t.Errorf("Request id not included: %s", out)
+	}
+}
+
+func TestContextLoggerNoRequest(t *testing.T) {
+	logger := hclog.NewNullLogger()
+	if ContextLogger(context.Background(), logger) != logger {
+		t.Errorf("Expected provided logger to be returned")
+	}
+	if id := RequestId(context.Background()); id != "" {
+		t.Errorf("Expected empty request id, received '%s'", id)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package utility
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Time format used in the name of rotated log files
+const LOG_FILE_TIME_FORMAT = "2006-01-02T15-04-05.000"
+
+// Extension of compressed rotated log files
+const LOG_FILE_COMPRESSED_EXT = ".gz"
+
+// Options for writing log files. Rotation is
+// disabled when no maximum size or age is set.
+type LogFileOptions struct {
+	// Append to an existing log file instead of truncating it
+	Append bool
+	// Size in bytes the log file may reach before it is rotated
+	MaxSize int64
+	// Time the log file may be written before it is rotated
+	MaxAge time.Duration
+	// Number of rotated log files to keep. All are kept when zero.
+	MaxBackups int
+	// Compress rotated log files
+	Compress bool
+}
+
+// Log file which is rotated once it exceeds the maximum size or
+// age. Rotated files are renamed with the time of the rotation,
+// optionally compressed, and the oldest are removed once the
+// number of rotated files exceeds the retention.
+type LogFile struct {
+	path    string
+	opts    LogFileOptions
+	file    *os.File
+	size    int64
+	opened  time.Time
+	m       sync.Mutex
+	cleanup sync.Mutex
+	pending sync.WaitGroup
+}
+
+// Open the log file, creating the parent directory if needed
+func OpenLogFile(path string, opts *LogFileOptions) (*LogFile, error) {
+	if opts == nil {
+		opts = &LogFileOptions{}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	l := &LogFile{path: path, opts: *opts}
+	md := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if opts.Append {
+		md = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := l.open(md)
+	if err != nil {
+		return nil, err
+	}
+	l.file = f
+	return l, nil
+}
+
+// Path of the log file
+func (l *LogFile) Path() string {
+	return l.path
+}
+
+// Write to the log file, rotating it first if required. When
+// rotation fails the current file continues to be written and
+// rotation is attempted again on the next write.
+func (l *LogFile) Write(p []byte) (int, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.rotationDue(len(p)) {
+		l.rotate()
+	}
+	n, err := l.file.Write(p)
+	l.size += int64(n)
+	return n, err
+}
+
+// Rotate the log file
+func (l *LogFile) Rotate() error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.rotate()
+}
+
+// Close the log file. Waits for compression and removal
+// of rotated log files to complete.
+func (l *LogFile) Close() error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	err := l.file.Close()
+	l.pending.Wait()
+	return err
+}
+
+func (l *LogFile) rotationDue(n int) bool {
+	if l.opts.MaxSize > 0 && l.size > 0 && l.size+int64(n) > l.opts.MaxSize {
+		return true
+	}
+	return l.opts.MaxAge > 0 && time.Since(l.opened) >= l.opts.MaxAge
+}
+
+func (l *LogFile) open(md int) (*os.File, error) {
+	f, err := os.OpenFile(l.path, md, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	l.size = info.Size()
+	l.opened = time.Now()
+	return f, nil
+}
+
+func (l *LogFile) rotate() error {
+	// The file must be closed before it can be renamed on Windows
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	rotated := l.path + "." + time.Now().UTC().Format(LOG_FILE_TIME_FORMAT)
+	renameErr := os.Rename(l.path, rotated)
+	md := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if renameErr != nil {
+		md = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := l.open(md)
+	if err != nil {
+		return err
+	}
+	l.file = f
+	if renameErr != nil {
+		return renameErr
+	}
+
+	l.pending.Add(1)
+	go func() {
+		defer l.pending.Done()
+		l.cleanup.Lock()
+		defer l.cleanup.Unlock()
+		if l.opts.Compress {
+			compressLogFile(rotated)
+		}
+		l.prune()
+	}()
+	return nil
+}
+
+// Rotated log files from newest to oldest
+func (l *LogFile) Backups() ([]string, error) {
+	matches, err := filepath.Glob(l.path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	backups := []string{}
+	for _, m := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(m, l.path+"."), LOG_FILE_COMPRESSED_EXT)
+		if _, err := time.Parse(LOG_FILE_TIME_FORMAT, stamp); err == nil {
+			backups = append(backups, m)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups, nil
+}
+
+// Remove rotated log files beyond the retention
+func (l *LogFile) prune() {
+	if l.opts.MaxBackups < 1 {
+		return
+	}
+	backups, err := l.Backups()
+	if err != nil {
+		return
+	}
+	for i := l.opts.MaxBackups; i < len(backups); i++ {
+		os.Remove(backups[i])
+	}
+}
+
+// Compress the log file. The uncompressed file is only
+// removed once the compressed file is complete.
+func compressLogFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	tmp := path + LOG_FILE_COMPRESSED_EXT + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if cErr := gz.Close(); err == nil {
+		err = cErr
+	}
+	if cErr := dst.Close(); err == nil {
+		err = cErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+LOG_FILE_COMPRESSED_EXT)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
@@ -0,0 +1,94 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package utility
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogFileRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "utility.log")
+	l, err := OpenLogFile(path, &LogFileOptions{MaxSize: 10, MaxBackups: 2, Compress: true})
+	if err != nil {
+		t.Fatalf("Failed to open log file: %s", err)
+	}
+	for _, line := range []string{"line-1\n", "line-2\n", "line-3\n", "line-4\n"} {
+		if _, err := l.Write([]byte(line)); err != nil {
+			t.Fatalf("Failed to write log file: %s", err)
+		}
+		// Rotated file names include the time of rotation
+		time.Sleep(2 * time.Millisecond)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Failed to close log file: %s", err)
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %s", err)
+	}
+	if string(contents) != "line-4\n" {
+		t.Errorf("Invalid log file contents '%s'", contents)
+	}
+	backups, err := l.Backups()
+	if err != nil {
+		t.Fatalf("Failed to list rotated log files: %s", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("Invalid number of rotated log files %d != 2", len(backups))
+	}
+	for i, expected := range []string{"line-3\n", "line-2\n"} {
+		if !strings.HasSuffix(backups[i], LOG_FILE_COMPRESSED_EXT) {
+			t.Errorf("Rotated log file not compressed: %s", backups[i])
+			continue
+		}
+		f, err := os.Open(backups[i])
+		if err != nil {
+			t.Fatalf("Failed to open rotated log file: %s", err)
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("Failed to read rotated log file: %s", err)
+		}
+		contents, _ := io.ReadAll(gz)
+		f.Close()
+		if string(contents) != expected {
+			t.Errorf("Invalid rotated log file contents '%s' != '%s'", contents, expected)
+		}
+	}
+}
+
+func TestLogFileAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "utility.log")
+	if err := os.WriteFile(path, []byte("previous\n"), 0644); err != nil {
+		t.Fatalf("Failed to write log file: %s", err)
+	}
+	l, err := OpenLogFile(path, &LogFileOptions{Append: true, MaxAge: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to open log file: %s", err)
+	}
+	defer l.Close()
+	l.Write([]byte("first\n"))
+	time.Sleep(30 * time.Millisecond)
+	l.Write([]byte("second\n"))
+
+	contents, _ := os.ReadFile(path)
+	if string(contents) != "second\n" {
+		t.Errorf("Invalid log file contents '%s'", contents)
+	}
+	backups, _ := l.Backups()
+	if len(backups) != 1 {
+		t.Fatalf("Invalid number of rotated log files %d != 1", len(backups))
+	}
+	contents, _ = os.ReadFile(backups[0])
+	if string(contents) != "previous\nfirst\n" {
+		t.Errorf("Invalid rotated log file contents '%s'", contents)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package utility
+
+import (
+	"fmt"
+	"strings"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+// System logs available for log output
+const (
+	LOG_OUTPUT_SYSLOG   = "syslog"
+	LOG_OUTPUT_JOURNALD = "journald"
+	LOG_OUTPUT_EVENTLOG = "eventlog"
+)
+
+// Log output writing to a system log. The level of the
+// message determines the priority within the system log.
+type LogOutput interface {
+	hclog.LevelWriter
+	Write(p []byte) (int, error)
+	Close() error
+}
+
+// Open the system log output. The name identifies the
+// messages written by the utility within the system log.
+func OpenLogOutput(output, name string) (LogOutput, error) {
+	for _, o := range LogOutputNames() {
+		if o == output {
+			return openLogOutput(output, name)
+		}
+	}
+	return nil, fmt.Errorf("unsupported log output '%s' (valid outputs: %s)", output,
+		strings.Join(LogOutputNames(), ", "))
+}
+
+// Trim the trailing newline added by the logger
+func logMessage(p []byte) string {
+	return strings.TrimRight(string(p), "\n")
+}
@@ -0,0 +1,12 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package utility
+
+func LogOutputNames() []string {
+	return []string{LOG_OUTPUT_SYSLOG}
+}
+
+func openLogOutput(output, name string) (LogOutput, error) {
+	return newSyslogOutput(name)
+}
@@ -0,0 +1,87 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package utility
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+// Socket used for the native journald protocol
+const JOURNALD_SOCKET = "/run/systemd/journal/socket"
+
+func LogOutputNames() []string {
+	return []string{LOG_OUTPUT_JOURNALD, LOG_OUTPUT_SYSLOG}
+}
+
+func openLogOutput(output, name string) (LogOutput, error) {
+	if output == LOG_OUTPUT_JOURNALD {
+		return newJournaldOutput(name)
+	}
+	return newSyslogOutput(name)
+}
+
+// Writes messages to journald using the native protocol
+// so the priority and identifier are recorded as fields
+type journaldOutput struct {
+	conn *net.UnixConn
+	name string
+}
+
+func newJournaldOutput(name string) (LogOutput, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: JOURNALD_SOCKET, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &journaldOutput{conn: conn, name: name}, nil
+}
+
+func (j *journaldOutput) Write(p []byte) (int, error) {
+	return j.LevelWrite(hclog.Info, p)
+}
+
+func (j *journaldOutput) LevelWrite(level hclog.Level, p []byte) (int, error) {
+	buf := &bytes.Buffer{}
+	journaldField(buf, "PRIORITY", strconv.Itoa(journaldPriority(level)))
+	journaldField(buf, "SYSLOG_IDENTIFIER", j.name)
+	journaldField(buf, "MESSAGE", logMessage(p))
+	if _, err := j.conn.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (j *journaldOutput) Close() error {
+	return j.conn.Close()
+}
+
+// Syslog priority for the log level
+func journaldPriority(level hclog.Level) int {
+	switch {
+	case level >= hclog.Error:
+		return 3
+	case level == hclog.Warn:
+		return 4
+	case level == hclog.Info:
+		return 6
+	}
+	return 7
+}
+
+// Values containing newlines must be written with an
+// explicit length instead of the simple key=value form
+func journaldField(buf *bytes.Buffer, key, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(key + "=" + value + "\n")
+		return
+	}
+	buf.WriteString(key + "\n")
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value + "\n")
+}
@@ -0,0 +1,52 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build !windows
+// +build !windows
+
+package utility
+
+import (
+	"log/syslog"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+type syslogOutput struct {
+	w *syslog.Writer
+}
+
+func newSyslogOutput(name string) (LogOutput, error) {
+	w, err := syslog.New(syslog.LOG_DAEMON|syslog.LOG_INFO, name)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogOutput{w: w}, nil
+}
+
+func (s *syslogOutput) Write(p []byte) (int, error) {
+	return s.LevelWrite(hclog.Info, p)
+}
+
+func (s *syslogOutput) LevelWrite(level hclog.Level, p []byte) (int, error) {
+	msg := logMessage(p)
+	var err error
+	switch {
+	case level >= hclog.Error:
+		err = s.w.Err(msg)
+	case level == hclog.Warn:
+		err = s.w.Warning(msg)
+	case level == hclog.Info:
+		err = s.w.Info(msg)
+	default:
+		err = s.w.Debug(msg)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (s *syslogOutput) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,56 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package utility
+
+import (
+	hclog "github.com/hashicorp/go-hclog"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// Event ID used for log messages written to the event log
+const EVENTLOG_LOG_EVENT_ID = 100
+
+func LogOutputNames() []string {
+	return []string{LOG_OUTPUT_EVENTLOG}
+}
+
+// Writes messages to the Windows event log. The name must
+// be a registered event source, which is registered when
+// the service is installed.
+func openLogOutput(output, name string) (LogOutput, error) {
+	l, err := eventlog.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &eventlogOutput{l: l}, nil
+}
+
+type eventlogOutput struct {
+	l *eventlog.Log
+}
+
+func (e *eventlogOutput) Write(p []byte) (int, error) {
+	return e.LevelWrite(hclog.Info, p)
+}
+
+func (e *eventlogOutput) LevelWrite(level hclog.Level, p []byte) (int, error) {
+	msg := logMessage(p)
+	var err error
+	switch {
+	case level >= hclog.Error:
+		err = e.l.Error(EVENTLOG_LOG_EVENT_ID, msg)
+	case level == hclog.Warn:
+		err = e.l.Warning(EVENTLOG_LOG_EVENT_ID, msg)
+	default:
+		err = e.l.Info(EVENTLOG_LOG_EVENT_ID, msg)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (e *eventlogOutput) Close() error {
+	return e.l.Close()
+}
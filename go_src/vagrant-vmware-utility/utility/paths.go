@@ -5,8 +5,12 @@ package utility
 
 import (
 	"path/filepath"
+	"sync"
 )
 
+var socketDirectory string
+var socketLock sync.Mutex
+
 func InstallDirectory() string {
 	return installDirectory()
 }
@@ -14,3 +18,22 @@ func InstallDirectory() string {
 func DirectoryFor(thing string) string {
 	return filepath.Join(installDirectory(), thing)
 }
+
+// Set the directory unix sockets of port forwards must be
+// located within. The default directory is used when set
+// to an empty value.
+func SetSocketDirectory(dir string) {
+	socketLock.Lock()
+	defer socketLock.Unlock()
+	socketDirectory = dir
+}
+
+// Directory unix sockets of port forwards must be located within
+func SocketDirectory() string {
+	socketLock.Lock()
+	defer socketLock.Unlock()
+	if socketDirectory != "" {
+		return socketDirectory
+	}
+	return DirectoryFor("sockets")
+}
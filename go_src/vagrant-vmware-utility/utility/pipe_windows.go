@@ -0,0 +1,452 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package utility
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"golang.org/x/sys/windows"
+)
+
+// Prefix of named pipe paths
+const PIPE_PREFIX = `\\.\pipe\`
+
+const PIPE_BUFFER_SIZE = 65536
+
+// Time to wait before retrying a connection to a busy named pipe
+const PIPE_DIAL_RETRY = 50 * time.Millisecond
+
+// Security descriptor granting access to the SYSTEM user and
+// Administrators group. Access for other users is appended.
+const PIPE_BASE_SDDL = "D:P(A;;GA;;;SY)(A;;GA;;;BA)"
+
+type pipeAddr string
+
+func (p pipeAddr) Network() string { return "npipe" }
+func (p pipeAddr) String() string  { return string(p) }
+
+// Named pipe listener
+type PipeListener struct {
+	path    string
+	sa      *windows.SecurityAttributes
+	pending windows.Handle
+	closed  windows.Handle
+	mu      sync.Mutex
+	done    bool
+	logger  hclog.Logger
+}
+
+// Create a named pipe listener with the SDDL security descriptor.
+// The first pipe instance is created immediately to prevent another
+// process from owning the pipe.
+func ListenPipe(path, sddl string, logger hclog.Logger) (*PipeListener, error) {
+	sd, err := windows.SecurityDescriptorFromString(sddl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid named pipe permissions '%s': %w", sddl, err)
+	}
+	l := &PipeListener{
+		path: path,
+		sa: &windows.SecurityAttributes{
+			Length:             uint32(unsafe.Sizeof(windows.SecurityAttributes{})),
+			SecurityDescriptor: sd,
+		},
+		logger: logger,
+	}
+	l.closed, err = windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	l.pending, err = l.createPipe(true)
+	if err != nil {
+		windows.CloseHandle(l.closed)
+		return nil, err
+	}
+	logger.Trace("named pipe listener created", "path", path)
+	return l, nil
+}
+
+func (l *PipeListener) createPipe(first bool) (windows.Handle, error) {
+	name, err := windows.UTF16PtrFromString(l.path)
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+	flags := uint32(windows.PIPE_ACCESS_DUPLEX | windows.FILE_FLAG_OVERLAPPED)
+	if first {
+		flags |= windows.FILE_FLAG_FIRST_PIPE_INSTANCE
+	}
+	mode := uint32(windows.PIPE_TYPE_BYTE | windows.PIPE_READMODE_BYTE |
+		windows.PIPE_WAIT | windows.PIPE_REJECT_REMOTE_CLIENTS)
+	return windows.CreateNamedPipe(name, flags, mode, windows.PIPE_UNLIMITED_INSTANCES,
+		PIPE_BUFFER_SIZE, PIPE_BUFFER_SIZE, 0, l.sa)
+}
+
+// Wait for a client to connect to the named pipe
+func (l *PipeListener) Accept() (net.Conn, error) {
+	return l.AcceptPipe()
+}
+
+// Wait for a client to connect to the named pipe. The next pipe
+// instance is created before the connection is returned so clients
+// do not see the pipe disappear between accepts.
+func (l *PipeListener) AcceptPipe() (*PipeConn, error) {
+	l.mu.Lock()
+	if l.done {
+		l.mu.Unlock()
+		return nil, net.ErrClosed
+	}
+	h := l.pending
+	l.pending = 0
+	l.mu.Unlock()
+
+	var err error
+	if h == 0 {
+		if h, err = l.createPipe(false); err != nil {
+			return nil, err
+		}
+	}
+	event, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		windows.CloseHandle(h)
+		return nil, err
+	}
+	defer windows.CloseHandle(event)
+	ov := &windows.Overlapped{HEvent: event}
+	err = windows.ConnectNamedPipe(h, ov)
+	switch err {
+	case nil, windows.ERROR_PIPE_CONNECTED:
+	case windows.ERROR_IO_PENDING:
+		r, werr := windows.WaitForMultipleObjects([]windows.Handle{event, l.closed}, false, windows.INFINITE)
+		if werr != nil || r != windows.WAIT_OBJECT_0 {
+			var n uint32
+			windows.CancelIoEx(h, ov)
+			windows.GetOverlappedResult(h, ov, &n, true)
+			windows.CloseHandle(h)
+			if werr != nil {
+				return nil, werr
+			}
+			return nil, net.ErrClosed
+		}
+		var n uint32
+		if err := windows.GetOverlappedResult(h, ov, &n, false); err != nil {
+			windows.CloseHandle(h)
+			return nil, err
+		}
+	default:
+		windows.CloseHandle(h)
+		return nil, err
+	}
+
+	l.mu.Lock()
+	if l.done {
+		l.mu.Unlock()
+		windows.CloseHandle(h)
+		return nil, net.ErrClosed
+	}
+	if next, err := l.createPipe(false); err == nil {
+		l.pending = next
+	} else {
+		// Creation is attempted again on the next accept
+		l.logger.Warn("failed to create named pipe instance", "path", l.path, "error", err)
+	}
+	l.mu.Unlock()
+
+	conn, err := newPipeConn(h, l.path)
+	if err != nil {
+		windows.CloseHandle(h)
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (l *PipeListener) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.done {
+		return net.ErrClosed
+	}
+	l.done = true
+	windows.SetEvent(l.closed)
+	if l.pending != 0 {
+		windows.CloseHandle(l.pending)
+		l.pending = 0
+	}
+	return nil
+}
+
+func (l *PipeListener) Addr() net.Addr {
+	return pipeAddr(l.path)
+}
+
+// Connect to a named pipe. The path may be provided with or
+// without the pipe prefix. Connections to a busy pipe are
+// retried until the context is done.
+func DialPipe(ctx context.Context, path string) (*PipeConn, error) {
+	if !strings.HasPrefix(path, PIPE_PREFIX) {
+		path = PIPE_PREFIX + path
+	}
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	// Only allow the server to identify the client, not impersonate it
+	flags := uint32(windows.FILE_FLAG_OVERLAPPED | windows.SECURITY_SQOS_PRESENT | windows.SECURITY_IDENTIFICATION)
+	for {
+		h, err := windows.CreateFile(name, windows.GENERIC_READ|windows.GENERIC_WRITE,
+			0, nil, windows.OPEN_EXISTING, flags, 0)
+		if err == nil {
+			conn, err := newPipeConn(h, path)
+			if err != nil {
+				windows.CloseHandle(h)
+				return nil, err
+			}
+			return conn, nil
+		}
+		if !errors.Is(err, windows.ERROR_PIPE_BUSY) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(PIPE_DIAL_RETRY):
+		}
+	}
+}
+
+// Connect to the local socket of the API
+func DialSocket(ctx context.Context, path string) (net.Conn, error) {
+	return DialPipe(ctx, path)
+}
+
+// Connection on a named pipe using overlapped IO so
+// that deadlines and close are able to interrupt pending IO
+type PipeConn struct {
+	handle        windows.Handle
+	path          string
+	readEvent     windows.Handle
+	writeEvent    windows.Handle
+	readWake      windows.Handle
+	writeWake     windows.Handle
+	closeEvent    windows.Handle
+	mu            sync.Mutex
+	closed        bool
+	readDeadline  time.Time
+	writeDeadline time.Time
+	active        sync.WaitGroup
+}
+
+func newPipeConn(h windows.Handle, path string) (*PipeConn, error) {
+	c := &PipeConn{handle: h, path: path}
+	events := []*windows.Handle{&c.readEvent, &c.writeEvent, &c.readWake, &c.writeWake, &c.closeEvent}
+	for i, e := range events {
+		// IO completion events are manual reset, wake events are auto reset
+		manual := uint32(1)
+		if e == &c.readWake || e == &c.writeWake {
+			manual = 0
+		}
+		var err error
+		if *e, err = windows.CreateEvent(nil, manual, 0, nil); err != nil {
+			for _, created := range events[:i] {
+				windows.CloseHandle(*created)
+			}
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Name of the user owning the process connected to the pipe
+func (c *PipeConn) ClientUser() (string, error) {
+	var pid uint32
+	if err := windows.GetNamedPipeClientProcessId(c.handle, &pid); err != nil {
+		return "", err
+	}
+	process, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return "", err
+	}
+	defer windows.CloseHandle(process)
+	var token windows.Token
+	if err := windows.OpenProcessToken(process, windows.TOKEN_QUERY, &token); err != nil {
+		return "", err
+	}
+	defer token.Close()
+	tokenUser, err := token.GetTokenUser()
+	if err != nil {
+		return "", err
+	}
+	account, domain, _, err := tokenUser.User.Sid.LookupAccount("")
+	if err != nil {
+		return tokenUser.User.Sid.String(), nil
+	}
+	return domain + `\` + account, nil
+}
+
+func (c *PipeConn) Read(b []byte) (int, error) {
+	n, err := c.io(c.readEvent, c.readWake, func() time.Time { return c.readDeadline },
+		func(done *uint32, ov *windows.Overlapped) error {
+			return windows.ReadFile(c.handle, b, done, ov)
+		})
+	if err == nil && n == 0 && len(b) > 0 {
+		err = io.EOF
+	}
+	if isPipeClosed(err) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (c *PipeConn) Write(b []byte) (int, error) {
+	written := 0
+	for written < len(b) {
+		n, err := c.io(c.writeEvent, c.writeWake, func() time.Time { return c.writeDeadline },
+			func(done *uint32, ov *windows.Overlapped) error {
+				return windows.WriteFile(c.handle, b[written:], done, ov)
+			})
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// Perform overlapped IO and wait for it to complete. Pending IO is
+// canceled when the deadline is reached or the connection is closed.
+func (c *PipeConn) io(event, wake windows.Handle, deadline func() time.Time,
+	op func(*uint32, *windows.Overlapped) error) (int, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, net.ErrClosed
+	}
+	if d := deadline(); !d.IsZero() && !time.Now().Before(d) {
+		c.mu.Unlock()
+		return 0, os.ErrDeadlineExceeded
+	}
+	c.active.Add(1)
+	c.mu.Unlock()
+	defer c.active.Done()
+
+	windows.ResetEvent(event)
+	ov := &windows.Overlapped{HEvent: event}
+	var n uint32
+	err := op(&n, ov)
+	if err == nil {
+		return int(n), nil
+	}
+	if err != windows.ERROR_IO_PENDING {
+		return int(n), err
+	}
+	for {
+		timeout := uint32(windows.INFINITE)
+		c.mu.Lock()
+		d := deadline()
+		c.mu.Unlock()
+		if !d.IsZero() {
+			remaining := time.Until(d)
+			if remaining <= 0 {
+				return c.cancel(ov, os.ErrDeadlineExceeded)
+			}
+			timeout = uint32((remaining + time.Millisecond - 1) / time.Millisecond)
+		}
+		r, err := windows.WaitForMultipleObjects([]windows.Handle{event, wake, c.closeEvent}, false, timeout)
+		switch {
+		case err != nil:
+			return c.cancel(ov, err)
+		case r == windows.WAIT_OBJECT_0:
+			if err := windows.GetOverlappedResult(c.handle, ov, &n, false); err != nil {
+				return int(n), err
+			}
+			return int(n), nil
+		case r == windows.WAIT_OBJECT_0+2:
+			return c.cancel(ov, net.ErrClosed)
+		}
+		// Deadline was updated or has been reached so
+		// it is checked again before waiting
+	}
+}
+
+// Cancel pending IO. If the IO completed before it could be
+// canceled the result of the IO is returned.
+func (c *PipeConn) cancel(ov *windows.Overlapped, reason error) (int, error) {
+	var n uint32
+	windows.CancelIoEx(c.handle, ov)
+	err := windows.GetOverlappedResult(c.handle, ov, &n, true)
+	if err == nil {
+		return int(n), nil
+	}
+	if errors.Is(err, windows.ERROR_OPERATION_ABORTED) {
+		return int(n), reason
+	}
+	return int(n), err
+}
+
+func (c *PipeConn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return net.ErrClosed
+	}
+	c.closed = true
+	c.mu.Unlock()
+	windows.SetEvent(c.closeEvent)
+	c.active.Wait()
+	windows.DisconnectNamedPipe(c.handle)
+	err := windows.CloseHandle(c.handle)
+	for _, e := range []windows.Handle{c.readEvent, c.writeEvent, c.readWake, c.writeWake, c.closeEvent} {
+		windows.CloseHandle(e)
+	}
+	return err
+}
+
+func (c *PipeConn) LocalAddr() net.Addr {
+	return pipeAddr(c.path)
+}
+
+func (c *PipeConn) RemoteAddr() net.Addr {
+	return pipeAddr(c.path)
+}
+
+func (c *PipeConn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
+}
+
+func (c *PipeConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+	c.readDeadline = t
+	return windows.SetEvent(c.readWake)
+}
+
+func (c *PipeConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+	c.writeDeadline = t
+	return windows.SetEvent(c.writeWake)
+}
+
+func isPipeClosed(err error) bool {
+	return errors.Is(err, windows.ERROR_BROKEN_PIPE) ||
+		errors.Is(err, windows.ERROR_PIPE_NOT_CONNECTED) ||
+		errors.Is(err, windows.ERROR_NO_DATA)
+}
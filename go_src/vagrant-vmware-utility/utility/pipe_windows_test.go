@@ -0,0 +1,239 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build windows
+// +build windows
+
+package utility
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/user"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+func testPipePath() string {
+	return fmt.Sprintf(`%svagrant-vmware-utility-test-%d-%d`, PIPE_PREFIX, os.Getpid(), time.Now().UnixNano())
+}
+
+func testPipeSddl(t *testing.T) string {
+	u, err := user.Current()
+	if err != nil {
+		t.Fatalf("Failed to determine current user: %s", err)
+	}
+	return PIPE_BASE_SDDL + "(A;;GRGW;;;" + u.Uid + ")"
+}
+
+func TestPipeAcceptDial(t *testing.T) {
+	path := testPipePath()
+	l, err := ListenPipe(path, testPipeSddl(t), hclog.NewNullLogger())
+	if err != nil {
+		t.Fatalf("Failed to create pipe listener: %s", err)
+	}
+	defer l.Close()
+
+	accepted := make(chan *PipeConn, 1)
+	go func() {
+		conn, err := l.AcceptPipe()
+		if err != nil {
+			t.Errorf("Failed to accept pipe connection: %s", err)
+			close(accepted)
+			return
+		}
+		accepted <- conn
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := DialPipe(ctx, path)
+	if err != nil {
+		t.Fatalf("Failed to dial pipe: %s", err)
+	}
+	defer client.Close()
+	server, ok := <-accepted
+	if !ok {
+		t.FailNow()
+	}
+	defer server.Close()
+
+	if _, err := client.Write([]byte("ping")); err != nil {
+		t.Fatalf("Failed to write to pipe: %s", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(server, buf); err != nil || string(buf) != "ping" {
+		t.Fatalf("Failed to read from pipe: %v", err)
+	}
+	if _, err := server.Write([]byte("pong")); err != nil {
+		t.Fatalf("Failed to write to pipe: %s", err)
+	}
+	if _, err := io.ReadFull(client, buf); err != nil || string(buf) != "pong" {
+		t.Fatalf("Failed to read from pipe: %v", err)
+	}
+	if client.RemoteAddr().String() != path || client.RemoteAddr().Network() != "npipe" {
+		t.Errorf("Invalid pipe address %s", client.RemoteAddr())
+	}
+
+	// Dial without the pipe prefix
+	go func() {
+		if conn, err := l.AcceptPipe(); err == nil {
+			conn.Close()
+		}
+	}()
+	conn, err := DialPipe(ctx, path[len(PIPE_PREFIX):])
+	if err != nil {
+		t.Fatalf("Failed to dial pipe without prefix: %s", err)
+	}
+	conn.Close()
+
+	// Closing the client is seen as EOF by the server
+	client.Close()
+	if _, err := server.Read(buf); err != io.EOF {
+		t.Errorf("Expected EOF after client close but received: %v", err)
+	}
+}
+
+func TestPipeClientUser(t *testing.T) {
+	path := testPipePath()
+	l, err := ListenPipe(path, testPipeSddl(t), hclog.NewNullLogger())
+	if err != nil {
+		t.Fatalf("Failed to create pipe listener: %s", err)
+	}
+	defer l.Close()
+
+	accepted := make(chan *PipeConn, 1)
+	go func() {
+		conn, err := l.AcceptPipe()
+		if err != nil {
+			close(accepted)
+			return
+		}
+		accepted <- conn
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := DialPipe(ctx, path)
+	if err != nil {
+		t.Fatalf("Failed to dial pipe: %s", err)
+	}
+	defer client.Close()
+	server, ok := <-accepted
+	if !ok {
+		t.Fatalf("Failed to accept pipe connection")
+	}
+	defer server.Close()
+
+	name, err := server.ClientUser()
+	if err != nil {
+		t.Fatalf("Failed to identify pipe client: %s", err)
+	}
+	u, err := user.Current()
+	if err != nil {
+		t.Fatalf("Failed to determine current user: %s", err)
+	}
+	if name != u.Username {
+		t.Errorf("Invalid pipe client user %s != %s", name, u.Username)
+	}
+}
+
+func TestPipeCloseWhileAccepting(t *testing.T) {
+	l, err := ListenPipe(testPipePath(), testPipeSddl(t), hclog.NewNullLogger())
+	if err != nil {
+		t.Fatalf("Failed to create pipe listener: %s", err)
+	}
+	result := make(chan error, 1)
+	go func() {
+		_, err := l.AcceptPipe()
+		result <- err
+	}()
+	// Allow the accept to begin waiting for a client
+	time.Sleep(100 * time.Millisecond)
+	if err := l.Close(); err != nil {
+		t.Fatalf("Failed to close pipe listener: %s", err)
+	}
+	select {
+	case err := <-result:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Errorf("Expected closed error but received: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Accept was not interrupted by close")
+	}
+	if _, err := l.AcceptPipe(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Expected closed error after close but received: %v", err)
+	}
+	if err := l.Close(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Expected closed error for second close but received: %v", err)
+	}
+}
+
+func TestPipeCloseWhileReading(t *testing.T) {
+	path := testPipePath()
+	l, err := ListenPipe(path, testPipeSddl(t), hclog.NewNullLogger())
+	if err != nil {
+		t.Fatalf("Failed to create pipe listener: %s", err)
+	}
+	defer l.Close()
+	go func() {
+		if conn, err := l.AcceptPipe(); err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := DialPipe(ctx, path)
+	if err != nil {
+		t.Fatalf("Failed to dial pipe: %s", err)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := client.Read(make([]byte, 1))
+		result <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	client.Close()
+	select {
+	case err := <-result:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Errorf("Expected closed error but received: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Read was not interrupted by close")
+	}
+}
+
+func TestPipeReadDeadline(t *testing.T) {
+	path := testPipePath()
+	l, err := ListenPipe(path, testPipeSddl(t), hclog.NewNullLogger())
+	if err != nil {
+		t.Fatalf("Failed to create pipe listener: %s", err)
+	}
+	defer l.Close()
+	go func() {
+		if conn, err := l.AcceptPipe(); err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := DialPipe(ctx, path)
+	if err != nil {
+		t.Fatalf("Failed to dial pipe: %s", err)
+	}
+	defer client.Close()
+
+	client.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if _, err := client.Read(make([]byte, 1)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Expected deadline error but received: %v", err)
+	}
+}
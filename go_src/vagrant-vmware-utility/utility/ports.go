@@ -0,0 +1,32 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+package utility
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Check if the given port is currently free on the host for the
+// given protocol. This is determined by attempting to bind the
+// port on all addresses which mimics how forwards are bound.
+func PortAvailable(protocol string, port int) bool {
+	addr := fmt.Sprintf(":%d", port)
+	if strings.Contains(protocol, "tcp") {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return false
+		}
+		l.Close()
+	}
+	if strings.Contains(protocol, "udp") {
+		c, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return false
+		}
+		c.Close()
+	}
+	return true
+}
@@ -46,6 +46,25 @@ func (r *RoutingDevice) Match(network string) bool {
 	return false
 }
 
+// Network of the device
+func (r *RoutingDevice) Network() *net.IPNet {
+	return &net.IPNet{IP: r.Address, Mask: r.Netmask}
+}
+
+// Check if the network of the device overlaps the given network
+func (r *RoutingDevice) Overlaps(network string) bool {
+	_, pNet, err := net.ParseCIDR(network)
+	if err != nil {
+		return false
+	}
+	return NetworksOverlap(r.Network(), pNet)
+}
+
+// Check if two networks share any addresses
+func NetworksOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
 func LoadRoutingTable(igetter InterfacesGetter, logger hclog.Logger) (table *RoutingTable, err error) {
 	if logger == nil {
 		logger = hclog.New(&hclog.LoggerOptions{
@@ -99,6 +118,17 @@ func (r *RoutingTable) DeviceByName(name string) *RoutingDevice {
 	return nil
 }
 
+// Devices with networks overlapping the given network
+func (r *RoutingTable) Overlapping(network string) []*RoutingDevice {
+	devices := []*RoutingDevice{}
+	for _, rDev := range r.Devices {
+		if rDev.Overlaps(network) {
+			devices = append(devices, rDev)
+		}
+	}
+	return devices
+}
+
 func getLocalInterfaces() (nifs []NetworkInterface, err error) {
 	interfaces, err := net.Interfaces()
 	if err != nil {
@@ -150,6 +150,34 @@ func TestRoutingDeviceMatch(t *testing.T) {
 	}
 }
 
+func TestRoutingTableOverlapping(t *testing.T) {
+	rt := &RoutingTable{
+		interfaces: func() ([]NetworkInterface, error) {
+			return generateFakeInterfaces(4), nil
+		},
+		logger: defaultUtilityLogger()}
+	err := rt.Load()
+	if err != nil {
+		panic(fmt.Sprintf(
+			"Failed to load interfaces: %s", err))
+	}
+	devs := rt.Overlapping("192.168.2.128/25")
+	if len(devs) != 1 || devs[0].Name != "dev2" {
+		t.Errorf("Expected overlap with dev2, received %v", devs)
+	}
+	devs = rt.Overlapping("192.168.0.0/16")
+	if len(devs) != 4 {
+		t.Errorf("Unexpected number of overlapping devices 4 != %d", len(devs))
+	}
+	devs = rt.Overlapping("10.0.0.0/8")
+	if len(devs) != 0 {
+		t.Errorf("Unexpected overlapping devices %v", devs)
+	}
+	if devs = rt.Overlapping("invalid"); len(devs) != 0 {
+		t.Errorf("Unexpected overlapping devices for invalid network %v", devs)
+	}
+}
+
 func generateFakeInterfaces(num int) (nifs []NetworkInterface) {
 	for i := 0; i < num; i++ {
 		nif := NetworkInterface{
@@ -0,0 +1,115 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build !windows
+// +build !windows
+
+package utility
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+// Unix socket listener which removes the socket when closed
+type UnixListener struct {
+	*net.UnixListener
+	path string
+	once sync.Once
+}
+
+// Close the listener and remove the socket. The socket is only
+// removed by the first close so a socket created at the path
+// after the listener was closed is never removed.
+func (l *UnixListener) Close() error {
+	err := net.ErrClosed
+	l.once.Do(func() {
+		err = l.UnixListener.Close()
+		if rErr := os.Remove(l.path); err == nil && rErr != nil && !os.IsNotExist(rErr) {
+			err = rErr
+		}
+	})
+	return err
+}
+
+// Create a unix socket listener with the given permissions and
+// group owner. A group of -1 leaves the group unchanged. The socket
+// is created within a private directory and linked into place once
+// the permissions have been applied. This prevents the socket from
+// being accessible with the default permissions and prevents the
+// permissions from being applied to any other file. The process
+// umask is not modified. A stale socket at the path is removed.
+func ListenUnix(path string, mode os.FileMode, gid int, logger hclog.Logger) (*UnixListener, error) {
+	if err := RemoveStaleSocket(path, logger); err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	private, err := os.MkdirTemp(dir, ".sock")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(private)
+
+	tmp := filepath.Join(private, "s")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	// The temporary path is removed with the private directory
+	l.SetUnlinkOnClose(false)
+	if gid >= 0 {
+		if err := os.Lchown(tmp, -1, gid); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	if err := os.Chmod(tmp, mode); err != nil {
+		l.Close()
+		return nil, err
+	}
+	// Linking fails if anything was created at the path
+	// after the stale socket was removed
+	if err := os.Link(tmp, path); err != nil {
+		l.Close()
+		return nil, err
+	}
+	logger.Trace("unix socket listener created", "path", path, "mode", mode)
+	return &UnixListener{UnixListener: l, path: path}, nil
+}
+
+// Remove an existing socket file if nothing is listening on it. Any
+// other kind of file at the path is left untouched.
+func RemoveStaleSocket(path string, logger hclog.Logger) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("path exists and is not a socket: %s", path)
+	}
+	if c, err := net.Dial("unix", path); err == nil {
+		c.Close()
+		return errors.New("socket is already in use: " + path)
+	}
+	logger.Debug("removing stale unix socket", "path", path)
+	return os.Remove(path)
+}
+
+// Connect to the local socket of the API
+func DialSocket(ctx context.Context, path string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", path)
+}
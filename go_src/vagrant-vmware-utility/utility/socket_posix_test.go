@@ -0,0 +1,75 @@
+// Copyright IBM Corp. 2021, 2025
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build !windows
+// +build !windows
+
+package utility
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+func TestListenUnix(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api.sock")
+	l, err := ListenUnix(path, 0640, -1, hclog.NewNullLogger())
+	if err != nil {
+		t.Fatalf("Failed to create socket: %s", err)
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat socket: %s", err)
+	}
+	if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0640 {
+		t.Errorf("Invalid socket mode %s", info.Mode())
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Private socket directory was not removed")
+	}
+	conn, err := DialSocket(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Failed to connect to socket: %s", err)
+	}
+	conn.Close()
+
+	if _, err := ListenUnix(path, 0600, -1, hclog.NewNullLogger()); err == nil {
+		t.Errorf("Expected error creating socket in use")
+	}
+	if err := l.Close(); err != nil {
+		t.Errorf("Failed to close listener: %s", err)
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("Socket was not removed on close")
+	}
+}
+
+func TestRemoveStaleSocket(t *testing.T) {
+	dir := t.TempDir()
+	sock := filepath.Join(dir, "in-use.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("Failed to create socket: %s", err)
+	}
+	if err := RemoveStaleSocket(sock, hclog.NewNullLogger()); err == nil {
+		t.Errorf("Expected error removing socket in use")
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+	if err := RemoveStaleSocket(sock, hclog.NewNullLogger()); err != nil {
+		t.Errorf("Failed to remove stale socket: %s", err)
+	}
+
+	file := filepath.Join(dir, "file")
+	os.WriteFile(file, []byte("data"), 0600)
+	if err := RemoveStaleSocket(file, hclog.NewNullLogger()); err == nil {
+		t.Errorf("Expected error removing file which is not a socket")
+	}
+}
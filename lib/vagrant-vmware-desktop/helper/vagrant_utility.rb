@@ -66,7 +66,14 @@ module HashiCorp
           @connection = Net::HTTP.new(host, port)
           @connection.use_ssl = true
           @connection.verify_mode = OpenSSL::SSL::VERIFY_PEER
-          @connection.ca_file = File.join(opts[:certificate_path], "vagrant-utility.crt")
+          # When the utility uses a separate certificate authority the
+          # server certificate is not self signed and cannot be used
+          # to verify the connection
+          ca_path = File.join(opts[:certificate_path], "vagrant-utility.ca.crt")
+          if !File.exist?(ca_path)
+            ca_path = File.join(opts[:certificate_path], "vagrant-utility.crt")
+          end
+          @connection.ca_file = ca_path
           @headers = {
             "Content-Type" => "application/vnd.hashicorp.vagrant.vmware.rest-v1+json",
             "Origin" => "https://#{host}:#{port}",
@@ -86,7 +93,8 @@ module HashiCorp
             )
           end
           begin
-            @connection.key = OpenSSL::PKey::RSA.new(File.read(key_path))
+            # Client keys may be RSA, ECDSA, or Ed25519
+            @connection.key = OpenSSL::PKey.read(File.read(key_path))
           rescue => err
             @logger.debug("key load failure - #{err.class}: #{err}")
             raise Errors::DriverAPIKeyError.new(
@@ -0,0 +1,47 @@
+# Copyright IBM Corp. 2021, 2025
+# SPDX-License-Identifier: MPL-2.0
+
+require "fileutils"
+require "openssl"
+require "tmpdir"
+
+require "vagrant-vmware-desktop/helper/vagrant_utility"
+
+describe HashiCorp::VagrantVMwareDesktop::Helper::VagrantUtility do
+  let(:certificate_path) { Dir.mktmpdir("vagrant-utility") }
+  let(:key) { OpenSSL::PKey::EC.generate("prime256v1") }
+
+  subject { described_class.new("127.0.0.1", 9922, certificate_path: certificate_path) }
+
+  before do
+    cert = OpenSSL::X509::Certificate.new
+    cert.version = 2
+    cert.serial = 1
+    cert.subject = cert.issuer = OpenSSL::X509::Name.parse("/CN=vagrant-utility")
+    cert.public_key = key
+    cert.not_before = Time.now
+    cert.not_after = Time.now + 3600
+    cert.sign(key, OpenSSL::Digest::SHA256.new)
+    File.write(File.join(certificate_path, "vagrant-utility.crt"), cert.to_pem)
+    File.write(File.join(certificate_path, "vagrant-utility.client.crt"), cert.to_pem)
+    File.write(File.join(certificate_path, "vagrant-utility.client.key"), key.private_to_pem)
+  end
+
+  after { FileUtils.rm_rf(certificate_path) }
+
+  it "should load an ECDSA client key" do
+    expect(subject.connection.key).to be_a(OpenSSL::PKey::EC)
+  end
+
+  it "should verify using the server certificate" do
+    expect(subject.connection.ca_file).to eq(File.join(certificate_path, "vagrant-utility.crt"))
+  end
+
+  context "with a separate certificate authority" do
+    before { File.write(File.join(certificate_path, "vagrant-utility.ca.crt"), "") }
+
+    it "should verify using the certificate authority" do
+      expect(subject.connection.ca_file).to eq(File.join(certificate_path, "vagrant-utility.ca.crt"))
+    end
+  end
+end